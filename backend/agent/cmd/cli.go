@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/control"
+	agentconfig "github.com/iwoov/snell-master/backend/pkg/config"
+)
+
+// cliCommands 通过控制套接字与运行中 Agent 交互的子命令。
+var cliCommands = map[string]func(args []string) int{
+	"status": runStatus,
+	"doctor": runDoctor,
+	"logs":   runLogs,
+}
+
+// cliFlags 子命令共用的参数。
+type cliFlags struct {
+	configPath string
+	socketPath string
+	jsonOutput bool
+}
+
+func newCLIFlagSet(name string, withJSON bool) (*flag.FlagSet, *cliFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	opts := &cliFlags{}
+	fs.StringVar(&opts.configPath, "config", "backend/agent/configs/agent.yaml", "path to agent config file")
+	fs.StringVar(&opts.socketPath, "socket", "", "control socket path (default: agent.control_socket from config)")
+	if withJSON {
+		fs.BoolVar(&opts.jsonOutput, "json", false, "print machine-readable JSON")
+	}
+	return fs, opts
+}
+
+// client 优先使用 -socket，否则从配置文件读取套接字路径。
+func (o *cliFlags) client() (*control.Client, error) {
+	if o.socketPath != "" {
+		return control.NewClient(o.socketPath), nil
+	}
+	cfg, err := agentconfig.LoadAgentConfig(o.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return control.NewClient(cfg.Agent.ControlSocket), nil
+}
+
+func runStatus(args []string) int {
+	fs, opts := newCLIFlagSet("status", true)
+	_ = fs.Parse(args)
+	c, err := opts.client()
+	if err != nil {
+		return cliError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status, err := c.Status(ctx)
+	if err != nil {
+		return cliError(err)
+	}
+	if opts.jsonOutput {
+		return printJSON(status)
+	}
+
+	fmt.Printf("Agent:   %s (up %s)\n", status.Version, (time.Duration(status.UptimeSeconds) * time.Second).String())
+	fmt.Printf("Node:    %s\n", status.NodeName)
+	fmt.Printf("Master:  %s\n", status.MasterURL)
+	fmt.Printf("Snell:   %s\n", status.SnellVersion)
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSER\tPORT\tVERSION\tSERVICE\tTRAFFIC")
+	for _, inst := range status.Instances {
+		fmt.Fprintf(tw, "%d\t%s\t%d\tv%d\t%s\t%s\n", inst.ID, inst.Username, inst.Port, inst.Version, inst.ServiceState, formatBytes(inst.TrafficBytes))
+	}
+	tw.Flush()
+	return 0
+}
+
+func runDoctor(args []string) int {
+	fs, opts := newCLIFlagSet("doctor", true)
+	_ = fs.Parse(args)
+	c, err := opts.client()
+	if err != nil {
+		return cliError(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	report, err := c.Doctor(ctx)
+	if err != nil {
+		return cliError(err)
+	}
+
+	if opts.jsonOutput {
+		printJSON(report)
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, check := range report.Checks {
+			fmt.Fprintf(tw, "[%s]\t%s\t%s\n", strings.ToUpper(check.Status), check.Name, check.Detail)
+		}
+		tw.Flush()
+	}
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
+
+func runLogs(args []string) int {
+	fs, opts := newCLIFlagSet("logs", false)
+	lines := fs.Int("n", 100, "number of lines to show")
+	follow := fs.Bool("f", false, "follow log output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snell-agent logs [-n lines] [-f] <instance-id>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	id, err := strconv.ParseUint(fs.Arg(0), 10, 64)
+	if err != nil {
+		return cliError(fmt.Errorf("invalid instance id %q", fs.Arg(0)))
+	}
+	c, err := opts.client()
+	if err != nil {
+		return cliError(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := c.Logs(ctx, uint(id), *lines, *follow, os.Stdout); err != nil {
+		return cliError(err)
+	}
+	return 0
+}
+
+func printJSON(v interface{}) int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return cliError(err)
+	}
+	return 0
+}
+
+func cliError(err error) int {
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	return 1
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
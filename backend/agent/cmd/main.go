@@ -6,21 +6,45 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/agent/internal/control"
 	"github.com/iwoov/snell-master/backend/agent/internal/manager"
 	"github.com/iwoov/snell-master/backend/agent/internal/monitor"
 	"github.com/iwoov/snell-master/backend/agent/internal/scheduler"
 	agentconfig "github.com/iwoov/snell-master/backend/pkg/config"
 	"github.com/iwoov/snell-master/backend/pkg/logger"
+	"github.com/iwoov/snell-master/backend/pkg/utils"
 )
 
+// logRotateInterval 实例日志轮转检查间隔（秒）。
+const logRotateInterval = 300
+
+// tokenPersistMu 串行化令牌写回，回调可能由多个调度任务并发触发。
+var tokenPersistMu sync.Mutex
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := cliCommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	var configPath string
+	var showVersion bool
 	flag.StringVar(&configPath, "config", "backend/agent/configs/agent.yaml", "path to agent config file")
+	flag.BoolVar(&showVersion, "version", false, "print agent version and exit")
 	flag.Parse()
 
+	if showVersion {
+		fmt.Println(scheduler.AgentVersion)
+		return
+	}
+
 	cfg, err := agentconfig.LoadAgentConfig(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
@@ -32,6 +56,7 @@ func main() {
 		os.Exit(1)
 	}
 
+	startedAt := time.Now()
 	log := logger.WithModule("main")
 	log.Infof("Starting Snell Agent node=%s location=%s", cfg.Agent.NodeName, cfg.Agent.Location)
 
@@ -40,8 +65,29 @@ func main() {
 	}
 
 	masterClient := client.NewMasterClient(cfg.Agent.MasterURL, cfg.Agent.APIToken)
+	if cfg.Agent.APIToken == "" {
+		if err := enroll(cfg, configPath, masterClient); err != nil {
+			log.Fatalf("enroll node: %v", err)
+		}
+	}
+	masterClient.SetTokenRotatedHook(func(string) {
+		persistRotatedToken(cfg, configPath, masterClient)
+	})
+	var agentUpdater *manager.AgentUpdater
+	if cfg.Agent.AutoUpdate {
+		agentUpdater, err = newAgentUpdater(cfg, masterClient)
+		if err != nil {
+			log.Warnf("Agent self-update disabled: %v", err)
+		}
+	}
 	instanceMgr := manager.NewInstanceManager(cfg.Agent.InstanceDir, cfg.Agent.SnellBinary, cfg.Agent.PortRangeStart, cfg.Agent.PortRangeEnd)
-	systemMonitor := monitor.NewSystemMonitor()
+	systemMonitor := monitor.NewSystemMonitor(monitor.WithMetrics(monitor.MetricSet{
+		CPU:     cfg.Monitor.EnableCPU,
+		Memory:  cfg.Monitor.EnableMemory,
+		Disk:    cfg.Monitor.EnableDisk,
+		Network: cfg.Monitor.EnableNetwork,
+		Load:    cfg.Monitor.EnableLoad,
+	}))
 	trafficMonitor := monitor.NewTrafficMonitor(nil)
 	snellInstaller := manager.NewSnellInstaller(cfg.Agent.SnellBinary, masterClient)
 
@@ -59,8 +105,36 @@ func main() {
 	}
 
 	syncScheduler := scheduler.NewSyncScheduler(masterClient, instanceMgr)
+	syncScheduler.SetSnellUpgrader(manager.NewSnellUpgrader(snellInstaller, instanceMgr, masterClient))
 	heartbeatScheduler := scheduler.NewHeartbeatScheduler(masterClient, instanceMgr, systemMonitor)
+	heartbeatScheduler.SetSnellVersionProvider(snellInstaller.CachedVersion)
 	trafficScheduler := scheduler.NewTrafficScheduler(masterClient, instanceMgr, trafficMonitor)
+	updateScheduler := scheduler.NewUpdateScheduler(agentUpdater)
+	probeScheduler := scheduler.NewProbeScheduler(masterClient)
+	logScheduler := scheduler.NewLogScheduler(masterClient, instanceMgr, manager.LogRotationPolicy{
+		MaxSize:    int64(cfg.Agent.InstanceLogMaxSizeMB) << 20,
+		MaxAge:     time.Duration(cfg.Agent.InstanceLogMaxAgeDays) * 24 * time.Hour,
+		MaxBackups: cfg.Agent.InstanceLogMaxBackups,
+	})
+	if agentUpdater != nil {
+		heartbeatScheduler.SetSuccessHook(agentUpdater.Confirm)
+	}
+
+	// Master 下发的运行时设置在独立协程中应用，local_overrides 中的项保持本地值
+	settingsTargets := scheduler.SettingsTargets{
+		Sync:          syncScheduler,
+		Heartbeat:     heartbeatScheduler,
+		Traffic:       trafficScheduler,
+		SystemMonitor: systemMonitor,
+	}
+	if agentUpdater != nil {
+		settingsTargets.Update = updateScheduler
+	}
+	settingsApplier := scheduler.NewSettingsApplier(scheduler.LocalRuntimeSettings(cfg), cfg.Agent.LocalOverrides, settingsTargets)
+	if err := settingsApplier.Start(); err != nil {
+		log.Fatalf("start settings applier: %v", err)
+	}
+	syncScheduler.SetSettingsHook(settingsApplier.Submit)
 
 	if err := syncScheduler.Start(cfg.Agent.ConfigSyncInterval); err != nil {
 		log.Fatalf("start sync scheduler: %v", err)
@@ -68,8 +142,40 @@ func main() {
 	if err := heartbeatScheduler.Start(cfg.Agent.HeartbeatInterval); err != nil {
 		log.Fatalf("start heartbeat scheduler: %v", err)
 	}
-	if err := trafficScheduler.Start(cfg.Agent.TrafficReportInterval); err != nil {
-		log.Fatalf("start traffic scheduler: %v", err)
+	if cfg.Monitor.EnableTraffic {
+		if err := trafficScheduler.Start(cfg.Agent.TrafficReportInterval); err != nil {
+			log.Fatalf("start traffic scheduler: %v", err)
+		}
+	} else {
+		log.Info("Traffic reporting disabled by monitor.enable_traffic")
+	}
+	if err := logScheduler.Start(logRotateInterval); err != nil {
+		log.Fatalf("start log scheduler: %v", err)
+	}
+	if err := probeScheduler.Start(); err != nil {
+		log.Fatalf("start probe scheduler: %v", err)
+	}
+	if agentUpdater != nil {
+		if err := updateScheduler.Start(cfg.Agent.UpdateCheckInterval); err != nil {
+			log.Fatalf("start update scheduler: %v", err)
+		}
+	}
+
+	controlDeps := control.Deps{
+		Version:      scheduler.AgentVersion,
+		NodeName:     cfg.Agent.NodeName,
+		MasterURL:    cfg.Agent.MasterURL,
+		InstanceMgr:  instanceMgr,
+		Installer:    snellInstaller,
+		MasterClient: masterClient,
+		StartedAt:    startedAt,
+	}
+	if cfg.Monitor.EnableTraffic {
+		controlDeps.TrafficMonitor = trafficMonitor
+	}
+	controlServer := control.NewServer(cfg.Agent.ControlSocket, controlDeps)
+	if err := controlServer.Start(); err != nil {
+		log.Warnf("Control socket disabled: %v", err)
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
@@ -77,15 +183,97 @@ func main() {
 	stop()
 
 	log.Info("Shutting down Snell Agent...")
+	controlServer.Stop()
+	settingsApplier.Stop()
+	probeScheduler.Stop()
+	logScheduler.Stop()
+	updateScheduler.Stop()
 	trafficScheduler.Stop()
 	heartbeatScheduler.Stop()
 	syncScheduler.Stop()
 
-	for _, inst := range instanceMgr.GetAllInstances() {
-		if err := instanceMgr.StopInstance(inst); err != nil {
-			log.Errorf("stop instance %d: %v", inst.ID, err)
+	// 升级或回滚触发的重启保留实例运行，避免中断用户连接
+	if agentUpdater != nil && agentUpdater.Restarting() {
+		log.Info("Restarting for agent update, instances left running")
+	} else {
+		for _, inst := range instanceMgr.GetAllInstances() {
+			if err := instanceMgr.StopInstance(inst); err != nil {
+				log.Errorf("stop instance %d: %v", inst.ID, err)
+			}
 		}
 	}
 
 	log.Info("Snell Agent stopped")
 }
+
+// newAgentUpdater 定位当前二进制并处理上次未确认的升级。
+func newAgentUpdater(cfg *agentconfig.AgentConfig, masterClient *client.MasterClient) (*manager.AgentUpdater, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locate agent binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+	updater := manager.NewAgentUpdater(scheduler.AgentVersion, exePath, cfg.Agent.ServiceName, masterClient)
+	if err := updater.Recover(); err != nil {
+		return nil, fmt.Errorf("recover agent update: %w", err)
+	}
+	return updater, nil
+}
+
+// persistRotatedToken 将 Master 轮换下发的新令牌写回配置文件，保证重启后仍可认证。
+// 写入客户端当前使用的令牌，并发回调的先后顺序不会让较旧的令牌覆盖较新的令牌。
+func persistRotatedToken(cfg *agentconfig.AgentConfig, configPath string, masterClient *client.MasterClient) {
+	tokenPersistMu.Lock()
+	defer tokenPersistMu.Unlock()
+	log := logger.WithModule("main")
+	token := masterClient.APIToken()
+	if token == cfg.Agent.APIToken {
+		return
+	}
+	if err := agentconfig.UpdateAgentConfigValues(configPath, "agent", map[string]string{"api_token": token}); err != nil {
+		log.Errorf("Persist rotated api token failed: %v", err)
+		return
+	}
+	cfg.Agent.APIToken = token
+	log.Info("API token rotated by Master, config updated")
+}
+
+// enroll 使用注册令牌换取永久 API Token，并写回配置文件以便下次启动直接使用。
+func enroll(cfg *agentconfig.AgentConfig, configPath string, masterClient *client.MasterClient) error {
+	log := logger.WithModule("main")
+
+	hostname, err := utils.GetHostname()
+	if err != nil {
+		log.Warnf("get hostname failed: %v", err)
+	}
+	publicIP, err := utils.GetPublicIP()
+	if err != nil {
+		return fmt.Errorf("get public ip: %w", err)
+	}
+
+	result, err := masterClient.Enroll(client.EnrollRequest{
+		Token:       cfg.Agent.EnrollmentToken,
+		NodeName:    cfg.Agent.NodeName,
+		Hostname:    hostname,
+		PublicIP:    publicIP,
+		Location:    cfg.Agent.Location,
+		CountryCode: cfg.Agent.CountryCode,
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg.Agent.APIToken = result.APIToken
+	cfg.Agent.EnrollmentToken = ""
+	if err := agentconfig.UpdateAgentConfigValues(configPath, "agent", map[string]string{
+		"api_token":        result.APIToken,
+		"enrollment_token": "",
+	}); err != nil {
+		return fmt.Errorf("persist api token: %w", err)
+	}
+
+	log.Infof("Node enrolled as %s (id=%d)", result.NodeName, result.NodeID)
+	return nil
+}
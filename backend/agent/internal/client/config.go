@@ -14,19 +14,46 @@ type InstanceConfig struct {
 	PSK      string `json:"psk"`
 	Version  int    `json:"version"`
 	OBFS     string `json:"obfs,omitempty"`
+	Listen   string `json:"listen,omitempty"`
+}
+
+// AgentSettings Master 下发的运行时设置，字段为空表示沿用本地配置。
+type AgentSettings struct {
+	HeartbeatInterval     *int                  `json:"heartbeat_interval,omitempty"`
+	ConfigSyncInterval    *int                  `json:"config_sync_interval,omitempty"`
+	TrafficReportInterval *int                  `json:"traffic_report_interval,omitempty"`
+	UpdateCheckInterval   *int                  `json:"update_check_interval,omitempty"`
+	LogLevel              *string               `json:"log_level,omitempty"`
+	Monitor               *AgentMonitorSettings `json:"monitor,omitempty"`
+}
+
+// AgentMonitorSettings Master 下发的监控开关。
+type AgentMonitorSettings struct {
+	EnableCPU     *bool `json:"enable_cpu,omitempty"`
+	EnableMemory  *bool `json:"enable_memory,omitempty"`
+	EnableDisk    *bool `json:"enable_disk,omitempty"`
+	EnableNetwork *bool `json:"enable_network,omitempty"`
+	EnableLoad    *bool `json:"enable_load,omitempty"`
+	EnableTraffic *bool `json:"enable_traffic,omitempty"`
+}
+
+// ConfigData 配置同步的内容，Settings 为空表示 Master 未配置运行时设置。
+// Paused 为 true 时节点处于要求停止实例的维护期，Instances 仍为完整列表。
+type ConfigData struct {
+	Instances []InstanceConfig `json:"instances"`
+	Settings  *AgentSettings   `json:"settings,omitempty"`
+	Paused    bool             `json:"paused,omitempty"`
 }
 
 // ConfigResponse 对应配置拉取接口的响应结构。
 type ConfigResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Data    struct {
-		Instances []InstanceConfig `json:"instances"`
-	} `json:"data"`
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    ConfigData `json:"data"`
 }
 
-// FetchConfig 从 Master 拉取实例配置。
-func (c *MasterClient) FetchConfig() ([]InstanceConfig, error) {
+// FetchConfig 从 Master 拉取实例配置与运行时设置。
+func (c *MasterClient) FetchConfig() (*ConfigData, error) {
 	respData, err := c.Get("/api/agent/config")
 	if err != nil {
 		return nil, err
@@ -41,5 +68,5 @@ func (c *MasterClient) FetchConfig() ([]InstanceConfig, error) {
 		return nil, fmt.Errorf("fetch config failed: %s", resp.Message)
 	}
 
-	return resp.Data.Instances, nil
+	return &resp.Data, nil
 }
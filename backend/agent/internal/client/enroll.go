@@ -0,0 +1,51 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EnrollRequest 使用注册令牌向 Master 自助注册节点。
+type EnrollRequest struct {
+	Token       string `json:"token"`
+	NodeName    string `json:"node_name,omitempty"`
+	Hostname    string `json:"hostname"`
+	PublicIP    string `json:"public_ip"`
+	Location    string `json:"location"`
+	CountryCode string `json:"country_code"`
+}
+
+// EnrollResult 注册成功后 Master 返回的节点信息。
+type EnrollResult struct {
+	NodeID   uint   `json:"node_id"`
+	NodeName string `json:"node_name"`
+	APIToken string `json:"api_token"`
+}
+
+type enrollResponse struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *EnrollResult `json:"data"`
+}
+
+// Enroll 调用 /api/agent/enroll 换取永久 API Token，成功后客户端自动使用新 Token。
+func (c *MasterClient) Enroll(req EnrollRequest) (*EnrollResult, error) {
+	data, err := c.Post("/api/agent/enroll", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp enrollResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal enroll response: %w", err)
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("enroll failed: %s", resp.Message)
+	}
+	if resp.Data == nil || resp.Data.APIToken == "" {
+		return nil, fmt.Errorf("enroll response has no api token")
+	}
+
+	c.SetAPIToken(resp.Data.APIToken)
+	return resp.Data, nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 实例事件类型。
+const (
+	EventApplyFailed    = "apply_failed"
+	EventRolledBack     = "rolled_back"
+	EventRollbackFailed = "rollback_failed"
+)
+
+// InstanceEvent 描述实例生命周期中需要告知 Master 的事件。
+type InstanceEvent struct {
+	InstanceID uint      `json:"instance_id"`
+	Type       string    `json:"type"`
+	Message    string    `json:"message"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// EventReportRequest 批量上报实例事件。
+type EventReportRequest struct {
+	Events []InstanceEvent `json:"events"`
+}
+
+// EventReportResponse 表示事件上报的响应。
+type EventReportResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ReportEvents 向 Master 上报实例事件。
+func (c *MasterClient) ReportEvents(events []InstanceEvent) error {
+	req := EventReportRequest{Events: events}
+
+	data, err := c.Post("/api/agent/events", req)
+	if err != nil {
+		return err
+	}
+
+	var resp EventReportResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("unmarshal event response: %w", err)
+	}
+
+	if resp.Code != 0 {
+		return fmt.Errorf("event report failed: %s", resp.Message)
+	}
+
+	return nil
+}
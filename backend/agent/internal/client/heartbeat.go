@@ -6,11 +6,25 @@ import (
 )
 
 // HeartbeatRequest 包含上报的节点心跳信息。
+// 可选指标使用指针，未采集时省略，Master 将保留原值。
 type HeartbeatRequest struct {
-	CPUUsage      int    `json:"cpu_usage"`
-	MemoryUsage   int    `json:"memory_usage"`
-	InstanceCount int    `json:"instance_count"`
-	Version       string `json:"version"`
+	CPUUsage       *int     `json:"cpu_usage,omitempty"`
+	MemoryUsage    *int     `json:"memory_usage,omitempty"`
+	DiskUsage      *int     `json:"disk_usage,omitempty"`
+	NetworkRxRate  *uint64  `json:"network_rx_rate,omitempty"`
+	NetworkTxRate  *uint64  `json:"network_tx_rate,omitempty"`
+	Load1          *float64 `json:"load1,omitempty"`
+	Load5          *float64 `json:"load5,omitempty"`
+	Load15         *float64 `json:"load15,omitempty"`
+	UptimeSeconds  uint64   `json:"uptime_seconds,omitempty"`
+	KernelVersion  string   `json:"kernel_version,omitempty"`
+	SnellVersion   string   `json:"snell_version,omitempty"`
+	PortRangeStart int      `json:"port_range_start,omitempty"`
+	PortRangeEnd   int      `json:"port_range_end,omitempty"`
+	InstanceCount  int      `json:"instance_count"`
+	Version        string   `json:"version"`
+
+	HeartbeatInterval int `json:"heartbeat_interval,omitempty"` // 当前心跳间隔（秒），Master 据此计算在线率
 }
 
 // HeartbeatResponse 表示心跳接口的响应。
@@ -19,15 +33,18 @@ type HeartbeatResponse struct {
 	Message string `json:"message"`
 }
 
-// ReportHeartbeat 将节点心跳上报给 Master。
+// ReportHeartbeat 将节点基础心跳上报给 Master。
 func (c *MasterClient) ReportHeartbeat(cpuUsage, memUsage, instanceCount int, version string) error {
-	req := HeartbeatRequest{
-		CPUUsage:      cpuUsage,
-		MemoryUsage:   memUsage,
+	return c.SendHeartbeat(HeartbeatRequest{
+		CPUUsage:      &cpuUsage,
+		MemoryUsage:   &memUsage,
 		InstanceCount: instanceCount,
 		Version:       version,
-	}
+	})
+}
 
+// SendHeartbeat 上报完整的心跳信息。
+func (c *MasterClient) SendHeartbeat(req HeartbeatRequest) error {
 	data, err := c.Post("/api/agent/heartbeat", req)
 	if err != nil {
 		return err
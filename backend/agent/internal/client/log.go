@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// LogRequest Master 下发的实例日志读取请求，Filter 为正则表达式。
+type LogRequest struct {
+	ID         string `json:"id"`
+	InstanceID uint   `json:"instance_id"`
+	Lines      int    `json:"lines"`
+	Filter     string `json:"filter,omitempty"`
+}
+
+// LogResponse 回传给 Master 的日志内容。
+type LogResponse struct {
+	RequestID string   `json:"request_id"`
+	Lines     []string `json:"lines"`
+	Truncated bool     `json:"truncated"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type logRequestsResponse struct {
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Data    []LogRequest `json:"data"`
+}
+
+// PollLogRequests 长轮询待处理的日志请求，Master 最多等待 waitSeconds 秒。
+func (c *MasterClient) PollLogRequests(waitSeconds int) ([]LogRequest, error) {
+	data, err := c.Get(fmt.Sprintf("/api/agent/log-requests?wait=%d", waitSeconds))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp logRequestsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal log requests: %w", err)
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("poll log requests failed: %s", resp.Message)
+	}
+	return resp.Data, nil
+}
+
+// ReportLogResponse 回传日志读取结果。
+func (c *MasterClient) ReportLogResponse(resp LogResponse) error {
+	data, err := c.Post("/api/agent/log-responses", resp)
+	if err != nil {
+		return err
+	}
+
+	var result StatusReportResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("unmarshal log response result: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("report log response failed: %s", result.Message)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,12 +15,18 @@ const (
 	defaultTimeout    = 10 * time.Second
 	defaultMaxRetries = 1
 	maxErrorBodyBytes = 1024
+	// maxResponseBodyBytes 限制成功响应的大小，配置类响应可能远超错误信息长度。
+	maxResponseBodyBytes = 4 << 20
+	// rotatedTokenHeader Master 在令牌轮换宽限期内通过该响应头下发新令牌。
+	rotatedTokenHeader = "X-Rotated-API-Token"
 )
 
 // MasterClient 封装了与 Master 服务的 HTTP 通信逻辑。
 type MasterClient struct {
 	baseURL    string
+	tokenMu    sync.RWMutex
 	apiToken   string
+	onRotated  func(token string)
 	httpClient *http.Client
 	maxRetries int
 }
@@ -42,6 +49,27 @@ func (c *MasterClient) SetHTTPClient(client *http.Client) {
 	c.httpClient = client
 }
 
+// SetAPIToken 替换请求使用的 API Token，例如自助注册成功后。
+func (c *MasterClient) SetAPIToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.apiToken = strings.TrimSpace(token)
+}
+
+// APIToken 返回当前使用的 API Token。
+func (c *MasterClient) APIToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.apiToken
+}
+
+// SetTokenRotatedHook 设置收到轮换后的新令牌时的回调，用于写回配置文件。
+func (c *MasterClient) SetTokenRotatedHook(fn func(token string)) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.onRotated = fn
+}
+
 // SetMaxRetries 设置网络错误时的最大重试次数。
 func (c *MasterClient) SetMaxRetries(max int) {
 	if max < 0 {
@@ -114,6 +142,7 @@ func (c *MasterClient) doRequest(method, endpoint string, payload []byte) ([]byt
 		if err != nil {
 			return nil, err
 		}
+		c.applyRotatedToken(resp.Header.Get(rotatedTokenHeader))
 		return data, nil
 	}
 
@@ -141,24 +170,45 @@ func (c *MasterClient) buildURL(endpoint string) string {
 }
 
 func (c *MasterClient) addAuthHeader(req *http.Request) {
-	if req == nil || c.apiToken == "" {
+	if req == nil {
 		return
 	}
-	req.Header.Set("X-API-Token", c.apiToken)
+	if token := c.APIToken(); token != "" {
+		req.Header.Set("X-API-Token", token)
+	}
+}
+
+// applyRotatedToken 切换到 Master 下发的新令牌并触发回调。
+func (c *MasterClient) applyRotatedToken(token string) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return
+	}
+	c.tokenMu.Lock()
+	if token == c.apiToken {
+		c.tokenMu.Unlock()
+		return
+	}
+	c.apiToken = token
+	hook := c.onRotated
+	c.tokenMu.Unlock()
+	if hook != nil {
+		hook(token)
+	}
 }
 
 func (c *MasterClient) handleResponse(resp *http.Response) ([]byte, error) {
 	defer resp.Body.Close()
 
-	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
 		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: string(data)}
 	}
 
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
 	return data, nil
 }
 
@@ -35,6 +35,37 @@ func TestMasterClientGet(t *testing.T) {
 	}
 }
 
+func TestMasterClientAppliesRotatedToken(t *testing.T) {
+	t.Parallel()
+
+	var seen []string
+	server := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("X-API-Token"))
+		if r.Header.Get("X-API-Token") == "old" {
+			w.Header().Set("X-Rotated-API-Token", "new")
+		}
+		_, _ = w.Write([]byte(`{"code":0}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewMasterClient(server.URL, "old")
+	var rotated []string
+	client.SetTokenRotatedHook(func(token string) {
+		rotated = append(rotated, token)
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get("/api/agent/config"); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+	if strings.Join(seen, ",") != "old,new" {
+		t.Fatalf("unexpected tokens sent: %v", seen)
+	}
+	if len(rotated) != 1 || rotated[0] != "new" || client.APIToken() != "new" {
+		t.Fatalf("unexpected rotation: hook=%v token=%s", rotated, client.APIToken())
+	}
+}
+
 func TestMasterClientPostHTTPError(t *testing.T) {
 	t.Parallel()
 
@@ -65,17 +96,22 @@ func TestFetchConfig(t *testing.T) {
 		if r.URL.Path != "/api/agent/config" {
 			t.Fatalf("unexpected path: %s", r.URL.Path)
 		}
-		_, _ = w.Write([]byte(`{"code":0,"message":"ok","data":{"instances":[{"id":1,"user_id":10,"username":"user","port":1234,"psk":"psk","version":1}]}}`))
+		_, _ = w.Write([]byte(`{"code":0,"message":"ok","data":{"instances":[{"id":1,"user_id":10,"username":"user","port":1234,"psk":"psk","version":1}],"settings":{"traffic_report_interval":120,"monitor":{"enable_disk":false}}}}`))
 	}))
 	t.Cleanup(server.Close)
 
 	client := NewMasterClient(server.URL, "token")
-	configs, err := client.FetchConfig()
+	config, err := client.FetchConfig()
 	if err != nil {
 		t.Fatalf("FetchConfig() error = %v", err)
 	}
-	if len(configs) != 1 || configs[0].ID != 1 {
-		t.Fatalf("unexpected configs: %#v", configs)
+	if len(config.Instances) != 1 || config.Instances[0].ID != 1 {
+		t.Fatalf("unexpected configs: %#v", config.Instances)
+	}
+	settings := config.Settings
+	if settings == nil || settings.TrafficReportInterval == nil || *settings.TrafficReportInterval != 120 ||
+		settings.Monitor == nil || settings.Monitor.EnableDisk == nil || *settings.Monitor.EnableDisk {
+		t.Fatalf("unexpected settings: %#v", settings)
 	}
 }
 
@@ -108,7 +144,7 @@ func TestReportHeartbeat(t *testing.T) {
 	if err := client.ReportHeartbeat(10, 20, 3, "v1.0"); err != nil {
 		t.Fatalf("ReportHeartbeat() error = %v", err)
 	}
-	if body.CPUUsage != 10 || body.MemoryUsage != 20 || body.InstanceCount != 3 || body.Version != "v1.0" {
+	if body.CPUUsage == nil || *body.CPUUsage != 10 || body.MemoryUsage == nil || *body.MemoryUsage != 20 || body.InstanceCount != 3 || body.Version != "v1.0" {
 		t.Fatalf("unexpected body: %#v", body)
 	}
 }
@@ -176,6 +212,36 @@ func TestGetSnellConfig(t *testing.T) {
 	}
 }
 
+func TestEnroll(t *testing.T) {
+	t.Parallel()
+
+	var body EnrollRequest
+	server := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/agent/enroll" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-API-Token"); got != "" {
+			t.Fatalf("enroll should not send api token, got %s", got)
+		}
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		_, _ = w.Write([]byte(`{"code":0,"message":"success","data":{"node_id":7,"node_name":"hk-01","api_token":"permanent"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewMasterClient(server.URL, "")
+	result, err := client.Enroll(EnrollRequest{Token: "join", Hostname: "hk-01", PublicIP: "1.2.3.4"})
+	if err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+	if result.NodeID != 7 || body.Token != "join" || body.PublicIP != "1.2.3.4" {
+		t.Fatalf("unexpected enroll result %#v body %#v", result, body)
+	}
+	if client.APIToken() != "permanent" {
+		t.Fatalf("client token not updated: %s", client.APIToken())
+	}
+}
+
 func newTestHTTPServer(t *testing.T, handler http.Handler) *httptest.Server {
 	t.Helper()
 
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProbeTarget 需要协助探测的实例端口。
+type ProbeTarget struct {
+	InstanceID uint   `json:"instance_id"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+}
+
+// ProbeTargets Master 下发的探测任务，Enabled 为 false 表示未开启 Agent 协助探测。
+type ProbeTargets struct {
+	Enabled         bool          `json:"enabled"`
+	IntervalSeconds int           `json:"interval_seconds"`
+	TimeoutMs       int64         `json:"timeout_ms"`
+	Targets         []ProbeTarget `json:"targets"`
+}
+
+// ProbeResult 单次 TCP 探测结果，Host 与探测目标一致。
+type ProbeResult struct {
+	InstanceID uint   `json:"instance_id"`
+	Host       string `json:"host"`
+	Reachable  bool   `json:"reachable"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ProbeReportRequest 批量上报探测结果。
+type ProbeReportRequest struct {
+	Results []ProbeResult `json:"results"`
+}
+
+type probeTargetsResponse struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *ProbeTargets `json:"data"`
+}
+
+// GetProbeTargets 获取需要本节点协助探测的实例。
+func (c *MasterClient) GetProbeTargets() (*ProbeTargets, error) {
+	data, err := c.Get("/api/agent/probe-targets")
+	if err != nil {
+		return nil, err
+	}
+
+	var resp probeTargetsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal probe targets: %w", err)
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("get probe targets failed: %s", resp.Message)
+	}
+	if resp.Data == nil {
+		return &ProbeTargets{}, nil
+	}
+	return resp.Data, nil
+}
+
+// ReportProbeResults 上报探测结果。
+func (c *MasterClient) ReportProbeResults(results []ProbeResult) error {
+	data, err := c.Post("/api/agent/probe-results", ProbeReportRequest{Results: results})
+	if err != nil {
+		return err
+	}
+
+	var resp StatusReportResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("unmarshal probe report response: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("probe report failed: %s", resp.Message)
+	}
+	return nil
+}
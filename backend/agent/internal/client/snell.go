@@ -10,6 +10,24 @@ type SnellConfig struct {
 	Version      string            `json:"version"`
 	BaseURL      string            `json:"base_url"`
 	DownloadURLs map[string]string `json:"download_urls"`
+	Checksums    map[string]string `json:"checksums"`
+}
+
+// Snell Server 升级状态。
+const (
+	SnellUpgradeUpgrading  = "upgrading"
+	SnellUpgradeSucceeded  = "succeeded"
+	SnellUpgradeFailed     = "failed"
+	SnellUpgradeRolledBack = "rolled_back"
+)
+
+// SnellUpgradeReport 上报 Snell Server 升级进度，Done/Total 为已重启/需重启的实例数。
+type SnellUpgradeReport struct {
+	Version string `json:"version"`
+	Status  string `json:"status"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Message string `json:"message"`
 }
 
 type snellConfigResponse struct {
@@ -38,3 +56,20 @@ func (c *MasterClient) GetSnellConfig() (*SnellConfig, error) {
 	}
 	return resp.Data, nil
 }
+
+// ReportSnellUpgrade 向 Master 上报 Snell Server 升级进度。
+func (c *MasterClient) ReportSnellUpgrade(report SnellUpgradeReport) error {
+	data, err := c.Post("/api/agent/snell-upgrade", report)
+	if err != nil {
+		return err
+	}
+
+	var resp StatusReportResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("unmarshal snell upgrade response: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("snell upgrade report failed: %s", resp.Message)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Agent 自升级状态。
+const (
+	UpdateStatusUpdating   = "updating"
+	UpdateStatusSucceeded  = "succeeded"
+	UpdateStatusFailed     = "failed"
+	UpdateStatusRolledBack = "rolled_back"
+)
+
+// AgentUpdate 描述 Master 发布的目标 Agent 版本，Version 为空表示无需升级。
+// 目标版本低于当前版本时，只有 AllowDowngrade 为真才会降级。
+type AgentUpdate struct {
+	Version        string `json:"version"`
+	DownloadURL    string `json:"download_url"`
+	SHA256         string `json:"sha256"`
+	AllowDowngrade bool   `json:"allow_downgrade"`
+}
+
+// UpdateStatusRequest 上报 Agent 自升级进度。
+type UpdateStatusRequest struct {
+	Version string `json:"version"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type agentUpdateResponse struct {
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Data    *AgentUpdate `json:"data"`
+}
+
+// GetAgentUpdate 查询当前节点在指定架构下的目标 Agent 版本。
+func (c *MasterClient) GetAgentUpdate(arch string) (*AgentUpdate, error) {
+	data, err := c.Get("/api/agent/update?arch=" + url.QueryEscape(arch))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp agentUpdateResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("unmarshal update response: %w", err)
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("get agent update failed: %s", resp.Message)
+	}
+	if resp.Data == nil {
+		return &AgentUpdate{}, nil
+	}
+	return resp.Data, nil
+}
+
+// ReportUpdateStatus 向 Master 上报 Agent 自升级进度。
+func (c *MasterClient) ReportUpdateStatus(req UpdateStatusRequest) error {
+	data, err := c.Post("/api/agent/update-status", req)
+	if err != nil {
+		return err
+	}
+
+	var resp StatusReportResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("unmarshal update status response: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("update status report failed: %s", resp.Message)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client 通过 Unix 套接字访问运行中的 Agent。
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient 创建连接到指定套接字的客户端。
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{httpClient: &http.Client{Transport: transport}}
+}
+
+// Status 查询守护进程状态。
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.getJSON(ctx, "/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Doctor 执行诊断。
+func (c *Client) Doctor(ctx context.Context) (*DoctorReport, error) {
+	var report DoctorReport
+	if err := c.getJSON(ctx, "/doctor", &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Logs 将实例日志最后 lines 行写入 w，follow 为 true 时持续输出直到 ctx 取消。
+func (c *Client) Logs(ctx context.Context, id uint, lines int, follow bool, w io.Writer) error {
+	query := url.Values{"lines": {strconv.Itoa(lines)}}
+	if follow {
+		query.Set("follow", "1")
+	}
+	resp, err := c.get(ctx, fmt.Sprintf("/logs/%d?%s", id, query.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(w, resp.Body); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://agent"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connect to agent: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var body struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &body) != nil || body.Error == "" {
+			body.Error = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("agent returned %d: %s", resp.StatusCode, body.Error)
+	}
+	return resp, nil
+}
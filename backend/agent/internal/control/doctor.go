@@ -0,0 +1,125 @@
+package control
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/manager"
+	"github.com/iwoov/snell-master/backend/pkg/utils"
+)
+
+// 诊断项结果级别。
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Check 单项诊断结果。
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// DoctorReport 诊断报告。
+type DoctorReport struct {
+	Checks []Check `json:"checks"`
+}
+
+// Failed 报告中是否存在失败项。
+func (r *DoctorReport) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleDoctor(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	writeJSON(w, http.StatusOK, s.doctor(ctx))
+}
+
+func (s *Server) doctor(ctx context.Context) *DoctorReport {
+	report := &DoctorReport{}
+	add := func(name, status, detail string) {
+		report.Checks = append(report.Checks, Check{Name: name, Status: status, Detail: detail})
+	}
+
+	if version, err := manager.CheckSystemd(); err != nil {
+		add("systemd", CheckFail, err.Error())
+	} else {
+		add("systemd", CheckOK, version)
+	}
+
+	switch {
+	case s.deps.TrafficMonitor == nil:
+		add("nftables", CheckWarn, "traffic accounting disabled")
+	default:
+		if err := s.deps.TrafficMonitor.Check(ctx); err != nil {
+			add("nftables", CheckFail, err.Error())
+		} else {
+			add("nftables", CheckOK, "table inet snell present")
+		}
+	}
+
+	if s.deps.Installer != nil {
+		if !s.deps.Installer.IsInstalled() {
+			add("snell", CheckFail, "binary not found at "+s.deps.Installer.BinaryPath)
+		} else if version, err := s.deps.Installer.GetVersion(ctx); err != nil {
+			add("snell", CheckFail, err.Error())
+		} else {
+			add("snell", CheckOK, version)
+		}
+	}
+
+	if s.deps.InstanceMgr != nil {
+		s.checkPorts(add)
+	}
+	if s.deps.MasterClient != nil {
+		s.checkMaster(add)
+	}
+	return report
+}
+
+// checkPorts 检查实例端口是否冲突、超出范围或被其他进程占用。
+func (s *Server) checkPorts(add func(name, status, detail string)) {
+	problems := s.deps.InstanceMgr.PortConflicts()
+	for _, inst := range s.deps.InstanceMgr.GetAllInstances() {
+		// 运行中的实例自身占用端口，仅检查未运行实例的端口
+		if s.deps.InstanceMgr.ServiceState(inst.ID) == "active" {
+			continue
+		}
+		if !utils.IsPortAvailable(inst.Port) {
+			problems = append(problems, fmt.Sprintf("port %d of stopped instance %d is held by another process", inst.Port, inst.ID))
+		}
+	}
+	if len(problems) > 0 {
+		add("ports", CheckFail, strings.Join(problems, "; "))
+		return
+	}
+	start, end := s.deps.InstanceMgr.PortRange()
+	add("ports", CheckOK, fmt.Sprintf("no conflicts in %d-%d", start, end))
+}
+
+// checkMaster 检查 Master 是否可达以及 API Token 是否有效。
+func (s *Server) checkMaster(add func(name, status, detail string)) {
+	started := time.Now()
+	if _, err := s.deps.MasterClient.Get("/api/ping"); err != nil {
+		add("master", CheckFail, err.Error())
+		return
+	}
+	add("master", CheckOK, fmt.Sprintf("%s reachable in %s", s.deps.MasterURL, time.Since(started).Round(time.Millisecond)))
+
+	if _, err := s.deps.MasterClient.GetSnellConfig(); err != nil {
+		add("api_token", CheckFail, err.Error())
+		return
+	}
+	add("api_token", CheckOK, "accepted by master")
+}
@@ -0,0 +1,140 @@
+package control
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLogLines  = 100
+	maxLogLines      = 10000
+	logPollInterval  = 500 * time.Millisecond
+	tailReadBlockLen = 64 * 1024
+)
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid instance id")
+		return
+	}
+	if s.deps.InstanceMgr == nil {
+		writeError(w, http.StatusServiceUnavailable, "instance manager unavailable")
+		return
+	}
+	if _, ok := s.deps.InstanceMgr.GetInstance(uint(id)); !ok {
+		writeError(w, http.StatusNotFound, "instance not found")
+		return
+	}
+	lines := defaultLogLines
+	if raw := r.URL.Query().Get("lines"); raw != "" {
+		lines, err = strconv.Atoi(raw)
+		if err != nil || lines < 0 {
+			writeError(w, http.StatusBadRequest, "invalid lines")
+			return
+		}
+		if lines > maxLogLines {
+			lines = maxLogLines
+		}
+	}
+
+	file, err := os.Open(s.deps.InstanceMgr.LogPath(uint(id)))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer file.Close()
+
+	offset, err := tailOffset(file, lines)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if offset, err = copyFrom(w, file, offset); err != nil {
+		return
+	}
+	if r.URL.Query().Get("follow") != "1" {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := file.Stat()
+		if err != nil {
+			return
+		}
+		// 日志被截断或轮转后从头读取
+		if info.Size() < offset {
+			offset = 0
+		}
+		if info.Size() == offset {
+			continue
+		}
+		if offset, err = copyFrom(w, file, offset); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// tailOffset 返回文件最后 lines 行的起始偏移。
+func tailOffset(file *os.File, lines int) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if lines == 0 {
+		return size, nil
+	}
+
+	end := size
+	newlines := 0
+	buf := make([]byte, tailReadBlockLen)
+	for end > 0 {
+		start := end - tailReadBlockLen
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			// 文件末尾的换行不计入行数
+			if chunk[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+			newlines++
+			if newlines == lines {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+func copyFrom(w io.Writer, file *os.File, offset int64) (int64, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+	n, err := io.Copy(w, file)
+	return offset + n, err
+}
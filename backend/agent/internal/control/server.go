@@ -0,0 +1,162 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/agent/internal/manager"
+	"github.com/iwoov/snell-master/backend/agent/internal/monitor"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+// Deps 控制接口读取的运行时组件，TrafficMonitor 为空表示未启用流量统计。
+type Deps struct {
+	Version        string
+	NodeName       string
+	MasterURL      string
+	InstanceMgr    *manager.InstanceManager
+	Installer      *manager.SnellInstaller
+	TrafficMonitor *monitor.TrafficMonitor
+	MasterClient   *client.MasterClient
+	StartedAt      time.Time
+}
+
+// InstanceStatus 单个实例的运行状态。
+type InstanceStatus struct {
+	ID           uint   `json:"id"`
+	Username     string `json:"username"`
+	Port         int    `json:"port"`
+	Version      int    `json:"version"`
+	ServiceState string `json:"service_state"`
+	TrafficBytes int64  `json:"traffic_bytes"`
+}
+
+// Status 守护进程状态。
+type Status struct {
+	Version       string           `json:"version"`
+	NodeName      string           `json:"node_name"`
+	MasterURL     string           `json:"master_url"`
+	SnellVersion  string           `json:"snell_version"`
+	StartedAt     time.Time        `json:"started_at"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Instances     []InstanceStatus `json:"instances"`
+}
+
+// Server 在本地 Unix 套接字上提供 status、doctor、logs 接口。
+type Server struct {
+	deps     Deps
+	path     string
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer 创建控制服务。
+func NewServer(path string, deps Deps) *Server {
+	if deps.StartedAt.IsZero() {
+		deps.StartedAt = time.Now()
+	}
+	s := &Server{deps: deps, path: path}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", s.handleStatus)
+	mux.HandleFunc("GET /doctor", s.handleDoctor)
+	mux.HandleFunc("GET /logs/{id}", s.handleLogs)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	return s
+}
+
+// Start 监听套接字，残留的旧套接字文件会被删除，权限限制为仅 root 可访问。
+func (s *Server) Start() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create socket dir: %w", err)
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove stale socket: %w", err)
+	}
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", s.path, err)
+	}
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("chmod socket: %w", err)
+	}
+	s.listener = listener
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.WithModule("control").Errorf("Control server stopped: %v", err)
+		}
+	}()
+	logger.WithModule("control").Infof("Control socket listening on %s", s.path)
+	return nil
+}
+
+// Stop 关闭服务并删除套接字文件。
+func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = s.server.Shutdown(ctx)
+	_ = os.Remove(s.path)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status(r.Context()))
+}
+
+func (s *Server) status(ctx context.Context) Status {
+	status := Status{
+		Version:       s.deps.Version,
+		NodeName:      s.deps.NodeName,
+		MasterURL:     s.deps.MasterURL,
+		StartedAt:     s.deps.StartedAt,
+		UptimeSeconds: int64(time.Since(s.deps.StartedAt).Seconds()),
+		Instances:     []InstanceStatus{},
+	}
+	if s.deps.Installer != nil {
+		status.SnellVersion = s.deps.Installer.CachedVersion()
+	}
+
+	var counters map[int]int64
+	if s.deps.TrafficMonitor != nil {
+		if result, err := s.deps.TrafficMonitor.PortCounters(ctx); err == nil {
+			counters = result
+		}
+	}
+	if s.deps.InstanceMgr == nil {
+		return status
+	}
+	for _, inst := range s.deps.InstanceMgr.GetAllInstances() {
+		status.Instances = append(status.Instances, InstanceStatus{
+			ID:           inst.ID,
+			Username:     inst.Username,
+			Port:         inst.Port,
+			Version:      inst.Version,
+			ServiceState: s.deps.InstanceMgr.ServiceState(inst.ID),
+			TrafficBytes: counters[inst.Port],
+		})
+	}
+	sort.Slice(status.Instances, func(i, j int) bool { return status.Instances[i].ID < status.Instances[j].ID })
+	return status
+}
+
+func writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
@@ -0,0 +1,95 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/manager"
+)
+
+func TestTailOffset(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(path, []byte("a\nb\nc\nd\n"), 0o644); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer file.Close()
+
+	tests := map[int]string{0: "", 2: "c\nd\n", 4: "a\nb\nc\nd\n", 10: "a\nb\nc\nd\n"}
+	for lines, expected := range tests {
+		offset, err := tailOffset(file, lines)
+		if err != nil {
+			t.Fatalf("tailOffset(%d) error = %v", lines, err)
+		}
+		var buf bytes.Buffer
+		if _, err := copyFrom(&buf, file, offset); err != nil {
+			t.Fatalf("copyFrom error = %v", err)
+		}
+		if buf.String() != expected {
+			t.Fatalf("tailOffset(%d) = %q, want %q", lines, buf.String(), expected)
+		}
+	}
+}
+
+func TestServerOverSocket(t *testing.T) {
+	t.Parallel()
+	instanceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(instanceDir, "instance_3.conf"), []byte("[snell-server]\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	var log strings.Builder
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(&log, "line %d\n", i)
+	}
+	if err := os.WriteFile(filepath.Join(instanceDir, "instance_3.log"), []byte(log.String()), 0o644); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	server := NewServer(socketPath, Deps{
+		Version:     "1.2.3",
+		NodeName:    "test-node",
+		InstanceMgr: manager.NewInstanceManager(instanceDir, "snell-server", 20000, 20010),
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(server.Stop)
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	c := NewClient(socketPath)
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Version != "1.2.3" || status.NodeName != "test-node" || len(status.Instances) != 1 || status.Instances[0].ID != 3 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Logs(context.Background(), 3, 2, false, &buf); err != nil {
+		t.Fatalf("Logs() error = %v", err)
+	}
+	if buf.String() != "line 4\nline 5\n" {
+		t.Fatalf("unexpected logs: %q", buf.String())
+	}
+	if err := c.Logs(context.Background(), 9, 2, false, &buf); err == nil || !strings.Contains(err.Error(), "instance not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
@@ -0,0 +1,493 @@
+package manager
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+const (
+	// defaultConfirmTimeout 新版本启动后需在该时间内成功上报心跳，否则回滚。
+	defaultConfirmTimeout = 3 * time.Minute
+	// maxUpdateBoots 新版本在确认前允许的启动次数，超过说明启动后崩溃，直接回滚。
+	maxUpdateBoots   = 3
+	maxFailedEntries = 10
+	probeTimeout     = 10 * time.Second
+)
+
+// pendingUpdate 记录已替换但尚未确认的升级。
+type pendingUpdate struct {
+	FromVersion string    `json:"from_version"`
+	ToVersion   string    `json:"to_version"`
+	StartedAt   time.Time `json:"started_at"`
+	Boots       int       `json:"boots"`
+}
+
+// updateState 持久化在二进制旁的升级状态。
+type updateState struct {
+	Pending *pendingUpdate `json:"pending,omitempty"`
+	Failed  []string       `json:"failed,omitempty"`
+}
+
+// AgentUpdater 负责 Agent 自身的下载、替换、重启与回滚。
+type AgentUpdater struct {
+	currentVersion string
+	exePath        string
+	masterClient   *client.MasterClient
+	httpClient     *http.Client
+	restart        func() error
+	confirmTimeout time.Duration
+
+	mu           sync.Mutex
+	awaiting     bool
+	confirmTimer *time.Timer
+	restarting   atomic.Bool
+	refused      string // 最近一次拒绝降级的目标版本，避免每次检查都上报
+}
+
+// NewAgentUpdater 创建 AgentUpdater，exePath 为当前运行的二进制，serviceName 为 systemd 服务名。
+func NewAgentUpdater(currentVersion, exePath, serviceName string, masterClient *client.MasterClient) *AgentUpdater {
+	return &AgentUpdater{
+		currentVersion: currentVersion,
+		exePath:        exePath,
+		masterClient:   masterClient,
+		httpClient:     &http.Client{Timeout: defaultDownloadTimeout},
+		restart: func() error {
+			output, err := exec.Command("systemctl", "--no-block", "restart", serviceName).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("systemctl restart %s: %w - %s", serviceName, err, strings.TrimSpace(string(output)))
+			}
+			return nil
+		},
+		confirmTimeout: defaultConfirmTimeout,
+	}
+}
+
+// Restarting 返回是否正在为升级或回滚重启，此时退出流程不应停止实例。
+func (u *AgentUpdater) Restarting() bool {
+	return u.restarting.Load()
+}
+
+// Recover 在启动时检查未确认的升级：新版本开始等待心跳确认，旧版本说明升级已被回滚。
+func (u *AgentUpdater) Recover() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	state, err := u.loadState()
+	if err != nil {
+		return err
+	}
+	pending := state.Pending
+	if pending == nil {
+		return nil
+	}
+
+	log := logger.WithModule("updater")
+	if pending.ToVersion != u.currentVersion {
+		log.Warnf("Agent update to %s did not take effect, running %s", pending.ToVersion, u.currentVersion)
+		state.Pending = nil
+		state.markFailed(pending.ToVersion)
+		u.report(pending.ToVersion, client.UpdateStatusRolledBack, "agent restarted on previous version")
+		return u.saveState(state)
+	}
+
+	pending.Boots++
+	if pending.Boots > maxUpdateBoots {
+		return u.rollbackLocked(state, fmt.Sprintf("agent restarted %d times before confirming update", pending.Boots-1))
+	}
+	if err := u.saveState(state); err != nil {
+		return err
+	}
+	u.awaiting = true
+	u.confirmTimer = time.AfterFunc(u.confirmTimeout, func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		if !u.awaiting {
+			return
+		}
+		state, err := u.loadState()
+		if err != nil {
+			log.Errorf("Load update state failed: %v", err)
+			return
+		}
+		if err := u.rollbackLocked(state, fmt.Sprintf("no successful heartbeat within %s", u.confirmTimeout)); err != nil {
+			log.Errorf("Rollback agent update failed: %v", err)
+		}
+	})
+	log.Infof("Agent updated from %s to %s, waiting for heartbeat confirmation", pending.FromVersion, pending.ToVersion)
+	return nil
+}
+
+// Confirm 在心跳成功后确认升级，删除备份的旧版本。
+func (u *AgentUpdater) Confirm() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.awaiting {
+		return
+	}
+	u.awaiting = false
+	if u.confirmTimer != nil {
+		u.confirmTimer.Stop()
+	}
+
+	log := logger.WithModule("updater")
+	state, err := u.loadState()
+	if err != nil {
+		log.Errorf("Load update state failed: %v", err)
+		return
+	}
+	state.Pending = nil
+	if err := u.saveState(state); err != nil {
+		log.Errorf("Save update state failed: %v", err)
+	}
+	if err := os.Remove(u.prevPath()); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Remove previous agent binary failed: %v", err)
+	}
+	u.report(u.currentVersion, client.UpdateStatusSucceeded, "")
+	log.Infof("Agent update to %s confirmed", u.currentVersion)
+}
+
+// Check 查询 Master 发布的目标版本，版本较新且未失败过时执行升级；
+// 目标版本较旧或无法比较时，只有下发时明确允许降级才会执行。
+func (u *AgentUpdater) Check(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.awaiting || u.restarting.Load() {
+		return nil
+	}
+
+	arch, err := DetectArch()
+	if err != nil {
+		return err
+	}
+	target, err := u.masterClient.GetAgentUpdate(arch)
+	if err != nil {
+		return fmt.Errorf("get agent update: %w", err)
+	}
+	if target.Version == "" || target.Version == u.currentVersion {
+		return nil
+	}
+	if !target.AllowDowngrade {
+		if cmp, err := compareVersions(target.Version, u.currentVersion); err != nil || cmp < 0 {
+			u.refuseDowngrade(target.Version, err)
+			return nil
+		}
+	}
+	u.refused = ""
+	state, err := u.loadState()
+	if err != nil {
+		return err
+	}
+	if state.hasFailed(target.Version) {
+		logger.WithModule("updater").Debugf("Skip agent version %s which failed before", target.Version)
+		return nil
+	}
+
+	if err := u.applyLocked(ctx, target, state); err != nil {
+		state.markFailed(target.Version)
+		if saveErr := u.saveState(state); saveErr != nil {
+			logger.WithModule("updater").Errorf("Save update state failed: %v", saveErr)
+		}
+		u.report(target.Version, client.UpdateStatusFailed, err.Error())
+		return err
+	}
+	return nil
+}
+
+// refuseDowngrade 记录并上报一次被拒绝的降级，同一目标版本只上报一次。
+func (u *AgentUpdater) refuseDowngrade(version string, cmpErr error) {
+	if u.refused == version {
+		return
+	}
+	u.refused = version
+	message := fmt.Sprintf("refusing to downgrade from %s without allow_downgrade", u.currentVersion)
+	if cmpErr != nil {
+		message = fmt.Sprintf("cannot compare with %s (%v), set allow_downgrade to install it", u.currentVersion, cmpErr)
+	}
+	logger.WithModule("updater").Warnf("Skip agent version %s: %s", version, message)
+	u.report(version, client.UpdateStatusFailed, message)
+}
+
+// applyLocked 下载并校验新版本，原子替换当前二进制后通过服务管理器重启。
+func (u *AgentUpdater) applyLocked(ctx context.Context, target *client.AgentUpdate, state *updateState) error {
+	log := logger.WithModule("updater")
+	log.Infof("Updating agent from %s to %s", u.currentVersion, target.Version)
+	u.report(target.Version, client.UpdateStatusUpdating, "")
+
+	newPath := u.exePath + ".new"
+	defer os.Remove(newPath)
+	if err := downloadFile(ctx, u.httpClient, target.DownloadURL, newPath); err != nil {
+		return fmt.Errorf("download agent: %w", err)
+	}
+	if err := verifyChecksum(newPath, target.SHA256); err != nil {
+		return err
+	}
+	if err := os.Chmod(newPath, 0o755); err != nil {
+		return fmt.Errorf("chmod agent: %w", err)
+	}
+	if err := probeVersion(ctx, newPath, target.Version); err != nil {
+		return err
+	}
+
+	if err := u.swap(newPath); err != nil {
+		return err
+	}
+	state.Pending = &pendingUpdate{
+		FromVersion: u.currentVersion,
+		ToVersion:   target.Version,
+		StartedAt:   time.Now(),
+	}
+	if err := u.saveState(state); err != nil {
+		u.restorePrevious()
+		state.Pending = nil
+		return err
+	}
+
+	u.restarting.Store(true)
+	if err := u.restart(); err != nil {
+		u.restarting.Store(false)
+		u.restorePrevious()
+		state.Pending = nil
+		return err
+	}
+	log.Infof("Agent binary replaced with %s, restarting", target.Version)
+	return nil
+}
+
+// rollbackLocked 恢复备份的旧版本并重启。
+func (u *AgentUpdater) rollbackLocked(state *updateState, reason string) error {
+	log := logger.WithModule("updater")
+	u.awaiting = false
+	version := u.currentVersion
+	if state.Pending != nil {
+		version = state.Pending.ToVersion
+	}
+	log.Warnf("Rolling back agent update %s: %s", version, reason)
+
+	if _, err := os.Stat(u.prevPath()); err != nil {
+		u.report(version, client.UpdateStatusFailed, "previous binary missing, cannot roll back: "+reason)
+		state.Pending = nil
+		state.markFailed(version)
+		if saveErr := u.saveState(state); saveErr != nil {
+			log.Errorf("Save update state failed: %v", saveErr)
+		}
+		return fmt.Errorf("previous agent binary not found: %w", err)
+	}
+	if err := os.Rename(u.prevPath(), u.exePath); err != nil {
+		return fmt.Errorf("restore previous agent: %w", err)
+	}
+	state.Pending = nil
+	state.markFailed(version)
+	if err := u.saveState(state); err != nil {
+		return err
+	}
+	u.report(version, client.UpdateStatusRolledBack, reason)
+
+	u.restarting.Store(true)
+	if err := u.restart(); err != nil {
+		u.restarting.Store(false)
+		return err
+	}
+	return nil
+}
+
+// swap 将当前二进制备份为 .prev，再把新版本原子替换到原路径。
+func (u *AgentUpdater) swap(newPath string) error {
+	if err := os.Rename(u.exePath, u.prevPath()); err != nil {
+		return fmt.Errorf("backup current agent: %w", err)
+	}
+	if err := os.Rename(newPath, u.exePath); err != nil {
+		u.restorePrevious()
+		return fmt.Errorf("install new agent: %w", err)
+	}
+	return nil
+}
+
+func (u *AgentUpdater) restorePrevious() {
+	if err := os.Rename(u.prevPath(), u.exePath); err != nil {
+		logger.WithModule("updater").Errorf("Restore previous agent failed: %v", err)
+	}
+}
+
+func (u *AgentUpdater) report(version, status, message string) {
+	if u.masterClient == nil {
+		return
+	}
+	req := client.UpdateStatusRequest{Version: version, Status: status, Message: message}
+	if err := u.masterClient.ReportUpdateStatus(req); err != nil {
+		logger.WithModule("updater").Warnf("Report update status failed: %v", err)
+	}
+}
+
+func (u *AgentUpdater) prevPath() string {
+	return u.exePath + ".prev"
+}
+
+func (u *AgentUpdater) statePath() string {
+	return u.exePath + ".update.json"
+}
+
+func (u *AgentUpdater) loadState() (*updateState, error) {
+	return readUpdateState(u.statePath())
+}
+
+func (u *AgentUpdater) saveState(state *updateState) error {
+	return writeUpdateState(u.statePath(), state)
+}
+
+// readUpdateState 读取升级状态文件，不存在时返回空状态。
+func readUpdateState(path string) (*updateState, error) {
+	state := &updateState{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read update state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parse update state: %w", err)
+	}
+	return state, nil
+}
+
+// writeUpdateState 原子写入升级状态文件。
+func writeUpdateState(path string, state *updateState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal update state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write update state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace update state: %w", err)
+	}
+	return nil
+}
+
+func (s *updateState) hasFailed(version string) bool {
+	for _, v := range s.Failed {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *updateState) markFailed(version string) {
+	if version == "" || s.hasFailed(version) {
+		return
+	}
+	s.Failed = append(s.Failed, version)
+	if len(s.Failed) > maxFailedEntries {
+		s.Failed = s.Failed[len(s.Failed)-maxFailedEntries:]
+	}
+}
+
+// probeVersion 运行新二进制的 -version，确认其可执行且版本与目标一致。
+func probeVersion(ctx context.Context, path, version string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "-version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("run new agent: %w - %s", err, strings.TrimSpace(string(output)))
+	}
+	if reported := strings.TrimSpace(string(output)); reported != version {
+		return fmt.Errorf("new agent reports version %q, expected %q", reported, version)
+	}
+	return nil
+}
+
+// compareVersions 按语义化版本比较 a 与 b，返回 -1、0 或 1；允许 v 前缀，忽略构建元数据。
+func compareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range va.core {
+		if va.core[i] != vb.core[i] {
+			return cmp.Compare(va.core[i], vb.core[i]), nil
+		}
+	}
+	// 预发布版本低于对应的正式版本
+	switch {
+	case va.pre == nil && vb.pre == nil:
+		return 0, nil
+	case va.pre == nil:
+		return 1, nil
+	case vb.pre == nil:
+		return -1, nil
+	}
+	for i := 0; i < len(va.pre) && i < len(vb.pre); i++ {
+		if c := comparePrerelease(va.pre[i], vb.pre[i]); c != 0 {
+			return c, nil
+		}
+	}
+	return cmp.Compare(len(va.pre), len(vb.pre)), nil
+}
+
+type semVersion struct {
+	core [3]int
+	pre  []string
+}
+
+func parseVersion(value string) (semVersion, error) {
+	var v semVersion
+	s := strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.pre = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", value)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", value)
+		}
+		v.core[i] = n
+	}
+	return v, nil
+}
+
+// comparePrerelease 数字标识按数值比较且低于非数字标识，其余按字典序。
+func comparePrerelease(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
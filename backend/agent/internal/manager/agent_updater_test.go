@@ -0,0 +1,319 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+)
+
+type fakeUpdateMaster struct {
+	mu        sync.Mutex
+	binary    []byte
+	checksum  string
+	version   string
+	downgrade bool
+	statuses  []string
+	fetches   int
+	server    *httptest.Server
+}
+
+func newFakeUpdateMaster(t *testing.T, version string, binary []byte) *fakeUpdateMaster {
+	t.Helper()
+	sum := sha256.Sum256(binary)
+	m := &fakeUpdateMaster{binary: binary, checksum: hex.EncodeToString(sum[:]), version: version}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/agent/update", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		fmt.Fprintf(w, `{"code":0,"message":"success","data":{"version":%q,"download_url":%q,"sha256":%q,"allow_downgrade":%v}}`,
+			m.version, m.server.URL+"/bin", m.checksum, m.downgrade)
+	})
+	mux.HandleFunc("/api/agent/update-status", func(w http.ResponseWriter, r *http.Request) {
+		var req client.UpdateStatusRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		m.mu.Lock()
+		m.statuses = append(m.statuses, req.Status)
+		m.mu.Unlock()
+		fmt.Fprint(w, `{"code":0,"message":"success"}`)
+	})
+	mux.HandleFunc("/bin", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.fetches++
+		m.mu.Unlock()
+		_, _ = w.Write(m.binary)
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *fakeUpdateMaster) reported() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.statuses...)
+}
+
+func newTestUpdater(t *testing.T, master *fakeUpdateMaster, version string) (*AgentUpdater, *int) {
+	t.Helper()
+	exePath := filepath.Join(t.TempDir(), "snell-agent")
+	if err := os.WriteFile(exePath, versionScript(version), 0o755); err != nil {
+		t.Fatalf("write agent binary: %v", err)
+	}
+	updater := NewAgentUpdater(version, exePath, "snell-agent", client.NewMasterClient(master.server.URL, "token"))
+	restarts := 0
+	updater.restart = func() error {
+		restarts++
+		return nil
+	}
+	return updater, &restarts
+}
+
+func versionScript(version string) []byte {
+	return []byte("#!/bin/sh\necho " + version + "\n")
+}
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+	if _, err := DetectArch(); err != nil {
+		t.Skipf("unsupported arch: %v", err)
+	}
+}
+
+func TestAgentUpdaterCheckReplacesBinary(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeUpdateMaster(t, "2.0.0", versionScript("2.0.0"))
+	updater, restarts := newTestUpdater(t, master, "1.0.0")
+
+	if err := updater.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if *restarts != 1 || !updater.Restarting() {
+		t.Fatalf("expected one restart, got %d (restarting=%v)", *restarts, updater.Restarting())
+	}
+	if data, _ := os.ReadFile(updater.exePath); string(data) != string(versionScript("2.0.0")) {
+		t.Fatalf("binary not replaced: %s", data)
+	}
+	if data, _ := os.ReadFile(updater.prevPath()); string(data) != string(versionScript("1.0.0")) {
+		t.Fatalf("previous binary not kept: %s", data)
+	}
+	state, err := updater.loadState()
+	if err != nil {
+		t.Fatalf("loadState() error = %v", err)
+	}
+	if state.Pending == nil || state.Pending.FromVersion != "1.0.0" || state.Pending.ToVersion != "2.0.0" {
+		t.Fatalf("unexpected pending update: %+v", state.Pending)
+	}
+	if got := master.reported(); len(got) != 1 || got[0] != client.UpdateStatusUpdating {
+		t.Fatalf("unexpected reported statuses: %v", got)
+	}
+}
+
+func TestAgentUpdaterRejectsChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeUpdateMaster(t, "2.0.0", versionScript("2.0.0"))
+	master.checksum = strings.Repeat("0", 64)
+	updater, restarts := newTestUpdater(t, master, "1.0.0")
+
+	err := updater.Check(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if *restarts != 0 {
+		t.Fatalf("restart should not be triggered")
+	}
+	if data, _ := os.ReadFile(updater.exePath); string(data) != string(versionScript("1.0.0")) {
+		t.Fatalf("binary should be untouched: %s", data)
+	}
+
+	// 失败过的版本不再重复下载
+	if err := updater.Check(context.Background()); err != nil {
+		t.Fatalf("second Check() error = %v", err)
+	}
+	if master.fetches != 1 {
+		t.Fatalf("failed version downloaded %d times", master.fetches)
+	}
+	if got := master.reported(); len(got) != 2 || got[1] != client.UpdateStatusFailed {
+		t.Fatalf("unexpected reported statuses: %v", got)
+	}
+}
+
+func TestAgentUpdaterConfirm(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeUpdateMaster(t, "2.0.0", versionScript("2.0.0"))
+	updater, restarts := newTestUpdater(t, master, "2.0.0")
+	if err := os.WriteFile(updater.prevPath(), versionScript("1.0.0"), 0o755); err != nil {
+		t.Fatalf("write previous binary: %v", err)
+	}
+	if err := updater.saveState(&updateState{Pending: &pendingUpdate{FromVersion: "1.0.0", ToVersion: "2.0.0"}}); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+
+	if err := updater.Recover(); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	updater.Confirm()
+
+	if *restarts != 0 {
+		t.Fatalf("confirmed update should not restart")
+	}
+	if _, err := os.Stat(updater.prevPath()); !os.IsNotExist(err) {
+		t.Fatalf("previous binary should be removed, stat err = %v", err)
+	}
+	state, _ := updater.loadState()
+	if state.Pending != nil {
+		t.Fatalf("pending update should be cleared: %+v", state.Pending)
+	}
+	if got := master.reported(); len(got) != 1 || got[0] != client.UpdateStatusSucceeded {
+		t.Fatalf("unexpected reported statuses: %v", got)
+	}
+}
+
+func TestAgentUpdaterRollsBackWithoutHeartbeat(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeUpdateMaster(t, "2.0.0", versionScript("2.0.0"))
+	updater, _ := newTestUpdater(t, master, "2.0.0")
+	updater.confirmTimeout = 20 * time.Millisecond
+	restarted := make(chan struct{}, 1)
+	updater.restart = func() error {
+		restarted <- struct{}{}
+		return nil
+	}
+	if err := os.WriteFile(updater.prevPath(), versionScript("1.0.0"), 0o755); err != nil {
+		t.Fatalf("write previous binary: %v", err)
+	}
+	if err := updater.saveState(&updateState{Pending: &pendingUpdate{FromVersion: "1.0.0", ToVersion: "2.0.0"}}); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+
+	if err := updater.Recover(); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	select {
+	case <-restarted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("rollback was not triggered")
+	}
+
+	if data, _ := os.ReadFile(updater.exePath); string(data) != string(versionScript("1.0.0")) {
+		t.Fatalf("previous binary not restored: %s", data)
+	}
+	updater.mu.Lock()
+	state, _ := updater.loadState()
+	updater.mu.Unlock()
+	if state.Pending != nil || !state.hasFailed("2.0.0") {
+		t.Fatalf("unexpected state after rollback: %+v", state)
+	}
+	if got := master.reported(); len(got) != 1 || got[0] != client.UpdateStatusRolledBack {
+		t.Fatalf("unexpected reported statuses: %v", got)
+	}
+}
+
+func TestAgentUpdaterRollsBackAfterRepeatedBoots(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeUpdateMaster(t, "2.0.0", versionScript("2.0.0"))
+	updater, restarts := newTestUpdater(t, master, "2.0.0")
+	if err := os.WriteFile(updater.prevPath(), versionScript("1.0.0"), 0o755); err != nil {
+		t.Fatalf("write previous binary: %v", err)
+	}
+	pending := &pendingUpdate{FromVersion: "1.0.0", ToVersion: "2.0.0", Boots: maxUpdateBoots}
+	if err := updater.saveState(&updateState{Pending: pending}); err != nil {
+		t.Fatalf("saveState() error = %v", err)
+	}
+
+	if err := updater.Recover(); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if *restarts != 1 {
+		t.Fatalf("expected rollback restart, got %d", *restarts)
+	}
+	if data, _ := os.ReadFile(updater.exePath); string(data) != string(versionScript("1.0.0")) {
+		t.Fatalf("previous binary not restored: %s", data)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2.0", "1.2", 0},
+		{"1.0.0+build.5", "1.0.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"1.0.0", "2.0.0", -1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-beta", "1.0.0-alpha", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+	}
+	for _, tc := range cases {
+		got, err := compareVersions(tc.a, tc.b)
+		if err != nil || got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, %v; want %d", tc.a, tc.b, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"", "latest", "1.x", "1.2.3.4", "-1.0.0"} {
+		if _, err := compareVersions(bad, "1.0.0"); err == nil {
+			t.Errorf("compareVersions(%q) accepted an invalid version", bad)
+		}
+	}
+}
+
+func TestAgentUpdaterRefusesDowngrade(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeUpdateMaster(t, "1.0.0", versionScript("1.0.0"))
+	updater, restarts := newTestUpdater(t, master, "2.0.0")
+
+	for i := 0; i < 2; i++ {
+		if err := updater.Check(context.Background()); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+	}
+	if *restarts != 0 || master.fetches != 0 {
+		t.Fatalf("downgrade applied without permission: restarts=%d fetches=%d", *restarts, master.fetches)
+	}
+	if data, _ := os.ReadFile(updater.exePath); string(data) != string(versionScript("2.0.0")) {
+		t.Fatalf("binary should be untouched: %s", data)
+	}
+	// 拒绝只上报一次，且不记为失败版本，之后允许降级时仍可安装
+	if got := master.reported(); len(got) != 1 || got[0] != client.UpdateStatusFailed {
+		t.Fatalf("unexpected reported statuses: %v", got)
+	}
+
+	master.mu.Lock()
+	master.downgrade = true
+	master.mu.Unlock()
+	if err := updater.Check(context.Background()); err != nil {
+		t.Fatalf("Check() with allow_downgrade error = %v", err)
+	}
+	if *restarts != 1 {
+		t.Fatalf("expected restart after allowed downgrade, got %d", *restarts)
+	}
+	if data, _ := os.ReadFile(updater.exePath); string(data) != string(versionScript("1.0.0")) {
+		t.Fatalf("binary not replaced: %s", data)
+	}
+}
@@ -0,0 +1,255 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+	"github.com/iwoov/snell-master/backend/pkg/utils"
+)
+
+const (
+	stagedSuffix = ".new"
+	backupSuffix = ".prev"
+
+	healthCheckTimeout  = 10 * time.Second
+	healthCheckInterval = 500 * time.Millisecond
+	maxPendingEvents    = 200
+)
+
+// ApplyError 描述一次配置应用失败及其回滚结果。
+type ApplyError struct {
+	InstanceID  uint
+	Err         error
+	RolledBack  bool
+	RollbackErr error
+}
+
+func (e *ApplyError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("apply instance %d: %v (rollback failed: %v)", e.InstanceID, e.Err, e.RollbackErr)
+	}
+	if e.RolledBack {
+		return fmt.Sprintf("apply instance %d: %v (rolled back)", e.InstanceID, e.Err)
+	}
+	return fmt.Sprintf("apply instance %d: %v", e.InstanceID, e.Err)
+}
+
+func (e *ApplyError) Unwrap() error { return e.Err }
+
+// ApplyConfig 以事务方式应用新配置：先写入候选文件，替换后重启并做健康检查，
+// 失败时自动恢复旧的配置与 unit 文件。
+func (m *InstanceManager) ApplyConfig(instance *Instance, remote client.InstanceConfig) error {
+	if instance == nil {
+		return fmt.Errorf("instance is nil")
+	}
+	log := logger.WithModule("manager")
+
+	next := *instance
+	next.Port = remote.Port
+	next.PSK = remote.PSK
+	next.Version = remote.Version
+	next.OBFS = remote.OBFS
+	next.Listen = remote.Listen
+
+	if next.Port != instance.Port && !utils.IsPortAvailable(next.Port) {
+		err := &ApplyError{InstanceID: instance.ID, Err: fmt.Errorf("port %d is not available", next.Port)}
+		m.recordEvent(instance.ID, client.EventApplyFailed, err.Err.Error())
+		return err
+	}
+
+	configPath, _ := m.generateFilePaths(instance.ID)
+	unitPath := serviceFilePath(instance.ID)
+	unit, err := m.renderServiceFile(&next)
+	if err != nil {
+		return fmt.Errorf("render service file: %w", err)
+	}
+
+	if err := stageFile(configPath, []byte(renderConfig(&next)), 0o644); err != nil {
+		return err
+	}
+	if err := stageFile(unitPath, unit, 0o644); err != nil {
+		_ = os.Remove(configPath + stagedSuffix)
+		return err
+	}
+	if err := commitStaged(configPath); err != nil {
+		_ = os.Remove(configPath + stagedSuffix)
+		_ = os.Remove(unitPath + stagedSuffix)
+		return err
+	}
+	if err := commitStaged(unitPath); err != nil {
+		_ = os.Remove(unitPath + stagedSuffix)
+		applyErr := &ApplyError{InstanceID: instance.ID, Err: err}
+		m.rollback(instance, configPath, unitPath, applyErr)
+		return applyErr
+	}
+
+	applyErr := m.restartAndVerify(&next)
+	if applyErr == nil {
+		instance.Port = next.Port
+		instance.PSK = next.PSK
+		instance.Version = next.Version
+		instance.OBFS = next.OBFS
+		instance.ConfigFile = configPath
+		instance.Status = InstanceStatusRunning
+		instance.LastUpdated = time.Now()
+		log.Infof("Instance %d config applied (Port=%d)", instance.ID, instance.Port)
+		return nil
+	}
+
+	result := &ApplyError{InstanceID: instance.ID, Err: applyErr}
+	log.Errorf("Apply config for instance %d failed: %v, rolling back", instance.ID, applyErr)
+	m.rollback(instance, configPath, unitPath, result)
+	return result
+}
+
+// rollback 恢复备份的配置与 unit 文件并重启旧实例，结果写入 applyErr。
+func (m *InstanceManager) rollback(instance *Instance, configPath, unitPath string, applyErr *ApplyError) {
+	log := logger.WithModule("manager")
+	m.recordEvent(instance.ID, client.EventApplyFailed, applyErr.Err.Error())
+
+	var errs []error
+	if err := restoreBackup(configPath); err != nil {
+		errs = append(errs, err)
+	}
+	if err := restoreBackup(unitPath); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		if err := m.restartAndVerify(instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		applyErr.RollbackErr = errors.Join(errs...)
+		instance.Status = InstanceStatusError
+		m.recordEvent(instance.ID, client.EventRollbackFailed, applyErr.RollbackErr.Error())
+		log.Errorf("Rollback instance %d failed: %v", instance.ID, applyErr.RollbackErr)
+		return
+	}
+
+	applyErr.RolledBack = true
+	instance.Status = InstanceStatusRunning
+	m.recordEvent(instance.ID, client.EventRolledBack, fmt.Sprintf("restored previous config (port %d)", instance.Port))
+	log.Warnf("Instance %d rolled back to previous config", instance.ID)
+}
+
+// restartAndVerify 重新加载 unit 并重启服务，随后等待端口可连接。
+func (m *InstanceManager) restartAndVerify(instance *Instance) error {
+	if err := m.systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if err := m.systemctl("enable", serviceName(instance.ID)); err != nil {
+		return err
+	}
+	if err := m.systemctl("restart", serviceName(instance.ID)); err != nil {
+		return err
+	}
+	return m.waitHealthy(instance, healthCheckTimeout)
+}
+
+// waitHealthy 在超时时间内检查服务处于 active 状态且端口可以建立 TCP 连接。
+func (m *InstanceManager) waitHealthy(instance *Instance, timeout time.Duration) error {
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(instance.Port))
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		if !m.isServiceActive(instance.ID) {
+			lastErr = fmt.Errorf("service %s is not active", serviceName(instance.ID))
+		} else if conn, err := net.DialTimeout("tcp", addr, healthCheckInterval); err != nil {
+			lastErr = fmt.Errorf("port %d not accepting connections: %w", instance.Port, err)
+		} else {
+			_ = conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("health check failed: %w", lastErr)
+		}
+		time.Sleep(healthCheckInterval)
+	}
+}
+
+// recordEvent 记录待上报事件，超过上限时丢弃最旧的事件。
+func (m *InstanceManager) recordEvent(instanceID uint, eventType, message string) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.events = append(m.events, client.InstanceEvent{
+		InstanceID: instanceID,
+		Type:       eventType,
+		Message:    message,
+		Timestamp:  time.Now(),
+	})
+	if len(m.events) > maxPendingEvents {
+		m.events = m.events[len(m.events)-maxPendingEvents:]
+	}
+}
+
+// DrainEvents 取出并清空待上报事件。
+func (m *InstanceManager) DrainEvents() []client.InstanceEvent {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	events := m.events
+	m.events = nil
+	return events
+}
+
+// RequeueEvents 在上报失败时将事件放回队列头部。
+func (m *InstanceManager) RequeueEvents(events []client.InstanceEvent) {
+	if len(events) == 0 {
+		return
+	}
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.events = append(append([]client.InstanceEvent{}, events...), m.events...)
+	if len(m.events) > maxPendingEvents {
+		m.events = m.events[len(m.events)-maxPendingEvents:]
+	}
+}
+
+// stageFile 将新内容写入 path 旁的候选文件。
+func stageFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.WriteFile(path+stagedSuffix, data, perm); err != nil {
+		return fmt.Errorf("write staged file: %w", err)
+	}
+	return nil
+}
+
+// commitStaged 将当前文件备份为 .prev 后用候选文件替换。
+func commitStaged(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Rename(path, path+backupSuffix); err != nil {
+			return fmt.Errorf("backup %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("stat %s: %w", path, err)
+	} else {
+		_ = os.Remove(path + backupSuffix)
+	}
+	if err := os.Rename(path+stagedSuffix, path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// restoreBackup 用 .prev 备份覆盖当前文件；不存在备份时删除当前文件。
+func restoreBackup(path string) error {
+	if _, err := os.Stat(path + backupSuffix); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("stat backup %s: %w", path, err)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+		return nil
+	}
+	if err := os.Rename(path+backupSuffix, path); err != nil {
+		return fmt.Errorf("restore %s: %w", path, err)
+	}
+	return nil
+}
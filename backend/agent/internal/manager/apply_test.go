@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+)
+
+func TestCommitStagedAndRestoreBackup(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "instance_1.conf")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	if err := stageFile(path, []byte("new"), 0o644); err != nil {
+		t.Fatalf("stageFile() error = %v", err)
+	}
+	if err := commitStaged(path); err != nil {
+		t.Fatalf("commitStaged() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Fatalf("unexpected content after commit: %s", data)
+	}
+	if data, _ := os.ReadFile(path + backupSuffix); string(data) != "old" {
+		t.Fatalf("unexpected backup content: %s", data)
+	}
+
+	if err := restoreBackup(path); err != nil {
+		t.Fatalf("restoreBackup() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Fatalf("unexpected content after restore: %s", data)
+	}
+	if _, err := os.Stat(path + backupSuffix); !os.IsNotExist(err) {
+		t.Fatalf("backup should be consumed, stat err = %v", err)
+	}
+}
+
+func TestRestoreBackupWithoutPrevious(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "instance_2.conf")
+	if err := stageFile(path, []byte("new"), 0o644); err != nil {
+		t.Fatalf("stageFile() error = %v", err)
+	}
+	if err := commitStaged(path); err != nil {
+		t.Fatalf("commitStaged() error = %v", err)
+	}
+	if err := restoreBackup(path); err != nil {
+		t.Fatalf("restoreBackup() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("file should be removed when no backup exists, stat err = %v", err)
+	}
+}
+
+func TestEventQueue(t *testing.T) {
+	t.Parallel()
+	m := NewInstanceManager(t.TempDir(), "snell-server", 10000, 10010)
+	m.recordEvent(1, client.EventApplyFailed, "boom")
+	m.recordEvent(1, client.EventRolledBack, "restored")
+
+	events := m.DrainEvents()
+	if len(events) != 2 || events[0].Type != client.EventApplyFailed {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+	if len(m.DrainEvents()) != 0 {
+		t.Fatal("expected queue to be empty after drain")
+	}
+
+	m.recordEvent(2, client.EventApplyFailed, "later")
+	m.RequeueEvents(events)
+	events = m.DrainEvents()
+	if len(events) != 3 || events[0].InstanceID != 1 || events[2].InstanceID != 2 {
+		t.Fatalf("unexpected requeued events: %#v", events)
+	}
+}
+
+func TestRenderConfigListen(t *testing.T) {
+	t.Parallel()
+	inst := &Instance{ID: 1, Port: 10001, PSK: "secret"}
+	if got := renderConfig(inst); !strings.Contains(got, "listen = 0.0.0.0:10001\n") {
+		t.Fatalf("expected ipv4 listen by default, got:\n%s", got)
+	}
+	inst.Listen = "::0"
+	if got := renderConfig(inst); !strings.Contains(got, "listen = ::0:10001\n") {
+		t.Fatalf("expected dual-stack listen, got:\n%s", got)
+	}
+
+	m := &InstanceManager{}
+	remote := client.InstanceConfig{ID: 1, Port: 10001, PSK: "secret"}
+	if m.isConfigChanged(&Instance{ID: 1, Port: 10001, PSK: "secret", Listen: "0.0.0.0"}, remote) {
+		t.Fatal("empty remote listen should match the default address")
+	}
+	remote.Listen = "::0"
+	if !m.isConfigChanged(&Instance{ID: 1, Port: 10001, PSK: "secret"}, remote) {
+		t.Fatal("listen change should be detected")
+	}
+}
@@ -13,15 +13,7 @@ func (m *InstanceManager) generateConfig(instance *Instance) (string, error) {
 		return "", fmt.Errorf("instance is nil")
 	}
 	path, _ := m.generateFilePaths(instance.ID)
-	var builder strings.Builder
-	builder.WriteString("[snell-server]\n")
-	builder.WriteString(fmt.Sprintf("listen = 0.0.0.0:%d\n", instance.Port))
-	builder.WriteString(fmt.Sprintf("psk = %s\n", instance.PSK))
-	if strings.TrimSpace(instance.OBFS) != "" {
-		builder.WriteString(fmt.Sprintf("obfs = %s\n", instance.OBFS))
-	}
-
-	if err := os.WriteFile(path, []byte(builder.String()), 0o644); err != nil {
+	if err := os.WriteFile(path, []byte(renderConfig(instance)), 0o644); err != nil {
 		return "", fmt.Errorf("write config: %w", err)
 	}
 
@@ -29,3 +21,26 @@ func (m *InstanceManager) generateConfig(instance *Instance) (string, error) {
 	instance.LastUpdated = time.Now()
 	return path, nil
 }
+
+// defaultListenAddress Master 未下发监听地址时使用。
+const defaultListenAddress = "0.0.0.0"
+
+// listenAddress 返回规范化的监听地址，::0 表示双栈监听。
+func listenAddress(listen string) string {
+	if listen = strings.TrimSpace(listen); listen != "" {
+		return listen
+	}
+	return defaultListenAddress
+}
+
+// renderConfig 返回实例对应的 snell-server 配置文本。
+func renderConfig(instance *Instance) string {
+	var builder strings.Builder
+	builder.WriteString("[snell-server]\n")
+	builder.WriteString(fmt.Sprintf("listen = %s:%d\n", listenAddress(instance.Listen), instance.Port))
+	builder.WriteString(fmt.Sprintf("psk = %s\n", instance.PSK))
+	if strings.TrimSpace(instance.OBFS) != "" {
+		builder.WriteString(fmt.Sprintf("obfs = %s\n", instance.OBFS))
+	}
+	return builder.String()
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
 )
 
 const (
@@ -26,10 +28,12 @@ type Instance struct {
 	PSK      string
 	Version  int
 	OBFS     string
+	Listen   string // 监听地址，为空时只监听 IPv4
 
 	ConfigFile string
 	LogFile    string
 	Status     int
+	Paused     bool // 节点维护期间已停止，配置与日志保留
 
 	LastUpdated time.Time
 }
@@ -42,6 +46,16 @@ type InstanceManager struct {
 	snellBinary    string
 	portRangeStart int
 	portRangeEnd   int
+
+	eventsMu sync.Mutex
+	events   []client.InstanceEvent
+
+	// syncMu 串行化 SyncInstances，调度重启时可能有两次同步同时进行
+	syncMu sync.Mutex
+
+	// pause/resume 维护期间停止与恢复实例服务，测试中替换
+	pause  func(*Instance) error
+	resume func(*Instance) error
 }
 
 // NewInstanceManager 创建实例管理器并确保必要目录存在。
@@ -54,6 +68,7 @@ func NewInstanceManager(instanceDir, snellBinary string, portStart, portEnd int)
 		portRangeStart: portStart,
 		portRangeEnd:   portEnd,
 	}
+	m.pause, m.resume = m.PauseInstance, m.StartInstance
 	m.RestoreInstances()
 	return m
 }
@@ -125,6 +140,22 @@ func (m *InstanceManager) generateFilePaths(id uint) (configPath, logPath string
 	return
 }
 
+// GetInstance 返回指定 ID 的实例。
+func (m *InstanceManager) GetInstance(id uint) (*Instance, bool) {
+	return m.getInstance(id)
+}
+
+// LogPath 返回实例日志文件路径。
+func (m *InstanceManager) LogPath(id uint) string {
+	_, logPath := m.generateFilePaths(id)
+	return logPath
+}
+
+// PortRange 返回 Agent 配置的实例端口范围。
+func (m *InstanceManager) PortRange() (int, int) {
+	return m.portRangeStart, m.portRangeEnd
+}
+
 // GetAllInstances 返回实例集合的切片拷贝。
 func (m *InstanceManager) GetAllInstances() []*Instance {
 	instances := m.copyInstances()
@@ -0,0 +1,176 @@
+package manager
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+const (
+	// rotatedLogTimeLayout 轮转文件名中的时间戳，按字典序即时间顺序。
+	rotatedLogTimeLayout = "20060102-150405"
+	// maxLogLineBytes 读取日志时单行的最大长度，超出部分被截断。
+	maxLogLineBytes = 64 * 1024
+)
+
+// LogRotationPolicy 实例日志轮转策略，字段为 0 表示不限制。
+type LogRotationPolicy struct {
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+}
+
+// RotateLogs 轮转超过大小限制的实例日志，并清理过期或超出数量的历史文件。
+// systemd 以追加方式持有日志文件句柄，因此采用复制后截断而非重命名。
+func (m *InstanceManager) RotateLogs(policy LogRotationPolicy) {
+	log := logger.WithModule("logs")
+	now := time.Now()
+	for _, inst := range m.GetAllInstances() {
+		logPath := m.LogPath(inst.ID)
+		if policy.MaxSize > 0 {
+			if info, err := os.Stat(logPath); err == nil && info.Size() >= policy.MaxSize {
+				if err := rotateLog(logPath, now); err != nil {
+					log.Warnf("Rotate log of instance %d failed: %v", inst.ID, err)
+				} else {
+					log.Infof("Rotated log of instance %d (%d bytes)", inst.ID, info.Size())
+				}
+			}
+		}
+		if err := pruneRotatedLogs(logPath, policy, now); err != nil {
+			log.Warnf("Prune rotated logs of instance %d failed: %v", inst.ID, err)
+		}
+	}
+}
+
+// ReadLog 返回实例日志中最后 lines 行，filter 非空时只统计匹配的行；
+// truncated 表示还有更早的行未返回。
+func (m *InstanceManager) ReadLog(id uint, lines int, filter *regexp.Regexp) ([]string, bool, error) {
+	if _, ok := m.getInstance(id); !ok {
+		return nil, false, fmt.Errorf("instance %d not found", id)
+	}
+	file, err := os.Open(m.LogPath(id))
+	if os.IsNotExist(err) {
+		return []string{}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	if lines <= 0 {
+		return []string{}, false, nil
+	}
+	// 环形缓冲保留最后 lines 条匹配行
+	ring := make([]string, lines)
+	count := 0
+	reader := bufio.NewReaderSize(file, maxLogLineBytes)
+	for {
+		line, err := readLogLine(reader)
+		if line != "" && (filter == nil || filter.MatchString(line)) {
+			ring[count%lines] = line
+			count++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if count <= lines {
+		return ring[:count], false, nil
+	}
+	start := count % lines
+	return append(ring[start:], ring[:start]...), true, nil
+}
+
+// readLogLine 读取一行并去掉换行符，超长行只保留前 maxLogLineBytes 字节。
+func readLogLine(reader *bufio.Reader) (string, error) {
+	line, isPrefix, err := reader.ReadLine()
+	text := string(line)
+	for isPrefix && err == nil {
+		_, isPrefix, err = reader.ReadLine()
+	}
+	return text, err
+}
+
+// rotateLog 将日志压缩复制为带时间戳的历史文件后截断原文件。
+func rotateLog(logPath string, now time.Time) error {
+	src, err := os.Open(logPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	rotatedPath := fmt.Sprintf("%s.%s.gz", logPath, now.Format(rotatedLogTimeLayout))
+	dst, err := os.OpenFile(rotatedPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(rotatedPath)
+		return fmt.Errorf("compress log: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(rotatedPath)
+		return fmt.Errorf("compress log: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(rotatedPath)
+		return err
+	}
+	// 复制与截断之间写入的少量日志会丢失，与 logrotate 的 copytruncate 行为一致
+	return os.Truncate(logPath, 0)
+}
+
+// pruneRotatedLogs 删除超过 MaxAge 或超出 MaxBackups 数量的历史日志，保留最新的。
+func pruneRotatedLogs(logPath string, policy LogRotationPolicy, now time.Time) error {
+	rotated, err := rotatedLogs(logPath)
+	if err != nil {
+		return err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+	for i, path := range rotated {
+		expired := policy.MaxBackups > 0 && i >= policy.MaxBackups
+		if !expired && policy.MaxAge > 0 {
+			if info, err := os.Stat(path); err == nil && now.Sub(info.ModTime()) > policy.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func rotatedLogs(logPath string) ([]string, error) {
+	matches, err := filepath.Glob(logPath + ".*.gz")
+	if err != nil {
+		return nil, err
+	}
+	result := matches[:0]
+	for _, path := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(path, logPath+"."), ".gz")
+		if _, err := time.Parse(rotatedLogTimeLayout, stamp); err == nil {
+			result = append(result, path)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLogTestManager(t *testing.T, content string) *InstanceManager {
+	t.Helper()
+	m := NewInstanceManager(t.TempDir(), "snell-server", 20000, 20010)
+	m.setInstance(&Instance{ID: 1, Port: 20001})
+	if err := os.WriteFile(m.LogPath(1), []byte(content), 0o644); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	return m
+}
+
+func TestReadLog(t *testing.T) {
+	t.Parallel()
+	m := newLogTestManager(t, "info a\nerror b\ninfo c\nerror d\ninfo e")
+
+	lines, truncated, err := m.ReadLog(1, 2, nil)
+	if err != nil || !truncated || strings.Join(lines, ",") != "error d,info e" {
+		t.Fatalf("ReadLog() = %v, %v, %v", lines, truncated, err)
+	}
+	lines, truncated, err = m.ReadLog(1, 5, regexp.MustCompile("^error"))
+	if err != nil || truncated || strings.Join(lines, ",") != "error b,error d" {
+		t.Fatalf("filtered ReadLog() = %v, %v, %v", lines, truncated, err)
+	}
+	if _, _, err := m.ReadLog(2, 5, nil); err == nil {
+		t.Fatal("expected error for unknown instance")
+	}
+}
+
+func TestRotateLogs(t *testing.T) {
+	t.Parallel()
+	m := newLogTestManager(t, strings.Repeat("x", 100)+"\n")
+	logPath := m.LogPath(1)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for i := 0; i < 3; i++ {
+		path := fmt.Sprintf("%s.%s.gz", logPath, old.Add(time.Duration(i)*time.Minute).Format(rotatedLogTimeLayout))
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("write rotated log: %v", err)
+		}
+	}
+	stale := fmt.Sprintf("%s.%s.gz", logPath, old.Format(rotatedLogTimeLayout))
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	m.RotateLogs(LogRotationPolicy{MaxSize: 50, MaxAge: 24 * time.Hour, MaxBackups: 3})
+
+	if info, err := os.Stat(logPath); err != nil || info.Size() != 0 {
+		t.Fatalf("log should be truncated: %v %v", info, err)
+	}
+	rotated, err := rotatedLogs(logPath)
+	if err != nil {
+		t.Fatalf("rotatedLogs() error = %v", err)
+	}
+	if len(rotated) != 3 {
+		t.Fatalf("expected 3 rotated logs, got %v", rotated)
+	}
+	for _, path := range rotated {
+		if path == stale {
+			t.Fatalf("stale rotated log not removed: %v", rotated)
+		}
+	}
+
+	newest := rotated[len(rotated)-1]
+	file, err := os.Open(newest)
+	if err != nil {
+		t.Fatalf("open rotated log: %v", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	if len(data) != 101 {
+		t.Fatalf("rotated log has %d bytes, want 101", len(data))
+	}
+}
@@ -1,5 +1,10 @@
 package manager
 
+import (
+	"fmt"
+	"sort"
+)
+
 // GetUsedPorts 返回当前实例占用的端口列表。
 func (m *InstanceManager) GetUsedPorts() []int {
 	instances := m.copyInstances()
@@ -19,3 +24,32 @@ func (m *InstanceManager) IsPortUsed(port int) bool {
 	}
 	return false
 }
+
+// PortConflicts 返回实例端口冲突或超出配置范围的问题描述。
+func (m *InstanceManager) PortConflicts() []string {
+	instances := m.copyInstances()
+	ids := make([]uint, 0, len(instances))
+	for id := range instances {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var problems []string
+	owners := make(map[int]uint, len(instances))
+	for _, id := range ids {
+		inst := instances[id]
+		// 从本地配置恢复、尚未同步的实例端口未知
+		if inst.Port == 0 {
+			continue
+		}
+		if inst.Port < m.portRangeStart || inst.Port > m.portRangeEnd {
+			problems = append(problems, fmt.Sprintf("instance %d port %d is outside %d-%d", id, inst.Port, m.portRangeStart, m.portRangeEnd))
+		}
+		if owner, ok := owners[inst.Port]; ok {
+			problems = append(problems, fmt.Sprintf("instances %d and %d share port %d", owner, id, inst.Port))
+			continue
+		}
+		owners[inst.Port] = id
+	}
+	return problems
+}
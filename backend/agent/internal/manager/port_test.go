@@ -0,0 +1,21 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPortConflicts(t *testing.T) {
+	t.Parallel()
+	m := NewInstanceManager(t.TempDir(), "snell-server", 20000, 20010)
+	m.setInstance(&Instance{ID: 1, Port: 20001})
+	m.setInstance(&Instance{ID: 2, Port: 20001})
+	m.setInstance(&Instance{ID: 3, Port: 30000})
+	m.setInstance(&Instance{ID: 4})
+
+	got := strings.Join(m.PortConflicts(), "; ")
+	expected := "instances 1 and 2 share port 20001; instance 3 port 30000 is outside 20000-20010"
+	if got != expected {
+		t.Fatalf("PortConflicts() = %q, want %q", got, expected)
+	}
+}
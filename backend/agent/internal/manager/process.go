@@ -68,6 +68,21 @@ func (m *InstanceManager) StopInstance(instance *Instance) error {
 	return nil
 }
 
+// PauseInstance 停止并禁用实例服务，保留配置、日志与 unit 文件，之后由 StartInstance 恢复。
+func (m *InstanceManager) PauseInstance(instance *Instance) error {
+	if instance == nil {
+		return fmt.Errorf("instance is nil")
+	}
+	// unit 尚未生成时 disable 会失败，此时服务本就没有运行
+	if err := m.systemctl("disable", "--now", serviceName(instance.ID)); err != nil && m.isServiceActive(instance.ID) {
+		return fmt.Errorf("stop service: %w", err)
+	}
+
+	instance.Status = InstanceStatusStopped
+	logger.WithModule("manager").Infof("Instance %d paused via Systemd", instance.ID)
+	return nil
+}
+
 // RestartInstance 重启实例。
 func (m *InstanceManager) RestartInstance(instance *Instance) error {
 	// Ensure config and service files are up to date and service is enabled
@@ -76,8 +91,7 @@ func (m *InstanceManager) RestartInstance(instance *Instance) error {
 	}
 
 	// Force restart to apply changes
-	serviceName := fmt.Sprintf("snell-instance-%d.service", instance.ID)
-	if err := m.systemctl("restart", serviceName); err != nil {
+	if err := m.systemctl("restart", serviceName(instance.ID)); err != nil {
 		return fmt.Errorf("restart service: %w", err)
 	}
 	return nil
@@ -10,8 +10,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/iwoov/snell-master/backend/agent/internal/client"
@@ -27,6 +29,9 @@ type SnellInstaller struct {
 	BinaryPath   string
 	masterClient *client.MasterClient
 	httpClient   *http.Client
+
+	versionMu     sync.Mutex
+	cachedVersion string
 }
 
 // NewSnellInstaller 创建 SnellInstaller。
@@ -64,24 +69,82 @@ func (s *SnellInstaller) GetVersion(ctx context.Context) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// CachedVersion 返回缓存的 Snell 版本，首次调用或安装后重新读取。
+func (s *SnellInstaller) CachedVersion() string {
+	s.versionMu.Lock()
+	defer s.versionMu.Unlock()
+	if s.cachedVersion != "" {
+		return s.cachedVersion
+	}
+	version, err := s.GetVersion(context.Background())
+	if err != nil {
+		return ""
+	}
+	if line, _, ok := strings.Cut(version, "\n"); ok {
+		version = strings.TrimSpace(line)
+	}
+	s.cachedVersion = version
+	return version
+}
+
+// invalidateVersion 清除版本缓存。
+func (s *SnellInstaller) invalidateVersion() {
+	s.versionMu.Lock()
+	s.cachedVersion = ""
+	s.versionMu.Unlock()
+}
+
 // DetectArch 返回当前运行环境对应的 Snell 架构名称。
 func (s *SnellInstaller) DetectArch() (string, error) {
+	return DetectArch()
+}
+
+// DetectArch 返回当前运行环境的架构名称，与 Master 下发的下载地址命名一致。
+func DetectArch() (string, error) {
 	return mapArch(runtime.GOARCH)
 }
 
-// Install 根据 Master 返回的配置下载安装 Snell。
+// Install 根据 Master 返回的配置下载安装 Snell，配置提供校验和时校验压缩包。
 func (s *SnellInstaller) Install(ctx context.Context, cfg *client.SnellConfig) error {
+	log := logger.WithModule("installer")
+	binary, cleanup, err := s.fetch(ctx, cfg, false)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if err := s.place(binary); err != nil {
+		return err
+	}
+
+	s.invalidateVersion()
+	version, err := s.GetVersion(ctx)
+	if err != nil {
+		log.Warnf("Snell installed but failed to read version: %v", err)
+	} else {
+		log.Infof("Snell installed successfully: %s", version)
+	}
+	return nil
+}
+
+// fetch 下载、校验并解压 Snell 压缩包，返回临时目录中的二进制路径及清理函数。
+// requireChecksum 为真时缺少当前架构的校验和视为错误。
+func (s *SnellInstaller) fetch(ctx context.Context, cfg *client.SnellConfig, requireChecksum bool) (string, func(), error) {
 	if cfg == nil {
-		return fmt.Errorf("snell config is nil")
+		return "", nil, fmt.Errorf("snell config is nil")
 	}
 	arch, err := s.DetectArch()
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
 	downloadURL := cfg.DownloadURLs[arch]
 	if downloadURL == "" {
-		return fmt.Errorf("snell download url not found for arch %s", arch)
+		return "", nil, fmt.Errorf("snell download url not found for arch %s", arch)
+	}
+	checksum := cfg.Checksums[arch]
+	if checksum == "" && requireChecksum {
+		return "", nil, fmt.Errorf("snell checksum not found for arch %s", arch)
 	}
 
 	log := logger.WithModule("installer")
@@ -89,77 +152,101 @@ func (s *SnellInstaller) Install(ctx context.Context, cfg *client.SnellConfig) e
 
 	tmpDir, err := os.MkdirTemp("", "snell-install-*")
 	if err != nil {
-		return fmt.Errorf("create temp dir: %w", err)
+		return "", nil, fmt.Errorf("create temp dir: %w", err)
 	}
-	defer os.RemoveAll(tmpDir)
+	cleanup := func() { _ = os.RemoveAll(tmpDir) }
 
 	archivePath := filepath.Join(tmpDir, "snell.zip")
-	if err := s.downloadFile(ctx, downloadURL, archivePath); err != nil {
-		return fmt.Errorf("download snell: %w", err)
+	if err := downloadFile(ctx, s.httpClient, downloadURL, archivePath); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("download snell: %w", err)
+	}
+	if checksum != "" {
+		if err := verifyChecksum(archivePath, checksum); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	} else {
+		log.Warnf("No checksum published for Snell %s (%s), skipping verification", cfg.Version, arch)
 	}
 
 	extractDir := filepath.Join(tmpDir, "extract")
 	if err := os.MkdirAll(extractDir, 0o755); err != nil {
-		return fmt.Errorf("create extract dir: %w", err)
+		cleanup()
+		return "", nil, fmt.Errorf("create extract dir: %w", err)
 	}
-
 	if err := unzipArchive(archivePath, extractDir); err != nil {
-		return fmt.Errorf("unzip snell: %w", err)
+		cleanup()
+		return "", nil, fmt.Errorf("unzip snell: %w", err)
 	}
 
 	binary, err := findSnellBinary(extractDir)
 	if err != nil {
-		return err
+		cleanup()
+		return "", nil, err
 	}
+	return binary, cleanup, nil
+}
 
+// place 将解压出的二进制移动到安装路径。
+func (s *SnellInstaller) place(binary string) error {
 	if err := os.MkdirAll(filepath.Dir(s.BinaryPath), 0o755); err != nil {
 		return fmt.Errorf("create binary dir: %w", err)
 	}
-
 	if err := moveFile(binary, s.BinaryPath); err != nil {
 		return fmt.Errorf("install binary: %w", err)
 	}
-
 	if err := os.Chmod(s.BinaryPath, 0o755); err != nil {
 		return fmt.Errorf("chmod binary: %w", err)
 	}
+	return nil
+}
 
-	version, err := s.GetVersion(ctx)
-	if err != nil {
-		log.Warnf("Snell installed but failed to read version: %v", err)
-	} else {
-		log.Infof("Snell installed successfully: %s", version)
+// replace 将当前二进制备份为 .prev 后安装新二进制，失败时恢复备份。
+func (s *SnellInstaller) replace(binary string) error {
+	if err := os.Rename(s.BinaryPath, s.prevPath()); err != nil {
+		return fmt.Errorf("backup current snell: %w", err)
+	}
+	if err := s.place(binary); err != nil {
+		if restoreErr := s.restorePrevious(); restoreErr != nil {
+			logger.WithModule("installer").Errorf("Restore previous snell failed: %v", restoreErr)
+		}
+		return err
 	}
+	s.invalidateVersion()
 	return nil
 }
 
-func (s *SnellInstaller) downloadFile(ctx context.Context, url, dest string) error {
-	if ctx == nil {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), defaultDownloadTimeout)
-		defer cancel()
+// restorePrevious 用 .prev 备份覆盖当前二进制。
+func (s *SnellInstaller) restorePrevious() error {
+	defer s.invalidateVersion()
+	if err := os.Rename(s.prevPath(), s.BinaryPath); err != nil {
+		return fmt.Errorf("restore previous snell: %w", err)
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
-	}
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("download request: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed: status %d", resp.StatusCode)
-	}
-	out, err := os.Create(dest)
-	if err != nil {
-		return fmt.Errorf("create file: %w", err)
+	return nil
+}
+
+// discardPrevious 升级确认成功后删除 .prev 备份。
+func (s *SnellInstaller) discardPrevious() {
+	if err := os.Remove(s.prevPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.WithModule("installer").Warnf("Remove previous snell failed: %v", err)
 	}
-	defer out.Close()
-	if _, err := io.Copy(out, resp.Body); err != nil {
-		return fmt.Errorf("write file: %w", err)
+}
+
+func (s *SnellInstaller) prevPath() string {
+	return s.BinaryPath + ".prev"
+}
+
+// snellVersionPattern 匹配 snell-server -v 输出中的版本号，例如 "snell-server v5.0.1" 或 "v5.0.0b1"。
+var snellVersionPattern = regexp.MustCompile(`v?(\d+(?:\.\d+)+(?:[a-z]+\d*)?)`)
+
+// parseSnellVersion 从版本输出或配置中提取不带 v 前缀的版本号，无法识别时返回空字符串。
+func parseSnellVersion(output string) string {
+	match := snellVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return ""
 	}
-	return nil
+	return match[1]
 }
 
 func unzipArchive(src, dest string) error {
@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+// SnellUpgrader 按 Master 下发的目标版本升级 Snell Server，并逐个重启实例。
+type SnellUpgrader struct {
+	installer    *SnellInstaller
+	instanceMgr  *InstanceManager
+	masterClient *client.MasterClient
+	restart      func(*Instance) error
+
+	mu sync.Mutex
+}
+
+// NewSnellUpgrader 创建 SnellUpgrader。
+func NewSnellUpgrader(installer *SnellInstaller, instanceMgr *InstanceManager, masterClient *client.MasterClient) *SnellUpgrader {
+	return &SnellUpgrader{
+		installer:    installer,
+		instanceMgr:  instanceMgr,
+		masterClient: masterClient,
+		restart:      instanceMgr.restartAndVerify,
+	}
+}
+
+// Check 拉取目标版本，与当前版本不同、未失败过且提供了校验和时执行升级。
+func (u *SnellUpgrader) Check(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cfg, err := u.masterClient.GetSnellConfig()
+	if err != nil {
+		return fmt.Errorf("get snell config: %w", err)
+	}
+	target := parseSnellVersion(cfg.Version)
+	current := parseSnellVersion(u.installer.CachedVersion())
+	if target == "" || current == "" || target == current {
+		return nil
+	}
+
+	log := logger.WithModule("upgrader")
+	state, err := readUpdateState(u.statePath())
+	if err != nil {
+		return err
+	}
+	if state.hasFailed(target) {
+		log.Debugf("Skip snell version %s which failed before", target)
+		return nil
+	}
+	arch, err := DetectArch()
+	if err != nil {
+		return err
+	}
+	if cfg.Checksums[arch] == "" {
+		log.Warnf("Snell %s has no checksum for %s, upgrade skipped", target, arch)
+		return nil
+	}
+
+	if err := u.upgrade(ctx, cfg, target, current); err != nil {
+		state.markFailed(target)
+		if saveErr := writeUpdateState(u.statePath(), state); saveErr != nil {
+			log.Errorf("Save snell upgrade state failed: %v", saveErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// upgrade 替换二进制后按实例 ID 顺序逐个重启，任一实例健康检查失败即回滚。
+func (u *SnellUpgrader) upgrade(ctx context.Context, cfg *client.SnellConfig, target, current string) error {
+	log := logger.WithModule("upgrader")
+	instances := u.runningInstances()
+	total := len(instances)
+	log.Infof("Upgrading Snell from %s to %s (%d instances)", current, target, total)
+	u.report(target, client.SnellUpgradeUpgrading, 0, total, "")
+
+	binary, cleanup, err := u.installer.fetch(ctx, cfg, true)
+	if err != nil {
+		u.report(target, client.SnellUpgradeFailed, 0, total, err.Error())
+		return err
+	}
+	defer cleanup()
+	if err := u.installer.replace(binary); err != nil {
+		u.report(target, client.SnellUpgradeFailed, 0, total, err.Error())
+		return err
+	}
+
+	for i, inst := range instances {
+		if err := u.restart(inst); err != nil {
+			err = fmt.Errorf("restart instance %d: %w", inst.ID, err)
+			u.rollback(target, instances[:i+1], total, err)
+			return err
+		}
+		u.report(target, client.SnellUpgradeUpgrading, i+1, total, "")
+	}
+
+	u.installer.discardPrevious()
+	u.report(target, client.SnellUpgradeSucceeded, total, total, "")
+	log.Infof("Snell upgraded to %s", target)
+	return nil
+}
+
+// rollback 恢复旧二进制并重启已切换到新版本的实例。
+func (u *SnellUpgrader) rollback(target string, restarted []*Instance, total int, cause error) {
+	log := logger.WithModule("upgrader")
+	log.Warnf("Rolling back Snell upgrade %s: %v", target, cause)
+	if err := u.installer.restorePrevious(); err != nil {
+		log.Errorf("Restore previous snell failed: %v", err)
+		u.report(target, client.SnellUpgradeFailed, len(restarted)-1, total, fmt.Sprintf("%v; %v", cause, err))
+		return
+	}
+	for _, inst := range restarted {
+		if err := u.restart(inst); err != nil {
+			log.Errorf("Restart instance %d after rollback failed: %v", inst.ID, err)
+		}
+	}
+	u.report(target, client.SnellUpgradeRolledBack, len(restarted)-1, total, cause.Error())
+}
+
+// runningInstances 返回按 ID 排序的运行中实例。
+func (u *SnellUpgrader) runningInstances() []*Instance {
+	var result []*Instance
+	for _, inst := range u.instanceMgr.GetAllInstances() {
+		if inst.Status == InstanceStatusRunning {
+			result = append(result, inst)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func (u *SnellUpgrader) report(version, status string, done, total int, message string) {
+	report := client.SnellUpgradeReport{Version: version, Status: status, Done: done, Total: total, Message: message}
+	if err := u.masterClient.ReportSnellUpgrade(report); err != nil {
+		logger.WithModule("upgrader").Warnf("Report snell upgrade failed: %v", err)
+	}
+}
+
+func (u *SnellUpgrader) statePath() string {
+	return u.installer.BinaryPath + ".upgrade.json"
+}
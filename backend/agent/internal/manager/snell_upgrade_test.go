@@ -0,0 +1,206 @@
+package manager
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+)
+
+type fakeSnellMaster struct {
+	mu       sync.Mutex
+	version  string
+	archive  []byte
+	checksum string
+	reports  []client.SnellUpgradeReport
+	server   *httptest.Server
+}
+
+func newFakeSnellMaster(t *testing.T, version string) *fakeSnellMaster {
+	t.Helper()
+	archive := snellArchive(t, snellScript(version))
+	sum := sha256.Sum256(archive)
+	m := &fakeSnellMaster{version: version, archive: archive, checksum: hex.EncodeToString(sum[:])}
+	arch, _ := DetectArch()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/agent/snell-config", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		fmt.Fprintf(w, `{"code":0,"message":"success","data":{"version":%q,"download_urls":{%q:%q},"checksums":{%q:%q}}}`,
+			m.version, arch, m.server.URL+"/snell.zip", arch, m.checksum)
+	})
+	mux.HandleFunc("/api/agent/snell-upgrade", func(w http.ResponseWriter, r *http.Request) {
+		var report client.SnellUpgradeReport
+		_ = json.NewDecoder(r.Body).Decode(&report)
+		m.mu.Lock()
+		m.reports = append(m.reports, report)
+		m.mu.Unlock()
+		fmt.Fprint(w, `{"code":0,"message":"success"}`)
+	})
+	mux.HandleFunc("/snell.zip", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(m.archive)
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *fakeSnellMaster) statuses() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]string, 0, len(m.reports))
+	for _, report := range m.reports {
+		result = append(result, fmt.Sprintf("%s:%d/%d", report.Status, report.Done, report.Total))
+	}
+	return result
+}
+
+func snellScript(version string) []byte {
+	return []byte("#!/bin/sh\necho snell-server v" + version + "\n")
+}
+
+func snellArchive(t *testing.T, binary []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	header := &zip.FileHeader{Name: "snell-server", Method: zip.Deflate}
+	header.SetMode(0o755)
+	entry, err := writer.CreateHeader(header)
+	if err != nil {
+		t.Fatalf("create zip entry: %v", err)
+	}
+	if _, err := entry.Write(binary); err != nil {
+		t.Fatalf("write zip entry: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func newTestSnellUpgrader(t *testing.T, master *fakeSnellMaster, current string, failOn uint) (*SnellUpgrader, *[]string) {
+	t.Helper()
+	binaryPath := filepath.Join(t.TempDir(), "snell-server")
+	if err := os.WriteFile(binaryPath, snellScript(current), 0o755); err != nil {
+		t.Fatalf("write snell binary: %v", err)
+	}
+	masterClient := client.NewMasterClient(master.server.URL, "token")
+	installer := NewSnellInstaller(binaryPath, masterClient)
+	instanceMgr := NewInstanceManager(t.TempDir(), binaryPath, 20000, 20010)
+	for _, id := range []uint{3, 1, 2} {
+		instanceMgr.setInstance(&Instance{ID: id, Port: 20000 + int(id), Status: InstanceStatusRunning})
+	}
+	instanceMgr.setInstance(&Instance{ID: 9, Port: 20009, Status: InstanceStatusStopped})
+
+	upgrader := NewSnellUpgrader(installer, instanceMgr, masterClient)
+	var restarts []string
+	upgrader.restart = func(inst *Instance) error {
+		version := parseSnellVersion(installer.CachedVersion())
+		restarts = append(restarts, fmt.Sprintf("%d@%s", inst.ID, version))
+		if inst.ID == failOn && version == master.version {
+			return fmt.Errorf("port not accepting connections")
+		}
+		return nil
+	}
+	return upgrader, &restarts
+}
+
+func TestParseSnellVersion(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"snell-server v5.0.1":       "5.0.1",
+		"5.0.1":                     "5.0.1",
+		"v4.1.0\nbuilt with go1.21": "4.1.0",
+		"snell-server v5.0.0b1":     "5.0.0b1",
+		"unknown":                   "",
+	}
+	for input, expected := range tests {
+		if got := parseSnellVersion(input); got != expected {
+			t.Fatalf("parseSnellVersion(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestSnellUpgraderRestartsInstancesInOrder(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeSnellMaster(t, "5.0.1")
+	upgrader, restarts := newTestSnellUpgrader(t, master, "4.1.0", 0)
+
+	if err := upgrader.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got := strings.Join(*restarts, ","); got != "1@5.0.1,2@5.0.1,3@5.0.1" {
+		t.Fatalf("unexpected restarts: %s", got)
+	}
+	if got := parseSnellVersion(upgrader.installer.CachedVersion()); got != "5.0.1" {
+		t.Fatalf("installed version = %s", got)
+	}
+	if _, err := os.Stat(upgrader.installer.prevPath()); !os.IsNotExist(err) {
+		t.Fatalf("previous binary should be removed, stat err = %v", err)
+	}
+	expected := "upgrading:0/3,upgrading:1/3,upgrading:2/3,upgrading:3/3,succeeded:3/3"
+	if got := strings.Join(master.statuses(), ","); got != expected {
+		t.Fatalf("unexpected reports: %s", got)
+	}
+}
+
+func TestSnellUpgraderRollsBackOnUnhealthyInstance(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeSnellMaster(t, "5.0.1")
+	upgrader, restarts := newTestSnellUpgrader(t, master, "4.1.0", 2)
+
+	if err := upgrader.Check(context.Background()); err == nil {
+		t.Fatal("expected upgrade error")
+	}
+	if got := strings.Join(*restarts, ","); got != "1@5.0.1,2@5.0.1,1@4.1.0,2@4.1.0" {
+		t.Fatalf("unexpected restarts: %s", got)
+	}
+	if got := parseSnellVersion(upgrader.installer.CachedVersion()); got != "4.1.0" {
+		t.Fatalf("previous binary not restored, version = %s", got)
+	}
+	expected := "upgrading:0/3,upgrading:1/3,rolled_back:1/3"
+	if got := strings.Join(master.statuses(), ","); got != expected {
+		t.Fatalf("unexpected reports: %s", got)
+	}
+
+	// 回滚过的版本不再重试
+	if err := upgrader.Check(context.Background()); err != nil {
+		t.Fatalf("second Check() error = %v", err)
+	}
+	if len(master.statuses()) != 3 {
+		t.Fatalf("failed version retried: %v", master.statuses())
+	}
+}
+
+func TestSnellUpgraderRejectsChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	requireShell(t)
+	master := newFakeSnellMaster(t, "5.0.1")
+	master.checksum = strings.Repeat("0", 64)
+	upgrader, restarts := newTestSnellUpgrader(t, master, "4.1.0", 0)
+
+	err := upgrader.Check(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if len(*restarts) != 0 {
+		t.Fatalf("instances should not be restarted: %v", *restarts)
+	}
+	if data, _ := os.ReadFile(upgrader.installer.BinaryPath); string(data) != string(snellScript("4.1.0")) {
+		t.Fatalf("binary should be untouched: %s", data)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"github.com/iwoov/snell-master/backend/pkg/logger"
 )
 
-// SyncInstances 根据 Master 下发的配置同步本地实例状态。
-func (m *InstanceManager) SyncInstances(remoteInstances []client.InstanceConfig) error {
+// SyncInstances 根据 Master 下发的配置同步本地实例状态，同一时间只进行一次同步。
+// paused 为 true 时节点处于要求停止实例的维护期，实例只停止而不删除，维护结束后按最新配置启动。
+func (m *InstanceManager) SyncInstances(remoteInstances []client.InstanceConfig, paused bool) error {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
 	log := logger.WithModule("manager")
 	log.Info("Starting instance sync...")
 
@@ -30,6 +33,14 @@ func (m *InstanceManager) SyncInstances(remoteInstances []client.InstanceConfig)
 
 	for _, remoteInst := range remoteInstances {
 		localInst, exists := m.getInstance(remoteInst.ID)
+		if paused {
+			m.pauseInstance(localInst, remoteInst)
+			continue
+		}
+		if exists && localInst.Paused {
+			m.resumeInstance(localInst, remoteInst)
+			continue
+		}
 		if !exists {
 			log.Infof("Creating new instance %d", remoteInst.ID)
 			newInst := &Instance{
@@ -40,6 +51,7 @@ func (m *InstanceManager) SyncInstances(remoteInstances []client.InstanceConfig)
 				PSK:      remoteInst.PSK,
 				Version:  remoteInst.Version,
 				OBFS:     remoteInst.OBFS,
+				Listen:   remoteInst.Listen,
 			}
 			if err := m.StartInstance(newInst); err != nil {
 				log.Errorf("Start instance %d failed: %v", remoteInst.ID, err)
@@ -50,14 +62,9 @@ func (m *InstanceManager) SyncInstances(remoteInstances []client.InstanceConfig)
 		}
 
 		if m.isConfigChanged(localInst, remoteInst) {
-			log.Infof("Config changed for instance %d, restarting", remoteInst.ID)
-			localInst.Port = remoteInst.Port
-			localInst.PSK = remoteInst.PSK
-			localInst.Version = remoteInst.Version
-			localInst.OBFS = remoteInst.OBFS
-			if err := m.RestartInstance(localInst); err != nil {
-				log.Errorf("Restart instance %d failed: %v", remoteInst.ID, err)
-				localInst.Status = InstanceStatusError
+			log.Infof("Config changed for instance %d, applying", remoteInst.ID)
+			if err := m.ApplyConfig(localInst, remoteInst); err != nil {
+				log.Errorf("Apply config for instance %d failed: %v", remoteInst.ID, err)
 			}
 		}
 	}
@@ -66,6 +73,45 @@ func (m *InstanceManager) SyncInstances(remoteInstances []client.InstanceConfig)
 	return nil
 }
 
+// pauseInstance 记录最新配置并停止实例，新实例只登记不启动。
+func (m *InstanceManager) pauseInstance(local *Instance, remote client.InstanceConfig) {
+	if local == nil {
+		local = &Instance{ID: remote.ID, Status: InstanceStatusStopped}
+		m.setInstance(local)
+	}
+	updateInstanceConfig(local, remote)
+	if local.Paused {
+		return
+	}
+	if err := m.pause(local); err != nil {
+		logger.WithModule("manager").Errorf("Pause instance %d failed: %v", local.ID, err)
+		return
+	}
+	local.Paused = true
+}
+
+// resumeInstance 维护结束后按最新配置重新生成文件并启动实例。
+func (m *InstanceManager) resumeInstance(local *Instance, remote client.InstanceConfig) {
+	updateInstanceConfig(local, remote)
+	if err := m.resume(local); err != nil {
+		logger.WithModule("manager").Errorf("Resume instance %d failed: %v", local.ID, err)
+		local.Status = InstanceStatusError
+		return
+	}
+	local.Paused = false
+	logger.WithModule("manager").Infof("Instance %d resumed after maintenance", local.ID)
+}
+
+func updateInstanceConfig(local *Instance, remote client.InstanceConfig) {
+	local.UserID = remote.UserID
+	local.Username = remote.Username
+	local.Port = remote.Port
+	local.PSK = remote.PSK
+	local.Version = remote.Version
+	local.OBFS = remote.OBFS
+	local.Listen = remote.Listen
+}
+
 func (m *InstanceManager) isConfigChanged(local *Instance, remote client.InstanceConfig) bool {
 	if local == nil {
 		return true
@@ -73,7 +119,8 @@ func (m *InstanceManager) isConfigChanged(local *Instance, remote client.Instanc
 	return local.Port != remote.Port ||
 		local.PSK != remote.PSK ||
 		local.Version != remote.Version ||
-		local.OBFS != remote.OBFS
+		local.OBFS != remote.OBFS ||
+		listenAddress(local.Listen) != listenAddress(remote.Listen)
 }
 
 func (m *InstanceManager) deleteInstanceFiles(instance *Instance) {
@@ -86,4 +133,17 @@ func (m *InstanceManager) deleteInstanceFiles(instance *Instance) {
 		}
 		_ = os.Remove(path)
 	}
+	if instance.ConfigFile != "" {
+		_ = os.Remove(instance.ConfigFile + backupSuffix)
+		_ = os.Remove(instance.ConfigFile + stagedSuffix)
+	}
+	if instance.LogFile != "" {
+		rotated, _ := rotatedLogs(instance.LogFile)
+		for _, path := range rotated {
+			_ = os.Remove(path)
+		}
+	}
+	unitPath := serviceFilePath(instance.ID)
+	_ = os.Remove(unitPath + backupSuffix)
+	_ = os.Remove(unitPath + stagedSuffix)
 }
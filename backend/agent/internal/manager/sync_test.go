@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+)
+
+func TestSyncInstancesPausedKeepsFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	m := NewInstanceManager(dir, "snell-server", 20000, 20010)
+	var calls []string
+	m.pause = func(inst *Instance) error {
+		calls = append(calls, "pause")
+		inst.Status = InstanceStatusStopped
+		return nil
+	}
+	m.resume = func(inst *Instance) error {
+		calls = append(calls, "resume")
+		inst.Status = InstanceStatusRunning
+		return nil
+	}
+
+	configPath, logPath := m.generateFilePaths(1)
+	for _, path := range []string{configPath, logPath, logPath + ".1.gz"} {
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	m.setInstance(&Instance{ID: 1, Port: 20001, PSK: "old", ConfigFile: configPath, LogFile: logPath, Status: InstanceStatusRunning})
+	remote := []client.InstanceConfig{{ID: 1, Port: 20001, PSK: "new", Version: 4}}
+
+	for i := 0; i < 2; i++ {
+		if err := m.SyncInstances(remote, true); err != nil {
+			t.Fatalf("paused sync: %v", err)
+		}
+	}
+	inst, ok := m.GetInstance(1)
+	if !ok || !inst.Paused || inst.Status != InstanceStatusStopped {
+		t.Fatalf("instance after paused sync = %+v, %v; want paused and kept", inst, ok)
+	}
+	if inst.PSK != "new" {
+		t.Fatalf("paused instance psk = %q, want the latest config", inst.PSK)
+	}
+	for _, path := range []string{configPath, logPath, logPath + ".1.gz"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("%s removed during maintenance: %v", path, err)
+		}
+	}
+
+	if err := m.SyncInstances(remote, false); err != nil {
+		t.Fatalf("resumed sync: %v", err)
+	}
+	if inst.Paused || inst.Status != InstanceStatusRunning {
+		t.Fatalf("instance after maintenance = %+v, want running", inst)
+	}
+	if got := strings.Join(calls, ","); got != "pause,resume" {
+		t.Fatalf("calls = %s, want pause,resume", got)
+	}
+}
+
+func TestSyncInstancesPausedRegistersNewInstance(t *testing.T) {
+	t.Parallel()
+	m := NewInstanceManager(t.TempDir(), "snell-server", 20000, 20010)
+	m.pause = func(inst *Instance) error { return nil }
+	m.resume = func(inst *Instance) error {
+		inst.Status = InstanceStatusRunning
+		return nil
+	}
+
+	remote := []client.InstanceConfig{{ID: 5, Port: 20005, PSK: "psk", Version: 4}}
+	if err := m.SyncInstances(remote, true); err != nil {
+		t.Fatalf("paused sync: %v", err)
+	}
+	inst, ok := m.GetInstance(5)
+	if !ok || !inst.Paused || inst.Port != 20005 {
+		t.Fatalf("new instance during maintenance = %+v, %v; want registered and paused", inst, ok)
+	}
+	if err := m.SyncInstances(remote, false); err != nil {
+		t.Fatalf("resumed sync: %v", err)
+	}
+	if inst.Paused || inst.Status != InstanceStatusRunning {
+		t.Fatalf("instance after maintenance = %+v, want running", inst)
+	}
+}
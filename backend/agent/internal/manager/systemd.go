@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 	"text/template"
 )
 
@@ -33,6 +34,21 @@ type serviceData struct {
 }
 
 func (m *InstanceManager) generateServiceFile(instance *Instance) (string, error) {
+	content, err := m.renderServiceFile(instance)
+	if err != nil {
+		return "", err
+	}
+
+	servicePath := serviceFilePath(instance.ID)
+	if err := os.WriteFile(servicePath, content, 0644); err != nil {
+		return "", err
+	}
+
+	return servicePath, nil
+}
+
+// renderServiceFile 渲染实例对应的 systemd unit 内容。
+func (m *InstanceManager) renderServiceFile(instance *Instance) ([]byte, error) {
 	configPath, logPath := m.generateFilePaths(instance.ID)
 
 	data := serviceData{
@@ -44,20 +60,22 @@ func (m *InstanceManager) generateServiceFile(instance *Instance) (string, error
 
 	tmpl, err := template.New("service").Parse(serviceTemplate)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", err
+		return nil, err
 	}
+	return buf.Bytes(), nil
+}
 
-	servicePath := fmt.Sprintf("/etc/systemd/system/snell-instance-%d.service", instance.ID)
-	if err := os.WriteFile(servicePath, buf.Bytes(), 0644); err != nil {
-		return "", err
-	}
+func serviceName(id uint) string {
+	return fmt.Sprintf("snell-instance-%d.service", id)
+}
 
-	return servicePath, nil
+func serviceFilePath(id uint) string {
+	return fmt.Sprintf("/etc/systemd/system/%s", serviceName(id))
 }
 
 func (m *InstanceManager) systemctl(args ...string) error {
@@ -69,23 +87,20 @@ func (m *InstanceManager) systemctl(args ...string) error {
 }
 
 func (m *InstanceManager) enableAndStartService(id uint) error {
-	serviceName := fmt.Sprintf("snell-instance-%d.service", id)
 	if err := m.systemctl("daemon-reload"); err != nil {
 		return err
 	}
-	if err := m.systemctl("enable", "--now", serviceName); err != nil {
+	if err := m.systemctl("enable", "--now", serviceName(id)); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (m *InstanceManager) stopAndDisableService(id uint) error {
-	serviceName := fmt.Sprintf("snell-instance-%d.service", id)
 	// Ignore errors if service not found
-	_ = m.systemctl("disable", "--now", serviceName)
+	_ = m.systemctl("disable", "--now", serviceName(id))
 
-	servicePath := fmt.Sprintf("/etc/systemd/system/%s", serviceName)
-	if err := os.Remove(servicePath); err != nil && !os.IsNotExist(err) {
+	if err := os.Remove(serviceFilePath(id)); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
@@ -93,7 +108,29 @@ func (m *InstanceManager) stopAndDisableService(id uint) error {
 }
 
 func (m *InstanceManager) isServiceActive(id uint) bool {
-	serviceName := fmt.Sprintf("snell-instance-%d.service", id)
-	cmd := exec.Command("systemctl", "is-active", "--quiet", serviceName)
+	cmd := exec.Command("systemctl", "is-active", "--quiet", serviceName(id))
 	return cmd.Run() == nil
 }
+
+// ServiceState 返回实例 systemd 服务的状态（active、inactive、failed 等），无法查询时返回 unknown。
+func (m *InstanceManager) ServiceState(id uint) string {
+	// is-active 在服务未运行时返回非零退出码，但仍会输出状态
+	output, _ := exec.Command("systemctl", "is-active", serviceName(id)).Output()
+	if state := strings.TrimSpace(string(output)); state != "" {
+		return state
+	}
+	return "unknown"
+}
+
+// CheckSystemd 检查 systemd 是否可用于托管实例。
+func CheckSystemd() (string, error) {
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return "", fmt.Errorf("systemd is not the init system: %w", err)
+	}
+	output, err := exec.Command("systemctl", "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("systemctl unavailable: %w", err)
+	}
+	version, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(version), nil
+}
@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// LoadAverage 表示 1/5/15 分钟平均负载。
+type LoadAverage struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
+}
+
+type loadFunc func() (LoadAverage, error)
+
+// ParseLoadAvg 解析 /proc/loadavg 内容。
+func ParseLoadAvg(content string) (LoadAverage, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 3 {
+		return LoadAverage{}, fmt.Errorf("invalid loadavg: %q", content)
+	}
+	values := make([]float64, 3)
+	for i := range values {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return LoadAverage{}, fmt.Errorf("parse loadavg: %w", err)
+		}
+		values[i] = v
+	}
+	return LoadAverage{Load1: values[0], Load5: values[1], Load15: values[2]}, nil
+}
+
+func defaultLoadAverage() (LoadAverage, error) {
+	if runtime.GOOS != "linux" {
+		return LoadAverage{}, errMetricUnsupported
+	}
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return LoadAverage{}, err
+	}
+	return ParseLoadAvg(string(data))
+}
+
+// Uptime 返回系统运行秒数。
+func Uptime() (uint64, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("invalid uptime")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse uptime: %w", err)
+	}
+	return uint64(seconds), nil
+}
+
+// KernelVersion 返回内核版本，例如 "Linux 6.1.0-18-amd64"。
+func KernelVersion() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(unix.ByteSliceToString(uts.Sysname[:]) + " " + unix.ByteSliceToString(uts.Release[:]))
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const procNetDev = "/proc/net/dev"
+
+// NetworkCounters 表示所有物理网卡的累计收发字节数。
+type NetworkCounters struct {
+	RxBytes uint64
+	TxBytes uint64
+}
+
+type networkFunc func() (NetworkCounters, error)
+
+// ParseNetDev 解析 /proc/net/dev 内容，累加除回环与虚拟网卡外的收发字节数。
+func ParseNetDev(r io.Reader) (NetworkCounters, error) {
+	var counters NetworkCounters
+	scanner := bufio.NewScanner(r)
+	for lineNo := 0; scanner.Scan(); lineNo++ {
+		if lineNo < 2 {
+			continue
+		}
+		name, data, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if skipInterface(name) {
+			continue
+		}
+		fields := strings.Fields(data)
+		if len(fields) < 16 {
+			return NetworkCounters{}, fmt.Errorf("invalid net/dev line for %s", name)
+		}
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return NetworkCounters{}, fmt.Errorf("parse rx bytes for %s: %w", name, err)
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return NetworkCounters{}, fmt.Errorf("parse tx bytes for %s: %w", name, err)
+		}
+		counters.RxBytes += rx
+		counters.TxBytes += tx
+	}
+	if err := scanner.Err(); err != nil {
+		return NetworkCounters{}, err
+	}
+	return counters, nil
+}
+
+// skipInterface 过滤回环、容器与隧道等虚拟网卡，避免重复计数。
+func skipInterface(name string) bool {
+	if name == "lo" {
+		return true
+	}
+	for _, prefix := range []string{"docker", "veth", "br-", "virbr", "tun", "tap", "wg"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultNetworkCounters() (NetworkCounters, error) {
+	if runtime.GOOS != "linux" {
+		return NetworkCounters{}, errMetricUnsupported
+	}
+	file, err := os.Open(procNetDev)
+	if err != nil {
+		return NetworkCounters{}, err
+	}
+	defer file.Close()
+	return ParseNetDev(file)
+}
+
+// networkRate 根据两次采样计算每秒收发字节数，计数器回绕时返回 0。
+func networkRate(prev, curr NetworkCounters, elapsed time.Duration) (uint64, uint64) {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+	rate := func(a, b uint64) uint64 {
+		if b < a {
+			return 0
+		}
+		return uint64(float64(b-a)/seconds + 0.5)
+	}
+	return rate(prev.RxBytes, curr.RxBytes), rate(prev.TxBytes, curr.TxBytes)
+}
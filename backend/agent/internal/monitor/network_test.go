@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 9999999     100    0    0    0     0          0         0  9999999     100    0    0    0     0       0          0
+  eth0: 1000000    2000    0    0    0     0          0         0   500000    1500    0    0    0     0       0          0
+  ens4:    2000      20    0    0    0     0          0         0     3000      30    0    0    0     0       0          0
+docker0:  777777      10    0    0    0     0          0         0   777777      10    0    0    0     0       0          0
+`
+
+func TestParseNetDev(t *testing.T) {
+	t.Parallel()
+
+	counters, err := ParseNetDev(strings.NewReader(sampleNetDev))
+	if err != nil {
+		t.Fatalf("ParseNetDev() error = %v", err)
+	}
+	if counters.RxBytes != 1002000 || counters.TxBytes != 503000 {
+		t.Fatalf("unexpected counters: %+v", counters)
+	}
+}
+
+func TestParseNetDevInvalidLine(t *testing.T) {
+	t.Parallel()
+
+	input := "header\nheader\n  eth0: 1 2 3\n"
+	if _, err := ParseNetDev(strings.NewReader(input)); err == nil {
+		t.Fatal("expected error for truncated line")
+	}
+}
+
+func TestNetworkRate(t *testing.T) {
+	t.Parallel()
+
+	prev := NetworkCounters{RxBytes: 1000, TxBytes: 5000}
+	curr := NetworkCounters{RxBytes: 4000, TxBytes: 4000}
+	rx, tx := networkRate(prev, curr, 2*time.Second)
+	if rx != 1500 || tx != 0 {
+		t.Fatalf("unexpected rate rx=%d tx=%d", rx, tx)
+	}
+}
+
+func TestParseLoadAvg(t *testing.T) {
+	t.Parallel()
+
+	load, err := ParseLoadAvg("0.52 0.58 0.59 1/467 12345\n")
+	if err != nil {
+		t.Fatalf("ParseLoadAvg() error = %v", err)
+	}
+	if load.Load1 != 0.52 || load.Load5 != 0.58 || load.Load15 != 0.59 {
+		t.Fatalf("unexpected load: %+v", load)
+	}
+	if _, err := ParseLoadAvg("bad"); err == nil {
+		t.Fatal("expected error for invalid content")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/sys/unix"
@@ -25,26 +26,53 @@ type cpuSample struct {
 	total uint64
 }
 
+// MetricSet 控制需要采集的指标，未启用的指标保持为零值。
+type MetricSet struct {
+	CPU     bool
+	Memory  bool
+	Disk    bool
+	Network bool
+	Load    bool
+}
+
+// AllMetrics 启用全部指标。
+var AllMetrics = MetricSet{CPU: true, Memory: true, Disk: true, Network: true, Load: true}
+
 // SystemMonitor 负责采集节点资源使用情况。
+// 心跳读取缓存值的同时，采集与设置变更可能在其他 goroutine 中进行：
+// updateMu 串行化采集（CPU 与网络采样依赖上一次的计数），mu 保护缓存的指标。
 type SystemMonitor struct {
+	metricsMu      sync.RWMutex
+	metrics        MetricSet
+	updateMu       sync.Mutex
+	mu             sync.RWMutex
 	cpuUsage       int
 	memoryUsage    int
 	diskUsage      int
+	networkRxRate  uint64
+	networkTxRate  uint64
+	load           LoadAverage
 	lastUpdated    time.Time
 	lastCPUSample  cpuSample
 	cpuSampleValid bool
+	lastNet        NetworkCounters
+	lastNetAt      time.Time
 
 	cpuFn  metricFunc
 	memFn  metricFunc
 	diskFn metricFunc
+	netFn  networkFunc
+	loadFn loadFunc
 }
 
 // NewSystemMonitor 创建 SystemMonitor，允许通过选项注入自定义采集实现。
 func NewSystemMonitor(opts ...SystemOption) *SystemMonitor {
-	monitor := &SystemMonitor{}
+	monitor := &SystemMonitor{metrics: AllMetrics}
 	monitor.cpuFn = monitor.defaultCPUUsage
 	monitor.memFn = defaultMemoryUsage
 	monitor.diskFn = defaultDiskUsage
+	monitor.netFn = defaultNetworkCounters
+	monitor.loadFn = defaultLoadAverage
 	for _, opt := range opts {
 		opt(monitor)
 	}
@@ -57,6 +85,13 @@ type SystemOption func(*SystemMonitor)
 func WithCPUProvider(fn metricFunc) SystemOption    { return func(m *SystemMonitor) { m.cpuFn = fn } }
 func WithMemoryProvider(fn metricFunc) SystemOption { return func(m *SystemMonitor) { m.memFn = fn } }
 func WithDiskProvider(fn metricFunc) SystemOption   { return func(m *SystemMonitor) { m.diskFn = fn } }
+func WithNetworkProvider(fn func() (NetworkCounters, error)) SystemOption {
+	return func(m *SystemMonitor) { m.netFn = fn }
+}
+func WithLoadProvider(fn func() (LoadAverage, error)) SystemOption {
+	return func(m *SystemMonitor) { m.loadFn = fn }
+}
+func WithMetrics(set MetricSet) SystemOption { return func(m *SystemMonitor) { m.metrics = set } }
 
 // Update 刷新所有指标。
 func (m *SystemMonitor) Update(ctx context.Context) error {
@@ -66,19 +101,59 @@ func (m *SystemMonitor) Update(ctx context.Context) error {
 		defer cancel()
 	}
 
-	cpuPercent, err := m.cpuFn(ctx)
-	if err != nil && !errors.Is(err, errMetricUnsupported) {
-		return fmt.Errorf("cpu usage: %w", err)
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	metrics := m.Metrics()
+	var cpuPercent, memPercent, diskPercent float64
+	var err error
+	if metrics.CPU {
+		cpuPercent, err = m.cpuFn(ctx)
+		if err != nil && !errors.Is(err, errMetricUnsupported) {
+			return fmt.Errorf("cpu usage: %w", err)
+		}
+	}
+	if metrics.Memory {
+		memPercent, err = m.memFn(ctx)
+		if err != nil && !errors.Is(err, errMetricUnsupported) {
+			return fmt.Errorf("memory usage: %w", err)
+		}
+	}
+	if metrics.Disk {
+		diskPercent, err = m.diskFn(ctx)
+		if err != nil && !errors.Is(err, errMetricUnsupported) {
+			return fmt.Errorf("disk usage: %w", err)
+		}
 	}
-	memPercent, err := m.memFn(ctx)
-	if err != nil && !errors.Is(err, errMetricUnsupported) {
-		return fmt.Errorf("memory usage: %w", err)
+	var load LoadAverage
+	if metrics.Load {
+		load, err = m.loadFn()
+		if err != nil && !errors.Is(err, errMetricUnsupported) {
+			return fmt.Errorf("load average: %w", err)
+		}
 	}
-	diskPercent, err := m.diskFn(ctx)
-	if err != nil && !errors.Is(err, errMetricUnsupported) {
-		return fmt.Errorf("disk usage: %w", err)
+	var counters NetworkCounters
+	var netErr error
+	if metrics.Network {
+		counters, netErr = m.netFn()
+		if netErr != nil && !errors.Is(netErr, errMetricUnsupported) {
+			return fmt.Errorf("network counters: %w", netErr)
+		}
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if metrics.Load {
+		m.load = load
+	}
+	if metrics.Network {
+		now := time.Now()
+		if netErr == nil && !m.lastNetAt.IsZero() {
+			m.networkRxRate, m.networkTxRate = networkRate(m.lastNet, counters, now.Sub(m.lastNetAt))
+		}
+		m.lastNet = counters
+		m.lastNetAt = now
+	}
 	m.cpuUsage = clampPercent(cpuPercent)
 	m.memoryUsage = clampPercent(memPercent)
 	m.diskUsage = clampPercent(diskPercent)
@@ -86,13 +161,60 @@ func (m *SystemMonitor) Update(ctx context.Context) error {
 	return nil
 }
 
-func (m *SystemMonitor) CPUUsage() int          { return m.cpuUsage }
-func (m *SystemMonitor) MemoryUsage() int       { return m.memoryUsage }
-func (m *SystemMonitor) DiskUsage() int         { return m.diskUsage }
-func (m *SystemMonitor) LastUpdated() time.Time { return m.lastUpdated }
-func (m *SystemMonitor) GetCPUUsage() int       { return m.cpuUsage }
-func (m *SystemMonitor) GetMemoryUsage() int    { return m.memoryUsage }
-func (m *SystemMonitor) GetDiskUsage() int      { return m.diskUsage }
+// SetMetrics 在运行中调整需要采集的指标，下一次 Update 生效。
+func (m *SystemMonitor) SetMetrics(set MetricSet) {
+	m.metricsMu.Lock()
+	defer m.metricsMu.Unlock()
+	m.metrics = set
+}
+
+// Metrics 返回当前启用的指标。
+func (m *SystemMonitor) Metrics() MetricSet {
+	m.metricsMu.RLock()
+	defer m.metricsMu.RUnlock()
+	return m.metrics
+}
+
+func (m *SystemMonitor) CPUUsage() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cpuUsage
+}
+
+func (m *SystemMonitor) MemoryUsage() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.memoryUsage
+}
+
+func (m *SystemMonitor) DiskUsage() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.diskUsage
+}
+
+func (m *SystemMonitor) LastUpdated() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastUpdated
+}
+
+func (m *SystemMonitor) Load() LoadAverage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.load
+}
+
+// NetworkRate 返回最近两次采样间的每秒收发字节数。
+func (m *SystemMonitor) NetworkRate() (rx, tx uint64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.networkRxRate, m.networkTxRate
+}
+
+func (m *SystemMonitor) GetCPUUsage() int    { return m.CPUUsage() }
+func (m *SystemMonitor) GetMemoryUsage() int { return m.MemoryUsage() }
+func (m *SystemMonitor) GetDiskUsage() int   { return m.DiskUsage() }
 
 func (m *SystemMonitor) defaultCPUUsage(context.Context) (float64, error) {
 	if runtime.GOOS != "linux" {
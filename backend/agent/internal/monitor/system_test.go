@@ -3,7 +3,9 @@ package monitor
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSystemMonitorUpdate(t *testing.T) {
@@ -41,3 +43,85 @@ func TestSystemMonitorUpdateError(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestSystemMonitorDisabledMetrics(t *testing.T) {
+	called := false
+	mon := NewSystemMonitor(
+		WithMetrics(MetricSet{CPU: true, Load: true}),
+		WithCPUProvider(func(context.Context) (float64, error) { return 40, nil }),
+		WithMemoryProvider(func(context.Context) (float64, error) { called = true; return 50, nil }),
+		WithLoadProvider(func() (LoadAverage, error) { return LoadAverage{Load1: 1.5}, nil }),
+		WithNetworkProvider(func() (NetworkCounters, error) { called = true; return NetworkCounters{}, nil }),
+	)
+
+	if err := mon.Update(context.Background()); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if called {
+		t.Fatal("disabled providers should not be called")
+	}
+	if mon.CPUUsage() != 40 || mon.MemoryUsage() != 0 || mon.Load().Load1 != 1.5 {
+		t.Fatalf("unexpected metrics: cpu=%d mem=%d load=%+v", mon.CPUUsage(), mon.MemoryUsage(), mon.Load())
+	}
+}
+
+func TestSystemMonitorNetworkRate(t *testing.T) {
+	counters := []NetworkCounters{{RxBytes: 0, TxBytes: 0}, {RxBytes: 1 << 20, TxBytes: 1 << 10}}
+	call := 0
+	mon := NewSystemMonitor(
+		WithMetrics(MetricSet{Network: true}),
+		WithNetworkProvider(func() (NetworkCounters, error) {
+			c := counters[call]
+			call++
+			return c, nil
+		}),
+	)
+
+	if err := mon.Update(context.Background()); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if rx, tx := mon.NetworkRate(); rx != 0 || tx != 0 {
+		t.Fatalf("first sample should not produce a rate, got rx=%d tx=%d", rx, tx)
+	}
+	mon.lastNetAt = mon.lastNetAt.Add(-time.Second)
+	if err := mon.Update(context.Background()); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if rx, _ := mon.NetworkRate(); rx < 1<<19 {
+		t.Fatalf("unexpected rx rate: %d", rx)
+	}
+}
+
+func TestSystemMonitorConcurrentAccess(t *testing.T) {
+	mon := NewSystemMonitor(
+		WithCPUProvider(func(context.Context) (float64, error) { return 10, nil }),
+		WithMemoryProvider(func(context.Context) (float64, error) { return 20, nil }),
+		WithDiskProvider(func(context.Context) (float64, error) { return 30, nil }),
+		WithLoadProvider(func() (LoadAverage, error) { return LoadAverage{Load1: 1}, nil }),
+		WithNetworkProvider(func() (NetworkCounters, error) { return NetworkCounters{}, nil }),
+	)
+
+	// 采集、设置变更与心跳读取并发进行，配合 -race 检查
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_ = mon.Update(context.Background())
+		}()
+		go func() {
+			defer wg.Done()
+			mon.SetMetrics(AllMetrics)
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = mon.CPUUsage(), mon.MemoryUsage()
+			_, _ = mon.DiskUsage(), mon.Load()
+			_, _ = mon.NetworkRate()
+		}()
+	}
+	wg.Wait()
+	if mon.CPUUsage() != 10 || mon.DiskUsage() != 30 {
+		t.Fatalf("unexpected metrics: cpu=%d disk=%d", mon.CPUUsage(), mon.DiskUsage())
+	}
+}
@@ -233,6 +233,17 @@ func (m *TrafficMonitor) UpdateTraffic(ctx context.Context, instances []*manager
 	return result, nil
 }
 
+// Check 检查 nft 命令可用且统计表已创建。
+func (m *TrafficMonitor) Check(ctx context.Context) error {
+	_, err := m.listRules(ctx)
+	return err
+}
+
+// PortCounters 返回各端口规则的累计字节数。
+func (m *TrafficMonitor) PortCounters(ctx context.Context) (map[int]int64, error) {
+	return m.readPortBytes(ctx)
+}
+
 // CleanupInstance 删除缓存数据。
 func (m *TrafficMonitor) CleanupInstance(instanceID uint) {
 	delete(m.lastStats, instanceID)
@@ -19,11 +19,14 @@ type HeartbeatScheduler struct {
 	masterClient  *client.MasterClient
 	instanceMgr   *manager.InstanceManager
 	systemMonitor *monitor.SystemMonitor
+	snellVersion  func() string
+	onSuccess     func()
+	kernelVersion string
 
 	stopCh   chan struct{}
 	stopped  chan struct{}
 	interval time.Duration
-	once     sync.Once
+	mu       sync.Mutex // 保护启停状态，设置变更时的 Restart 与退出时的 Stop 可能并发
 }
 
 func NewHeartbeatScheduler(masterClient *client.MasterClient, instanceMgr *manager.InstanceManager, systemMonitor *monitor.SystemMonitor) *HeartbeatScheduler {
@@ -34,8 +37,24 @@ func NewHeartbeatScheduler(masterClient *client.MasterClient, instanceMgr *manag
 	}
 }
 
+// SetSnellVersionProvider 设置读取 snell-server 版本的函数。
+func (s *HeartbeatScheduler) SetSnellVersionProvider(fn func() string) {
+	s.snellVersion = fn
+}
+
+// SetSuccessHook 设置心跳上报成功后的回调，用于确认 Agent 升级。
+func (s *HeartbeatScheduler) SetSuccessHook(fn func()) {
+	s.onSuccess = fn
+}
+
 // Start 启动心跳调度。
 func (s *HeartbeatScheduler) Start(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.start(intervalSeconds)
+}
+
+func (s *HeartbeatScheduler) start(intervalSeconds int) error {
 	if s.masterClient == nil || s.instanceMgr == nil || s.systemMonitor == nil {
 		return fmt.Errorf("heartbeat scheduler dependencies are nil")
 	}
@@ -49,15 +68,16 @@ func (s *HeartbeatScheduler) Start(intervalSeconds int) error {
 	s.stopCh = make(chan struct{})
 	s.stopped = make(chan struct{})
 
-	go s.run()
-	go s.sendHeartbeat()
+	// 间隔按值传入，Restart 修改 s.interval 时不影响仍在发送的首次心跳
+	go s.run(s.interval)
+	go s.sendHeartbeat(s.interval)
 
 	logger.WithModule("scheduler").Infof("Heartbeat scheduler started (interval: %ds)", intervalSeconds)
 	return nil
 }
 
-func (s *HeartbeatScheduler) run() {
-	ticker := time.NewTicker(s.interval)
+func (s *HeartbeatScheduler) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer func() {
 		ticker.Stop()
 		close(s.stopped)
@@ -66,14 +86,14 @@ func (s *HeartbeatScheduler) run() {
 	for {
 		select {
 		case <-ticker.C:
-			s.sendHeartbeat()
+			s.sendHeartbeat(interval)
 		case <-s.stopCh:
 			return
 		}
 	}
 }
 
-func (s *HeartbeatScheduler) sendHeartbeat() {
+func (s *HeartbeatScheduler) sendHeartbeat(interval time.Duration) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -81,27 +101,85 @@ func (s *HeartbeatScheduler) sendHeartbeat() {
 		logger.WithModule("scheduler").Warnf("system monitor update failed: %v", err)
 	}
 
-	cpuUsage := s.systemMonitor.CPUUsage()
-	memUsage := s.systemMonitor.MemoryUsage()
-	instanceCount := s.instanceMgr.GetRunningCount()
-
-	if err := s.masterClient.ReportHeartbeat(cpuUsage, memUsage, instanceCount, AgentVersion); err != nil {
+	req := s.buildRequest(interval)
+	if err := s.masterClient.SendHeartbeat(req); err != nil {
 		logger.WithModule("scheduler").Errorf("Report heartbeat failed: %v", err)
 		return
 	}
+	if s.onSuccess != nil {
+		s.onSuccess()
+	}
 
-	logger.WithModule("scheduler").Debugf("Heartbeat sent (CPU: %d%%, MEM: %d%%, instances: %d)", cpuUsage, memUsage, instanceCount)
+	logger.WithModule("scheduler").Debugf("Heartbeat sent (CPU: %d%%, MEM: %d%%, instances: %d)", s.systemMonitor.CPUUsage(), s.systemMonitor.MemoryUsage(), req.InstanceCount)
+}
+
+// buildRequest 根据启用的监控项组装心跳请求。
+func (s *HeartbeatScheduler) buildRequest(interval time.Duration) client.HeartbeatRequest {
+	metrics := s.systemMonitor.Metrics()
+	portStart, portEnd := s.instanceMgr.PortRange()
+	req := client.HeartbeatRequest{
+		InstanceCount:  s.instanceMgr.GetRunningCount(),
+		Version:        AgentVersion,
+		PortRangeStart: portStart,
+		PortRangeEnd:   portEnd,
+
+		HeartbeatInterval: int(interval / time.Second),
+	}
+	if metrics.CPU {
+		cpu := s.systemMonitor.CPUUsage()
+		req.CPUUsage = &cpu
+	}
+	if metrics.Memory {
+		mem := s.systemMonitor.MemoryUsage()
+		req.MemoryUsage = &mem
+	}
+	if metrics.Disk {
+		disk := s.systemMonitor.DiskUsage()
+		req.DiskUsage = &disk
+	}
+	if metrics.Network {
+		rx, tx := s.systemMonitor.NetworkRate()
+		req.NetworkRxRate = &rx
+		req.NetworkTxRate = &tx
+	}
+	if metrics.Load {
+		load := s.systemMonitor.Load()
+		req.Load1, req.Load5, req.Load15 = &load.Load1, &load.Load5, &load.Load15
+	}
+	if uptime, err := monitor.Uptime(); err == nil {
+		req.UptimeSeconds = uptime
+	}
+	if s.kernelVersion == "" {
+		s.kernelVersion = monitor.KernelVersion()
+	}
+	req.KernelVersion = s.kernelVersion
+	if s.snellVersion != nil {
+		req.SnellVersion = s.snellVersion()
+	}
+	return req
 }
 
 // Stop 停止心跳调度。
 func (s *HeartbeatScheduler) Stop() {
-	s.once.Do(func() {
-		if s.stopCh == nil {
-			return
-		}
-		close(s.stopCh)
-		<-s.stopped
-		s.stopCh = nil
-		logger.WithModule("scheduler").Info("Heartbeat scheduler stopped")
-	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+func (s *HeartbeatScheduler) stop() {
+	if s.stopCh == nil {
+		return
+	}
+	close(s.stopCh)
+	<-s.stopped
+	s.stopCh = nil
+	logger.WithModule("scheduler").Info("Heartbeat scheduler stopped")
+}
+
+// Restart 停止后以新的间隔重新启动，也可用于启动已停止的调度。
+func (s *HeartbeatScheduler) Restart(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	return s.start(intervalSeconds)
 }
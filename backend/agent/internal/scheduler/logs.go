@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/agent/internal/manager"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+const (
+	// logPollWaitSeconds 日志请求长轮询时间，需小于 MasterClient 的请求超时。
+	logPollWaitSeconds = 5
+	logPollRetryDelay  = 10 * time.Second
+	maxLogRequestLines = 2000
+)
+
+// LogScheduler 定期轮转实例日志，并响应 Master 的日志读取请求。
+type LogScheduler struct {
+	masterClient *client.MasterClient
+	instanceMgr  *manager.InstanceManager
+	policy       manager.LogRotationPolicy
+
+	interval time.Duration
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func NewLogScheduler(masterClient *client.MasterClient, instanceMgr *manager.InstanceManager, policy manager.LogRotationPolicy) *LogScheduler {
+	return &LogScheduler{masterClient: masterClient, instanceMgr: instanceMgr, policy: policy}
+}
+
+// Start 启动日志轮转与日志请求轮询。
+func (s *LogScheduler) Start(intervalSeconds int) error {
+	if s.masterClient == nil || s.instanceMgr == nil {
+		return fmt.Errorf("log scheduler dependencies are nil")
+	}
+	if s.stopCh != nil {
+		return fmt.Errorf("log scheduler already started")
+	}
+	if intervalSeconds <= 0 {
+		intervalSeconds = 300
+	}
+	s.interval = time.Duration(intervalSeconds) * time.Second
+	s.stopCh = make(chan struct{})
+
+	s.wg.Add(2)
+	go s.rotate()
+	go s.serveRequests()
+
+	logger.WithModule("scheduler").Infof("Log scheduler started (rotate interval: %ds)", intervalSeconds)
+	return nil
+}
+
+func (s *LogScheduler) rotate() {
+	ticker := time.NewTicker(s.interval)
+	defer func() {
+		ticker.Stop()
+		s.wg.Done()
+	}()
+
+	s.instanceMgr.RotateLogs(s.policy)
+	for {
+		select {
+		case <-ticker.C:
+			s.instanceMgr.RotateLogs(s.policy)
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// serveRequests 持续长轮询 Master，出错时等待一段时间再重试。
+func (s *LogScheduler) serveRequests() {
+	defer s.wg.Done()
+	log := logger.WithModule("scheduler")
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		requests, err := s.masterClient.PollLogRequests(logPollWaitSeconds)
+		if err != nil {
+			log.Debugf("Poll log requests failed: %v", err)
+			select {
+			case <-time.After(logPollRetryDelay):
+			case <-s.stopCh:
+				return
+			}
+			continue
+		}
+		for _, req := range requests {
+			if err := s.masterClient.ReportLogResponse(s.readLog(req)); err != nil {
+				log.Warnf("Report log response %s failed: %v", req.ID, err)
+			}
+		}
+	}
+}
+
+func (s *LogScheduler) readLog(req client.LogRequest) client.LogResponse {
+	resp := client.LogResponse{RequestID: req.ID}
+	var filter *regexp.Regexp
+	if req.Filter != "" {
+		compiled, err := regexp.Compile(req.Filter)
+		if err != nil {
+			resp.Error = fmt.Sprintf("invalid filter: %v", err)
+			return resp
+		}
+		filter = compiled
+	}
+	lines := req.Lines
+	if lines > maxLogRequestLines {
+		lines = maxLogRequestLines
+	}
+	result, truncated, err := s.instanceMgr.ReadLog(req.InstanceID, lines, filter)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	resp.Lines = result
+	resp.Truncated = truncated
+	return resp
+}
+
+// Stop 停止调度，进行中的长轮询最多等待一个请求超时。
+func (s *LogScheduler) Stop() {
+	s.once.Do(func() {
+		if s.stopCh == nil {
+			return
+		}
+		close(s.stopCh)
+		s.wg.Wait()
+		s.stopCh = nil
+		logger.WithModule("scheduler").Info("Log scheduler stopped")
+	})
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+const (
+	// probeIdleInterval 未开启协助探测时重新查询的间隔。
+	probeIdleInterval   = 5 * time.Minute
+	probeConcurrency    = 8
+	defaultProbeTimeout = 3 * time.Second
+)
+
+// ProbeScheduler 按 Master 的配置协助探测其他节点实例端口的可达性。
+type ProbeScheduler struct {
+	masterClient *client.MasterClient
+
+	stopCh  chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func NewProbeScheduler(masterClient *client.MasterClient) *ProbeScheduler {
+	return &ProbeScheduler{masterClient: masterClient}
+}
+
+// Start 启动协助探测，探测间隔由 Master 下发。
+func (s *ProbeScheduler) Start() error {
+	if s.masterClient == nil {
+		return fmt.Errorf("probe scheduler dependencies are nil")
+	}
+	if s.stopCh != nil {
+		return fmt.Errorf("probe scheduler already started")
+	}
+	s.stopCh = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go s.run()
+
+	logger.WithModule("scheduler").Info("Probe scheduler started")
+	return nil
+}
+
+func (s *ProbeScheduler) run() {
+	defer close(s.stopped)
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(s.probe())
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// probe 执行一轮探测并返回下一轮的等待时间。
+func (s *ProbeScheduler) probe() time.Duration {
+	log := logger.WithModule("scheduler")
+	targets, err := s.masterClient.GetProbeTargets()
+	if err != nil {
+		log.Debugf("Get probe targets failed: %v", err)
+		return probeIdleInterval
+	}
+	if !targets.Enabled || targets.IntervalSeconds <= 0 {
+		return probeIdleInterval
+	}
+	next := time.Duration(targets.IntervalSeconds) * time.Second
+	if len(targets.Targets) == 0 {
+		return next
+	}
+
+	timeout := time.Duration(targets.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	results := make([]client.ProbeResult, len(targets.Targets))
+	sem := make(chan struct{}, probeConcurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets.Targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target client.ProbeTarget) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = probeTCP(target, timeout)
+		}(i, target)
+	}
+	wg.Wait()
+
+	if err := s.masterClient.ReportProbeResults(results); err != nil {
+		log.Warnf("Report probe results failed: %v", err)
+	}
+	return next
+}
+
+// Stop 停止协助探测。
+func (s *ProbeScheduler) Stop() {
+	s.once.Do(func() {
+		if s.stopCh == nil {
+			return
+		}
+		close(s.stopCh)
+		<-s.stopped
+		s.stopCh = nil
+		logger.WithModule("scheduler").Info("Probe scheduler stopped")
+	})
+}
+
+func probeTCP(target client.ProbeTarget, timeout time.Duration) client.ProbeResult {
+	result := client.ProbeResult{InstanceID: target.InstanceID, Host: target.Host}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	started := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(target.Host, strconv.Itoa(target.Port)))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	conn.Close()
+	result.Reachable = true
+	result.LatencyMs = time.Since(started).Milliseconds()
+	return result
+}
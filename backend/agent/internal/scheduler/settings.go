@@ -0,0 +1,245 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/client"
+	"github.com/iwoov/snell-master/backend/agent/internal/monitor"
+	agentconfig "github.com/iwoov/snell-master/backend/pkg/config"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+// RuntimeSettings Agent 运行中可调整的设置。
+type RuntimeSettings struct {
+	HeartbeatInterval     int
+	ConfigSyncInterval    int
+	TrafficReportInterval int
+	UpdateCheckInterval   int
+	LogLevel              string
+	Metrics               monitor.MetricSet
+	EnableTraffic         bool
+}
+
+// LocalRuntimeSettings 返回本地配置文件中的设置。
+func LocalRuntimeSettings(cfg *agentconfig.AgentConfig) RuntimeSettings {
+	return RuntimeSettings{
+		HeartbeatInterval:     cfg.Agent.HeartbeatInterval,
+		ConfigSyncInterval:    cfg.Agent.ConfigSyncInterval,
+		TrafficReportInterval: cfg.Agent.TrafficReportInterval,
+		UpdateCheckInterval:   cfg.Agent.UpdateCheckInterval,
+		LogLevel:              cfg.Agent.LogLevel,
+		Metrics: monitor.MetricSet{
+			CPU:     cfg.Monitor.EnableCPU,
+			Memory:  cfg.Monitor.EnableMemory,
+			Disk:    cfg.Monitor.EnableDisk,
+			Network: cfg.Monitor.EnableNetwork,
+			Load:    cfg.Monitor.EnableLoad,
+		},
+		EnableTraffic: cfg.Monitor.EnableTraffic,
+	}
+}
+
+// mergeSettings 以 Master 下发的值覆盖本地设置，pinned 中的项与非法间隔保持本地值。
+func mergeSettings(local RuntimeSettings, remote *client.AgentSettings, pinned map[string]bool) RuntimeSettings {
+	merged := local
+	if remote == nil {
+		return merged
+	}
+	for _, interval := range []struct {
+		key   string
+		dst   *int
+		value *int
+	}{
+		{"heartbeat_interval", &merged.HeartbeatInterval, remote.HeartbeatInterval},
+		{"config_sync_interval", &merged.ConfigSyncInterval, remote.ConfigSyncInterval},
+		{"traffic_report_interval", &merged.TrafficReportInterval, remote.TrafficReportInterval},
+		{"update_check_interval", &merged.UpdateCheckInterval, remote.UpdateCheckInterval},
+	} {
+		if interval.value != nil && *interval.value > 0 && !pinned[interval.key] {
+			*interval.dst = *interval.value
+		}
+	}
+	if remote.LogLevel != nil && *remote.LogLevel != "" && !pinned["log_level"] {
+		merged.LogLevel = *remote.LogLevel
+	}
+	if m := remote.Monitor; m != nil {
+		for _, toggle := range []struct {
+			key   string
+			dst   *bool
+			value *bool
+		}{
+			{"monitor.enable_cpu", &merged.Metrics.CPU, m.EnableCPU},
+			{"monitor.enable_memory", &merged.Metrics.Memory, m.EnableMemory},
+			{"monitor.enable_disk", &merged.Metrics.Disk, m.EnableDisk},
+			{"monitor.enable_network", &merged.Metrics.Network, m.EnableNetwork},
+			{"monitor.enable_load", &merged.Metrics.Load, m.EnableLoad},
+			{"monitor.enable_traffic", &merged.EnableTraffic, m.EnableTraffic},
+		} {
+			if toggle.value != nil && !pinned[toggle.key] {
+				*toggle.dst = *toggle.value
+			}
+		}
+	}
+	return merged
+}
+
+// SettingsTargets 设置变化时需要调整的组件，Update 为空表示未启用自动升级。
+type SettingsTargets struct {
+	Sync          *SyncScheduler
+	Heartbeat     *HeartbeatScheduler
+	Traffic       *TrafficScheduler
+	Update        *UpdateScheduler
+	SystemMonitor *monitor.SystemMonitor
+}
+
+// SettingsApplier 在独立协程中应用 Master 下发的运行时设置，按需重启相关调度。
+type SettingsApplier struct {
+	local   RuntimeSettings
+	pinned  map[string]bool
+	targets SettingsTargets
+
+	mu      sync.Mutex
+	current RuntimeSettings
+
+	pending chan *client.AgentSettings
+	stopCh  chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewSettingsApplier 创建设置应用器，local 为调度当前使用的本地设置。
+func NewSettingsApplier(local RuntimeSettings, localOverrides []string, targets SettingsTargets) *SettingsApplier {
+	pinned := make(map[string]bool, len(localOverrides))
+	for _, key := range localOverrides {
+		pinned[key] = true
+	}
+	return &SettingsApplier{
+		local:   local,
+		pinned:  pinned,
+		targets: targets,
+		current: local,
+		pending: make(chan *client.AgentSettings, 1),
+	}
+}
+
+// Start 启动设置应用协程。
+func (a *SettingsApplier) Start() error {
+	if a.targets.Sync == nil || a.targets.Heartbeat == nil || a.targets.Traffic == nil || a.targets.SystemMonitor == nil {
+		return fmt.Errorf("settings applier dependencies are nil")
+	}
+	if a.stopCh != nil {
+		return fmt.Errorf("settings applier already started")
+	}
+	a.stopCh = make(chan struct{})
+	a.stopped = make(chan struct{})
+	go a.run()
+	return nil
+}
+
+// Submit 提交最新下发的设置，只保留最后一次，不会阻塞配置同步。
+func (a *SettingsApplier) Submit(remote *client.AgentSettings) {
+	for {
+		select {
+		case a.pending <- remote:
+			return
+		default:
+		}
+		select {
+		case <-a.pending:
+		default:
+		}
+	}
+}
+
+// Current 返回当前生效的设置。
+func (a *SettingsApplier) Current() RuntimeSettings {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+func (a *SettingsApplier) run() {
+	defer close(a.stopped)
+	for {
+		select {
+		case remote := <-a.pending:
+			a.apply(mergeSettings(a.local, remote, a.pinned))
+		case <-a.stopCh:
+			return
+		}
+	}
+}
+
+// apply 只调整发生变化的部分，重启失败时保留原值以便下次同步重试。
+func (a *SettingsApplier) apply(next RuntimeSettings) {
+	log := logger.WithModule("settings")
+	current := a.Current()
+	if next == current {
+		return
+	}
+
+	if next.LogLevel != current.LogLevel {
+		if err := logger.SetAgentLevel(next.LogLevel); err != nil {
+			log.Warnf("Ignore remote log level: %v", err)
+			next.LogLevel = current.LogLevel
+		} else {
+			log.Infof("Log level changed to %s", next.LogLevel)
+		}
+	}
+
+	if next.ConfigSyncInterval != current.ConfigSyncInterval {
+		if err := a.targets.Sync.Restart(next.ConfigSyncInterval); err != nil {
+			log.Errorf("Restart sync scheduler failed: %v", err)
+			next.ConfigSyncInterval = current.ConfigSyncInterval
+		}
+	}
+
+	if next.Metrics != current.Metrics {
+		a.targets.SystemMonitor.SetMetrics(next.Metrics)
+	}
+	if next.HeartbeatInterval != current.HeartbeatInterval {
+		if err := a.targets.Heartbeat.Restart(next.HeartbeatInterval); err != nil {
+			log.Errorf("Restart heartbeat scheduler failed: %v", err)
+			next.HeartbeatInterval = current.HeartbeatInterval
+		}
+	}
+
+	if next.EnableTraffic != current.EnableTraffic || next.TrafficReportInterval != current.TrafficReportInterval {
+		if next.EnableTraffic {
+			if err := a.targets.Traffic.Restart(next.TrafficReportInterval); err != nil {
+				log.Errorf("Restart traffic scheduler failed: %v", err)
+				next.EnableTraffic = current.EnableTraffic
+				next.TrafficReportInterval = current.TrafficReportInterval
+			}
+		} else if current.EnableTraffic {
+			a.targets.Traffic.Stop()
+			log.Info("Traffic reporting disabled by remote settings")
+		}
+	}
+
+	if a.targets.Update != nil && next.UpdateCheckInterval != current.UpdateCheckInterval {
+		if err := a.targets.Update.Restart(next.UpdateCheckInterval); err != nil {
+			log.Errorf("Restart update scheduler failed: %v", err)
+			next.UpdateCheckInterval = current.UpdateCheckInterval
+		}
+	}
+
+	a.mu.Lock()
+	a.current = next
+	a.mu.Unlock()
+	log.Infof("Runtime settings applied: heartbeat=%ds sync=%ds traffic=%ds (enabled=%t) update=%ds log_level=%s",
+		next.HeartbeatInterval, next.ConfigSyncInterval, next.TrafficReportInterval, next.EnableTraffic, next.UpdateCheckInterval, next.LogLevel)
+}
+
+// Stop 停止设置应用协程，需在停止各调度之前调用。
+func (a *SettingsApplier) Stop() {
+	a.once.Do(func() {
+		if a.stopCh == nil {
+			return
+		}
+		close(a.stopCh)
+		<-a.stopped
+		a.stopCh = nil
+	})
+}
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -14,18 +15,37 @@ import (
 type SyncScheduler struct {
 	masterClient *client.MasterClient
 	instanceMgr  *manager.InstanceManager
+	upgrader     *manager.SnellUpgrader
+	onSettings   func(*client.AgentSettings)
 
 	interval time.Duration
 	stopCh   chan struct{}
 	stopped  chan struct{}
-	once     sync.Once
+	mu       sync.Mutex // 保护启停状态，设置变更时的 Restart 与退出时的 Stop 可能并发
+	syncMu   sync.Mutex // 串行化同步过程，重启调度时上一次同步可能仍在进行
 }
 
 func NewSyncScheduler(masterClient *client.MasterClient, instanceMgr *manager.InstanceManager) *SyncScheduler {
 	return &SyncScheduler{masterClient: masterClient, instanceMgr: instanceMgr}
 }
 
+// SetSnellUpgrader 设置 Snell 升级器，每次同步实例后检查目标版本。
+func (s *SyncScheduler) SetSnellUpgrader(upgrader *manager.SnellUpgrader) {
+	s.upgrader = upgrader
+}
+
+// SetSettingsHook 设置收到 Master 下发的运行时设置后的回调，回调不应阻塞。
+func (s *SyncScheduler) SetSettingsHook(fn func(*client.AgentSettings)) {
+	s.onSettings = fn
+}
+
 func (s *SyncScheduler) Start(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.start(intervalSeconds)
+}
+
+func (s *SyncScheduler) start(intervalSeconds int) error {
 	if s.masterClient == nil || s.instanceMgr == nil {
 		return fmt.Errorf("sync scheduler dependencies are nil")
 	}
@@ -64,25 +84,66 @@ func (s *SyncScheduler) run() {
 }
 
 func (s *SyncScheduler) syncConfig() {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
 	logger.WithModule("scheduler").Debug("Sync scheduler fetching config")
-	instances, err := s.masterClient.FetchConfig()
+	config, err := s.masterClient.FetchConfig()
 	if err != nil {
 		logger.WithModule("scheduler").Errorf("Fetch config failed: %v", err)
 		return
 	}
-	if err := s.instanceMgr.SyncInstances(instances); err != nil {
+	if s.onSettings != nil {
+		s.onSettings(config.Settings)
+	}
+	if err := s.instanceMgr.SyncInstances(config.Instances, config.Paused); err != nil {
 		logger.WithModule("scheduler").Errorf("Sync instances failed: %v", err)
 	}
+	s.reportEvents()
+	s.upgradeSnell()
+}
+
+// upgradeSnell 在同步后串行执行 Snell 升级，避免与实例配置应用同时重启服务。
+func (s *SyncScheduler) upgradeSnell() {
+	if s.upgrader == nil {
+		return
+	}
+	if err := s.upgrader.Check(context.Background()); err != nil {
+		logger.WithModule("scheduler").Errorf("Snell upgrade failed: %v", err)
+	}
+}
+
+// reportEvents 上报实例配置应用事件，失败时放回队列等待下次同步。
+func (s *SyncScheduler) reportEvents() {
+	events := s.instanceMgr.DrainEvents()
+	if len(events) == 0 {
+		return
+	}
+	if err := s.masterClient.ReportEvents(events); err != nil {
+		logger.WithModule("scheduler").Errorf("Report instance events failed: %v", err)
+		s.instanceMgr.RequeueEvents(events)
+	}
 }
 
 func (s *SyncScheduler) Stop() {
-	s.once.Do(func() {
-		if s.stopCh == nil {
-			return
-		}
-		close(s.stopCh)
-		<-s.stopped
-		s.stopCh = nil
-		logger.WithModule("scheduler").Info("Sync scheduler stopped")
-	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+func (s *SyncScheduler) stop() {
+	if s.stopCh == nil {
+		return
+	}
+	close(s.stopCh)
+	<-s.stopped
+	s.stopCh = nil
+	logger.WithModule("scheduler").Info("Sync scheduler stopped")
+}
+
+// Restart 停止后以新的间隔重新启动，也可用于启动已停止的调度。
+func (s *SyncScheduler) Restart(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	return s.start(intervalSeconds)
 }
@@ -21,7 +21,7 @@ type TrafficScheduler struct {
 	interval time.Duration
 	stopCh   chan struct{}
 	stopped  chan struct{}
-	once     sync.Once
+	mu       sync.Mutex // 保护启停状态，设置变更时的 Restart 与退出时的 Stop 可能并发
 }
 
 func NewTrafficScheduler(masterClient *client.MasterClient, instanceMgr *manager.InstanceManager, trafficMonitor *monitor.TrafficMonitor) *TrafficScheduler {
@@ -29,6 +29,12 @@ func NewTrafficScheduler(masterClient *client.MasterClient, instanceMgr *manager
 }
 
 func (s *TrafficScheduler) Start(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.start(intervalSeconds)
+}
+
+func (s *TrafficScheduler) start(intervalSeconds int) error {
 	if s.masterClient == nil || s.instanceMgr == nil || s.trafficMonitor == nil {
 		return fmt.Errorf("traffic scheduler dependencies are nil")
 	}
@@ -104,13 +110,25 @@ func (s *TrafficScheduler) reportTraffic() {
 }
 
 func (s *TrafficScheduler) Stop() {
-	s.once.Do(func() {
-		if s.stopCh == nil {
-			return
-		}
-		close(s.stopCh)
-		<-s.stopped
-		s.stopCh = nil
-		logger.WithModule("scheduler").Info("Traffic scheduler stopped")
-	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+func (s *TrafficScheduler) stop() {
+	if s.stopCh == nil {
+		return
+	}
+	close(s.stopCh)
+	<-s.stopped
+	s.stopCh = nil
+	logger.WithModule("scheduler").Info("Traffic scheduler stopped")
+}
+
+// Restart 停止后以新的间隔重新启动，也可用于启动已停止的调度。
+func (s *TrafficScheduler) Restart(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	return s.start(intervalSeconds)
 }
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/agent/internal/manager"
+	"github.com/iwoov/snell-master/backend/pkg/logger"
+)
+
+// UpdateScheduler 定期检查 Master 发布的 Agent 版本。
+type UpdateScheduler struct {
+	updater *manager.AgentUpdater
+
+	interval time.Duration
+	stopCh   chan struct{}
+	stopped  chan struct{}
+	mu       sync.Mutex // 保护启停状态，设置变更时的 Restart 与退出时的 Stop 可能并发
+}
+
+func NewUpdateScheduler(updater *manager.AgentUpdater) *UpdateScheduler {
+	return &UpdateScheduler{updater: updater}
+}
+
+// Start 启动升级检查调度。
+func (s *UpdateScheduler) Start(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.start(intervalSeconds)
+}
+
+func (s *UpdateScheduler) start(intervalSeconds int) error {
+	if s.updater == nil {
+		return fmt.Errorf("update scheduler dependencies are nil")
+	}
+	if s.stopCh != nil {
+		return fmt.Errorf("update scheduler already started")
+	}
+	if intervalSeconds <= 0 {
+		intervalSeconds = 600
+	}
+	s.interval = time.Duration(intervalSeconds) * time.Second
+	s.stopCh = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go s.run()
+
+	logger.WithModule("scheduler").Infof("Update scheduler started (interval: %ds)", intervalSeconds)
+	return nil
+}
+
+func (s *UpdateScheduler) run() {
+	ticker := time.NewTicker(s.interval)
+	defer func() {
+		ticker.Stop()
+		close(s.stopped)
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.updater.Check(context.Background()); err != nil {
+				logger.WithModule("scheduler").Errorf("Agent update failed: %v", err)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Stop 停止升级检查调度。
+func (s *UpdateScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+func (s *UpdateScheduler) stop() {
+	if s.stopCh == nil {
+		return
+	}
+	close(s.stopCh)
+	<-s.stopped
+	s.stopCh = nil
+	logger.WithModule("scheduler").Info("Update scheduler stopped")
+}
+
+// Restart 停止后以新的间隔重新启动，也可用于启动已停止的调度。
+func (s *UpdateScheduler) Restart(intervalSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	return s.start(intervalSeconds)
+}
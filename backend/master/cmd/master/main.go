@@ -48,14 +48,22 @@ func main() {
 		Config:       cfg,
 		DB:           db,
 	})
+	if err := services.Node.HashLegacyTokens(); err != nil {
+		logInstance.Fatalf("hash node tokens: %v", err)
+	}
 	handlers := handler.NewHandlers(services, db, startTime)
 
 	manager := scheduler.NewManager()
 	manager.Add(scheduler.ScheduleDailyReset(repos.User, logInstance))
 	manager.Add(scheduler.ScheduleMonthlyReset(repos.User, logInstance))
-	manager.Add(scheduler.ScheduleHealthCheck(db, logInstance, 30*time.Second))
+	manager.Add(scheduler.ScheduleHealthCheck(services.NodeStatus, logInstance, 30*time.Second))
+	manager.Add(scheduler.ScheduleHeartbeatRetention(services.NodeMetrics, logInstance))
+	manager.Add(scheduler.ScheduleMaintenanceCleanup(services.Maintenance, logInstance))
+	manager.Add(scheduler.ScheduleInstanceProbe(services.Probe, logInstance))
+	manager.Add(scheduler.ScheduleProbeRetention(services.Probe, logInstance))
+	manager.Add(scheduler.ScheduleSubscribeAccessRetention(services.Subscribe, logInstance))
 
-	engine := api.SetupRouter(cfg, handlers, services.Log, db)
+	engine := api.SetupRouter(cfg, handlers, services.Log, services.Node)
 
 	server := &http.Server{
 		Addr:    cfg.Server.Address(),
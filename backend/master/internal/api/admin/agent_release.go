@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// AgentReleaseHandler 管理 Agent 版本发布与升级下发。
+type AgentReleaseHandler struct {
+	svc *service.AgentUpdateService
+}
+
+// NewAgentReleaseHandler 构造函数。
+func NewAgentReleaseHandler(svc *service.AgentUpdateService) *AgentReleaseHandler {
+	return &AgentReleaseHandler{svc: svc}
+}
+
+// List 返回已登记的版本。
+func (h *AgentReleaseHandler) List(c *gin.Context) {
+	releases, err := h.svc.ListReleases()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, releases)
+}
+
+// Publish 登记版本各架构的校验和。
+func (h *AgentReleaseHandler) Publish(c *gin.Context) {
+	var req struct {
+		Version   string            `json:"version" binding:"required"`
+		Checksums map[string]string `json:"checksums" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	release, err := h.svc.PublishRelease(req.Version, req.Checksums)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Created(c, release)
+}
+
+// Delete 删除版本。
+func (h *AgentReleaseHandler) Delete(c *gin.Context) {
+	version := c.Param("version")
+	if err := h.svc.DeleteRelease(version); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"deleted": version})
+}
+
+// Rollout 下发升级目标。
+func (h *AgentReleaseHandler) Rollout(c *gin.Context) {
+	var req service.AgentRolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := h.svc.Rollout(req); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, req)
+}
+
+// Progress 返回各节点的升级进度。
+func (h *AgentReleaseHandler) Progress(c *gin.Context) {
+	progress, err := h.svc.Progress()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, progress)
+}
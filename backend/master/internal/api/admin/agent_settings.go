@@ -0,0 +1,86 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// AgentSettingsHandler 管理下发给 Agent 的运行时设置。
+type AgentSettingsHandler struct {
+	svc *service.AgentSettingsService
+}
+
+// NewAgentSettingsHandler 构造函数。
+func NewAgentSettingsHandler(svc *service.AgentSettingsService) *AgentSettingsHandler {
+	return &AgentSettingsHandler{svc: svc}
+}
+
+// GetGlobal 返回全局 Agent 设置。
+// GET /api/admin/agent-settings
+func (h *AgentSettingsHandler) GetGlobal(c *gin.Context) {
+	settings, err := h.svc.Global()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, settings)
+}
+
+// SetGlobal 替换全局 Agent 设置，省略的字段沿用各 Agent 的本地配置。
+// PUT /api/admin/agent-settings  body: {"traffic_report_interval": 120, "log_level": "debug", "monitor": {"enable_disk": false}}
+func (h *AgentSettingsHandler) SetGlobal(c *gin.Context) {
+	var req model.AgentSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	settings, err := h.svc.SetGlobal(req)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, settings)
+}
+
+// GetNode 返回节点的全局、节点级与生效的 Agent 设置。
+// GET /api/admin/nodes/:id/agent-settings
+func (h *AgentSettingsHandler) GetNode(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	settings, err := h.svc.ForNode(uint(id))
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	common.Success(c, settings)
+}
+
+// SetNode 替换节点级 Agent 设置，传入 {} 表示完全跟随全局。
+// PUT /api/admin/nodes/:id/agent-settings
+func (h *AgentSettingsHandler) SetNode(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req model.AgentSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	settings, err := h.svc.SetForNode(uint(id), req)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, settings)
+}
@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// EnrollmentHandler 管理节点注册令牌。
+type EnrollmentHandler struct {
+	svc *service.EnrollmentService
+}
+
+// NewEnrollmentHandler 构造函数。
+func NewEnrollmentHandler(svc *service.EnrollmentService) *EnrollmentHandler {
+	return &EnrollmentHandler{svc: svc}
+}
+
+// List 返回注册令牌列表。
+func (h *EnrollmentHandler) List(c *gin.Context) {
+	tokens, err := h.svc.ListTokens()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, tokens)
+}
+
+// Create 创建注册令牌，响应中包含仅展示一次的明文令牌。
+func (h *EnrollmentHandler) Create(c *gin.Context) {
+	var req struct {
+		Description    string `json:"description"`
+		MaxUses        int    `json:"max_uses"`
+		ExpiresInHours int    `json:"expires_in_hours"`
+		GroupID        *uint  `json:"group_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	ttl := time.Duration(req.ExpiresInHours) * time.Hour
+	token, record, err := h.svc.CreateToken(req.Description, req.MaxUses, ttl, req.GroupID, middleware.GetUserID(c))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Created(c, gin.H{"token": token, "enrollment": record})
+}
+
+// Revoke 吊销注册令牌。
+func (h *EnrollmentHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.RevokeToken(uint(id)); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"revoked": id})
+}
@@ -140,3 +140,19 @@ func (h *InstanceHandler) Restart(c *gin.Context) {
 	}
 	common.Success(c, gin.H{"restarted": id})
 }
+
+// Events 返回实例的配置应用事件。
+func (h *InstanceHandler) Events(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	events, err := h.svc.GetInstanceEvents(uint(id), limit)
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	common.Success(c, events)
+}
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// InstanceLogHandler 通过 Agent 读取实例日志。
+type InstanceLogHandler struct {
+	svc *service.InstanceLogService
+}
+
+// NewInstanceLogHandler 构造函数。
+func NewInstanceLogHandler(svc *service.InstanceLogService) *InstanceLogHandler {
+	return &InstanceLogHandler{svc: svc}
+}
+
+// Get 返回实例日志最后 lines 行，filter 为正则表达式时只返回匹配行。
+// GET /api/admin/instances/:id/logs?lines=&filter=
+func (h *InstanceLogHandler) Get(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	lines, _ := strconv.Atoi(c.DefaultQuery("lines", "100"))
+	result, err := h.svc.Fetch(c.Request.Context(), uint(id), service.InstanceLogQuery{
+		Lines:  lines,
+		Filter: c.Query("filter"),
+	})
+	switch {
+	case err == nil:
+		common.Success(c, result)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		common.Fail(c, http.StatusNotFound, "instance not found")
+	case errors.Is(err, service.ErrAgentNoResponse):
+		common.Fail(c, http.StatusGatewayTimeout, err.Error())
+	default:
+		common.Fail(c, http.StatusBadRequest, err.Error())
+	}
+}
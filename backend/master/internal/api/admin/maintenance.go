@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// MaintenanceHandler 管理节点维护窗口。
+type MaintenanceHandler struct {
+	svc *service.MaintenanceService
+}
+
+// NewMaintenanceHandler 构造函数。
+func NewMaintenanceHandler(svc *service.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{svc: svc}
+}
+
+// List 返回进行中或计划中的维护窗口。
+// GET /api/admin/maintenance
+func (h *MaintenanceHandler) List(c *gin.Context) {
+	notices, err := h.svc.List()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, notices)
+}
+
+// Schedule 设置节点维护窗口。
+// PUT /api/admin/nodes/:id/maintenance
+func (h *MaintenanceHandler) Schedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req service.MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	notice, err := h.svc.Schedule(uint(id), req)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, notice)
+}
+
+// End 结束或取消节点维护。
+// DELETE /api/admin/nodes/:id/maintenance
+func (h *MaintenanceHandler) End(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.End(uint(id)); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, nil)
+}
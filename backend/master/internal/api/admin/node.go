@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/model"
 	"github.com/iwoov/snell-master/backend/master/internal/service"
 	"github.com/iwoov/snell-master/backend/master/internal/template"
 )
@@ -16,13 +18,15 @@ import (
 type NodeHandler struct {
 	svc           *service.NodeService
 	configService *service.SystemConfigService
+	metricsSvc    *service.NodeMetricsService
 }
 
 // NewNodeHandler 构造函数。
-func NewNodeHandler(svc *service.NodeService, configService *service.SystemConfigService) *NodeHandler {
+func NewNodeHandler(svc *service.NodeService, configService *service.SystemConfigService, metricsSvc *service.NodeMetricsService) *NodeHandler {
 	return &NodeHandler{
 		svc:           svc,
 		configService: configService,
+		metricsSvc:    metricsSvc,
 	}
 }
 
@@ -48,12 +52,13 @@ func (h *NodeHandler) Create(c *gin.Context) {
 		common.Fail(c, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	node, err := h.svc.RegisterNode(req.Name, req.Endpoint, req.Location, req.CountryCode)
+	node, token, err := h.svc.RegisterNode(req.Name, req.Endpoint, req.Location, req.CountryCode)
 	if err != nil {
 		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	common.Created(c, node)
+	// 明文令牌只在创建时返回一次
+	common.Created(c, nodeWithToken{Node: node, APIToken: token})
 }
 
 // Get 返回详情。
@@ -105,22 +110,72 @@ func (h *NodeHandler) Delete(c *gin.Context) {
 	common.Success(c, gin.H{"deleted": id})
 }
 
-// RegenerateToken 重新生成 API Token。
+// Endpoints 返回节点地址列表。
+// GET /api/admin/nodes/:id/endpoints
+func (h *NodeHandler) Endpoints(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	endpoints, err := h.svc.GetEndpoints(uint(id))
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	common.Success(c, endpoints)
+}
+
+// SetEndpoints 整体替换节点地址，priority 越小越优先。
+// PUT /api/admin/nodes/:id/endpoints  body: {"endpoints": [{"type": "ipv6", "address": "2001:db8::1", "label": "v6", "priority": 0}]}
+func (h *NodeHandler) SetEndpoints(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req struct {
+		Endpoints []service.NodeEndpointInput `json:"endpoints" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	endpoints, err := h.svc.SetEndpoints(uint(id), req.Endpoints)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, endpoints)
+}
+
+// RegenerateToken 轮换 API Token，宽限期内旧令牌仍然有效，新令牌随响应下发给 Agent。
+// POST /api/admin/nodes/:id/token  body: {"grace_hours": 24}（可选）
 func (h *NodeHandler) RegenerateToken(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		common.Fail(c, http.StatusBadRequest, "invalid id")
 		return
 	}
-	token, err := h.svc.RegenerateToken(uint(id))
+	var req struct {
+		GraceHours *int `json:"grace_hours"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.Fail(c, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	rotation, err := h.svc.RotateToken(uint(id), req.GraceHours)
 	if err != nil {
 		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	common.Success(c, gin.H{"token": token})
+	common.Success(c, rotation)
 }
 
-// GetInstallScript 生成并下载节点部署脚本
+// GetInstallScript 生成并下载节点部署脚本。脚本不包含 API Token，
+// 运行时由参数传入创建节点或 POST /api/admin/nodes/:id/token 返回的令牌。
 // GET /api/admin/nodes/:id/install-script
 func (h *NodeHandler) GetInstallScript(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -146,7 +201,6 @@ func (h *NodeHandler) GetInstallScript(c *gin.Context) {
 	// 构造脚本数据
 	scriptData := template.InstallScriptData{
 		MasterURL:        systemConfig.MasterURL,
-		APIToken:         node.APIToken,
 		AgentVersion:     systemConfig.AgentVersion,
 		NodeName:         node.Name,
 		AgentDownloadURL: systemConfig.AgentDownloadURL,
@@ -167,3 +221,47 @@ func (h *NodeHandler) GetInstallScript(c *gin.Context) {
 	// 返回脚本内容
 	c.String(http.StatusOK, script)
 }
+
+// Metrics 返回节点指标时序。
+// GET /api/admin/nodes/:id/metrics?from=&to=&bucket=
+func (h *NodeHandler) Metrics(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	now := time.Now()
+	to, err := parseTimeParam(c.Query("to"), now)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid to")
+		return
+	}
+	from, err := parseTimeParam(c.Query("from"), to.Add(-24*time.Hour))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid from")
+		return
+	}
+	metrics, err := h.metricsSvc.GetMetrics(uint(id), from, to, c.Query("bucket"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, metrics)
+}
+
+// parseTimeParam 解析 RFC3339 或 Unix 秒格式的时间参数，为空时返回默认值。
+func parseTimeParam(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// nodeWithToken 创建节点时附带一次性返回的明文令牌。
+type nodeWithToken struct {
+	*model.Node
+	APIToken string `json:"api_token"`
+}
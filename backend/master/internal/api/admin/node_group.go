@@ -0,0 +1,198 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// NodeGroupHandler 管理节点组与用户授权。
+type NodeGroupHandler struct {
+	svc *service.NodeGroupService
+}
+
+// NewNodeGroupHandler 构造函数。
+func NewNodeGroupHandler(svc *service.NodeGroupService) *NodeGroupHandler {
+	return &NodeGroupHandler{svc: svc}
+}
+
+// List 返回全部节点组。
+func (h *NodeGroupHandler) List(c *gin.Context) {
+	groups, err := h.svc.ListGroups()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, groups)
+}
+
+// Create 创建节点组。
+func (h *NodeGroupHandler) Create(c *gin.Context) {
+	var req struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+		NodeIDs     []uint   `json:"node_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	group, err := h.svc.CreateGroup(req.Name, req.Description, req.Tags, req.NodeIDs)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Created(c, group)
+}
+
+// Get 返回节点组详情。
+func (h *NodeGroupHandler) Get(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	group, err := h.svc.GetGroup(uint(id))
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	common.Success(c, group)
+}
+
+// Update 修改节点组。
+func (h *NodeGroupHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	group, err := h.svc.UpdateGroup(uint(id), updates)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, group)
+}
+
+// Delete 删除节点组。
+func (h *NodeGroupHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DeleteGroup(uint(id)); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"deleted": id})
+}
+
+// SetNodes 替换节点组成员。
+func (h *NodeGroupHandler) SetNodes(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req struct {
+		NodeIDs []uint `json:"node_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	group, err := h.svc.SetGroupNodes(uint(id), req.NodeIDs)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, group)
+}
+
+// AddNode 将单个节点加入节点组。
+func (h *NodeGroupHandler) AddNode(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	nodeID, err := strconv.Atoi(c.Param("node_id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid node id")
+		return
+	}
+	if err := h.svc.AddNodeToGroup(uint(id), uint(nodeID)); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"added": nodeID})
+}
+
+// RemoveNode 将单个节点移出节点组。
+func (h *NodeGroupHandler) RemoveNode(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	nodeID, err := strconv.Atoi(c.Param("node_id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid node id")
+		return
+	}
+	if err := h.svc.RemoveNodeFromGroup(uint(id), uint(nodeID)); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"removed": nodeID})
+}
+
+// GetUserEntitlement 返回用户的节点组与标签授权。
+func (h *NodeGroupHandler) GetUserEntitlement(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	entitlement, err := h.svc.GetUserEntitlement(uint(userID))
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	common.Success(c, entitlement)
+}
+
+// SetUserEntitlement 替换用户的节点组与标签授权。
+func (h *NodeGroupHandler) SetUserEntitlement(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req struct {
+		GroupIDs []uint   `json:"group_ids"`
+		Tags     []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	entitlement, err := h.svc.SetUserEntitlement(uint(userID), req.GroupIDs, req.Tags)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, entitlement)
+}
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// NodeStatusHandler 提供节点状态历史与在线率查询。
+type NodeStatusHandler struct {
+	svc *service.NodeStatusService
+}
+
+// NewNodeStatusHandler 构造函数。
+func NewNodeStatusHandler(svc *service.NodeStatusService) *NodeStatusHandler {
+	return &NodeStatusHandler{svc: svc}
+}
+
+// Overview 返回所有节点的状态与在线率。
+// GET /api/admin/node-status
+func (h *NodeStatusHandler) Overview(c *gin.Context) {
+	summaries, err := h.svc.Overview()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, summaries)
+}
+
+// Events 返回节点状态变化记录。
+// GET /api/admin/nodes/:id/status-events?from=&to=&limit=
+func (h *NodeStatusHandler) Events(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	from, to, ok := parseRange(c, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	events, err := h.svc.ListEvents(uint(id), from, to, limit)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, events)
+}
+
+// Uptime 返回节点在时间范围内的在线率。
+// GET /api/admin/nodes/:id/uptime?from=&to=
+func (h *NodeStatusHandler) Uptime(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	from, to, ok := parseRange(c, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+	uptime, err := h.svc.GetUptime(uint(id), from, to)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, uptime)
+}
+
+// parseRange 解析 from/to 查询参数，缺省时取截至当前的 span 时长，解析失败时已写入响应。
+func parseRange(c *gin.Context, span time.Duration) (time.Time, time.Time, bool) {
+	to, err := parseTimeParam(c.Query("to"), time.Now())
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid to")
+		return time.Time{}, time.Time{}, false
+	}
+	from, err := parseTimeParam(c.Query("from"), to.Add(-span))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid from")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// ProbeHandler 提供实例端口可达性探测记录查询。
+type ProbeHandler struct {
+	svc *service.ProbeService
+}
+
+// NewProbeHandler 构造函数。
+func NewProbeHandler(svc *service.ProbeService) *ProbeHandler {
+	return &ProbeHandler{svc: svc}
+}
+
+// History 返回实例的可达状态、探测记录与按来源汇总的统计。
+// GET /api/admin/instances/:id/probes?from=&to=&limit=
+func (h *ProbeHandler) History(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	from, to, ok := parseRange(c, 24*time.Hour)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	history, err := h.svc.History(uint(id), from, to, limit)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, history)
+}
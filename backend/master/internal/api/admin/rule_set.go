@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// RuleSetHandler 管理模板可引用的规则集。
+type RuleSetHandler struct {
+	svc *service.RuleSetService
+}
+
+// NewRuleSetHandler 构造函数。
+func NewRuleSetHandler(svc *service.RuleSetService) *RuleSetHandler {
+	return &RuleSetHandler{svc: svc}
+}
+
+// List 返回规则集。
+// GET /api/admin/rule-sets
+func (h *RuleSetHandler) List(c *gin.Context) {
+	items, err := h.svc.ListRuleSets()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, items)
+}
+
+// Create 新建规则集。
+// POST /api/admin/rule-sets
+func (h *RuleSetHandler) Create(c *gin.Context) {
+	var req service.RuleSetInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	set, err := h.svc.CreateRuleSet(req)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Created(c, set)
+}
+
+// Update 编辑规则集。
+// PUT /api/admin/rule-sets/:id
+func (h *RuleSetHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	set, err := h.svc.UpdateRuleSet(uint(id), updates)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, set)
+}
+
+// Delete 删除规则集。
+// DELETE /api/admin/rule-sets/:id
+func (h *RuleSetHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DeleteRuleSet(uint(id)); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"deleted": id})
+}
@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// SnellReleaseHandler 管理 Snell Server 版本校验和与升级下发。
+type SnellReleaseHandler struct {
+	svc *service.SnellUpgradeService
+}
+
+// NewSnellReleaseHandler 构造函数。
+func NewSnellReleaseHandler(svc *service.SnellUpgradeService) *SnellReleaseHandler {
+	return &SnellReleaseHandler{svc: svc}
+}
+
+// List 返回已登记校验和的版本。
+func (h *SnellReleaseHandler) List(c *gin.Context) {
+	releases, err := h.svc.ListReleases()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, releases)
+}
+
+// Publish 登记版本各架构压缩包的校验和。
+func (h *SnellReleaseHandler) Publish(c *gin.Context) {
+	var req struct {
+		Version   string            `json:"version" binding:"required"`
+		Checksums map[string]string `json:"checksums" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	release, err := h.svc.PublishChecksums(req.Version, req.Checksums)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Created(c, release)
+}
+
+// Delete 删除版本校验和。
+func (h *SnellReleaseHandler) Delete(c *gin.Context) {
+	version := c.Param("version")
+	if err := h.svc.DeleteRelease(version); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"deleted": version})
+}
+
+// Pin 固定节点或全局的 Snell 版本。
+func (h *SnellReleaseHandler) Pin(c *gin.Context) {
+	var req service.SnellPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := h.svc.Pin(req); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, req)
+}
+
+// Progress 返回各节点的 Snell 版本与升级进度。
+func (h *SnellReleaseHandler) Progress(c *gin.Context) {
+	progress, err := h.svc.Progress()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, progress)
+}
@@ -20,8 +20,23 @@ func NewSubscribeHandler(svc *service.SubscribeService) *SubscribeHandler {
 	return &SubscribeHandler{svc: svc}
 }
 
-// List 返回所有订阅记录。
+// List 返回订阅令牌，指定 user_id 时只返回该用户的令牌。
+// GET /api/admin/subscriptions?user_id=
 func (h *SubscribeHandler) List(c *gin.Context) {
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := strconv.Atoi(raw)
+		if err != nil {
+			common.Fail(c, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		tokens, err := h.svc.ListUserTokens(uint(userID))
+		if err != nil {
+			common.Fail(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		common.Success(c, tokens)
+		return
+	}
 	tokens, err := h.svc.ListTokens()
 	if err != nil {
 		common.Fail(c, http.StatusInternalServerError, err.Error())
@@ -30,17 +45,18 @@ func (h *SubscribeHandler) List(c *gin.Context) {
 	common.Success(c, tokens)
 }
 
-// Create 创建订阅令牌。
+// Create 为用户新增订阅令牌。
+// POST /api/admin/subscriptions
 func (h *SubscribeHandler) Create(c *gin.Context) {
 	var req struct {
-		UserID     uint  `json:"user_id" binding:"required"`
-		TemplateID *uint `json:"template_id"`
+		UserID uint `json:"user_id" binding:"required"`
+		service.SubscribeTokenInput
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.Fail(c, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	token, err := h.svc.CreateToken(req.UserID, req.TemplateID, nil)
+	token, err := h.svc.CreateToken(req.UserID, req.SubscribeTokenInput)
 	if err != nil {
 		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
@@ -48,6 +64,27 @@ func (h *SubscribeHandler) Create(c *gin.Context) {
 	common.Created(c, token)
 }
 
+// Update 修改令牌设置。
+// PUT /api/admin/subscriptions/:id
+func (h *SubscribeHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	token, err := h.svc.UpdateToken(uint(id), updates)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, token)
+}
+
 // Delete 删除令牌。
 func (h *SubscribeHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -62,11 +99,12 @@ func (h *SubscribeHandler) Delete(c *gin.Context) {
 	common.Success(c, gin.H{"deleted": id})
 }
 
-// Regenerate 重新生成令牌。
+// Regenerate 重新生成令牌值。
+// POST /api/admin/subscriptions/:id/regenerate
 func (h *SubscribeHandler) Regenerate(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		common.Fail(c, http.StatusBadRequest, "invalid user id")
+		common.Fail(c, http.StatusBadRequest, "invalid id")
 		return
 	}
 	token, err := h.svc.RegenerateToken(uint(id))
@@ -74,5 +112,49 @@ func (h *SubscribeHandler) Regenerate(c *gin.Context) {
 		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	common.Success(c, gin.H{"token": token})
+	common.Success(c, token)
+}
+
+// Flagged 返回被泄露检测标记的令牌。
+// GET /api/admin/subscriptions/flagged
+func (h *SubscribeHandler) Flagged(c *gin.Context) {
+	tokens, err := h.svc.ListFlaggedTokens()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, tokens)
+}
+
+// Unflag 清除令牌的泄露标记。
+// POST /api/admin/subscriptions/:id/unflag
+func (h *SubscribeHandler) Unflag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	token, err := h.svc.ClearFlag(uint(id))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, token)
+}
+
+// AccessLogs 返回令牌最近的访问记录。
+// GET /api/admin/subscriptions/:id/access-logs?limit=200
+func (h *SubscribeHandler) AccessLogs(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	logs, err := h.svc.ListAccessLogs(uint(id), limit)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, logs)
 }
@@ -7,17 +7,20 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
 	"github.com/iwoov/snell-master/backend/master/internal/service"
+	"github.com/iwoov/snell-master/backend/master/internal/template"
 )
 
-// TemplateHandler 管理 Surge 模板。
+// TemplateHandler 管理订阅模板。
 type TemplateHandler struct {
-	svc *service.TemplateService
+	svc          *service.TemplateService
+	subscribeSvc *service.SubscribeService
 }
 
 // NewTemplateHandler 构造函数。
-func NewTemplateHandler(svc *service.TemplateService) *TemplateHandler {
-	return &TemplateHandler{svc: svc}
+func NewTemplateHandler(svc *service.TemplateService, subscribeSvc *service.SubscribeService) *TemplateHandler {
+	return &TemplateHandler{svc: svc, subscribeSvc: subscribeSvc}
 }
 
 // List 返回模板。
@@ -36,13 +39,14 @@ func (h *TemplateHandler) Create(c *gin.Context) {
 		Name        string `json:"name" binding:"required"`
 		Content     string `json:"content" binding:"required"`
 		Description string `json:"description"`
+		Target      string `json:"target"`
 		IsDefault   bool   `json:"is_default"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		common.Fail(c, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	tpl, err := h.svc.CreateTemplate(req.Name, req.Content, req.Description, req.IsDefault)
+	tpl, err := h.svc.CreateTemplate(req.Name, req.Content, req.Description, req.Target, req.IsDefault, middleware.GetUserID(c))
 	if err != nil {
 		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
@@ -50,7 +54,7 @@ func (h *TemplateHandler) Create(c *gin.Context) {
 	common.Created(c, tpl)
 }
 
-// Update 编辑模板。
+// Update 编辑模板，内容变化时记录新修订，可选 note 作为修订说明。
 func (h *TemplateHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -62,7 +66,7 @@ func (h *TemplateHandler) Update(c *gin.Context) {
 		common.Fail(c, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	tpl, err := h.svc.UpdateTemplate(uint(id), updates)
+	tpl, err := h.svc.UpdateTemplate(uint(id), updates, middleware.GetUserID(c))
 	if err != nil {
 		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
@@ -97,3 +101,140 @@ func (h *TemplateHandler) SetDefault(c *gin.Context) {
 	}
 	common.Success(c, gin.H{"default": id})
 }
+
+// Preview 以指定用户的数据渲染模板，content/target 为空时使用已保存的值，便于保存前检查错误。
+// POST /api/admin/templates/:id/preview
+func (h *TemplateHandler) Preview(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req struct {
+		UserID    uint   `json:"user_id" binding:"required"`
+		Content   string `json:"content"`
+		Target    string `json:"target"`
+		Endpoints string `json:"endpoints"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	tpl, err := h.svc.GetTemplate(uint(id))
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, "template not found")
+		return
+	}
+	content := tpl.Content
+	if req.Content != "" {
+		content = req.Content
+	}
+	target := tpl.Target
+	if req.Target != "" {
+		if target, err = template.ParseTarget(req.Target); err != nil {
+			common.Fail(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	mode, err := template.ParseEndpointMode(req.Endpoints)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.subscribeSvc.PreviewTemplate(req.UserID, target, content, template.Options{EndpointMode: mode})
+	if err != nil {
+		common.Fail(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	common.Success(c, gin.H{
+		"target":       result.Target,
+		"content_type": result.ContentType,
+		"content":      result.Body,
+	})
+}
+
+// Revisions 返回模板的修订历史。
+// GET /api/admin/templates/:id/revisions
+func (h *TemplateHandler) Revisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	revs, err := h.svc.ListRevisions(uint(id))
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, "template not found")
+		return
+	}
+	common.Success(c, revs)
+}
+
+// Revision 返回指定修订。
+// GET /api/admin/templates/:id/revisions/:revision
+func (h *TemplateHandler) Revision(c *gin.Context) {
+	id, revision, ok := templateRevisionParams(c)
+	if !ok {
+		return
+	}
+	rev, err := h.svc.GetRevision(id, revision)
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, "revision not found")
+		return
+	}
+	common.Success(c, rev)
+}
+
+// CompareRevisions 返回两个修订之间的差异。
+// GET /api/admin/templates/:id/compare?from=1&to=2
+func (h *TemplateHandler) CompareRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid from")
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid to")
+		return
+	}
+	diff, err := h.svc.CompareRevisions(uint(id), from, to)
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	common.Success(c, diff)
+}
+
+// Rollback 将模板恢复为指定修订的内容。
+// POST /api/admin/templates/:id/revisions/:revision/rollback
+func (h *TemplateHandler) Rollback(c *gin.Context) {
+	id, revision, ok := templateRevisionParams(c)
+	if !ok {
+		return
+	}
+	tpl, err := h.svc.RollbackTemplate(id, revision, middleware.GetUserID(c))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, tpl)
+}
+
+func templateRevisionParams(c *gin.Context) (uint, int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return 0, 0, false
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid revision")
+		return 0, 0, false
+	}
+	return uint(id), revision, true
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// EnrollHandler 处理节点使用注册令牌的自助注册。
+type EnrollHandler struct {
+	svc *service.EnrollmentService
+}
+
+// NewEnrollHandler 构造函数。
+func NewEnrollHandler(svc *service.EnrollmentService) *EnrollHandler {
+	return &EnrollHandler{svc: svc}
+}
+
+// Enroll 校验注册令牌并返回节点的永久 API Token。
+// POST /api/agent/enroll
+func (h *EnrollHandler) Enroll(c *gin.Context) {
+	var req EnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": "invalid request body"})
+		return
+	}
+	publicIP := req.PublicIP
+	if publicIP == "" {
+		publicIP = c.ClientIP()
+	}
+	node, apiToken, err := h.svc.Enroll(service.EnrollRequest{
+		Token:       req.Token,
+		Name:        req.NodeName,
+		Hostname:    req.Hostname,
+		PublicIP:    publicIP,
+		Location:    req.Location,
+		CountryCode: req.CountryCode,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEnrollmentTokenInvalid), errors.Is(err, repository.ErrEnrollmentTokenUnusable):
+			c.JSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "message": err.Error()})
+		case errors.Is(err, service.ErrInvalidEnrollRequest):
+			c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"code": http.StatusInternalServerError, "message": "enroll node failed"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": EnrollResponse{
+			NodeID:   node.ID,
+			NodeName: node.Name,
+			APIToken: apiToken,
+		},
+	})
+}
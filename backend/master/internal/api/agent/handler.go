@@ -15,11 +15,12 @@ type Handler struct {
 	nodeSvc     *service.NodeService
 	instanceSvc *service.InstanceService
 	trafficSvc  *service.TrafficService
+	settingsSvc *service.AgentSettingsService
 }
 
 // NewHandler 构造函数。
-func NewHandler(nodeSvc *service.NodeService, instanceSvc *service.InstanceService, trafficSvc *service.TrafficService) *Handler {
-	return &Handler{nodeSvc: nodeSvc, instanceSvc: instanceSvc, trafficSvc: trafficSvc}
+func NewHandler(nodeSvc *service.NodeService, instanceSvc *service.InstanceService, trafficSvc *service.TrafficService, settingsSvc *service.AgentSettingsService) *Handler {
+	return &Handler{nodeSvc: nodeSvc, instanceSvc: instanceSvc, trafficSvc: trafficSvc, settingsSvc: settingsSvc}
 }
 
 // GetConfig 返回节点上的实例配置。
@@ -34,7 +35,19 @@ func (h *Handler) GetConfig(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
 	}
-	result := ConfigResponse{Instances: make([]InstanceConfig, 0, len(instances))}
+	listen, err := h.nodeSvc.ListenAddress(node.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	// 维护期间仍下发完整列表，缺失的实例会被 Agent 当作已删除并清理文件
+	result := ConfigResponse{
+		Instances: make([]InstanceConfig, 0, len(instances)),
+		Paused:    node.MaintenanceStopInstances && node.InMaintenance(time.Now()),
+	}
+	if settings := h.settingsSvc.Effective(node); !settings.IsEmpty() {
+		result.Settings = &settings
+	}
 	for _, inst := range instances {
 		username := ""
 		username = inst.User.Username
@@ -46,6 +59,7 @@ func (h *Handler) GetConfig(c *gin.Context) {
 			PSK:      inst.PSK,
 			Version:  inst.Version,
 			Obfs:     inst.Obfs,
+			Listen:   listen,
 		})
 	}
 	// 包装为标准响应格式
@@ -68,7 +82,27 @@ func (h *Handler) Heartbeat(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
 		return
 	}
-	if err := h.nodeSvc.UpdateHeartbeat(node.APIToken, req.CPUUsage, req.MemoryUsage, req.InstanceCount, req.Status, req.Version); err != nil {
+	report := service.HeartbeatReport{
+		Status:         req.Status,
+		Version:        req.Version,
+		CPUUsage:       req.CPUUsage,
+		MemoryUsage:    req.MemoryUsage,
+		DiskUsage:      req.DiskUsage,
+		NetworkRxRate:  req.NetworkRxRate,
+		NetworkTxRate:  req.NetworkTxRate,
+		Load1:          req.Load1,
+		Load5:          req.Load5,
+		Load15:         req.Load15,
+		UptimeSeconds:  req.UptimeSeconds,
+		KernelVersion:  req.KernelVersion,
+		SnellVersion:   req.SnellVersion,
+		PortRangeStart: req.PortRangeStart,
+		PortRangeEnd:   req.PortRangeEnd,
+		InstanceCount:  req.InstanceCount,
+
+		HeartbeatInterval: req.HeartbeatInterval,
+	}
+	if err := h.nodeSvc.UpdateHeartbeat(node.ID, report); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
 		return
 	}
@@ -107,3 +141,31 @@ func (h *Handler) ReportInstanceStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
+
+// ReportEvents 记录实例配置应用与回滚事件。
+func (h *Handler) ReportEvents(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	var req EventReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid request body"})
+		return
+	}
+	inputs := make([]service.InstanceEventInput, 0, len(req.Events))
+	for _, event := range req.Events {
+		inputs = append(inputs, service.InstanceEventInput{
+			InstanceID: event.InstanceID,
+			Type:       event.Type,
+			Message:    event.Message,
+			OccurredAt: event.Timestamp,
+		})
+	}
+	if err := h.instanceSvc.RecordEvents(node.ID, inputs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
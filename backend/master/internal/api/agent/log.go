@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// maxLogPollWait 日志请求长轮询的最长等待时间。
+const maxLogPollWait = 30 * time.Second
+
+// LogHandler 处理 Master 下发的实例日志读取请求。
+type LogHandler struct {
+	svc *service.InstanceLogService
+}
+
+// NewLogHandler 构造函数。
+func NewLogHandler(svc *service.InstanceLogService) *LogHandler {
+	return &LogHandler{svc: svc}
+}
+
+// PollRequests 长轮询待处理的日志请求，wait 为最长等待秒数。
+// GET /api/agent/log-requests?wait=
+func (h *LogHandler) PollRequests(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	seconds, _ := strconv.Atoi(c.DefaultQuery("wait", "0"))
+	wait := time.Duration(seconds) * time.Second
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxLogPollWait {
+		wait = maxLogPollWait
+	}
+	requests := h.svc.Poll(c.Request.Context(), node.ID, wait)
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": requests})
+}
+
+// ReportResponse 接收 Agent 读取到的日志。
+// POST /api/agent/log-responses
+func (h *LogHandler) ReportResponse(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	var req service.LogResponse
+	if err := c.ShouldBindJSON(&req); err != nil || req.RequestID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": "invalid request body"})
+		return
+	}
+	if err := h.svc.Complete(node.ID, req); err != nil {
+		// 请求可能已超时被丢弃，Agent 无需重试
+		c.JSON(http.StatusNotFound, gin.H{"code": http.StatusNotFound, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
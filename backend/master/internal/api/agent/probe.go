@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// ProbeHandler 处理 Agent 协助的实例端口探测。
+type ProbeHandler struct {
+	svc *service.ProbeService
+}
+
+// NewProbeHandler 构造函数。
+func NewProbeHandler(svc *service.ProbeService) *ProbeHandler {
+	return &ProbeHandler{svc: svc}
+}
+
+// GetTargets 返回需要该节点协助探测的其他节点实例。
+// GET /api/agent/probe-targets
+func (h *ProbeHandler) GetTargets(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	targets, err := h.svc.ProbeTargets(node.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": http.StatusInternalServerError, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": targets})
+}
+
+// ReportResults 记录 Agent 的探测结果。
+// POST /api/agent/probe-results
+func (h *ProbeHandler) ReportResults(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	var req ProbeReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": "invalid request body"})
+		return
+	}
+	if err := h.svc.RecordAgentResults(node.ID, req.Results); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
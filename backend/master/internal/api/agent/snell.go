@@ -1,26 +1,35 @@
 package agent
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
 	"github.com/iwoov/snell-master/backend/master/internal/service"
 )
 
 // SnellHandler Snell 配置处理器
 type SnellHandler struct {
-	configService *service.SystemConfigService
+	upgradeSvc *service.SnellUpgradeService
 }
 
 // NewSnellHandler 创建 Snell 配置处理器实例
-func NewSnellHandler(configService *service.SystemConfigService) *SnellHandler {
+func NewSnellHandler(upgradeSvc *service.SnellUpgradeService) *SnellHandler {
 	return &SnellHandler{
-		configService: configService,
+		upgradeSvc: upgradeSvc,
 	}
 }
 
-// GetSnellConfig 获取 Snell Server 下载配置
+// GetSnellConfig 获取节点目标版本的 Snell Server 下载配置与校验和
 // GET /api/agent/snell-config
 func (h *SnellHandler) GetSnellConfig(c *gin.Context) {
-	config, err := h.configService.GetSnellConfig()
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	config, err := h.upgradeSvc.ConfigForNode(node)
 	if err != nil {
 		c.JSON(500, gin.H{
 			"code":    500,
@@ -35,3 +44,30 @@ func (h *SnellHandler) GetSnellConfig(c *gin.Context) {
 		"data":    config,
 	})
 }
+
+// ReportUpgrade 记录 Snell Server 升级进度
+// POST /api/agent/snell-upgrade
+func (h *SnellHandler) ReportUpgrade(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	var req SnellUpgradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": "invalid request body"})
+		return
+	}
+	report := service.SnellUpgradeReport{
+		Version: req.Version,
+		Status:  req.Status,
+		Done:    req.Done,
+		Total:   req.Total,
+		Message: req.Message,
+	}
+	if err := h.upgradeSvc.ReportProgress(node.ID, report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
@@ -1,8 +1,17 @@
 package agent
 
+import (
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
 // ConfigResponse 返回节点实例配置。
 type ConfigResponse struct {
-	Instances []InstanceConfig `json:"instances"`
+	Instances []InstanceConfig     `json:"instances"`
+	Settings  *model.AgentSettings `json:"settings,omitempty"` // Agent 运行时设置，未配置时省略
+	Paused    bool                 `json:"paused,omitempty"`   // 维护期间要求停止实例，Agent 停止实例但保留配置与状态
 }
 
 // InstanceConfig 返回节点实例的配置。
@@ -14,15 +23,29 @@ type InstanceConfig struct {
 	PSK      string `json:"psk"`
 	Version  int    `json:"version"`
 	Obfs     string `json:"obfs,omitempty"`
+	Listen   string `json:"listen"` // 监听地址，节点有 IPv6 地址时为 ::0（双栈）
 }
 
-// HeartbeatRequest 节点心跳上报。
+// HeartbeatRequest 节点心跳上报，可选指标未采集时为空。
 type HeartbeatRequest struct {
-	CPUUsage      float64 `json:"cpu_usage"`
-	MemoryUsage   float64 `json:"memory_usage"`
-	InstanceCount int     `json:"instance_count"`
-	Version       string  `json:"version"`
-	Status        string  `json:"status"`
+	CPUUsage       *float64 `json:"cpu_usage"`
+	MemoryUsage    *float64 `json:"memory_usage"`
+	DiskUsage      *float64 `json:"disk_usage"`
+	NetworkRxRate  *uint64  `json:"network_rx_rate"`
+	NetworkTxRate  *uint64  `json:"network_tx_rate"`
+	Load1          *float64 `json:"load1"`
+	Load5          *float64 `json:"load5"`
+	Load15         *float64 `json:"load15"`
+	UptimeSeconds  uint64   `json:"uptime_seconds"`
+	KernelVersion  string   `json:"kernel_version"`
+	SnellVersion   string   `json:"snell_version"`
+	PortRangeStart int      `json:"port_range_start"`
+	PortRangeEnd   int      `json:"port_range_end"`
+	InstanceCount  int      `json:"instance_count"`
+	Version        string   `json:"version"`
+	Status         string   `json:"status"`
+
+	HeartbeatInterval int `json:"heartbeat_interval"` // Agent 当前的心跳间隔（秒），旧版本 Agent 不上报
 }
 
 // TrafficReportRequest 节点流量上报。
@@ -49,3 +72,54 @@ type InstanceStatus struct {
 	InstanceID uint   `json:"instance_id"`
 	Status     string `json:"status"`
 }
+
+// EventReportRequest 实例事件上报。
+type EventReportRequest struct {
+	Events []InstanceEvent `json:"events"`
+}
+
+// InstanceEvent 单条实例事件。
+type InstanceEvent struct {
+	InstanceID uint      `json:"instance_id"`
+	Type       string    `json:"type"`
+	Message    string    `json:"message"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// EnrollRequest 节点自助注册请求。
+type EnrollRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NodeName    string `json:"node_name"`
+	Hostname    string `json:"hostname"`
+	PublicIP    string `json:"public_ip"`
+	Location    string `json:"location"`
+	CountryCode string `json:"country_code"`
+}
+
+// EnrollResponse 节点自助注册结果。
+type EnrollResponse struct {
+	NodeID   uint   `json:"node_id"`
+	NodeName string `json:"node_name"`
+	APIToken string `json:"api_token"`
+}
+
+// UpdateStatusRequest Agent 自升级状态上报。
+type UpdateStatusRequest struct {
+	Version string `json:"version"`
+	Status  string `json:"status" binding:"required"`
+	Message string `json:"message"`
+}
+
+// SnellUpgradeRequest Snell Server 升级进度上报。
+type SnellUpgradeRequest struct {
+	Version string `json:"version"`
+	Status  string `json:"status" binding:"required"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Message string `json:"message"`
+}
+
+// ProbeReportRequest Agent 协助探测的结果上报。
+type ProbeReportRequest struct {
+	Results []service.ProbeResult `json:"results"`
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// UpdateHandler 处理 Agent 自升级。
+type UpdateHandler struct {
+	svc *service.AgentUpdateService
+}
+
+// NewUpdateHandler 构造函数。
+func NewUpdateHandler(svc *service.AgentUpdateService) *UpdateHandler {
+	return &UpdateHandler{svc: svc}
+}
+
+// GetUpdate 返回节点应升级到的 Agent 版本、下载地址与校验和。
+// GET /api/agent/update?arch=
+func (h *UpdateHandler) GetUpdate(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	arch := c.Query("arch")
+	if arch == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": "arch is required"})
+		return
+	}
+	target, err := h.svc.TargetForNode(node, arch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": http.StatusInternalServerError, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": target})
+}
+
+// ReportStatus 记录 Agent 自升级进度。
+// POST /api/agent/update-status
+func (h *UpdateHandler) ReportStatus(c *gin.Context) {
+	node := middleware.GetNode(c)
+	if node == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "node unavailable"})
+		return
+	}
+	var req UpdateStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": "invalid request body"})
+		return
+	}
+	if err := h.svc.ReportStatus(node.ID, req.Version, req.Status, req.Message); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
@@ -19,20 +19,37 @@ type Handlers struct {
 	Admin           *adminapi.AdminHandler
 	AdminUser       *adminapi.UserHandler
 	Node            *adminapi.NodeHandler
+	NodeStatus      *adminapi.NodeStatusHandler
+	NodeGroup       *adminapi.NodeGroupHandler
+	Maintenance     *adminapi.MaintenanceHandler
+	Enrollment      *adminapi.EnrollmentHandler
+	AgentRelease    *adminapi.AgentReleaseHandler
+	AgentSettings   *adminapi.AgentSettingsHandler
+	SnellRelease    *adminapi.SnellReleaseHandler
 	Instance        *adminapi.InstanceHandler
+	InstanceLog     *adminapi.InstanceLogHandler
+	Probe           *adminapi.ProbeHandler
 	Traffic         *adminapi.TrafficHandler
 	Subscribe       *adminapi.SubscribeHandler
 	Template        *adminapi.TemplateHandler
+	RuleSet         *adminapi.RuleSetHandler
 	Log             *adminapi.LogHandler
 	Dashboard       *adminapi.DashboardHandler
 	SystemConfig    *adminapi.SystemConfigHandler
 	UserProfile     *userapi.ProfileHandler
 	UserInstance    *userapi.InstanceHandler
+	UserProbe       *userapi.ProbeHandler
 	UserTraffic     *userapi.TrafficHandler
 	UserSubscribe   *userapi.SubscribeHandler
+	UserMaintenance *userapi.MaintenanceHandler
 	Agent           *agentapi.Handler
 	AgentSnell      *agentapi.SnellHandler
+	AgentEnroll     *agentapi.EnrollHandler
+	AgentUpdate     *agentapi.UpdateHandler
+	AgentLog        *agentapi.LogHandler
+	AgentProbe      *agentapi.ProbeHandler
 	PublicSubscribe *publicapi.SubscribeHandler
+	PublicStatus    *publicapi.StatusHandler
 }
 
 // NewHandlers 初始化所有 Handler。
@@ -42,20 +59,37 @@ func NewHandlers(services *service.Services, db *gorm.DB, startTime time.Time) *
 		Health:          publicapi.NewHealthHandler(db, startTime),
 		Admin:           adminapi.NewAdminHandler(services.Admin),
 		AdminUser:       adminapi.NewUserHandler(services.User),
-		Node:            adminapi.NewNodeHandler(services.Node, services.SystemConfig),
+		Node:            adminapi.NewNodeHandler(services.Node, services.SystemConfig, services.NodeMetrics),
+		NodeStatus:      adminapi.NewNodeStatusHandler(services.NodeStatus),
+		NodeGroup:       adminapi.NewNodeGroupHandler(services.NodeGroup),
+		Maintenance:     adminapi.NewMaintenanceHandler(services.Maintenance),
+		Enrollment:      adminapi.NewEnrollmentHandler(services.Enrollment),
+		AgentRelease:    adminapi.NewAgentReleaseHandler(services.AgentUpdate),
+		AgentSettings:   adminapi.NewAgentSettingsHandler(services.AgentSettings),
+		SnellRelease:    adminapi.NewSnellReleaseHandler(services.SnellUpgrade),
 		Instance:        adminapi.NewInstanceHandler(services.Instance),
+		InstanceLog:     adminapi.NewInstanceLogHandler(services.InstanceLog),
+		Probe:           adminapi.NewProbeHandler(services.Probe),
 		Traffic:         adminapi.NewTrafficHandler(services.Traffic),
 		Subscribe:       adminapi.NewSubscribeHandler(services.Subscribe),
-		Template:        adminapi.NewTemplateHandler(services.Template),
+		Template:        adminapi.NewTemplateHandler(services.Template, services.Subscribe),
+		RuleSet:         adminapi.NewRuleSetHandler(services.RuleSet),
 		Log:             adminapi.NewLogHandler(services.Log),
 		Dashboard:       adminapi.NewDashboardHandler(services.Dashboard),
 		SystemConfig:    adminapi.NewSystemConfigHandler(services.SystemConfig),
 		UserProfile:     userapi.NewProfileHandler(services.User),
 		UserInstance:    userapi.NewInstanceHandler(services.Instance),
+		UserProbe:       userapi.NewProbeHandler(services.Instance, services.Probe),
 		UserTraffic:     userapi.NewTrafficHandler(services.Traffic),
 		UserSubscribe:   userapi.NewSubscribeHandler(services.Subscribe),
-		Agent:           agentapi.NewHandler(services.Node, services.Instance, services.Traffic),
-		AgentSnell:      agentapi.NewSnellHandler(services.SystemConfig),
+		UserMaintenance: userapi.NewMaintenanceHandler(services.Maintenance),
+		Agent:           agentapi.NewHandler(services.Node, services.Instance, services.Traffic, services.AgentSettings),
+		AgentSnell:      agentapi.NewSnellHandler(services.SnellUpgrade),
+		AgentEnroll:     agentapi.NewEnrollHandler(services.Enrollment),
+		AgentUpdate:     agentapi.NewUpdateHandler(services.AgentUpdate),
+		AgentLog:        agentapi.NewLogHandler(services.InstanceLog),
+		AgentProbe:      agentapi.NewProbeHandler(services.Probe),
 		PublicSubscribe: publicapi.NewSubscribeHandler(services.Subscribe),
+		PublicStatus:    publicapi.NewStatusHandler(services.NodeStatus),
 	}
 }
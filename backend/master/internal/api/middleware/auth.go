@@ -6,9 +6,9 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
 	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
 	jwtutil "github.com/iwoov/snell-master/pkg/jwt"
 )
 
@@ -42,31 +42,38 @@ func AuthMiddleware(secret string) gin.HandlerFunc {
 	}
 }
 
+// RotatedTokenHeader 令牌轮换宽限期内携带新令牌的响应头。
+const RotatedTokenHeader = "X-Rotated-API-Token"
+
 // AgentAuth 验证节点 API Token。
-func AgentAuth(db *gorm.DB) gin.HandlerFunc {
+func AgentAuth(nodeSvc *service.NodeService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiToken := strings.TrimSpace(c.GetHeader("X-API-Token"))
 		if apiToken == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "missing api token"})
 			return
 		}
-		if db == nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "database unavailable"})
+		if nodeSvc == nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "node service unavailable"})
 			return
 		}
 
-		var node model.Node
-		if err := db.Where("api_token = ?", apiToken).First(&node).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
+		node, rotated, err := nodeSvc.Authenticate(apiToken)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidNodeToken) {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "invalid api token"})
 				return
 			}
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "query node failed"})
 			return
 		}
+		// 旧令牌仍在宽限期内时通过已认证的响应下发新令牌
+		if rotated != "" {
+			c.Header(RotatedTokenHeader, rotated)
+		}
 
 		c.Set(contextNodeIDKey, node.ID)
-		c.Set(contextNodeKey, &node)
+		c.Set(contextNodeKey, node)
 		c.Next()
 	}
 }
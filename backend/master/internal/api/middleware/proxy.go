@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/pkg/logger"
+)
+
+// UntrustedForwardWarning 未配置受信任代理时，首次收到带转发头的请求记录一次警告。
+// 此时 ClientIP 为代理地址，按 IP 限流会作用于所有经代理的客户端，订阅泄露检测也无法区分来源。
+func UntrustedForwardWarning() gin.HandlerFunc {
+	var once sync.Once
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Forwarded-For") != "" || c.GetHeader("X-Real-IP") != "" {
+			once.Do(func() {
+				logger.WithFields(logrus.Fields{"remote_ip": c.RemoteIP()}).
+					Warn("request carries X-Forwarded-For but server.trusted_proxies is empty; per-IP rate limits and leak detection see the proxy address, set trusted_proxies when running behind a reverse proxy")
+			})
+		}
+		c.Next()
+	}
+}
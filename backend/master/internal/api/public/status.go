@@ -0,0 +1,35 @@
+package public
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// StatusHandler 提供公开节点状态页数据。
+type StatusHandler struct {
+	svc *service.NodeStatusService
+}
+
+// NewStatusHandler 构造函数。
+func NewStatusHandler(svc *service.NodeStatusService) *StatusHandler {
+	return &StatusHandler{svc: svc}
+}
+
+// Status 返回节点状态与在线率，未开放状态页时返回 404。
+// GET /api/status
+func (h *StatusHandler) Status(c *gin.Context) {
+	if !h.svc.StatusPageEnabled() {
+		common.Fail(c, http.StatusNotFound, "status page disabled")
+		return
+	}
+	nodes, err := h.svc.PublicStatus()
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, "failed to load node status")
+		return
+	}
+	common.Success(c, gin.H{"nodes": nodes})
+}
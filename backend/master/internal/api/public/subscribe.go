@@ -1,11 +1,19 @@
 package public
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/iwoov/snell-master/backend/master/internal/model"
 	"github.com/iwoov/snell-master/backend/master/internal/service"
+	"github.com/iwoov/snell-master/backend/master/internal/template"
 )
 
 // SubscribeHandler 提供订阅内容。
@@ -18,18 +26,88 @@ func NewSubscribeHandler(subscribeSvc *service.SubscribeService) *SubscribeHandl
 	return &SubscribeHandler{subscribeSvc: subscribeSvc}
 }
 
-// GetSurgeSubscription 返回 Surge 配置。
-func (h *SubscribeHandler) GetSurgeSubscription(c *gin.Context) {
+// GetSubscription 返回订阅配置。target 指定客户端格式，未指定时使用令牌设置的格式或按 User-Agent 识别；
+// endpoints=all 时节点的每个地址各生成一个代理。响应带 ETag，If-None-Match 命中时返回 304；
+// 超过令牌或 IP 的请求频率限制时返回 429。
+// GET /api/subscribe/:token?target=surge|clash|stash|loon&endpoints=preferred|all
+func (h *SubscribeHandler) GetSubscription(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "token required"})
 		return
 	}
-	content, err := h.subscribeSvc.GenerateSurgeConfig(token)
+	if err := h.subscribeSvc.AllowRequest(token, c.ClientIP()); err != nil {
+		var limited *service.RateLimitError
+		if errors.As(err, &limited) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": err.Error()})
+		return
+	}
+	target, err := template.ParseTarget(c.Query("target"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	mode, err := template.ParseEndpointMode(c.Query("endpoints"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	content, err := h.subscribeSvc.GenerateConfig(token, service.SubscriptionRequest{
+		Target:    target,
+		UserAgent: c.GetHeader("User-Agent"),
+		ClientIP:  c.ClientIP(),
+		Options:   template.Options{EndpointMode: mode},
+	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
-	c.Header("Content-Type", "text/plain; charset=utf-8")
-	c.String(http.StatusOK, content)
+	body := content.Body
+	if content.Target == model.TemplateTargetSurge {
+		body = fmt.Sprintf("#!MANAGED-CONFIG %s interval=%d strict=%t\n%s",
+			subscriptionURL(c, content.MasterURL), content.UpdateInterval, content.ManagedStrict, body)
+	}
+	c.Header("Subscription-Userinfo", content.Userinfo)
+	// Clash 系客户端按小时读取更新间隔
+	c.Header("Profile-Update-Interval", strconv.Itoa(max(content.UpdateInterval/3600, 1)))
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(content.Filename))
+	// 客户端可缓存内容，但每次使用前需要用 ETag 重新验证
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("ETag", content.ETag)
+	if etagMatches(c.GetHeader("If-None-Match"), content.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, content.ContentType, []byte(body))
+}
+
+// etagMatches 按弱比较判断 If-None-Match 是否包含当前 ETag。
+func etagMatches(header, etag string) bool {
+	if header == "" || etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionURL 返回本次请求的完整订阅地址，配置了 master_url 时以其为准。
+func subscriptionURL(c *gin.Context, masterURL string) string {
+	if masterURL == "" {
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		masterURL = scheme + "://" + c.Request.Host
+	}
+	return masterURL + c.Request.URL.RequestURI()
 }
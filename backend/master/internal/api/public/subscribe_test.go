@@ -0,0 +1,27 @@
+package public
+
+import "testing"
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"abc123"`
+	cases := []struct {
+		header string
+		want   bool
+	}{
+		{``, false},
+		{`"abc123"`, true},
+		{`W/"abc123"`, true},
+		{`"other", "abc123"`, true},
+		{`"other"`, false},
+		{`*`, true},
+		{`abc123`, false},
+	}
+	for _, tc := range cases {
+		if got := etagMatches(tc.header, etag); got != tc.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+	if etagMatches(`*`, "") {
+		t.Error("empty etag should never match")
+	}
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 
 	"github.com/iwoov/snell-master/backend/master/internal/api/handler"
 	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
@@ -11,7 +10,7 @@ import (
 )
 
 // SetupRouter 注册所有路由并返回 gin Engine。
-func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service.LogService, db *gorm.DB) *gin.Engine {
+func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service.LogService, nodeSvc *service.NodeService) *gin.Engine {
 	switch cfg.Server.Mode {
 	case gin.ReleaseMode:
 		gin.SetMode(gin.ReleaseMode)
@@ -20,7 +19,14 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 	}
 
 	r := gin.New()
+	// 只采信受信任代理转发的客户端地址，限流与订阅访问记录依赖 ClientIP；配置已在加载时校验
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		_ = r.SetTrustedProxies(nil)
+	}
 	r.Use(middleware.Logger(cfg.Log.IgnorePaths), middleware.CORS(cfg.CORS), gin.Recovery())
+	if len(cfg.Server.TrustedProxies) == 0 {
+		r.Use(middleware.UntrustedForwardWarning())
+	}
 
 	r.GET("/healthz", handlers.Health.Health)
 
@@ -28,9 +34,10 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 	{
 		publicGroup.POST("/auth/admin/login", handlers.Auth.AdminLogin)
 		publicGroup.POST("/auth/user/login", handlers.Auth.UserLogin)
-		publicGroup.GET("/subscribe/:token", handlers.PublicSubscribe.GetSurgeSubscription)
+		publicGroup.GET("/subscribe/:token", handlers.PublicSubscribe.GetSubscription)
 		publicGroup.GET("/health", handlers.Health.Health)
 		publicGroup.GET("/ping", handlers.Health.Ping)
+		publicGroup.GET("/status", handlers.PublicStatus.Status)
 	}
 
 	adminGroup := r.Group("/api/admin")
@@ -55,6 +62,8 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 		users.POST("/:id/reset", handlers.AdminUser.ResetTraffic)
 		users.POST("/:id/status", handlers.AdminUser.UpdateStatus)
 		users.POST("/:id/nodes", handlers.AdminUser.AssignNodes)
+		users.GET("/:id/entitlements", handlers.NodeGroup.GetUserEntitlement)
+		users.PUT("/:id/entitlements", handlers.NodeGroup.SetUserEntitlement)
 
 		nodes := adminGroup.Group("/nodes")
 		nodes.GET("", handlers.Node.List)
@@ -63,7 +72,48 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 		nodes.PUT("/:id", handlers.Node.Update)
 		nodes.DELETE("/:id", handlers.Node.Delete)
 		nodes.POST("/:id/token", handlers.Node.RegenerateToken)
+		nodes.GET("/:id/endpoints", handlers.Node.Endpoints)
+		nodes.PUT("/:id/endpoints", handlers.Node.SetEndpoints)
 		nodes.GET("/:id/install-script", handlers.Node.GetInstallScript)
+		nodes.GET("/:id/metrics", handlers.Node.Metrics)
+		nodes.GET("/:id/status-events", handlers.NodeStatus.Events)
+		nodes.GET("/:id/uptime", handlers.NodeStatus.Uptime)
+		nodes.PUT("/:id/maintenance", handlers.Maintenance.Schedule)
+		nodes.DELETE("/:id/maintenance", handlers.Maintenance.End)
+		nodes.GET("/:id/agent-settings", handlers.AgentSettings.GetNode)
+		nodes.PUT("/:id/agent-settings", handlers.AgentSettings.SetNode)
+
+		nodeGroups := adminGroup.Group("/node-groups")
+		nodeGroups.GET("", handlers.NodeGroup.List)
+		nodeGroups.POST("", handlers.NodeGroup.Create)
+		nodeGroups.GET("/:id", handlers.NodeGroup.Get)
+		nodeGroups.PUT("/:id", handlers.NodeGroup.Update)
+		nodeGroups.DELETE("/:id", handlers.NodeGroup.Delete)
+		nodeGroups.PUT("/:id/nodes", handlers.NodeGroup.SetNodes)
+		nodeGroups.POST("/:id/nodes/:node_id", handlers.NodeGroup.AddNode)
+		nodeGroups.DELETE("/:id/nodes/:node_id", handlers.NodeGroup.RemoveNode)
+
+		enrollments := adminGroup.Group("/enrollment-tokens")
+		enrollments.GET("", handlers.Enrollment.List)
+		enrollments.POST("", handlers.Enrollment.Create)
+		enrollments.DELETE("/:id", handlers.Enrollment.Revoke)
+
+		agentReleases := adminGroup.Group("/agent-releases")
+		agentReleases.GET("", handlers.AgentRelease.List)
+		agentReleases.POST("", handlers.AgentRelease.Publish)
+		agentReleases.DELETE("/:version", handlers.AgentRelease.Delete)
+		agentReleases.POST("/rollout", handlers.AgentRelease.Rollout)
+		agentReleases.GET("/progress", handlers.AgentRelease.Progress)
+
+		adminGroup.GET("/agent-settings", handlers.AgentSettings.GetGlobal)
+		adminGroup.PUT("/agent-settings", handlers.AgentSettings.SetGlobal)
+
+		snellReleases := adminGroup.Group("/snell-releases")
+		snellReleases.GET("", handlers.SnellRelease.List)
+		snellReleases.POST("", handlers.SnellRelease.Publish)
+		snellReleases.DELETE("/:version", handlers.SnellRelease.Delete)
+		snellReleases.POST("/pin", handlers.SnellRelease.Pin)
+		snellReleases.GET("/progress", handlers.SnellRelease.Progress)
 
 		instances := adminGroup.Group("/instances")
 		instances.GET("", handlers.Instance.List)
@@ -72,6 +122,9 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 		instances.DELETE("/:id", handlers.Instance.Delete)
 		instances.PUT("/:id/status", handlers.Instance.UpdateStatus)
 		instances.POST("/:id/restart", handlers.Instance.Restart)
+		instances.GET("/:id/events", handlers.Instance.Events)
+		instances.GET("/:id/logs", handlers.InstanceLog.Get)
+		instances.GET("/:id/probes", handlers.Probe.History)
 
 		traffic := adminGroup.Group("/traffic")
 		traffic.GET("/summary", handlers.Traffic.Summary)
@@ -82,8 +135,12 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 		subs := adminGroup.Group("/subscriptions")
 		subs.GET("", handlers.Subscribe.List)
 		subs.POST("", handlers.Subscribe.Create)
+		subs.PUT("/:id", handlers.Subscribe.Update)
 		subs.DELETE("/:id", handlers.Subscribe.Delete)
 		subs.POST("/:id/regenerate", handlers.Subscribe.Regenerate)
+		subs.GET("/flagged", handlers.Subscribe.Flagged)
+		subs.POST("/:id/unflag", handlers.Subscribe.Unflag)
+		subs.GET("/:id/access-logs", handlers.Subscribe.AccessLogs)
 
 		templates := adminGroup.Group("/templates")
 		templates.GET("", handlers.Template.List)
@@ -91,6 +148,17 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 		templates.PUT("/:id", handlers.Template.Update)
 		templates.DELETE("/:id", handlers.Template.Delete)
 		templates.POST("/:id/default", handlers.Template.SetDefault)
+		templates.POST("/:id/preview", handlers.Template.Preview)
+		templates.GET("/:id/revisions", handlers.Template.Revisions)
+		templates.GET("/:id/revisions/:revision", handlers.Template.Revision)
+		templates.POST("/:id/revisions/:revision/rollback", handlers.Template.Rollback)
+		templates.GET("/:id/compare", handlers.Template.CompareRevisions)
+
+		ruleSets := adminGroup.Group("/rule-sets")
+		ruleSets.GET("", handlers.RuleSet.List)
+		ruleSets.POST("", handlers.RuleSet.Create)
+		ruleSets.PUT("/:id", handlers.RuleSet.Update)
+		ruleSets.DELETE("/:id", handlers.RuleSet.Delete)
 
 		// 系统配置管理路由
 		sysConfigs := adminGroup.Group("/system-configs")
@@ -100,6 +168,8 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 
 		adminGroup.GET("/logs", handlers.Log.List)
 		adminGroup.GET("/dashboard/stats", handlers.Dashboard.Stats)
+		adminGroup.GET("/node-status", handlers.NodeStatus.Overview)
+		adminGroup.GET("/maintenance", handlers.Maintenance.List)
 	}
 
 	userGroup := r.Group("/api/user")
@@ -111,19 +181,35 @@ func SetupRouter(cfg *config.Config, handlers *handler.Handlers, logSvc *service
 		userGroup.POST("/password", handlers.UserProfile.ChangePassword)
 		userGroup.GET("/instances", handlers.UserInstance.ListMyInstances)
 		userGroup.GET("/instances/:id", handlers.UserInstance.GetMyInstance)
+		userGroup.GET("/instances/:id/probes", handlers.UserProbe.History)
 		userGroup.GET("/traffic", handlers.UserTraffic.GetMyTraffic)
-		userGroup.GET("/subscriptions", handlers.UserSubscribe.GetMySubscription)
-		userGroup.POST("/subscriptions/regenerate", handlers.UserSubscribe.RegenerateToken)
+		userGroup.GET("/subscriptions", handlers.UserSubscribe.List)
+		userGroup.POST("/subscriptions", handlers.UserSubscribe.Create)
+		userGroup.PUT("/subscriptions/:id", handlers.UserSubscribe.Update)
+		userGroup.DELETE("/subscriptions/:id", handlers.UserSubscribe.Delete)
+		userGroup.POST("/subscriptions/:id/regenerate", handlers.UserSubscribe.Regenerate)
+		userGroup.GET("/maintenance", handlers.UserMaintenance.List)
 	}
 
+	// 自助注册使用一次性注册令牌，不经过 AgentAuth
+	r.POST("/api/agent/enroll", handlers.AgentEnroll.Enroll)
+
 	agentGroup := r.Group("/api/agent")
-	agentGroup.Use(middleware.AgentAuth(db))
+	agentGroup.Use(middleware.AgentAuth(nodeSvc))
 	{
 		agentGroup.GET("/config", handlers.Agent.GetConfig)
 		agentGroup.POST("/heartbeat", handlers.Agent.Heartbeat)
 		agentGroup.POST("/traffic", handlers.Agent.ReportTraffic)
 		agentGroup.POST("/status", handlers.Agent.ReportInstanceStatus)
+		agentGroup.POST("/events", handlers.Agent.ReportEvents)
 		agentGroup.GET("/snell-config", handlers.AgentSnell.GetSnellConfig)
+		agentGroup.POST("/snell-upgrade", handlers.AgentSnell.ReportUpgrade)
+		agentGroup.GET("/update", handlers.AgentUpdate.GetUpdate)
+		agentGroup.POST("/update-status", handlers.AgentUpdate.ReportStatus)
+		agentGroup.GET("/log-requests", handlers.AgentLog.PollRequests)
+		agentGroup.POST("/log-responses", handlers.AgentLog.ReportResponse)
+		agentGroup.GET("/probe-targets", handlers.AgentProbe.GetTargets)
+		agentGroup.POST("/probe-results", handlers.AgentProbe.ReportResults)
 	}
 
 	return r
@@ -0,0 +1,31 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// MaintenanceHandler 用户查看节点维护通知。
+type MaintenanceHandler struct {
+	svc *service.MaintenanceService
+}
+
+// NewMaintenanceHandler 构造函数。
+func NewMaintenanceHandler(svc *service.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{svc: svc}
+}
+
+// List 返回我的节点上进行中或计划中的维护。
+func (h *MaintenanceHandler) List(c *gin.Context) {
+	notices, err := h.svc.UserNotices(middleware.GetUserID(c))
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, notices)
+}
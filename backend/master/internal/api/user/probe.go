@@ -0,0 +1,59 @@
+package user
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/iwoov/snell-master/backend/master/internal/api/common"
+	"github.com/iwoov/snell-master/backend/master/internal/api/middleware"
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// maxProbeHours 用户可查询的探测记录时长上限。
+const maxProbeHours = 7 * 24
+
+// ProbeHandler 用户查看实例可达性。
+type ProbeHandler struct {
+	instanceSvc *service.InstanceService
+	probeSvc    *service.ProbeService
+}
+
+// NewProbeHandler 构造函数。
+func NewProbeHandler(instanceSvc *service.InstanceService, probeSvc *service.ProbeService) *ProbeHandler {
+	return &ProbeHandler{instanceSvc: instanceSvc, probeSvc: probeSvc}
+}
+
+// History 返回我的实例最近 hours 小时的可达性探测记录。
+// GET /api/user/instances/:id/probes?hours=
+func (h *ProbeHandler) History(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours <= 0 || hours > maxProbeHours {
+		common.Fail(c, http.StatusBadRequest, "invalid hours")
+		return
+	}
+	inst, err := h.instanceSvc.GetInstanceByID(uint(id))
+	if err != nil {
+		common.Fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if inst.UserID != userID {
+		common.Fail(c, http.StatusForbidden, "not your instance")
+		return
+	}
+	now := time.Now()
+	history, err := h.probeSvc.History(inst.ID, now.Add(-time.Duration(hours)*time.Hour), now, 0)
+	if err != nil {
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, history)
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
@@ -20,24 +21,92 @@ func NewSubscribeHandler(svc *service.SubscribeService) *SubscribeHandler {
 	return &SubscribeHandler{svc: svc}
 }
 
-// GetMySubscription 返回当前订阅。
-func (h *SubscribeHandler) GetMySubscription(c *gin.Context) {
-	userID := middleware.GetUserID(c)
-	token, err := h.svc.GetTokenByUser(userID)
+// List 返回当前用户的全部订阅令牌及访问统计。
+// GET /api/user/subscriptions
+func (h *SubscribeHandler) List(c *gin.Context) {
+	tokens, err := h.svc.ListUserTokens(middleware.GetUserID(c))
 	if err != nil {
-		common.Fail(c, http.StatusNotFound, err.Error())
+		common.Fail(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.Success(c, tokens)
+}
+
+// Create 新增订阅令牌，例如为新设备单独创建链接。
+// POST /api/user/subscriptions
+func (h *SubscribeHandler) Create(c *gin.Context) {
+	var req service.SubscribeTokenInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	token, err := h.svc.CreateToken(middleware.GetUserID(c), req)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Created(c, token)
+}
+
+// Update 修改自己的订阅令牌。
+// PUT /api/user/subscriptions/:id
+func (h *SubscribeHandler) Update(c *gin.Context) {
+	id, ok := h.ownedTokenID(c)
+	if !ok {
+		return
+	}
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	token, err := h.svc.UpdateToken(id, updates)
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	common.Success(c, token)
 }
 
-// RegenerateToken 重新生成订阅令牌。
-func (h *SubscribeHandler) RegenerateToken(c *gin.Context) {
-	userID := middleware.GetUserID(c)
-	token, err := h.svc.RegenerateToken(userID)
+// Delete 删除自己的订阅令牌，只影响对应设备。
+// DELETE /api/user/subscriptions/:id
+func (h *SubscribeHandler) Delete(c *gin.Context) {
+	id, ok := h.ownedTokenID(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteToken(id); err != nil {
+		common.Fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.Success(c, gin.H{"deleted": id})
+}
+
+// Regenerate 重新生成自己的订阅令牌。
+// POST /api/user/subscriptions/:id/regenerate
+func (h *SubscribeHandler) Regenerate(c *gin.Context) {
+	id, ok := h.ownedTokenID(c)
+	if !ok {
+		return
+	}
+	token, err := h.svc.RegenerateToken(id)
 	if err != nil {
 		common.Fail(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	common.Success(c, gin.H{"token": token})
+	common.Success(c, token)
+}
+
+// ownedTokenID 解析路径中的令牌 ID 并确认属于当前用户。
+func (h *SubscribeHandler) ownedTokenID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.Fail(c, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	if _, err := h.svc.GetUserToken(middleware.GetUserID(c), uint(id)); err != nil {
+		common.Fail(c, http.StatusNotFound, "subscription not found")
+		return 0, false
+	}
+	return uint(id), true
 }
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Agent 自升级状态。
+const (
+	AgentUpdateUpdating   = "updating"
+	AgentUpdateSucceeded  = "succeeded"
+	AgentUpdateFailed     = "failed"
+	AgentUpdateRolledBack = "rolled_back"
+)
+
+// AgentRelease 记录某个 Agent 版本在指定架构下的二进制校验和。
+type AgentRelease struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Version   string    `gorm:"size:32;not null" json:"version"`
+	Arch      string    `gorm:"size:16;not null" json:"arch"`
+	SHA256    string    `gorm:"column:sha256;size:64;not null" json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,72 @@
+package model
+
+// AgentSettings 随配置同步下发给 Agent 的运行时设置，字段为空表示沿用 Agent 本地配置。
+type AgentSettings struct {
+	HeartbeatInterval     *int                  `json:"heartbeat_interval,omitempty"`
+	ConfigSyncInterval    *int                  `json:"config_sync_interval,omitempty"`
+	TrafficReportInterval *int                  `json:"traffic_report_interval,omitempty"`
+	UpdateCheckInterval   *int                  `json:"update_check_interval,omitempty"`
+	LogLevel              *string               `json:"log_level,omitempty"`
+	Monitor               *AgentMonitorSettings `json:"monitor,omitempty"`
+}
+
+// AgentMonitorSettings Agent 监控开关。
+type AgentMonitorSettings struct {
+	EnableCPU     *bool `json:"enable_cpu,omitempty"`
+	EnableMemory  *bool `json:"enable_memory,omitempty"`
+	EnableDisk    *bool `json:"enable_disk,omitempty"`
+	EnableNetwork *bool `json:"enable_network,omitempty"`
+	EnableLoad    *bool `json:"enable_load,omitempty"`
+	EnableTraffic *bool `json:"enable_traffic,omitempty"`
+}
+
+// Merge 返回以 override 中已设置的字段覆盖后的设置。
+func (s AgentSettings) Merge(override AgentSettings) AgentSettings {
+	merged := s
+	if override.HeartbeatInterval != nil {
+		merged.HeartbeatInterval = override.HeartbeatInterval
+	}
+	if override.ConfigSyncInterval != nil {
+		merged.ConfigSyncInterval = override.ConfigSyncInterval
+	}
+	if override.TrafficReportInterval != nil {
+		merged.TrafficReportInterval = override.TrafficReportInterval
+	}
+	if override.UpdateCheckInterval != nil {
+		merged.UpdateCheckInterval = override.UpdateCheckInterval
+	}
+	if override.LogLevel != nil {
+		merged.LogLevel = override.LogLevel
+	}
+	if override.Monitor != nil {
+		monitor := AgentMonitorSettings{}
+		if s.Monitor != nil {
+			monitor = *s.Monitor
+		}
+		monitor = monitor.merge(*override.Monitor)
+		merged.Monitor = &monitor
+	}
+	return merged
+}
+
+func (m AgentMonitorSettings) merge(override AgentMonitorSettings) AgentMonitorSettings {
+	merged := m
+	for _, pair := range []struct{ dst, src **bool }{
+		{&merged.EnableCPU, &override.EnableCPU},
+		{&merged.EnableMemory, &override.EnableMemory},
+		{&merged.EnableDisk, &override.EnableDisk},
+		{&merged.EnableNetwork, &override.EnableNetwork},
+		{&merged.EnableLoad, &override.EnableLoad},
+		{&merged.EnableTraffic, &override.EnableTraffic},
+	} {
+		if *pair.src != nil {
+			*pair.dst = *pair.src
+		}
+	}
+	return merged
+}
+
+// IsEmpty 判断是否没有设置任何字段。
+func (s AgentSettings) IsEmpty() bool {
+	return s == AgentSettings{}
+}
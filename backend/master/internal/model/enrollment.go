@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// EnrollmentToken 节点自助注册令牌，仅保存令牌摘要。
+type EnrollmentToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TokenHash   string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	TokenPrefix string     `gorm:"size:16;not null" json:"token_prefix"`
+	Description string     `gorm:"size:255" json:"description"`
+	MaxUses     int        `gorm:"not null;default:1" json:"max_uses"`
+	UsedCount   int        `gorm:"not null;default:0" json:"used_count"`
+	GroupID     *uint      `json:"group_id"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedBy   *uint      `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Usable 判断令牌在给定时间是否仍可使用。
+func (t *EnrollmentToken) Usable(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt) && t.UsedCount < t.MaxUses
+}
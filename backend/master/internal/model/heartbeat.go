@@ -8,11 +8,36 @@ type NodeHeartbeat struct {
 	NodeID        uint      `gorm:"index;not null" json:"node_id"`
 	Status        string    `gorm:"size:32;not null" json:"status"`
 	Message       string    `gorm:"size:255" json:"message"`
-	CPUUsage      float64   `json:"cpu_usage"`
-	MemoryUsage   float64   `json:"memory_usage"`
+	CPUUsage      *float64  `json:"cpu_usage"` // Agent 未采集时为空
+	MemoryUsage   *float64  `json:"memory_usage"`
+	DiskUsage     *float64  `json:"disk_usage"`
+	NetworkRxRate uint64    `json:"network_rx_rate"`
+	NetworkTxRate uint64    `json:"network_tx_rate"`
+	Load1         float64   `gorm:"column:load1" json:"load1"`
 	InstanceCount int       `json:"instance_count"`
 	Version       string    `gorm:"size:32" json:"version"`
 	CreatedAt     time.Time `json:"created_at"`
 
 	Node Node `json:"node,omitempty"`
 }
+
+// NodeMetricRollup 存储按小时降采样后的节点心跳指标。
+type NodeMetricRollup struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	NodeID           uint      `gorm:"uniqueIndex:idx_rollup_node_bucket;not null" json:"node_id"`
+	BucketStart      time.Time `gorm:"uniqueIndex:idx_rollup_node_bucket;not null" json:"bucket_start"`
+	Samples          int       `json:"samples"`
+	CPUAvg           float64   `json:"cpu_avg"`
+	CPUMax           float64   `json:"cpu_max"`
+	MemoryAvg        float64   `json:"memory_avg"`
+	MemoryMax        float64   `json:"memory_max"`
+	DiskAvg          float64   `json:"disk_avg"`
+	DiskMax          float64   `json:"disk_max"`
+	InstanceCountAvg float64   `json:"instance_count_avg"`
+	InstanceCountMax int       `json:"instance_count_max"`
+
+	// 各项指标实际参与平均的样本数，Agent 未采集的心跳不计入
+	CPUSamples    int `json:"cpu_samples"`
+	MemorySamples int `json:"memory_samples"`
+	DiskSamples   int `json:"disk_samples"`
+}
@@ -4,18 +4,23 @@ import "time"
 
 // SnellInstance 表示运行在节点上的 Snell 服务实例。
 type SnellInstance struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	UserID      uint      `gorm:"index;not null" json:"user_id"`
-	NodeID      uint      `gorm:"index;not null" json:"node_id"`
-	Port        int       `gorm:"not null" json:"port"`
-	PSK         string    `gorm:"size:255;not null" json:"psk"`
-	Version     int       `gorm:"default:4" json:"version"`
-	Obfs        string    `gorm:"size:64" json:"obfs"`
-	ConfigPath  string    `gorm:"size:255" json:"config_path"`
-	ServiceName string    `gorm:"size:128" json:"service_name"`
-	Status      string    `gorm:"size:32;default:'stopped'" json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	UserID      uint   `gorm:"index;not null" json:"user_id"`
+	NodeID      uint   `gorm:"index;not null" json:"node_id"`
+	Port        int    `gorm:"not null" json:"port"`
+	PSK         string `gorm:"size:255;not null" json:"psk"`
+	Version     int    `gorm:"default:4" json:"version"`
+	Obfs        string `gorm:"size:64" json:"obfs"`
+	ConfigPath  string `gorm:"size:255" json:"config_path"`
+	ServiceName string `gorm:"size:128" json:"service_name"`
+	Status      string `gorm:"size:32;default:'stopped'" json:"status"`
+	// 最近一次 Master 探测结果，连续失败达到阈值后标记为不可达
+	Reachability       string     `gorm:"size:16;default:'unknown'" json:"reachability"`
+	ProbeFailures      int        `gorm:"default:0" json:"probe_failures"`
+	LastProbeAt        *time.Time `json:"last_probe_at"`
+	LastProbeLatencyMs int64      `gorm:"default:0" json:"last_probe_latency_ms"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 
 	User User `json:"user,omitempty"`
 	Node Node `json:"node,omitempty"`
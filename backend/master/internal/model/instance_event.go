@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// 实例事件类型。
+const (
+	InstanceEventApplyFailed    = "apply_failed"
+	InstanceEventRolledBack     = "rolled_back"
+	InstanceEventRollbackFailed = "rollback_failed"
+)
+
+// InstanceEvent 记录 Agent 上报的实例配置应用事件。
+type InstanceEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	InstanceID uint      `gorm:"index;not null" json:"instance_id"`
+	NodeID     uint      `gorm:"index;not null" json:"node_id"`
+	EventType  string    `gorm:"size:32;not null" json:"event_type"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurred_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
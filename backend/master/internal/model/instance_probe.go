@@ -0,0 +1,33 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// 实例可达性，以 Master 的探测结果为准。
+const (
+	InstanceReachabilityUnknown = "unknown"
+	InstanceReachable           = "reachable"
+	InstanceUnreachable         = "unreachable"
+)
+
+// ProbeSourceMaster 由 Master 发起的探测。
+const ProbeSourceMaster = "master"
+
+// ProbeSourceNode 返回由指定节点 Agent 发起的探测来源标识。
+func ProbeSourceNode(nodeID uint) string {
+	return fmt.Sprintf("node:%d", nodeID)
+}
+
+// InstanceProbe 记录一次对实例在某个节点地址上端口的 TCP 探测。
+type InstanceProbe struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	InstanceID uint      `gorm:"index;not null" json:"instance_id"`
+	Source     string    `gorm:"size:32;not null" json:"source"`
+	Address    string    `gorm:"size:255" json:"address"`
+	Reachable  bool      `gorm:"not null" json:"reachable"`
+	LatencyMs  int64     `gorm:"default:0" json:"latency_ms"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
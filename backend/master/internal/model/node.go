@@ -4,21 +4,70 @@ import "time"
 
 // Node 表示 Snell 节点。
 type Node struct {
-	ID             uint       `gorm:"primaryKey" json:"id"`
-	Name           string     `gorm:"uniqueIndex;size:64;not null" json:"name"`
-	APIToken       string     `gorm:"uniqueIndex;size:128;not null" json:"api_token"`
-	Endpoint       string     `gorm:"size:255;not null" json:"endpoint"`
-	Location       string     `gorm:"size:100" json:"location"`
-	CountryCode    string     `gorm:"size:8" json:"country_code"`
-	Status         string     `gorm:"size:32;default:'offline'" json:"status"`
-	CPUUsage       float64    `gorm:"default:0" json:"cpu_usage"`
-	MemoryUsage    float64    `gorm:"default:0" json:"memory_usage"`
-	DiskUsage      float64    `gorm:"default:0" json:"disk_usage"`
-	BandwidthUsage float64    `gorm:"default:0" json:"bandwidth_usage"`
-	LastSeenAt     *time.Time `json:"last_seen_at"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	Name                string     `gorm:"uniqueIndex;size:64;not null" json:"name"`
+	APITokenHash        string     `gorm:"column:api_token;uniqueIndex;size:128;not null" json:"-"` // API Token 的 SHA-256
+	APITokenHashed      bool       `gorm:"default:false" json:"-"`
+	Endpoint            string     `gorm:"size:255;not null" json:"endpoint"` // 优先地址，与 Endpoints 同步
+	Location            string     `gorm:"size:100" json:"location"`
+	CountryCode         string     `gorm:"size:8" json:"country_code"`
+	HideFromAutoGroups  bool       `gorm:"default:false" json:"hide_from_auto_groups"` // 不进入订阅的地区自动代理组
+	Status              string     `gorm:"size:32;default:'offline'" json:"status"`
+	CPUUsage            float64    `gorm:"default:0" json:"cpu_usage"`
+	MemoryUsage         float64    `gorm:"default:0" json:"memory_usage"`
+	DiskUsage           float64    `gorm:"default:0" json:"disk_usage"`
+	BandwidthUsage      float64    `gorm:"default:0" json:"bandwidth_usage"` // 网卡收发速率之和（字节/秒）
+	NetworkRxRate       uint64     `gorm:"default:0" json:"network_rx_rate"`
+	NetworkTxRate       uint64     `gorm:"default:0" json:"network_tx_rate"`
+	Load1               float64    `gorm:"column:load1;default:0" json:"load1"`
+	Load5               float64    `gorm:"column:load5;default:0" json:"load5"`
+	Load15              float64    `gorm:"column:load15;default:0" json:"load15"`
+	UptimeSeconds       uint64     `gorm:"default:0" json:"uptime_seconds"`
+	HeartbeatInterval   int        `gorm:"default:0" json:"heartbeat_interval"` // Agent 上报的心跳间隔（秒），0 表示未知
+	KernelVersion       string     `gorm:"size:128" json:"kernel_version"`
+	SnellVersion        string     `gorm:"size:64" json:"snell_version"`
+	AgentVersion        string     `gorm:"size:32" json:"agent_version"`
+	AgentTargetVersion  string     `gorm:"size:32" json:"agent_target_version"`        // 为空时跟随全局 agent_version
+	AgentAllowDowngrade bool       `gorm:"default:false" json:"agent_allow_downgrade"` // 单独指定的目标版本低于当前版本时允许降级
+	AgentUpdateStatus   string     `gorm:"size:20" json:"agent_update_status"`
+	AgentUpdateMessage  string     `json:"agent_update_message"`
+	AgentUpdateAt       *time.Time `json:"agent_update_at"`
+	SnellTargetVersion  string     `gorm:"size:32" json:"snell_target_version"` // 为空时跟随全局 snell_version
+	SnellUpgradeStatus  string     `gorm:"size:20" json:"snell_upgrade_status"`
+	SnellUpgradeMessage string     `json:"snell_upgrade_message"`
+	SnellUpgradeDone    int        `gorm:"default:0" json:"snell_upgrade_done"`
+	SnellUpgradeTotal   int        `gorm:"default:0" json:"snell_upgrade_total"`
+	SnellUpgradeAt      *time.Time `json:"snell_upgrade_at"`
+	PortRangeStart      int        `gorm:"default:0" json:"port_range_start"`
+	PortRangeEnd        int        `gorm:"default:0" json:"port_range_end"`
+	InstanceCount       int        `gorm:"default:0" json:"instance_count"`
+	LastSeenAt          *time.Time `json:"last_seen_at"`
+	Hostname            string     `gorm:"size:255" json:"hostname"`
+	EnrollmentTokenID   *uint      `json:"enrollment_token_id,omitempty"`
+	// 维护窗口，MaintenanceEndAt 为空表示手动结束
+	MaintenanceStartAt       *time.Time `json:"maintenance_start_at"`
+	MaintenanceEndAt         *time.Time `json:"maintenance_end_at"`
+	MaintenanceStopInstances bool       `gorm:"default:false" json:"maintenance_stop_instances"`
+	MaintenanceMessage       string     `json:"maintenance_message"`
+	// 令牌轮换：宽限期内旧令牌仍可认证，新令牌加密保存直到 Agent 取走
+	PreviousTokenHash      string     `gorm:"size:128" json:"-"`
+	PreviousTokenExpiresAt *time.Time `json:"previous_token_expires_at"`
+	PendingTokenSealed     string     `json:"-"`
+	TokenRotatedAt         *time.Time `json:"token_rotated_at"`
+	// 节点级 Agent 运行时设置（JSON），覆盖全局 agent_settings
+	AgentSettings string    `gorm:"type:text" json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 
 	Users     []User          `gorm:"many2many:user_nodes" json:"users,omitempty"`
 	Instances []SnellInstance `gorm:"foreignKey:NodeID" json:"instances,omitempty"`
+	Endpoints []NodeEndpoint  `gorm:"foreignKey:NodeID" json:"endpoints,omitempty"`
+}
+
+// InMaintenance 判断节点在给定时间是否处于维护窗口内。
+func (n *Node) InMaintenance(now time.Time) bool {
+	if n.MaintenanceStartAt == nil || now.Before(*n.MaintenanceStartAt) {
+		return false
+	}
+	return n.MaintenanceEndAt == nil || now.Before(*n.MaintenanceEndAt)
 }
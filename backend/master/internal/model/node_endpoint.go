@@ -0,0 +1,76 @@
+package model
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 节点地址类型。
+const (
+	EndpointTypeIPv4   = "ipv4"
+	EndpointTypeIPv6   = "ipv6"
+	EndpointTypeDomain = "domain"
+)
+
+// NodeEndpoint 节点的一个连接地址，Priority 越小越优先。
+type NodeEndpoint struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	NodeID    uint      `gorm:"index;not null" json:"node_id"`
+	Type      string    `gorm:"size:16;not null" json:"type"`
+	Address   string    `gorm:"size:255;not null" json:"address"`
+	Label     string    `gorm:"size:64" json:"label"`
+	Priority  int       `gorm:"default:0" json:"priority"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DetectEndpointType 根据地址格式推断类型，无法解析为 IP 的视为域名。
+func DetectEndpointType(address string) string {
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return EndpointTypeDomain
+	case ip.To4() != nil && !strings.Contains(address, ":"):
+		return EndpointTypeIPv4
+	default:
+		return EndpointTypeIPv6
+	}
+}
+
+// SortedEndpoints 按优先级返回节点地址；未配置地址时以 Endpoint 字段兜底。
+func (n *Node) SortedEndpoints() []NodeEndpoint {
+	if len(n.Endpoints) == 0 {
+		if n.Endpoint == "" {
+			return nil
+		}
+		return []NodeEndpoint{{NodeID: n.ID, Type: DetectEndpointType(n.Endpoint), Address: n.Endpoint}}
+	}
+	endpoints := append([]NodeEndpoint(nil), n.Endpoints...)
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Priority != endpoints[j].Priority {
+			return endpoints[i].Priority < endpoints[j].Priority
+		}
+		return endpoints[i].ID < endpoints[j].ID
+	})
+	return endpoints
+}
+
+// PreferredAddress 返回优先级最高的地址。
+func (n *Node) PreferredAddress() string {
+	if endpoints := n.SortedEndpoints(); len(endpoints) > 0 {
+		return endpoints[0].Address
+	}
+	return ""
+}
+
+// HasIPv6 判断节点是否配置了 IPv6 地址，Agent 据此决定是否双栈监听。
+func (n *Node) HasIPv6() bool {
+	for _, endpoint := range n.SortedEndpoints() {
+		if endpoint.Type == EndpointTypeIPv6 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// NodeGroup 表示一组节点，可以附带标签并整体授权给用户。
+type NodeGroup struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex;size:64;not null" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Tags    []string `gorm:"-" json:"tags"`
+	NodeIDs []uint   `gorm:"-" json:"node_ids"`
+}
+
+// NodeGroupTag 节点组标签。
+type NodeGroupTag struct {
+	GroupID uint   `gorm:"primaryKey" json:"group_id"`
+	Tag     string `gorm:"primaryKey;size:64" json:"tag"`
+}
+
+// NodeGroupMember 节点组成员。
+type NodeGroupMember struct {
+	GroupID   uint      `gorm:"primaryKey" json:"group_id"`
+	NodeID    uint      `gorm:"primaryKey" json:"node_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserNodeGroup 用户被授权的节点组。
+type UserNodeGroup struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	GroupID   uint      `gorm:"primaryKey" json:"group_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserNodeTag 用户被授权的节点组标签。
+type UserNodeTag struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	Tag       string    `gorm:"primaryKey;size:64" json:"tag"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserEntitlement 汇总用户的组与标签授权。
+type UserEntitlement struct {
+	UserID   uint     `json:"user_id"`
+	NodeIDs  []uint   `json:"node_ids"`
+	GroupIDs []uint   `json:"group_ids"`
+	Tags     []string `json:"tags"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 节点状态。
+const (
+	NodeStatusOnline  = "online"
+	NodeStatusOffline = "offline"
+)
+
+// 节点状态变化原因。
+const (
+	NodeStatusCauseHeartbeat        = "heartbeat"
+	NodeStatusCauseHeartbeatTimeout = "heartbeat_timeout"
+	NodeStatusCauseManual           = "manual"
+)
+
+// NodeStatusEvent 记录节点的一次状态变化。
+type NodeStatusEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	NodeID     uint      `gorm:"index;not null" json:"node_id"`
+	FromStatus string    `gorm:"size:20;not null" json:"from_status"`
+	ToStatus   string    `gorm:"size:20;not null" json:"to_status"`
+	Cause      string    `gorm:"size:32;not null" json:"cause"`
+	CreatedAt  time.Time `json:"created_at"`
+}
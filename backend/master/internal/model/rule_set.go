@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// 规则集来源：inline 为管理员维护的规则行，remote 为客户端自行下载的规则列表。
+const (
+	RuleSetSourceInline = "inline"
+	RuleSetSourceRemote = "remote"
+)
+
+// 远程规则列表的内容类型，对应 Clash 规则集的 behavior。
+const (
+	RuleSetBehaviorClassical = "classical"
+	RuleSetBehaviorDomain    = "domain"
+	RuleSetBehaviorIPCIDR    = "ipcidr"
+)
+
+// RuleSet 可被多个模板按名称引用的规则列表，Policy 为命中规则时使用的策略。
+type RuleSet struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex;size:64;not null" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Source      string    `gorm:"size:16;not null" json:"source"`
+	Behavior    string    `gorm:"size:16;not null" json:"behavior"`
+	Content     string    `gorm:"type:text" json:"content"` // inline 规则，每行一条，不含策略
+	URL         string    `gorm:"size:512" json:"url"`
+	Policy      string    `gorm:"size:64;not null" json:"policy"`
+	Interval    int       `gorm:"not null" json:"interval"` // 客户端刷新远程规则的间隔（秒）
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
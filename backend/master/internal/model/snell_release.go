@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Snell Server 升级状态。
+const (
+	SnellUpgradeUpgrading  = "upgrading"
+	SnellUpgradeSucceeded  = "succeeded"
+	SnellUpgradeFailed     = "failed"
+	SnellUpgradeRolledBack = "rolled_back"
+)
+
+// SnellRelease 记录某个 Snell Server 版本在指定架构下压缩包的校验和。
+type SnellRelease struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Version   string    `gorm:"size:32;not null" json:"version"`
+	Arch      string    `gorm:"size:16;not null" json:"arch"`
+	SHA256    string    `gorm:"column:sha256;size:64;not null" json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -2,18 +2,39 @@ package model
 
 import "time"
 
-// SubscribeToken 用户订阅令牌。
+// SubscribeToken 用户订阅令牌，一个用户可为不同设备创建多个令牌；RevisionID 非空时固定使用所绑定模板的该修订。
 type SubscribeToken struct {
-	ID           uint       `gorm:"primaryKey" json:"id"`
-	UserID       uint       `gorm:"index;not null" json:"user_id"`
-	Token        string     `gorm:"uniqueIndex;size:128;not null" json:"token"`
-	TemplateID   *uint      `json:"template_id"`
-	ExpiresAt    *time.Time `json:"expires_at"`
-	LastAccessAt *time.Time `json:"last_access_at"`
-	AccessCount  int64      `gorm:"default:0" json:"access_count"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"index;not null" json:"user_id"`
+	Token         string     `gorm:"uniqueIndex;size:128;not null" json:"token"`
+	Label         string     `gorm:"size:64" json:"label"`
+	TemplateID    *uint      `json:"template_id"`
+	RevisionID    *uint      `gorm:"column:template_revision_id" json:"template_revision_id"`
+	Target        string     `gorm:"size:16" json:"target"` // 为空时按请求参数或 User-Agent 选择格式
+	Enabled       bool       `gorm:"not null" json:"enabled"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	LastAccessAt  *time.Time `json:"last_access_at"`
+	LastAccessIP  string     `gorm:"size:64" json:"last_access_ip"`
+	LastUserAgent string     `gorm:"size:255" json:"last_user_agent"`
+	AccessCount   int64      `gorm:"default:0" json:"access_count"`
+	FlaggedAt     *time.Time `json:"flagged_at"` // 非空表示访问来源超过泄露检测阈值
+	FlagReason    string     `json:"flag_reason"`
+	RotatedAt     *time.Time `json:"rotated_at"` // 最近一次重新生成令牌的时间，之前的访问不再计入检测
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 
 	User     User      `json:"user,omitempty"`
 	Template *Template `json:"template,omitempty"`
 }
+
+// SubscribeAccessLog 一次成功的订阅拉取，Network 为来源 IP 所在网段。
+type SubscribeAccessLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TokenID   uint      `gorm:"index;not null" json:"token_id"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	Network   string    `gorm:"size:64" json:"network"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Target    string    `gorm:"size:16" json:"target"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -2,13 +2,37 @@ package model
 
 import "time"
 
-// Template Surge订阅模板。
+// 模板的目标客户端格式。
+const (
+	TemplateTargetSurge = "surge"
+	TemplateTargetClash = "clash"
+	TemplateTargetStash = "stash"
+	TemplateTargetLoon  = "loon"
+)
+
+// Template 订阅模板，Target 决定渲染出的客户端格式。
 type Template struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
 	Name        string    `gorm:"uniqueIndex;size:100;not null" json:"name"`
 	Description string    `gorm:"size:255" json:"description"`
 	Content     string    `gorm:"type:text;not null" json:"content"`
+	Target      string    `gorm:"size:16;not null;default:surge" json:"target"`
 	IsDefault   bool      `gorm:"default:false" json:"is_default"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
+
+// TemplateRevision 模板每次保存后的不可变快照，Diff 为相对上一修订内容的 unified diff。
+type TemplateRevision struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TemplateID  uint      `gorm:"uniqueIndex:idx_template_revision;not null" json:"template_id"`
+	Revision    int       `gorm:"uniqueIndex:idx_template_revision;not null" json:"revision"`
+	Name        string    `gorm:"size:100;not null" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Target      string    `gorm:"size:16;not null" json:"target"`
+	Content     string    `gorm:"type:text;not null" json:"content"`
+	Diff        string    `gorm:"type:text" json:"diff"`
+	Note        string    `gorm:"size:255" json:"note"`
+	CreatedBy   *uint     `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -7,8 +7,7 @@ type UserNode struct {
 	ID        uint       `gorm:"primaryKey" json:"id"`
 	UserID    uint       `gorm:"uniqueIndex:idx_user_node;not null" json:"user_id"`
 	NodeID    uint       `gorm:"uniqueIndex:idx_user_node;not null" json:"node_id"`
-	Connected *time.Time `json:"connected_at"`
-	CreatedAt time.Time  `json:"created_at"`
+	Connected *time.Time `gorm:"column:connected_at" json:"connected_at"`
 }
 
 // TableName 显式指定表名。
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// AgentReleaseRepository 管理 Agent 发布版本与节点升级状态。
+type AgentReleaseRepository interface {
+	Save(releases []model.AgentRelease) error
+	List() ([]model.AgentRelease, error)
+	ListByVersion(version string) ([]model.AgentRelease, error)
+	Get(version, arch string) (*model.AgentRelease, error)
+	DeleteVersion(version string) error
+	SetNodeTargets(nodeIDs []uint, version string, allowDowngrade bool) error
+	ClearNodeTargets() error
+	UpdateNodeStatus(nodeID uint, status, message string) error
+}
+
+type agentReleaseRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentReleaseRepository 构建实现。
+func NewAgentReleaseRepository(db *gorm.DB) AgentReleaseRepository {
+	return &agentReleaseRepository{db: db}
+}
+
+// Save 写入发布记录，同一版本和架构已存在时覆盖校验和。
+func (r *agentReleaseRepository) Save(releases []model.AgentRelease) error {
+	if len(releases) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "version"}, {Name: "arch"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sha256"}),
+	}).Create(&releases).Error
+}
+
+func (r *agentReleaseRepository) List() ([]model.AgentRelease, error) {
+	var releases []model.AgentRelease
+	if err := r.db.Order("created_at DESC, arch ASC").Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+func (r *agentReleaseRepository) ListByVersion(version string) ([]model.AgentRelease, error) {
+	var releases []model.AgentRelease
+	if err := r.db.Where("version = ?", version).Order("arch ASC").Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+func (r *agentReleaseRepository) Get(version, arch string) (*model.AgentRelease, error) {
+	var release model.AgentRelease
+	if err := r.db.Where("version = ? AND arch = ?", version, arch).First(&release).Error; err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+func (r *agentReleaseRepository) DeleteVersion(version string) error {
+	return r.db.Where("version = ?", version).Delete(&model.AgentRelease{}).Error
+}
+
+// SetNodeTargets 为指定节点设置目标版本及是否允许降级，version 为空表示取消单独指定。
+func (r *agentReleaseRepository) SetNodeTargets(nodeIDs []uint, version string, allowDowngrade bool) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.Node{}).Where("id IN ?", nodeIDs).Updates(map[string]interface{}{
+		"agent_target_version":  version,
+		"agent_allow_downgrade": allowDowngrade && version != "",
+	}).Error
+}
+
+// ClearNodeTargets 取消所有节点的单独目标版本。
+func (r *agentReleaseRepository) ClearNodeTargets() error {
+	return r.db.Model(&model.Node{}).Where("agent_target_version <> ''").Updates(map[string]interface{}{
+		"agent_target_version":  "",
+		"agent_allow_downgrade": false,
+	}).Error
+}
+
+func (r *agentReleaseRepository) UpdateNodeStatus(nodeID uint, status, message string) error {
+	now := time.Now()
+	return r.db.Model(&model.Node{}).Where("id = ?", nodeID).Updates(map[string]interface{}{
+		"agent_update_status":  status,
+		"agent_update_message": message,
+		"agent_update_at":      &now,
+	}).Error
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+var (
+	// ErrEnrollmentTokenInvalid 注册令牌不存在。
+	ErrEnrollmentTokenInvalid = errors.New("enrollment token is invalid")
+	// ErrEnrollmentTokenUnusable 注册令牌已过期、已吊销或已用完。
+	ErrEnrollmentTokenUnusable = errors.New("enrollment token is expired, revoked or exhausted")
+)
+
+// EnrollmentRepository 管理节点注册令牌。
+type EnrollmentRepository interface {
+	Create(token *model.EnrollmentToken) error
+	GetByID(id uint) (*model.EnrollmentToken, error)
+	List() ([]model.EnrollmentToken, error)
+	Revoke(id uint) error
+	Delete(id uint) error
+	Enroll(tokenHash string, node *model.Node) error
+}
+
+type enrollmentRepository struct {
+	db *gorm.DB
+}
+
+// NewEnrollmentRepository 返回实现。
+func NewEnrollmentRepository(db *gorm.DB) EnrollmentRepository {
+	return &enrollmentRepository{db: db}
+}
+
+func (r *enrollmentRepository) Create(token *model.EnrollmentToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *enrollmentRepository) GetByID(id uint) (*model.EnrollmentToken, error) {
+	var token model.EnrollmentToken
+	if err := r.db.First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *enrollmentRepository) List() ([]model.EnrollmentToken, error) {
+	var tokens []model.EnrollmentToken
+	if err := r.db.Order("id DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *enrollmentRepository) Revoke(id uint) error {
+	now := time.Now()
+	return r.db.Model(&model.EnrollmentToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", &now).Error
+}
+
+func (r *enrollmentRepository) Delete(id uint) error {
+	return r.db.Delete(&model.EnrollmentToken{}, id).Error
+}
+
+// Enroll 在同一事务内校验并消耗令牌、创建节点，节点名冲突时追加序号。
+func (r *enrollmentRepository) Enroll(tokenHash string, node *model.Node) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var token model.EnrollmentToken
+		if err := tx.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrEnrollmentTokenInvalid
+			}
+			return err
+		}
+		now := time.Now()
+		if !token.Usable(now) {
+			return ErrEnrollmentTokenUnusable
+		}
+
+		result := tx.Model(&model.EnrollmentToken{}).
+			Where("id = ? AND used_count < max_uses", token.ID).
+			Updates(map[string]interface{}{
+				"used_count":   gorm.Expr("used_count + 1"),
+				"last_used_at": &now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEnrollmentTokenUnusable
+		}
+
+		name, err := uniqueNodeName(tx, node.Name)
+		if err != nil {
+			return err
+		}
+		node.Name = name
+		node.EnrollmentTokenID = &token.ID
+		if err := tx.Create(node).Error; err != nil {
+			return err
+		}
+
+		if token.GroupID != nil {
+			member := model.NodeGroupMember{GroupID: *token.GroupID, NodeID: node.ID, CreatedAt: now}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func uniqueNodeName(tx *gorm.DB, base string) (string, error) {
+	name := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Model(&model.Node{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
@@ -7,10 +7,19 @@ type Repositories struct {
 	Admin        AdminRepository
 	User         UserRepository
 	Node         NodeRepository
+	NodeMetric   NodeMetricRepository
+	NodeStatus   NodeStatusRepository
+	NodeGroup    NodeGroupRepository
+	Enrollment   EnrollmentRepository
+	AgentRelease AgentReleaseRepository
+	SnellRelease SnellReleaseRepository
 	Instance     InstanceRepository
+	Probe        InstanceProbeRepository
 	Traffic      TrafficRepository
 	Subscribe    SubscribeRepository
+	SubscribeLog SubscribeAccessRepository
 	Template     TemplateRepository
+	RuleSet      RuleSetRepository
 	Log          LogRepository
 	SystemConfig SystemConfigRepository
 }
@@ -21,10 +30,19 @@ func NewRepositories(db *gorm.DB) *Repositories {
 		Admin:        NewAdminRepository(db),
 		User:         NewUserRepository(db),
 		Node:         NewNodeRepository(db),
+		NodeMetric:   NewNodeMetricRepository(db),
+		NodeStatus:   NewNodeStatusRepository(db),
+		NodeGroup:    NewNodeGroupRepository(db),
+		Enrollment:   NewEnrollmentRepository(db),
+		AgentRelease: NewAgentReleaseRepository(db),
+		SnellRelease: NewSnellReleaseRepository(db),
 		Instance:     NewInstanceRepository(db),
+		Probe:        NewInstanceProbeRepository(db),
 		Traffic:      NewTrafficRepository(db),
 		Subscribe:    NewSubscribeRepository(db),
+		SubscribeLog: NewSubscribeAccessRepository(db),
 		Template:     NewTemplateRepository(db),
+		RuleSet:      NewRuleSetRepository(db),
 		Log:          NewLogRepository(db),
 		SystemConfig: NewSystemConfigRepository(db),
 	}
@@ -24,6 +24,8 @@ type InstanceRepository interface {
 	GetByNode(nodeID uint) ([]model.SnellInstance, error)
 	GetByUser(userID uint) ([]model.SnellInstance, error)
 	CheckPortConflict(nodeID uint, port int) (bool, error)
+	CreateEvents(events []model.InstanceEvent) error
+	ListEvents(instanceID uint, limit int) ([]model.InstanceEvent, error)
 }
 
 type instanceRepository struct {
@@ -48,7 +50,7 @@ func (r *instanceRepository) GetByID(id uint) (*model.SnellInstance, error) {
 }
 
 func (r *instanceRepository) List(filter InstanceFilter) ([]model.SnellInstance, error) {
-	query := r.db.Preload("User").Preload("Node")
+	query := r.db.Preload("User").Preload("Node").Preload("Node.Endpoints", orderEndpoints)
 	if filter.UserID != nil {
 		query = query.Where("user_id = ?", *filter.UserID)
 	}
@@ -101,3 +103,22 @@ func (r *instanceRepository) CheckPortConflict(nodeID uint, port int) (bool, err
 	}
 	return count > 0, nil
 }
+
+func (r *instanceRepository) CreateEvents(events []model.InstanceEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return r.db.Create(&events).Error
+}
+
+func (r *instanceRepository) ListEvents(instanceID uint, limit int) ([]model.InstanceEvent, error) {
+	var events []model.InstanceEvent
+	query := r.db.Where("instance_id = ?", instanceID).Order("occurred_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// InstanceProbeRepository 维护实例端口探测记录。
+type InstanceProbeRepository interface {
+	CreateProbes(probes []model.InstanceProbe) error
+	ListProbes(instanceID uint, from, to time.Time, limit int) ([]model.InstanceProbe, error)
+	UpdateReachability(instanceID uint, updates map[string]interface{}) error
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+type instanceProbeRepository struct {
+	db *gorm.DB
+}
+
+// NewInstanceProbeRepository 构建实现。
+func NewInstanceProbeRepository(db *gorm.DB) InstanceProbeRepository {
+	return &instanceProbeRepository{db: db}
+}
+
+func (r *instanceProbeRepository) CreateProbes(probes []model.InstanceProbe) error {
+	if len(probes) == 0 {
+		return nil
+	}
+	return r.db.Create(&probes).Error
+}
+
+// ListProbes 返回 [from, to) 范围内最新的 limit 条记录，按时间升序排列。
+func (r *instanceProbeRepository) ListProbes(instanceID uint, from, to time.Time, limit int) ([]model.InstanceProbe, error) {
+	var probes []model.InstanceProbe
+	query := r.db.Where("instance_id = ? AND created_at >= ? AND created_at < ?", instanceID, from, to).
+		Order("created_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&probes).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(probes)-1; i < j; i, j = i+1, j-1 {
+		probes[i], probes[j] = probes[j], probes[i]
+	}
+	return probes, nil
+}
+
+// UpdateReachability 仅更新探测相关字段，避免覆盖同时发生的其他修改。
+func (r *instanceProbeRepository) UpdateReachability(instanceID uint, updates map[string]interface{}) error {
+	return r.db.Model(&model.SnellInstance{}).Where("id = ?", instanceID).UpdateColumns(updates).Error
+}
+
+func (r *instanceProbeRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&model.InstanceProbe{})
+	return result.RowsAffected, result.Error
+}
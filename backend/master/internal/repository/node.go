@@ -12,13 +12,21 @@ import (
 type NodeRepository interface {
 	Create(node *model.Node) error
 	GetByID(id uint) (*model.Node, error)
-	GetByToken(token string) (*model.Node, error)
+	GetByTokenHash(hash string) (*model.Node, error)
+	GetByPreviousTokenHash(hash string, now time.Time) (*model.Node, error)
+	ListLegacyTokens() ([]model.Node, error)
+	UpdateToken(nodeID uint, updates map[string]interface{}) error
 	List() ([]model.Node, error)
 	Update(node *model.Node) error
 	Delete(id uint) error
-	UpdateHeartbeat(nodeID uint, cpu, mem float64, instances int, status string) error
+	UpdateHeartbeat(nodeID uint, updates map[string]interface{}) error
 	SaveHeartbeat(record *model.NodeHeartbeat) error
 	GetOnlineNodes(within time.Duration) ([]model.Node, error)
+	SetMaintenance(nodeID uint, start, end *time.Time, stopInstances bool, message string) error
+	ClearExpiredMaintenance(now time.Time) ([]uint, error)
+	ListEndpoints(nodeID uint) ([]model.NodeEndpoint, error)
+	ReplaceEndpoints(nodeID uint, endpoints []model.NodeEndpoint) error
+	SetAgentSettings(nodeID uint, settings string) error
 }
 
 type nodeRepository struct {
@@ -36,23 +44,51 @@ func (r *nodeRepository) Create(node *model.Node) error {
 
 func (r *nodeRepository) GetByID(id uint) (*model.Node, error) {
 	var node model.Node
-	if err := r.db.Preload("Instances").First(&node, id).Error; err != nil {
+	if err := r.db.Preload("Instances").Preload("Endpoints", orderEndpoints).First(&node, id).Error; err != nil {
 		return nil, err
 	}
 	return &node, nil
 }
 
-func (r *nodeRepository) GetByToken(token string) (*model.Node, error) {
+func (r *nodeRepository) GetByTokenHash(hash string) (*model.Node, error) {
 	var node model.Node
-	if err := r.db.Where("api_token = ?", token).First(&node).Error; err != nil {
+	if err := r.db.Where("api_token = ? AND api_token_hashed = ?", hash, true).First(&node).Error; err != nil {
 		return nil, err
 	}
 	return &node, nil
 }
 
+// GetByPreviousTokenHash 查询轮换前的令牌仍在宽限期内的节点。
+func (r *nodeRepository) GetByPreviousTokenHash(hash string, now time.Time) (*model.Node, error) {
+	var node model.Node
+	if err := r.db.Where("previous_token_hash = ? AND previous_token_expires_at > ?", hash, now).First(&node).Error; err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// ListLegacyTokens 返回 api_token 仍为明文的节点。
+func (r *nodeRepository) ListLegacyTokens() ([]model.Node, error) {
+	var nodes []model.Node
+	if err := r.db.Where("api_token_hashed = ?", false).Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// UpdateToken 写入令牌相关字段。
+func (r *nodeRepository) UpdateToken(nodeID uint, updates map[string]interface{}) error {
+	values := make(map[string]interface{}, len(updates)+1)
+	for key, value := range updates {
+		values[key] = value
+	}
+	values["updated_at"] = time.Now()
+	return r.db.Model(&model.Node{}).Where("id = ?", nodeID).Updates(values).Error
+}
+
 func (r *nodeRepository) List() ([]model.Node, error) {
 	var nodes []model.Node
-	if err := r.db.Order("id DESC").Find(&nodes).Error; err != nil {
+	if err := r.db.Preload("Endpoints", orderEndpoints).Order("id DESC").Find(&nodes).Error; err != nil {
 		return nil, err
 	}
 	return nodes, nil
@@ -66,17 +102,16 @@ func (r *nodeRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Node{}, id).Error
 }
 
-func (r *nodeRepository) UpdateHeartbeat(nodeID uint, cpu, mem float64, instances int, status string) error {
+// UpdateHeartbeat 写入心跳上报的字段，并刷新最后在线时间。
+func (r *nodeRepository) UpdateHeartbeat(nodeID uint, updates map[string]interface{}) error {
 	now := time.Now()
-	updates := map[string]interface{}{
-		"cpu_usage":      cpu,
-		"memory_usage":   mem,
-		"instance_count": instances,
-		"status":         status,
-		"last_seen_at":   &now,
-		"updated_at":     now,
+	values := make(map[string]interface{}, len(updates)+2)
+	for key, value := range updates {
+		values[key] = value
 	}
-	return r.db.Model(&model.Node{}).Where("id = ?", nodeID).Updates(updates).Error
+	values["last_seen_at"] = &now
+	values["updated_at"] = now
+	return r.db.Model(&model.Node{}).Where("id = ?", nodeID).Updates(values).Error
 }
 
 func (r *nodeRepository) SaveHeartbeat(record *model.NodeHeartbeat) error {
@@ -94,3 +129,84 @@ func (r *nodeRepository) GetOnlineNodes(within time.Duration) ([]model.Node, err
 	}
 	return nodes, nil
 }
+
+// SetMaintenance 写入维护窗口，start 为空表示清除维护状态。
+func (r *nodeRepository) SetMaintenance(nodeID uint, start, end *time.Time, stopInstances bool, message string) error {
+	return r.db.Model(&model.Node{}).Where("id = ?", nodeID).Updates(map[string]interface{}{
+		"maintenance_start_at":       start,
+		"maintenance_end_at":         end,
+		"maintenance_stop_instances": stopInstances,
+		"maintenance_message":        message,
+		"updated_at":                 time.Now(),
+	}).Error
+}
+
+// ClearExpiredMaintenance 清除已过结束时间的维护窗口，返回受影响的节点 ID。
+func (r *nodeRepository) ClearExpiredMaintenance(now time.Time) ([]uint, error) {
+	var nodeIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Node{}).
+			Where("maintenance_end_at IS NOT NULL AND maintenance_end_at <= ?", now).
+			Pluck("id", &nodeIDs).Error; err != nil {
+			return err
+		}
+		if len(nodeIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&model.Node{}).Where("id IN ?", nodeIDs).Updates(map[string]interface{}{
+			"maintenance_start_at":       nil,
+			"maintenance_end_at":         nil,
+			"maintenance_stop_instances": false,
+			"maintenance_message":        "",
+			"updated_at":                 now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodeIDs, nil
+}
+
+func orderEndpoints(db *gorm.DB) *gorm.DB {
+	return db.Order("priority, id")
+}
+
+// ListEndpoints 按优先级返回节点的地址列表。
+func (r *nodeRepository) ListEndpoints(nodeID uint) ([]model.NodeEndpoint, error) {
+	var endpoints []model.NodeEndpoint
+	if err := orderEndpoints(r.db.Where("node_id = ?", nodeID)).Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// ReplaceEndpoints 整体替换节点地址，并将 nodes.endpoint 同步为第一个（优先）地址。
+func (r *nodeRepository) ReplaceEndpoints(nodeID uint, endpoints []model.NodeEndpoint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("node_id = ?", nodeID).Delete(&model.NodeEndpoint{}).Error; err != nil {
+			return err
+		}
+		if len(endpoints) == 0 {
+			return nil
+		}
+		for i := range endpoints {
+			endpoints[i].ID = 0
+			endpoints[i].NodeID = nodeID
+		}
+		if err := tx.Create(&endpoints).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Node{}).Where("id = ?", nodeID).Updates(map[string]interface{}{
+			"endpoint":   endpoints[0].Address,
+			"updated_at": time.Now(),
+		}).Error
+	})
+}
+
+// SetAgentSettings 写入节点级 Agent 运行时设置，空字符串表示跟随全局。
+func (r *nodeRepository) SetAgentSettings(nodeID uint, settings string) error {
+	return r.db.Model(&model.Node{}).Where("id = ?", nodeID).Updates(map[string]interface{}{
+		"agent_settings": settings,
+		"updated_at":     time.Now(),
+	}).Error
+}
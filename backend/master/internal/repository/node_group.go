@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// NodeGroupRepository 管理节点组及其授权关系。
+type NodeGroupRepository interface {
+	Create(group *model.NodeGroup) error
+	GetByID(id uint) (*model.NodeGroup, error)
+	List() ([]model.NodeGroup, error)
+	Update(group *model.NodeGroup) error
+	Delete(id uint) error
+	SetNodes(groupID uint, nodeIDs []uint) error
+	AddNodes(groupID uint, nodeIDs []uint) error
+	RemoveNode(groupID, nodeID uint) error
+	GetEntitledUserIDs(groupID uint) ([]uint, error)
+	GetEntitlement(userID uint) (*model.UserEntitlement, error)
+	SetEntitlement(userID uint, groupIDs []uint, tags []string) error
+}
+
+type nodeGroupRepository struct {
+	db *gorm.DB
+}
+
+// NewNodeGroupRepository 构建实现。
+func NewNodeGroupRepository(db *gorm.DB) NodeGroupRepository {
+	return &nodeGroupRepository{db: db}
+}
+
+func (r *nodeGroupRepository) Create(group *model.NodeGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+		return replaceGroupTags(tx, group.ID, group.Tags)
+	})
+}
+
+func (r *nodeGroupRepository) GetByID(id uint) (*model.NodeGroup, error) {
+	var group model.NodeGroup
+	if err := r.db.First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	groups := []model.NodeGroup{group}
+	if err := r.loadRelations(groups); err != nil {
+		return nil, err
+	}
+	return &groups[0], nil
+}
+
+func (r *nodeGroupRepository) List() ([]model.NodeGroup, error) {
+	var groups []model.NodeGroup
+	if err := r.db.Order("id DESC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadRelations(groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *nodeGroupRepository) Update(group *model.NodeGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(group).Error; err != nil {
+			return err
+		}
+		return replaceGroupTags(tx, group.ID, group.Tags)
+	})
+}
+
+func (r *nodeGroupRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, value := range []interface{}{&model.NodeGroupTag{}, &model.NodeGroupMember{}, &model.UserNodeGroup{}} {
+			if err := tx.Where("group_id = ?", id).Delete(value).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&model.NodeGroup{}, id).Error
+	})
+}
+
+func (r *nodeGroupRepository) SetNodes(groupID uint, nodeIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", groupID).Delete(&model.NodeGroupMember{}).Error; err != nil {
+			return err
+		}
+		return insertGroupMembers(tx, groupID, nodeIDs)
+	})
+}
+
+func (r *nodeGroupRepository) AddNodes(groupID uint, nodeIDs []uint) error {
+	return insertGroupMembers(r.db, groupID, nodeIDs)
+}
+
+func (r *nodeGroupRepository) RemoveNode(groupID, nodeID uint) error {
+	return r.db.Where("group_id = ? AND node_id = ?", groupID, nodeID).Delete(&model.NodeGroupMember{}).Error
+}
+
+func (r *nodeGroupRepository) GetEntitledUserIDs(groupID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.db.Raw(`
+		SELECT user_id FROM user_node_groups WHERE group_id = ?
+		UNION
+		SELECT ut.user_id FROM user_node_tags ut
+		JOIN node_group_tags gt ON gt.tag = ut.tag
+		WHERE gt.group_id = ?`, groupID, groupID).Scan(&userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+func (r *nodeGroupRepository) GetEntitlement(userID uint) (*model.UserEntitlement, error) {
+	result := &model.UserEntitlement{UserID: userID, NodeIDs: []uint{}, GroupIDs: []uint{}, Tags: []string{}}
+	if err := r.db.Model(&model.UserNode{}).Where("user_id = ?", userID).Order("node_id").Pluck("node_id", &result.NodeIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&model.UserNodeGroup{}).Where("user_id = ?", userID).Order("group_id").Pluck("group_id", &result.GroupIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&model.UserNodeTag{}).Where("user_id = ?", userID).Order("tag").Pluck("tag", &result.Tags).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *nodeGroupRepository) SetEntitlement(userID uint, groupIDs []uint, tags []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserNodeGroup{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserNodeTag{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if len(groupIDs) > 0 {
+			rows := make([]model.UserNodeGroup, 0, len(groupIDs))
+			for _, id := range groupIDs {
+				rows = append(rows, model.UserNodeGroup{UserID: userID, GroupID: id, CreatedAt: now})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		if len(tags) > 0 {
+			rows := make([]model.UserNodeTag, 0, len(tags))
+			for _, tag := range tags {
+				rows = append(rows, model.UserNodeTag{UserID: userID, Tag: tag, CreatedAt: now})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *nodeGroupRepository) loadRelations(groups []model.NodeGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(groups))
+	index := make(map[uint]int, len(groups))
+	for i := range groups {
+		ids = append(ids, groups[i].ID)
+		index[groups[i].ID] = i
+		groups[i].Tags = []string{}
+		groups[i].NodeIDs = []uint{}
+	}
+
+	var tags []model.NodeGroupTag
+	if err := r.db.Where("group_id IN ?", ids).Order("tag").Find(&tags).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		g := &groups[index[tag.GroupID]]
+		g.Tags = append(g.Tags, tag.Tag)
+	}
+
+	var members []model.NodeGroupMember
+	if err := r.db.Where("group_id IN ?", ids).Order("node_id").Find(&members).Error; err != nil {
+		return err
+	}
+	for _, member := range members {
+		g := &groups[index[member.GroupID]]
+		g.NodeIDs = append(g.NodeIDs, member.NodeID)
+	}
+	return nil
+}
+
+func replaceGroupTags(tx *gorm.DB, groupID uint, tags []string) error {
+	if err := tx.Where("group_id = ?", groupID).Delete(&model.NodeGroupTag{}).Error; err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	rows := make([]model.NodeGroupTag, 0, len(tags))
+	for _, tag := range tags {
+		rows = append(rows, model.NodeGroupTag{GroupID: groupID, Tag: tag})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func insertGroupMembers(tx *gorm.DB, groupID uint, nodeIDs []uint) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	rows := make([]model.NodeGroupMember, 0, len(nodeIDs))
+	for _, id := range nodeIDs {
+		rows = append(rows, model.NodeGroupMember{GroupID: groupID, NodeID: id, CreatedAt: now})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// NodeMetricRepository 读取心跳时序数据并维护降采样结果。
+type NodeMetricRepository interface {
+	ListHeartbeats(nodeID uint, from, to time.Time) ([]model.NodeHeartbeat, error)
+	ListRollups(nodeID uint, from, to time.Time) ([]model.NodeMetricRollup, error)
+	ListHeartbeatsBefore(before time.Time, limit int) ([]model.NodeHeartbeat, error)
+	CompactHeartbeats(rollups []model.NodeMetricRollup, heartbeatIDs []uint) error
+	DeleteRollupsBefore(before time.Time) (int64, error)
+}
+
+type nodeMetricRepository struct {
+	db *gorm.DB
+}
+
+// NewNodeMetricRepository 构建实现。
+func NewNodeMetricRepository(db *gorm.DB) NodeMetricRepository {
+	return &nodeMetricRepository{db: db}
+}
+
+func (r *nodeMetricRepository) ListHeartbeats(nodeID uint, from, to time.Time) ([]model.NodeHeartbeat, error) {
+	var records []model.NodeHeartbeat
+	err := r.db.Where("node_id = ? AND created_at >= ? AND created_at < ?", nodeID, from, to).
+		Order("created_at ASC").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *nodeMetricRepository) ListRollups(nodeID uint, from, to time.Time) ([]model.NodeMetricRollup, error) {
+	var rollups []model.NodeMetricRollup
+	err := r.db.Where("node_id = ? AND bucket_start >= ? AND bucket_start < ?", nodeID, from, to).
+		Order("bucket_start ASC").Find(&rollups).Error
+	if err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+func (r *nodeMetricRepository) ListHeartbeatsBefore(before time.Time, limit int) ([]model.NodeHeartbeat, error) {
+	var records []model.NodeHeartbeat
+	query := r.db.Where("created_at < ?", before).Order("node_id ASC, created_at ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CompactHeartbeats 在同一事务中合并小时数据并删除已降采样的原始心跳。
+func (r *nodeMetricRepository) CompactHeartbeats(rollups []model.NodeMetricRollup, heartbeatIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range rollups {
+			rollup := rollups[i]
+			var existing []model.NodeMetricRollup
+			if err := tx.Where("node_id = ? AND bucket_start = ?", rollup.NodeID, rollup.BucketStart).Limit(1).Find(&existing).Error; err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				merged := mergeRollups(existing[0], rollup)
+				if err := tx.Save(&merged).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rollup).Error; err != nil {
+				return err
+			}
+		}
+		if len(heartbeatIDs) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", heartbeatIDs).Delete(&model.NodeHeartbeat{}).Error
+	})
+}
+
+func (r *nodeMetricRepository) DeleteRollupsBefore(before time.Time) (int64, error) {
+	res := r.db.Where("bucket_start < ?", before).Delete(&model.NodeMetricRollup{})
+	return res.RowsAffected, res.Error
+}
+
+// mergeRollups 按样本数加权合并同一小时的两份降采样结果，CPU、内存与磁盘按各自的样本数加权。
+func mergeRollups(a, b model.NodeMetricRollup) model.NodeMetricRollup {
+	total := a.Samples + b.Samples
+	if total == 0 {
+		return a
+	}
+	weighted := func(x float64, xn int, y float64, yn int) float64 {
+		if xn+yn == 0 {
+			return 0
+		}
+		return (x*float64(xn) + y*float64(yn)) / float64(xn+yn)
+	}
+	a.CPUAvg = weighted(a.CPUAvg, a.CPUSamples, b.CPUAvg, b.CPUSamples)
+	a.MemoryAvg = weighted(a.MemoryAvg, a.MemorySamples, b.MemoryAvg, b.MemorySamples)
+	a.DiskAvg = weighted(a.DiskAvg, a.DiskSamples, b.DiskAvg, b.DiskSamples)
+	a.InstanceCountAvg = weighted(a.InstanceCountAvg, a.Samples, b.InstanceCountAvg, b.Samples)
+	a.CPUMax = max(a.CPUMax, b.CPUMax)
+	a.MemoryMax = max(a.MemoryMax, b.MemoryMax)
+	a.DiskMax = max(a.DiskMax, b.DiskMax)
+	a.InstanceCountMax = max(a.InstanceCountMax, b.InstanceCountMax)
+	a.Samples = total
+	a.CPUSamples += b.CPUSamples
+	a.MemorySamples += b.MemorySamples
+	a.DiskSamples += b.DiskSamples
+	return a
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// NodeStatusRepository 维护节点状态变化记录。
+type NodeStatusRepository interface {
+	CreateEvent(event *model.NodeStatusEvent) error
+	ListEvents(nodeID uint, from, to time.Time, limit int) ([]model.NodeStatusEvent, error)
+	LastEventBefore(nodeID uint, before time.Time) (*model.NodeStatusEvent, error)
+	FirstEventFrom(nodeID uint, from time.Time) (*model.NodeStatusEvent, error)
+	MarkStaleOffline(threshold time.Time) ([]model.NodeStatusEvent, error)
+}
+
+type nodeStatusRepository struct {
+	db *gorm.DB
+}
+
+// NewNodeStatusRepository 构建实现。
+func NewNodeStatusRepository(db *gorm.DB) NodeStatusRepository {
+	return &nodeStatusRepository{db: db}
+}
+
+func (r *nodeStatusRepository) CreateEvent(event *model.NodeStatusEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *nodeStatusRepository) ListEvents(nodeID uint, from, to time.Time, limit int) ([]model.NodeStatusEvent, error) {
+	var events []model.NodeStatusEvent
+	query := r.db.Where("node_id = ? AND created_at >= ? AND created_at < ?", nodeID, from, to).
+		Order("created_at ASC, id ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// LastEventBefore 返回指定时间之前最后一次状态变化，不存在时返回 nil。
+func (r *nodeStatusRepository) LastEventBefore(nodeID uint, before time.Time) (*model.NodeStatusEvent, error) {
+	var events []model.NodeStatusEvent
+	err := r.db.Where("node_id = ? AND created_at < ?", nodeID, before).
+		Order("created_at DESC, id DESC").Limit(1).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &events[0], nil
+}
+
+// FirstEventFrom 返回指定时间及之后的第一次状态变化，不存在时返回 nil。
+func (r *nodeStatusRepository) FirstEventFrom(nodeID uint, from time.Time) (*model.NodeStatusEvent, error) {
+	var events []model.NodeStatusEvent
+	err := r.db.Where("node_id = ? AND created_at >= ?", nodeID, from).
+		Order("created_at ASC, id ASC").Limit(1).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &events[0], nil
+}
+
+// MarkStaleOffline 将超时未心跳的节点置为离线，并在同一事务中记录状态变化。
+func (r *nodeStatusRepository) MarkStaleOffline(threshold time.Time) ([]model.NodeStatusEvent, error) {
+	var events []model.NodeStatusEvent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var nodes []model.Node
+		err := tx.Where("status <> ? AND (last_seen_at IS NULL OR last_seen_at < ?)", model.NodeStatusOffline, threshold).
+			Find(&nodes).Error
+		if err != nil || len(nodes) == 0 {
+			return err
+		}
+		now := time.Now()
+		ids := make([]uint, 0, len(nodes))
+		for _, node := range nodes {
+			ids = append(ids, node.ID)
+			events = append(events, model.NodeStatusEvent{
+				NodeID:     node.ID,
+				FromStatus: node.Status,
+				ToStatus:   model.NodeStatusOffline,
+				Cause:      model.NodeStatusCauseHeartbeatTimeout,
+				CreatedAt:  now,
+			})
+		}
+		if err := tx.Model(&model.Node{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{"status": model.NodeStatusOffline, "updated_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&events).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// RuleSetRepository 管理规则集。
+type RuleSetRepository interface {
+	Create(set *model.RuleSet) error
+	Update(set *model.RuleSet) error
+	Delete(id uint) error
+	GetByID(id uint) (*model.RuleSet, error)
+	List() ([]model.RuleSet, error)
+}
+
+type ruleSetRepository struct {
+	db *gorm.DB
+}
+
+// NewRuleSetRepository 返回实现。
+func NewRuleSetRepository(db *gorm.DB) RuleSetRepository {
+	return &ruleSetRepository{db: db}
+}
+
+func (r *ruleSetRepository) Create(set *model.RuleSet) error {
+	return r.db.Create(set).Error
+}
+
+func (r *ruleSetRepository) Update(set *model.RuleSet) error {
+	return r.db.Save(set).Error
+}
+
+func (r *ruleSetRepository) Delete(id uint) error {
+	return r.db.Delete(&model.RuleSet{}, id).Error
+}
+
+func (r *ruleSetRepository) GetByID(id uint) (*model.RuleSet, error) {
+	var set model.RuleSet
+	if err := r.db.First(&set, id).Error; err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (r *ruleSetRepository) List() ([]model.RuleSet, error) {
+	var sets []model.RuleSet
+	if err := r.db.Order("id").Find(&sets).Error; err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// SnellReleaseRepository 管理 Snell Server 版本校验和与节点升级状态。
+type SnellReleaseRepository interface {
+	Save(releases []model.SnellRelease) error
+	List() ([]model.SnellRelease, error)
+	ListByVersion(version string) ([]model.SnellRelease, error)
+	DeleteVersion(version string) error
+	SetNodeTargets(nodeIDs []uint, version string) error
+	ClearNodeTargets() error
+	UpdateNodeProgress(nodeID uint, status, message string, done, total int) error
+}
+
+type snellReleaseRepository struct {
+	db *gorm.DB
+}
+
+// NewSnellReleaseRepository 构建实现。
+func NewSnellReleaseRepository(db *gorm.DB) SnellReleaseRepository {
+	return &snellReleaseRepository{db: db}
+}
+
+// Save 写入校验和，同一版本和架构已存在时覆盖。
+func (r *snellReleaseRepository) Save(releases []model.SnellRelease) error {
+	if len(releases) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "version"}, {Name: "arch"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sha256"}),
+	}).Create(&releases).Error
+}
+
+func (r *snellReleaseRepository) List() ([]model.SnellRelease, error) {
+	var releases []model.SnellRelease
+	if err := r.db.Order("created_at DESC, arch ASC").Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+func (r *snellReleaseRepository) ListByVersion(version string) ([]model.SnellRelease, error) {
+	var releases []model.SnellRelease
+	if err := r.db.Where("version = ?", version).Order("arch ASC").Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+func (r *snellReleaseRepository) DeleteVersion(version string) error {
+	return r.db.Where("version = ?", version).Delete(&model.SnellRelease{}).Error
+}
+
+// SetNodeTargets 为指定节点固定版本，version 为空表示跟随全局版本。
+func (r *snellReleaseRepository) SetNodeTargets(nodeIDs []uint, version string) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.Node{}).Where("id IN ?", nodeIDs).
+		Update("snell_target_version", version).Error
+}
+
+// ClearNodeTargets 取消所有节点的固定版本。
+func (r *snellReleaseRepository) ClearNodeTargets() error {
+	return r.db.Model(&model.Node{}).Where("snell_target_version <> ''").
+		Update("snell_target_version", "").Error
+}
+
+func (r *snellReleaseRepository) UpdateNodeProgress(nodeID uint, status, message string, done, total int) error {
+	now := time.Now()
+	return r.db.Model(&model.Node{}).Where("id = ?", nodeID).Updates(map[string]interface{}{
+		"snell_upgrade_status":  status,
+		"snell_upgrade_message": message,
+		"snell_upgrade_done":    done,
+		"snell_upgrade_total":   total,
+		"snell_upgrade_at":      &now,
+	}).Error
+}
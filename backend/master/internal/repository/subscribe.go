@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/iwoov/snell-master/backend/master/internal/model"
 )
@@ -13,11 +14,14 @@ type SubscribeRepository interface {
 	Create(token *model.SubscribeToken) error
 	GetByID(id uint) (*model.SubscribeToken, error)
 	GetByToken(token string) (*model.SubscribeToken, error)
-	GetByUser(userID uint) (*model.SubscribeToken, error)
+	ListByUser(userID uint) ([]model.SubscribeToken, error)
+	CountByUser(userID uint) (int64, error)
 	Update(token *model.SubscribeToken) error
 	Delete(id uint) error
 	List() ([]model.SubscribeToken, error)
-	IncrementAccess(id uint) error
+	IncrementAccess(id uint, ip, userAgent string) error
+	ListFlagged() ([]model.SubscribeToken, error)
+	SetFlag(id uint, flaggedAt *time.Time, reason string) error
 }
 
 type subscribeRepository struct {
@@ -49,16 +53,23 @@ func (r *subscribeRepository) GetByToken(token string) (*model.SubscribeToken, e
 	return &sub, nil
 }
 
-func (r *subscribeRepository) GetByUser(userID uint) (*model.SubscribeToken, error) {
-	var token model.SubscribeToken
-	if err := r.db.Where("user_id = ?", userID).Preload("Template").First(&token).Error; err != nil {
+func (r *subscribeRepository) ListByUser(userID uint) ([]model.SubscribeToken, error) {
+	var tokens []model.SubscribeToken
+	if err := r.db.Where("user_id = ?", userID).Preload("Template").Order("id").Find(&tokens).Error; err != nil {
 		return nil, err
 	}
-	return &token, nil
+	return tokens, nil
 }
 
+func (r *subscribeRepository) CountByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.SubscribeToken{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// Update 保存令牌字段，不写回预加载的模板与用户。
 func (r *subscribeRepository) Update(token *model.SubscribeToken) error {
-	return r.db.Save(token).Error
+	return r.db.Omit(clause.Associations).Save(token).Error
 }
 
 func (r *subscribeRepository) Delete(id uint) error {
@@ -73,12 +84,33 @@ func (r *subscribeRepository) List() ([]model.SubscribeToken, error) {
 	return tokens, nil
 }
 
-func (r *subscribeRepository) IncrementAccess(id uint) error {
+func (r *subscribeRepository) IncrementAccess(id uint, ip, userAgent string) error {
 	now := time.Now()
 	return r.db.Model(&model.SubscribeToken{}).
 		Where("id = ?", id).
 		Updates(map[string]interface{}{
-			"access_count":   gorm.Expr("access_count + 1"),
-			"last_access_at": &now,
+			"access_count":    gorm.Expr("access_count + 1"),
+			"last_access_at":  &now,
+			"last_access_ip":  ip,
+			"last_user_agent": userAgent,
+		}).Error
+}
+
+// ListFlagged 返回被泄露检测标记的令牌，最近标记的在前。
+func (r *subscribeRepository) ListFlagged() ([]model.SubscribeToken, error) {
+	var tokens []model.SubscribeToken
+	if err := r.db.Where("flagged_at IS NOT NULL").Preload("User").Preload("Template").Order("flagged_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// SetFlag 设置或清除（flaggedAt 为 nil）泄露标记。
+func (r *subscribeRepository) SetFlag(id uint, flaggedAt *time.Time, reason string) error {
+	return r.db.Model(&model.SubscribeToken{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"flagged_at":  flaggedAt,
+			"flag_reason": reason,
 		}).Error
 }
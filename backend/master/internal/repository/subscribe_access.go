@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// SubscribeAccessStats 令牌在一段时间内的访问来源统计。
+type SubscribeAccessStats struct {
+	Requests int64
+	IPs      int64
+	Networks int64
+}
+
+// SubscribeAccessRepository 维护订阅访问日志。
+type SubscribeAccessRepository interface {
+	Create(log *model.SubscribeAccessLog) error
+	ListByToken(tokenID uint, limit int) ([]model.SubscribeAccessLog, error)
+	Stats(tokenID uint, since time.Time) (*SubscribeAccessStats, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+type subscribeAccessRepository struct {
+	db *gorm.DB
+}
+
+// NewSubscribeAccessRepository 构建实现。
+func NewSubscribeAccessRepository(db *gorm.DB) SubscribeAccessRepository {
+	return &subscribeAccessRepository{db: db}
+}
+
+func (r *subscribeAccessRepository) Create(log *model.SubscribeAccessLog) error {
+	return r.db.Create(log).Error
+}
+
+// ListByToken 返回最新的 limit 条记录，按时间倒序排列。
+func (r *subscribeAccessRepository) ListByToken(tokenID uint, limit int) ([]model.SubscribeAccessLog, error) {
+	var logs []model.SubscribeAccessLog
+	query := r.db.Where("token_id = ?", tokenID).Order("created_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// Stats 统计 since 之后的请求数以及不同 IP 与网段的数量。
+func (r *subscribeAccessRepository) Stats(tokenID uint, since time.Time) (*SubscribeAccessStats, error) {
+	var stats SubscribeAccessStats
+	err := r.db.Model(&model.SubscribeAccessLog{}).
+		Select("COUNT(*) AS requests, COUNT(DISTINCT ip) AS ips, COUNT(DISTINCT network) AS networks").
+		Where("token_id = ? AND created_at >= ?", tokenID, since).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (r *subscribeAccessRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&model.SubscribeAccessLog{})
+	return result.RowsAffected, result.Error
+}
@@ -6,15 +6,20 @@ import (
 	"github.com/iwoov/snell-master/backend/master/internal/model"
 )
 
-// TemplateRepository 管理 Surge 模板。
+// TemplateRepository 管理订阅模板。
 type TemplateRepository interface {
 	Create(tpl *model.Template) error
 	Update(tpl *model.Template) error
 	Delete(id uint) error
 	GetByID(id uint) (*model.Template, error)
-	GetDefault() (*model.Template, error)
+	GetDefault(target string) (*model.Template, error)
 	List() ([]model.Template, error)
 	SetDefault(id uint) error
+	SaveWithRevision(tpl *model.Template, rev *model.TemplateRevision) error
+	ListRevisions(templateID uint) ([]model.TemplateRevision, error)
+	GetRevision(templateID uint, revision int) (*model.TemplateRevision, error)
+	GetRevisionByID(id uint) (*model.TemplateRevision, error)
+	LatestRevision(templateID uint) (*model.TemplateRevision, error)
 }
 
 type templateRepository struct {
@@ -46,9 +51,10 @@ func (r *templateRepository) GetByID(id uint) (*model.Template, error) {
 	return &tpl, nil
 }
 
-func (r *templateRepository) GetDefault() (*model.Template, error) {
+// GetDefault 返回指定目标格式的默认模板。
+func (r *templateRepository) GetDefault(target string) (*model.Template, error) {
 	var tpl model.Template
-	if err := r.db.Where("is_default = ?", true).First(&tpl).Error; err != nil {
+	if err := r.db.Where("is_default = ? AND target = ?", true, target).First(&tpl).Error; err != nil {
 		return nil, err
 	}
 	return &tpl, nil
@@ -62,11 +68,67 @@ func (r *templateRepository) List() ([]model.Template, error) {
 	return tpl, nil
 }
 
+// SetDefault 设为默认模板，只取消同一目标格式的其他默认模板。
 func (r *templateRepository) SetDefault(id uint) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(&model.Template{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+		var tpl model.Template
+		if err := tx.First(&tpl, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Template{}).Where("is_default = ? AND target = ?", true, tpl.Target).Update("is_default", false).Error; err != nil {
 			return err
 		}
 		return tx.Model(&model.Template{}).Where("id = ?", id).Update("is_default", true).Error
 	})
 }
+
+// SaveWithRevision 在同一事务中保存模板（ID 为 0 时新建）并追加下一个修订号的快照。
+func (r *templateRepository) SaveWithRevision(tpl *model.Template, rev *model.TemplateRevision) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(tpl).Error; err != nil {
+			return err
+		}
+		var latest int
+		if err := tx.Model(&model.TemplateRevision{}).Where("template_id = ?", tpl.ID).
+			Select("COALESCE(MAX(revision), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		rev.ID = 0
+		rev.TemplateID = tpl.ID
+		rev.Revision = latest + 1
+		return tx.Create(rev).Error
+	})
+}
+
+// ListRevisions 返回模板的全部修订，最新的在前。
+func (r *templateRepository) ListRevisions(templateID uint) ([]model.TemplateRevision, error) {
+	var revs []model.TemplateRevision
+	if err := r.db.Where("template_id = ?", templateID).Order("revision DESC").Find(&revs).Error; err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
+
+func (r *templateRepository) GetRevision(templateID uint, revision int) (*model.TemplateRevision, error) {
+	var rev model.TemplateRevision
+	if err := r.db.Where("template_id = ? AND revision = ?", templateID, revision).First(&rev).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *templateRepository) GetRevisionByID(id uint) (*model.TemplateRevision, error) {
+	var rev model.TemplateRevision
+	if err := r.db.First(&rev, id).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func (r *templateRepository) LatestRevision(templateID uint) (*model.TemplateRevision, error) {
+	var rev model.TemplateRevision
+	if err := r.db.Where("template_id = ?", templateID).Order("revision DESC").First(&rev).Error; err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
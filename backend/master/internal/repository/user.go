@@ -29,6 +29,7 @@ type UserRepository interface {
 	GetUsersByStatus(status int) ([]model.User, error)
 	AssignNodes(userID uint, nodeIDs []uint) error
 	GetUserNodes(userID uint) ([]model.Node, error)
+	GetNodeUserIDs(nodeID uint) ([]uint, error)
 }
 
 type userRepository struct {
@@ -95,8 +96,10 @@ func (r *userRepository) Update(user *model.User) error {
 
 func (r *userRepository) Delete(id uint) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("user_id = ?", id).Delete(&model.UserNode{}).Error; err != nil {
-			return err
+		for _, value := range []interface{}{&model.UserNode{}, &model.UserNodeGroup{}, &model.UserNodeTag{}} {
+			if err := tx.Where("user_id = ?", id).Delete(value).Error; err != nil {
+				return err
+			}
 		}
 		if err := tx.Delete(&model.User{}, id).Error; err != nil {
 			return err
@@ -150,10 +153,44 @@ func (r *userRepository) AssignNodes(userID uint, nodeIDs []uint) error {
 	})
 }
 
+// GetUserNodes 返回用户可用的节点：直接分配、节点组授权与标签授权三者的并集。
 func (r *userRepository) GetUserNodes(userID uint) ([]model.Node, error) {
 	var nodes []model.Node
-	if err := r.db.Model(&model.User{ID: userID}).Association("Nodes").Find(&nodes); err != nil {
+	err := r.db.Where(`id IN (
+		SELECT node_id FROM user_nodes WHERE user_id = ?
+		UNION
+		SELECT m.node_id FROM node_group_members m
+		JOIN user_node_groups ug ON ug.group_id = m.group_id
+		WHERE ug.user_id = ?
+		UNION
+		SELECT m.node_id FROM node_group_members m
+		JOIN node_group_tags gt ON gt.group_id = m.group_id
+		JOIN user_node_tags ut ON ut.tag = gt.tag
+		WHERE ut.user_id = ?
+	)`, userID, userID, userID).Preload("Endpoints", orderEndpoints).Order("id").Find(&nodes).Error
+	if err != nil {
 		return nil, err
 	}
 	return nodes, nil
 }
+
+// GetNodeUserIDs 返回可以使用节点的用户：直接分配、节点组授权与标签授权三者的并集。
+func (r *userRepository) GetNodeUserIDs(nodeID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.db.Raw(`
+		SELECT user_id FROM user_nodes WHERE node_id = ?
+		UNION
+		SELECT ug.user_id FROM user_node_groups ug
+		JOIN node_group_members m ON m.group_id = ug.group_id
+		WHERE m.node_id = ?
+		UNION
+		SELECT ut.user_id FROM user_node_tags ut
+		JOIN node_group_tags gt ON gt.tag = ut.tag
+		JOIN node_group_members m ON m.group_id = gt.group_id
+		WHERE m.node_id = ?
+		ORDER BY 1`, nodeID, nodeID, nodeID).Scan(&userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
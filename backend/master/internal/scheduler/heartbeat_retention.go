@@ -0,0 +1,18 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// ScheduleHeartbeatRetention 每小时将过期心跳降采样为小时数据并清理过期数据。
+func ScheduleHeartbeatRetention(metricsSvc *service.NodeMetricsService, logger *logrus.Logger) *Task {
+	return newTask(5*time.Minute, time.Hour, func() {
+		if err := metricsSvc.CompactHeartbeats(); err != nil && logger != nil {
+			logger.WithError(err).Error("heartbeat retention failed")
+		}
+	})
+}
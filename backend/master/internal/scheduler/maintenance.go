@@ -0,0 +1,18 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// ScheduleMaintenanceCleanup 每分钟清除已到结束时间的节点维护窗口。
+func ScheduleMaintenanceCleanup(maintenanceSvc *service.MaintenanceService, logger *logrus.Logger) *Task {
+	return newTask(time.Minute, time.Minute, func() {
+		if err := maintenanceSvc.ClearExpired(); err != nil && logger != nil {
+			logger.WithError(err).Error("maintenance cleanup failed")
+		}
+	})
+}
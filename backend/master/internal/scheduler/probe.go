@@ -0,0 +1,27 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// ScheduleInstanceProbe 每 30 秒检查一次，按 probe_interval_seconds 探测实例端口可达性。
+func ScheduleInstanceProbe(probeSvc *service.ProbeService, logger *logrus.Logger) *Task {
+	return newTask(time.Minute, 30*time.Second, func() {
+		if err := probeSvc.RunDue(); err != nil && logger != nil {
+			logger.WithError(err).Error("instance probe failed")
+		}
+	})
+}
+
+// ScheduleProbeRetention 每小时清理过期的探测记录。
+func ScheduleProbeRetention(probeSvc *service.ProbeService, logger *logrus.Logger) *Task {
+	return newTask(10*time.Minute, time.Hour, func() {
+		if err := probeSvc.Cleanup(); err != nil && logger != nil {
+			logger.WithError(err).Error("probe retention failed")
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/service"
+)
+
+// ScheduleSubscribeAccessRetention 每小时清理过期的订阅访问记录。
+func ScheduleSubscribeAccessRetention(subscribeSvc *service.SubscribeService, logger *logrus.Logger) *Task {
+	return newTask(15*time.Minute, time.Hour, func() {
+		if err := subscribeSvc.CleanupAccessLogs(); err != nil && logger != nil {
+			logger.WithError(err).Error("subscribe access log retention failed")
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+const (
+	agentSettingsKey = "agent_settings"
+	// Agent 定时任务间隔的允许范围（秒）
+	minAgentInterval = 5
+	maxAgentInterval = 86400
+)
+
+// NodeAgentSettings 节点的 Agent 设置：全局、节点覆盖与合并后的生效值。
+type NodeAgentSettings struct {
+	NodeID    uint                `json:"node_id"`
+	Global    model.AgentSettings `json:"global"`
+	Node      model.AgentSettings `json:"node"`
+	Effective model.AgentSettings `json:"effective"`
+}
+
+// AgentSettingsService 管理下发给 Agent 的运行时设置。
+type AgentSettingsService struct {
+	nodeRepo   repository.NodeRepository
+	configRepo repository.SystemConfigRepository
+	logger     *logrus.Logger
+}
+
+// NewAgentSettingsService 构造函数。
+func NewAgentSettingsService(nodeRepo repository.NodeRepository, configRepo repository.SystemConfigRepository, logger *logrus.Logger) *AgentSettingsService {
+	return &AgentSettingsService{nodeRepo: nodeRepo, configRepo: configRepo, logger: logger}
+}
+
+// Global 返回全局 Agent 设置。
+func (s *AgentSettingsService) Global() (model.AgentSettings, error) {
+	values, err := s.configRepo.GetByKeys([]string{agentSettingsKey})
+	if err != nil {
+		return model.AgentSettings{}, err
+	}
+	return parseAgentSettings(values[agentSettingsKey])
+}
+
+// SetGlobal 校验并保存全局 Agent 设置。
+func (s *AgentSettingsService) SetGlobal(settings model.AgentSettings) (model.AgentSettings, error) {
+	value, err := encodeAgentSettings(&settings)
+	if err != nil {
+		return model.AgentSettings{}, err
+	}
+	if value == "" {
+		value = "{}"
+	}
+	if err := s.configRepo.Set(agentSettingsKey, value); err != nil {
+		return model.AgentSettings{}, err
+	}
+	s.logger.WithField("settings", value).Info("global agent settings updated")
+	return settings, nil
+}
+
+// ForNode 返回节点的 Agent 设置详情。
+func (s *AgentSettingsService) ForNode(nodeID uint) (*NodeAgentSettings, error) {
+	node, err := s.nodeRepo.GetByID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	global, err := s.Global()
+	if err != nil {
+		return nil, err
+	}
+	override, err := parseAgentSettings(node.AgentSettings)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeAgentSettings{NodeID: node.ID, Global: global, Node: override, Effective: global.Merge(override)}, nil
+}
+
+// SetForNode 校验并保存节点级覆盖，传入空设置表示完全跟随全局。
+func (s *AgentSettingsService) SetForNode(nodeID uint, settings model.AgentSettings) (*NodeAgentSettings, error) {
+	if _, err := s.nodeRepo.GetByID(nodeID); err != nil {
+		return nil, err
+	}
+	value, err := encodeAgentSettings(&settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.nodeRepo.SetAgentSettings(nodeID, value); err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(logrus.Fields{"node_id": nodeID, "settings": value}).Info("node agent settings updated")
+	return s.ForNode(nodeID)
+}
+
+// Effective 返回下发给节点的设置；全局设置损坏时只记录日志，避免阻塞配置同步。
+func (s *AgentSettingsService) Effective(node *model.Node) model.AgentSettings {
+	global, err := s.Global()
+	if err != nil {
+		s.logger.WithError(err).Warn("load global agent settings failed")
+	}
+	override, err := parseAgentSettings(node.AgentSettings)
+	if err != nil {
+		s.logger.WithError(err).WithField("node_id", node.ID).Warn("load node agent settings failed")
+	}
+	return global.Merge(override)
+}
+
+func parseAgentSettings(value string) (model.AgentSettings, error) {
+	var settings model.AgentSettings
+	if strings.TrimSpace(value) == "" {
+		return settings, nil
+	}
+	if err := json.Unmarshal([]byte(value), &settings); err != nil {
+		return model.AgentSettings{}, fmt.Errorf("invalid agent settings: %w", err)
+	}
+	return settings, nil
+}
+
+// encodeAgentSettings 校验设置并序列化，未设置任何字段时返回空字符串。
+func encodeAgentSettings(settings *model.AgentSettings) (string, error) {
+	if err := validateAgentSettings(settings); err != nil {
+		return "", err
+	}
+	if settings.IsEmpty() {
+		return "", nil
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func validateAgentSettings(settings *model.AgentSettings) error {
+	for _, interval := range []struct {
+		name  string
+		value *int
+	}{
+		{"heartbeat_interval", settings.HeartbeatInterval},
+		{"config_sync_interval", settings.ConfigSyncInterval},
+		{"traffic_report_interval", settings.TrafficReportInterval},
+		{"update_check_interval", settings.UpdateCheckInterval},
+	} {
+		if interval.value != nil && (*interval.value < minAgentInterval || *interval.value > maxAgentInterval) {
+			return fmt.Errorf("%s must be between %d and %d seconds", interval.name, minAgentInterval, maxAgentInterval)
+		}
+	}
+	if settings.LogLevel != nil {
+		level := strings.ToLower(strings.TrimSpace(*settings.LogLevel))
+		switch level {
+		case "debug", "info", "warn", "error":
+			settings.LogLevel = &level
+		default:
+			return fmt.Errorf("unsupported log_level %q", *settings.LogLevel)
+		}
+	}
+	if settings.Monitor != nil && *settings.Monitor == (model.AgentMonitorSettings{}) {
+		settings.Monitor = nil
+	}
+	return nil
+}
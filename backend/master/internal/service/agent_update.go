@@ -0,0 +1,338 @@
+package service
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+// supportedArches 与部署脚本及 Snell 下载地址的架构命名保持一致。
+var supportedArches = map[string]struct{}{
+	"amd64":   {},
+	"i386":    {},
+	"aarch64": {},
+	"armv7l":  {},
+}
+
+// ReleaseSummary 按版本聚合的各架构校验和。
+type ReleaseSummary struct {
+	Version   string            `json:"version"`
+	Checksums map[string]string `json:"checksums"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// AgentRolloutRequest 描述一次升级下发：All 为真时修改全局版本，否则只为 NodeIDs 指定目标版本。
+// 目标版本低于节点当前版本时，Agent 只在 AllowDowngrade 为真时降级。
+type AgentRolloutRequest struct {
+	Version        string `json:"version"`
+	NodeIDs        []uint `json:"node_ids"`
+	All            bool   `json:"all"`
+	AllowDowngrade bool   `json:"allow_downgrade"`
+}
+
+// AgentUpdateTarget Agent 应升级到的版本，Version 为空表示无需升级。
+type AgentUpdateTarget struct {
+	Version        string `json:"version"`
+	DownloadURL    string `json:"download_url"`
+	SHA256         string `json:"sha256"`
+	AllowDowngrade bool   `json:"allow_downgrade,omitempty"`
+}
+
+// AgentNodeProgress 单个节点的 Agent 升级进度。
+type AgentNodeProgress struct {
+	NodeID        uint       `json:"node_id"`
+	Name          string     `json:"name"`
+	Status        string     `json:"status"`
+	AgentVersion  string     `json:"agent_version"`
+	TargetVersion string     `json:"target_version"`
+	Pinned        bool       `json:"pinned"`
+	UpdateStatus  string     `json:"update_status"`
+	UpdateMessage string     `json:"update_message"`
+	UpdateAt      *time.Time `json:"update_at"`
+}
+
+// AgentUpdateService 管理 Agent 发布版本与分批升级。
+type AgentUpdateService struct {
+	repo       repository.AgentReleaseRepository
+	nodeRepo   repository.NodeRepository
+	configRepo repository.SystemConfigRepository
+	logger     *logrus.Logger
+}
+
+// NewAgentUpdateService 构造函数。
+func NewAgentUpdateService(repo repository.AgentReleaseRepository, nodeRepo repository.NodeRepository, configRepo repository.SystemConfigRepository, logger *logrus.Logger) *AgentUpdateService {
+	return &AgentUpdateService{repo: repo, nodeRepo: nodeRepo, configRepo: configRepo, logger: logger}
+}
+
+// PublishRelease 登记版本各架构二进制的 SHA-256 校验和。
+func (s *AgentUpdateService) PublishRelease(version string, checksums map[string]string) (*ReleaseSummary, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return nil, fmt.Errorf("version is required")
+	}
+	normalized, err := normalizeChecksums(checksums)
+	if err != nil {
+		return nil, err
+	}
+	releases := make([]model.AgentRelease, 0, len(normalized))
+	for arch, sum := range normalized {
+		releases = append(releases, model.AgentRelease{Version: version, Arch: arch, SHA256: sum})
+	}
+	if err := s.repo.Save(releases); err != nil {
+		return nil, err
+	}
+	saved, err := s.repo.ListByVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	summaries := summarizeReleases(saved)
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("release not saved")
+	}
+	return &summaries[0], nil
+}
+
+// ListReleases 返回所有已登记的版本。
+func (s *AgentUpdateService) ListReleases() ([]ReleaseSummary, error) {
+	releases, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	return summarizeReleases(releases), nil
+}
+
+// DeleteRelease 删除版本记录，仍被作为升级目标时拒绝删除。
+func (s *AgentUpdateService) DeleteRelease(version string) error {
+	globalVersion, autoUpdate := s.globalTarget()
+	if autoUpdate && globalVersion == version {
+		return fmt.Errorf("version %s is the global rollout target", version)
+	}
+	nodes, err := s.nodeRepo.List()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.AgentTargetVersion == version {
+			return fmt.Errorf("version %s is pinned on node %s", version, node.Name)
+		}
+	}
+	return s.repo.DeleteVersion(version)
+}
+
+// Rollout 下发升级目标，可先为部分节点指定版本，确认无误后再全量下发。
+func (s *AgentUpdateService) Rollout(req AgentRolloutRequest) error {
+	version := strings.TrimSpace(req.Version)
+	if version != "" {
+		releases, err := s.repo.ListByVersion(version)
+		if err != nil {
+			return err
+		}
+		if len(releases) == 0 {
+			return fmt.Errorf("version %s has not been published", version)
+		}
+	}
+
+	if req.All {
+		if version == "" {
+			return s.configRepo.Set("agent_auto_update", "false")
+		}
+		if err := s.configRepo.BatchSet(map[string]string{
+			"agent_version":         version,
+			"agent_auto_update":     "true",
+			"agent_allow_downgrade": strconv.FormatBool(req.AllowDowngrade),
+		}); err != nil {
+			return err
+		}
+		if err := s.repo.ClearNodeTargets(); err != nil {
+			return err
+		}
+		s.logger.Infof("agent rollout to all nodes: version=%s allow_downgrade=%v", version, req.AllowDowngrade)
+		return nil
+	}
+
+	if len(req.NodeIDs) == 0 {
+		return fmt.Errorf("node_ids is required when all is false")
+	}
+	for _, id := range req.NodeIDs {
+		if _, err := s.nodeRepo.GetByID(id); err != nil {
+			return fmt.Errorf("node %d not found", id)
+		}
+	}
+	if err := s.repo.SetNodeTargets(req.NodeIDs, version, req.AllowDowngrade); err != nil {
+		return err
+	}
+	s.logger.Infof("agent rollout to %d nodes: version=%s allow_downgrade=%v", len(req.NodeIDs), version, req.AllowDowngrade)
+	return nil
+}
+
+// TargetForNode 返回节点在指定架构下应升级到的版本，无需升级或缺少校验和时返回空目标。
+func (s *AgentUpdateService) TargetForNode(node *model.Node, arch string) (*AgentUpdateTarget, error) {
+	globalVersion, autoUpdate := s.globalTarget()
+	version := resolveAgentTarget(node, globalVersion, autoUpdate)
+	if version == "" || version == node.AgentVersion {
+		return &AgentUpdateTarget{}, nil
+	}
+	releases, err := s.repo.ListByVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	var release *model.AgentRelease
+	for i := range releases {
+		if releases[i].Arch == arch {
+			release = &releases[i]
+			break
+		}
+	}
+	if release == nil {
+		s.logger.Warnf("agent release %s has no checksum for arch %s, skip update for node %d", version, arch, node.ID)
+		return &AgentUpdateTarget{}, nil
+	}
+	configs, err := s.configRepo.GetByKeys([]string{"agent_download_url", "agent_allow_downgrade"})
+	if err != nil {
+		return nil, err
+	}
+	downloadURL := strings.NewReplacer("{version}", version, "{arch}", arch).Replace(configs["agent_download_url"])
+	if downloadURL == "" {
+		return nil, fmt.Errorf("agent_download_url is not configured")
+	}
+	// 单独指定版本的节点使用节点上的降级许可，否则跟随全局下发
+	allowDowngrade := node.AgentAllowDowngrade
+	if node.AgentTargetVersion == "" {
+		allowDowngrade, _ = strconv.ParseBool(configs["agent_allow_downgrade"])
+	}
+	return &AgentUpdateTarget{Version: version, DownloadURL: downloadURL, SHA256: release.SHA256, AllowDowngrade: allowDowngrade}, nil
+}
+
+// ReportStatus 记录 Agent 上报的升级状态。
+func (s *AgentUpdateService) ReportStatus(nodeID uint, version, status, message string) error {
+	switch status {
+	case model.AgentUpdateUpdating, model.AgentUpdateSucceeded, model.AgentUpdateFailed, model.AgentUpdateRolledBack:
+	default:
+		return fmt.Errorf("unsupported update status %q", status)
+	}
+	if version != "" {
+		message = strings.TrimSpace(fmt.Sprintf("%s %s", version, message))
+	}
+	if status == model.AgentUpdateFailed || status == model.AgentUpdateRolledBack {
+		s.logger.Warnf("agent update on node %d %s: %s", nodeID, status, message)
+	}
+	return s.repo.UpdateNodeStatus(nodeID, status, message)
+}
+
+// Progress 返回所有节点的当前版本、目标版本与升级状态。
+func (s *AgentUpdateService) Progress() ([]AgentNodeProgress, error) {
+	nodes, err := s.nodeRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	globalVersion, autoUpdate := s.globalTarget()
+	result := make([]AgentNodeProgress, 0, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		result = append(result, AgentNodeProgress{
+			NodeID:        node.ID,
+			Name:          node.Name,
+			Status:        node.Status,
+			AgentVersion:  node.AgentVersion,
+			TargetVersion: resolveAgentTarget(node, globalVersion, autoUpdate),
+			Pinned:        node.AgentTargetVersion != "",
+			UpdateStatus:  node.AgentUpdateStatus,
+			UpdateMessage: node.AgentUpdateMessage,
+			UpdateAt:      node.AgentUpdateAt,
+		})
+	}
+	return result, nil
+}
+
+func (s *AgentUpdateService) globalTarget() (string, bool) {
+	configs, err := s.configRepo.GetByKeys([]string{"agent_version", "agent_auto_update"})
+	if err != nil {
+		return "", false
+	}
+	autoUpdate, _ := strconv.ParseBool(configs["agent_auto_update"])
+	return configs["agent_version"], autoUpdate
+}
+
+// resolveAgentTarget 节点单独指定的版本优先，否则在开启自动升级时跟随全局版本。
+func resolveAgentTarget(node *model.Node, globalVersion string, autoUpdate bool) string {
+	if node.AgentTargetVersion != "" {
+		return node.AgentTargetVersion
+	}
+	if autoUpdate {
+		return globalVersion
+	}
+	return ""
+}
+
+// normalizeChecksums 校验架构名称与 SHA-256 格式，返回小写的校验和。
+func normalizeChecksums(checksums map[string]string) (map[string]string, error) {
+	if len(checksums) == 0 {
+		return nil, fmt.Errorf("at least one checksum is required")
+	}
+	normalized := make(map[string]string, len(checksums))
+	for arch, sum := range checksums {
+		if _, ok := supportedArches[arch]; !ok {
+			return nil, fmt.Errorf("unsupported arch %q", arch)
+		}
+		sum = strings.ToLower(strings.TrimSpace(sum))
+		if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != 32 {
+			return nil, fmt.Errorf("invalid sha256 for arch %s", arch)
+		}
+		normalized[arch] = sum
+	}
+	return normalized, nil
+}
+
+func summarizeReleases(releases []model.AgentRelease) []ReleaseSummary {
+	var builder releaseSummaryBuilder
+	for _, release := range releases {
+		builder.add(release.Version, release.Arch, release.SHA256, release.CreatedAt)
+	}
+	return builder.result()
+}
+
+// releaseSummaryBuilder 将按架构存储的校验和聚合为版本列表。
+type releaseSummaryBuilder struct {
+	index     map[string]int
+	summaries []ReleaseSummary
+}
+
+func (b *releaseSummaryBuilder) add(version, arch, sum string, createdAt time.Time) {
+	if b.index == nil {
+		b.index = make(map[string]int)
+	}
+	i, ok := b.index[version]
+	if !ok {
+		i = len(b.summaries)
+		b.index[version] = i
+		b.summaries = append(b.summaries, ReleaseSummary{
+			Version:   version,
+			Checksums: make(map[string]string),
+			CreatedAt: createdAt,
+		})
+	}
+	b.summaries[i].Checksums[arch] = sum
+	if createdAt.Before(b.summaries[i].CreatedAt) {
+		b.summaries[i].CreatedAt = createdAt
+	}
+}
+
+// result 按创建时间倒序返回聚合结果。
+func (b *releaseSummaryBuilder) result() []ReleaseSummary {
+	summaries := b.summaries
+	if summaries == nil {
+		summaries = make([]ReleaseSummary, 0)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].CreatedAt.After(summaries[j].CreatedAt)
+	})
+	return summaries
+}
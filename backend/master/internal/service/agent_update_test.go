@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAgentRolloutAllowDowngrade(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := NewAgentUpdateService(repos.AgentRelease, repos.Node, repos.SystemConfig, newTestLogger())
+	if err := repos.SystemConfig.Set("agent_download_url", "https://example.com/{version}/{arch}"); err != nil {
+		t.Fatalf("set download url: %v", err)
+	}
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		if _, err := svc.PublishRelease(version, map[string]string{"amd64": strings.Repeat("a", 64)}); err != nil {
+			t.Fatalf("publish %s: %v", version, err)
+		}
+	}
+	pinned := newTestNode(t, repos, "pinned")
+	global := newTestNode(t, repos, "global")
+
+	target := func(id uint) *AgentUpdateTarget {
+		t.Helper()
+		node, err := repos.Node.GetByID(id)
+		if err != nil {
+			t.Fatalf("get node: %v", err)
+		}
+		result, err := svc.TargetForNode(node, "amd64")
+		if err != nil {
+			t.Fatalf("TargetForNode: %v", err)
+		}
+		return result
+	}
+
+	steps := []struct {
+		name        string
+		req         AgentRolloutRequest
+		node        uint
+		wantVersion string
+		wantAllow   bool
+	}{
+		{"global rollout", AgentRolloutRequest{Version: "2.0.0", All: true}, global.ID, "2.0.0", false},
+		{"global downgrade", AgentRolloutRequest{Version: "1.0.0", All: true, AllowDowngrade: true}, global.ID, "1.0.0", true},
+		{"pinned downgrade", AgentRolloutRequest{Version: "1.0.0", NodeIDs: []uint{pinned.ID}, AllowDowngrade: true}, pinned.ID, "1.0.0", true},
+		{"pinned without permission", AgentRolloutRequest{Version: "2.0.0", NodeIDs: []uint{pinned.ID}}, pinned.ID, "2.0.0", false},
+		{"global rollout clears pins", AgentRolloutRequest{Version: "2.0.0", All: true}, pinned.ID, "2.0.0", false},
+	}
+	for _, step := range steps {
+		if err := svc.Rollout(step.req); err != nil {
+			t.Fatalf("%s: rollout: %v", step.name, err)
+		}
+		got := target(step.node)
+		if got.Version != step.wantVersion || got.AllowDowngrade != step.wantAllow {
+			t.Fatalf("%s: target = %+v, want version %s allow_downgrade %v", step.name, got, step.wantVersion, step.wantAllow)
+		}
+	}
+}
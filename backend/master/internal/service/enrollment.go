@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+	"github.com/iwoov/snell-master/pkg/utils"
+)
+
+const (
+	defaultEnrollmentTTL = 24 * time.Hour
+	maxEnrollmentTTL     = 30 * 24 * time.Hour
+)
+
+// ErrInvalidEnrollRequest 注册请求缺少必要信息或格式不正确。
+var ErrInvalidEnrollRequest = errors.New("invalid enroll request")
+
+// EnrollRequest Agent 自助注册时上报的节点信息。
+type EnrollRequest struct {
+	Token       string
+	Name        string
+	Hostname    string
+	PublicIP    string
+	Location    string
+	CountryCode string
+}
+
+// EnrollmentService 管理节点注册令牌与自助注册流程。
+type EnrollmentService struct {
+	repo     repository.EnrollmentRepository
+	groupSvc *NodeGroupService
+	logger   *logrus.Logger
+}
+
+// NewEnrollmentService 构造函数。
+func NewEnrollmentService(repo repository.EnrollmentRepository, groupSvc *NodeGroupService, logger *logrus.Logger) *EnrollmentService {
+	return &EnrollmentService{repo: repo, groupSvc: groupSvc, logger: logger}
+}
+
+// CreateToken 创建注册令牌，明文令牌只在此处返回一次。
+func (s *EnrollmentService) CreateToken(description string, maxUses int, ttl time.Duration, groupID *uint, createdBy uint) (string, *model.EnrollmentToken, error) {
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+	if ttl <= 0 {
+		ttl = defaultEnrollmentTTL
+	}
+	if ttl > maxEnrollmentTTL {
+		return "", nil, fmt.Errorf("enrollment token lifetime cannot exceed %s", maxEnrollmentTTL)
+	}
+	if groupID != nil {
+		if _, err := s.groupSvc.GetGroup(*groupID); err != nil {
+			return "", nil, fmt.Errorf("node group %d not found", *groupID)
+		}
+	}
+
+	plain, err := utils.GenerateEnrollmentToken()
+	if err != nil {
+		return "", nil, err
+	}
+	record := &model.EnrollmentToken{
+		TokenHash:   utils.HashToken(plain),
+		TokenPrefix: plain[:8],
+		Description: description,
+		MaxUses:     maxUses,
+		GroupID:     groupID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if createdBy > 0 {
+		record.CreatedBy = &createdBy
+	}
+	if err := s.repo.Create(record); err != nil {
+		return "", nil, err
+	}
+	return plain, record, nil
+}
+
+// ListTokens 返回所有注册令牌。
+func (s *EnrollmentService) ListTokens() ([]model.EnrollmentToken, error) {
+	return s.repo.List()
+}
+
+// RevokeToken 吊销注册令牌。
+func (s *EnrollmentService) RevokeToken(id uint) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return err
+	}
+	return s.repo.Revoke(id)
+}
+
+// Enroll 校验注册令牌并创建节点，返回节点及明文永久 API Token。
+func (s *EnrollmentService) Enroll(req EnrollRequest) (*model.Node, string, error) {
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		return nil, "", repository.ErrEnrollmentTokenInvalid
+	}
+	publicIP := strings.TrimSpace(req.PublicIP)
+	if publicIP == "" {
+		return nil, "", fmt.Errorf("%w: public ip is required", ErrInvalidEnrollRequest)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = strings.TrimSpace(req.Hostname)
+	}
+	if name == "" {
+		name = "node-" + publicIP
+	}
+
+	endpoints, err := normalizeEndpoints([]NodeEndpointInput{{Address: publicIP}})
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidEnrollRequest, err)
+	}
+	apiToken, err := utils.GenerateAPIToken()
+	if err != nil {
+		return nil, "", err
+	}
+	node := &model.Node{
+		Name:           name,
+		Hostname:       strings.TrimSpace(req.Hostname),
+		Endpoint:       endpoints[0].Address,
+		Endpoints:      endpoints,
+		Location:       strings.TrimSpace(req.Location),
+		CountryCode:    strings.ToUpper(strings.TrimSpace(req.CountryCode)),
+		APITokenHash:   utils.HashToken(apiToken),
+		APITokenHashed: true,
+		Status:         model.NodeStatusOffline,
+	}
+	if err := s.repo.Enroll(utils.HashToken(token), node); err != nil {
+		return nil, "", err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"node_id":  node.ID,
+		"name":     node.Name,
+		"endpoint": node.Endpoint,
+	}).Info("node enrolled")
+
+	if node.EnrollmentTokenID != nil {
+		if record, err := s.repo.GetByID(*node.EnrollmentTokenID); err == nil && record.GroupID != nil {
+			s.groupSvc.provisionGroup(*record.GroupID)
+		}
+	}
+	return node, apiToken, nil
+}
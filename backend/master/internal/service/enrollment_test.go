@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+func TestEnrollErrors(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := NewEnrollmentService(repos.Enrollment, nil, newTestLogger())
+	token, _, err := svc.CreateToken("test", 1, 0, nil, 0)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if _, _, err := svc.Enroll(EnrollRequest{Token: token, PublicIP: "1.2.3.4"}); err != nil {
+		t.Fatalf("first enroll: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		req  EnrollRequest
+		want error
+	}{
+		{"missing token", EnrollRequest{PublicIP: "1.2.3.4"}, repository.ErrEnrollmentTokenInvalid},
+		{"unknown token", EnrollRequest{Token: "unknown", PublicIP: "1.2.3.4"}, repository.ErrEnrollmentTokenInvalid},
+		{"exhausted token", EnrollRequest{Token: token, PublicIP: "1.2.3.5"}, repository.ErrEnrollmentTokenUnusable},
+		{"missing public ip", EnrollRequest{Token: token}, ErrInvalidEnrollRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := svc.Enroll(tc.req); !errors.Is(err, tc.want) {
+				t.Fatalf("Enroll error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -10,16 +10,27 @@ import (
 
 // Services 聚合所有业务服务。
 type Services struct {
-	Admin        *AdminService
-	User         *UserService
-	Node         *NodeService
-	Instance     *InstanceService
-	Traffic      *TrafficService
-	Subscribe    *SubscribeService
-	Template     *TemplateService
-	Log          *LogService
-	Dashboard    *DashboardService
-	SystemConfig *SystemConfigService
+	Admin         *AdminService
+	User          *UserService
+	Node          *NodeService
+	NodeMetrics   *NodeMetricsService
+	NodeStatus    *NodeStatusService
+	NodeGroup     *NodeGroupService
+	Maintenance   *MaintenanceService
+	Enrollment    *EnrollmentService
+	AgentUpdate   *AgentUpdateService
+	AgentSettings *AgentSettingsService
+	SnellUpgrade  *SnellUpgradeService
+	Instance      *InstanceService
+	InstanceLog   *InstanceLogService
+	Probe         *ProbeService
+	Traffic       *TrafficService
+	Subscribe     *SubscribeService
+	Template      *TemplateService
+	RuleSet       *RuleSetService
+	Log           *LogService
+	Dashboard     *DashboardService
+	SystemConfig  *SystemConfigService
 }
 
 // ServiceDeps 注入依赖。
@@ -35,25 +46,46 @@ func NewServices(deps ServiceDeps) *Services {
 	repos := deps.Repositories
 	adminSvc := NewAdminService(repos.Admin, deps.Logger, deps.Config.JWT.Secret, deps.Config.JWT.ExpireHours)
 	userSvc := NewUserService(repos.User, repos.Admin, deps.Logger, deps.Config.JWT.Secret, deps.Config.JWT.ExpireHours)
-	nodeSvc := NewNodeService(repos.Node, repos.Instance, deps.Logger)
+	nodeSvc := NewNodeService(repos.Node, repos.Instance, repos.NodeStatus, repos.SystemConfig, deps.Config.JWT.Secret, deps.Logger)
+	nodeMetricsSvc := NewNodeMetricsService(repos.NodeMetric, repos.Node, repos.SystemConfig, deps.Logger)
+	nodeStatusSvc := NewNodeStatusService(repos.NodeStatus, repos.Node, repos.SystemConfig, deps.Logger)
 	instanceSvc := NewInstanceService(repos.Instance, repos.User, repos.Node, repos.Admin, deps.Logger)
+	maintenanceSvc := NewMaintenanceService(repos.Node, repos.User, instanceSvc, deps.Logger)
+	nodeGroupSvc := NewNodeGroupService(repos.NodeGroup, repos.Node, repos.User, instanceSvc, deps.Logger)
+	enrollmentSvc := NewEnrollmentService(repos.Enrollment, nodeGroupSvc, deps.Logger)
+	agentUpdateSvc := NewAgentUpdateService(repos.AgentRelease, repos.Node, repos.SystemConfig, deps.Logger)
 	trafficSvc := NewTrafficService(repos.Traffic, repos.User, deps.Logger)
-	subscribeSvc := NewSubscribeService(repos.Subscribe, repos.Template, repos.User, repos.Node, repos.Instance, deps.Logger)
-	templateSvc := NewTemplateService(repos.Template, deps.Logger)
+	subscribeSvc := NewSubscribeService(repos.Subscribe, repos.SubscribeLog, repos.Template, repos.User, repos.Node, repos.Instance, repos.NodeGroup, repos.RuleSet, repos.SystemConfig, deps.Logger)
+	if err := subscribeSvc.WatchChanges(deps.DB); err != nil {
+		deps.Logger.WithError(err).Warn("register subscription cache invalidation failed")
+	}
+	templateSvc := NewTemplateService(repos.Template, repos.RuleSet, deps.Logger)
 	logSvc := NewLogService(repos.Log, deps.Logger)
 	dashboardSvc := NewDashboardService(deps.DB)
 	systemConfigSvc := NewSystemConfigService(repos.SystemConfig, deps.Logger)
+	snellUpgradeSvc := NewSnellUpgradeService(repos.SnellRelease, repos.Node, systemConfigSvc, deps.Logger)
 
 	return &Services{
-		Admin:        adminSvc,
-		User:         userSvc,
-		Node:         nodeSvc,
-		Instance:     instanceSvc,
-		Traffic:      trafficSvc,
-		Subscribe:    subscribeSvc,
-		Template:     templateSvc,
-		Log:          logSvc,
-		Dashboard:    dashboardSvc,
-		SystemConfig: systemConfigSvc,
+		Admin:         adminSvc,
+		User:          userSvc,
+		Node:          nodeSvc,
+		NodeMetrics:   nodeMetricsSvc,
+		NodeStatus:    nodeStatusSvc,
+		NodeGroup:     nodeGroupSvc,
+		Maintenance:   maintenanceSvc,
+		Enrollment:    enrollmentSvc,
+		AgentUpdate:   agentUpdateSvc,
+		AgentSettings: NewAgentSettingsService(repos.Node, repos.SystemConfig, deps.Logger),
+		SnellUpgrade:  snellUpgradeSvc,
+		Instance:      instanceSvc,
+		InstanceLog:   NewInstanceLogService(repos.Instance, repos.Node, deps.Logger),
+		Probe:         NewProbeService(repos.Probe, repos.Instance, repos.SystemConfig, deps.Logger),
+		Traffic:       trafficSvc,
+		Subscribe:     subscribeSvc,
+		Template:      templateSvc,
+		RuleSet:       NewRuleSetService(repos.RuleSet, repos.Template, deps.Logger),
+		Log:           logSvc,
+		Dashboard:     dashboardSvc,
+		SystemConfig:  systemConfigSvc,
 	}
 }
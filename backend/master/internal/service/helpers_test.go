@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+	"github.com/iwoov/snell-master/pkg/config"
+	"github.com/iwoov/snell-master/pkg/database"
+)
+
+// newTestDB 在临时目录中创建应用全部迁移的数据库。
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "master.db")
+	if err := database.RunMigrations(path, filepath.Join("..", "..", "migrations")); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	db, err := database.InitDB(config.DatabaseConfig{Path: path})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db.Logger = gormlogger.Default.LogMode(gormlogger.Silent)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newTestRepos(t *testing.T) (*gorm.DB, *repository.Repositories) {
+	t.Helper()
+	db := newTestDB(t)
+	return db, repository.NewRepositories(db)
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newTestNode 创建一个离线节点。
+func newTestNode(t *testing.T, repos *repository.Repositories, name string) *model.Node {
+	t.Helper()
+	node := &model.Node{Name: name, APITokenHash: "hash-" + name, Endpoint: "1.2.3.4", Status: model.NodeStatusOffline}
+	if err := repos.Node.Create(node); err != nil {
+		t.Fatalf("create node %s: %v", name, err)
+	}
+	return node
+}
+
+// newTestUser 创建一个启用的用户。
+func newTestUser(t *testing.T, repos *repository.Repositories, name string) *model.User {
+	t.Helper()
+	user := &model.User{Username: name, PasswordHash: "x", Email: fmt.Sprintf("%s@example.com", name), Status: 1}
+	if err := repos.User.Create(user); err != nil {
+		t.Fatalf("create user %s: %v", name, err)
+	}
+	return user
+}
+
+// countInstances 返回用户在节点上的实例数。
+func countInstances(t *testing.T, repos *repository.Repositories, userID, nodeID uint) int {
+	t.Helper()
+	instances, err := repos.Instance.GetByUser(userID)
+	if err != nil {
+		t.Fatalf("list instances: %v", err)
+	}
+	count := 0
+	for _, inst := range instances {
+		if inst.NodeID == nodeID {
+			count++
+		}
+	}
+	return count
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -10,6 +11,9 @@ import (
 	"github.com/iwoov/snell-master/pkg/utils"
 )
 
+// defaultInstanceVersion 自动创建实例时使用的 Snell 协议版本。
+const defaultInstanceVersion = 4
+
 // InstanceService 管理 Snell 实例。
 type InstanceService struct {
 	repo      repository.InstanceRepository
@@ -50,10 +54,14 @@ func (s *InstanceService) CreateInstance(userID, nodeID uint, version int, obfs
 		s.logger.Infof("Auto-created user record for admin: %s (ID: %d)", admin.Username, admin.ID)
 	}
 
-	// 检查节点
-	if _, err := s.nodeRepo.GetByID(nodeID); err != nil {
+	// 检查节点，维护中的节点不再分配新实例
+	node, err := s.nodeRepo.GetByID(nodeID)
+	if err != nil {
 		return nil, err
 	}
+	if node.InMaintenance(time.Now()) {
+		return nil, fmt.Errorf("node %s is in maintenance", node.Name)
+	}
 
 	port := utils.AllocatePort(userID)
 	for {
@@ -135,3 +143,172 @@ func (s *InstanceService) RestartInstance(id uint) error {
 	}
 	return nil
 }
+
+// InstanceEventInput 描述 Agent 上报的单条实例事件。
+type InstanceEventInput struct {
+	InstanceID uint
+	Type       string
+	Message    string
+	OccurredAt time.Time
+}
+
+// RecordEvents 保存节点上报的实例事件，回滚失败的实例标记为 error。
+func (s *InstanceService) RecordEvents(nodeID uint, inputs []InstanceEventInput) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+	instances, err := s.repo.GetByNode(nodeID)
+	if err != nil {
+		return err
+	}
+	owned := make(map[uint]struct{}, len(instances))
+	for _, inst := range instances {
+		owned[inst.ID] = struct{}{}
+	}
+
+	events := make([]model.InstanceEvent, 0, len(inputs))
+	failed := make(map[uint]struct{})
+	for _, input := range inputs {
+		if _, ok := owned[input.InstanceID]; !ok {
+			continue
+		}
+		occurredAt := input.OccurredAt
+		if occurredAt.IsZero() {
+			occurredAt = time.Now()
+		}
+		events = append(events, model.InstanceEvent{
+			InstanceID: input.InstanceID,
+			NodeID:     nodeID,
+			EventType:  input.Type,
+			Message:    input.Message,
+			OccurredAt: occurredAt,
+		})
+		if input.Type == model.InstanceEventRollbackFailed {
+			failed[input.InstanceID] = struct{}{}
+		}
+		if s.logger != nil {
+			s.logger.WithFields(logrus.Fields{
+				"instance_id": input.InstanceID,
+				"node_id":     nodeID,
+				"event":       input.Type,
+			}).Warn(input.Message)
+		}
+	}
+	if err := s.repo.CreateEvents(events); err != nil {
+		return fmt.Errorf("save instance events: %w", err)
+	}
+	for id := range failed {
+		if err := s.repo.UpdateStatus(id, "error"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetInstanceEvents 返回实例最近的事件。
+func (s *InstanceService) GetInstanceEvents(instanceID uint, limit int) ([]model.InstanceEvent, error) {
+	if _, err := s.repo.GetByID(instanceID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvents(instanceID, limit)
+}
+
+// ProvisionEntitledInstances 为用户在其可用但尚无实例的节点上创建实例。
+func (s *InstanceService) ProvisionEntitledInstances(userID uint) (int, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	if user.Status == 0 {
+		return 0, nil
+	}
+	nodes, err := s.userRepo.GetUserNodes(userID)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := s.repo.GetByUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	provisioned := make(map[uint]struct{}, len(existing))
+	for _, inst := range existing {
+		provisioned[inst.NodeID] = struct{}{}
+	}
+
+	created := 0
+	now := time.Now()
+	for _, node := range nodes {
+		if _, ok := provisioned[node.ID]; ok || node.InMaintenance(now) {
+			continue
+		}
+		if _, err := s.CreateInstance(userID, node.ID, defaultInstanceVersion, ""); err != nil {
+			return created, fmt.Errorf("provision instance on node %d: %w", node.ID, err)
+		}
+		created++
+	}
+	if created > 0 && s.logger != nil {
+		s.logger.WithFields(logrus.Fields{"user_id": userID, "created": created}).Info("provisioned entitled instances")
+	}
+	return created, nil
+}
+
+// ProvisionNode 为可以使用节点的所有用户补建实例，用于维护期间跳过的节点，失败仅记录日志。
+func (s *InstanceService) ProvisionNode(nodeID uint) int {
+	userIDs, err := s.userRepo.GetNodeUserIDs(nodeID)
+	if err != nil {
+		s.logger.WithError(err).WithField("node_id", nodeID).Warn("list node users failed")
+		return 0
+	}
+	total := 0
+	for _, userID := range userIDs {
+		created, err := s.ProvisionEntitledInstances(userID)
+		if err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{"node_id": nodeID, "user_id": userID}).Warn("provision entitled instances failed")
+		}
+		total += created
+	}
+	return total
+}
+
+// DeprovisionRevokedInstances 删除用户在给定节点上、已不被任何直接/节点组/标签授权覆盖的实例。
+func (s *InstanceService) DeprovisionRevokedInstances(userID uint, nodeIDs []uint) (int, error) {
+	if len(nodeIDs) == 0 {
+		return 0, nil
+	}
+	nodes, err := s.userRepo.GetUserNodes(userID)
+	if err != nil {
+		return 0, err
+	}
+	entitled := make(map[uint]struct{}, len(nodes))
+	for _, node := range nodes {
+		entitled[node.ID] = struct{}{}
+	}
+	revoked := make(map[uint]struct{}, len(nodeIDs))
+	for _, id := range nodeIDs {
+		if _, ok := entitled[id]; !ok {
+			revoked[id] = struct{}{}
+		}
+	}
+	if len(revoked) == 0 {
+		return 0, nil
+	}
+	existing, err := s.repo.GetByUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, inst := range existing {
+		if _, ok := revoked[inst.NodeID]; !ok {
+			continue
+		}
+		if err := s.repo.Delete(inst.ID); err != nil {
+			return deleted, fmt.Errorf("deprovision instance %d: %w", inst.ID, err)
+		}
+		deleted++
+	}
+	if deleted > 0 && s.logger != nil {
+		s.logger.WithFields(logrus.Fields{"user_id": userID, "deleted": deleted}).Info("deprovisioned revoked instances")
+	}
+	return deleted, nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+const (
+	defaultInstanceLogLines = 100
+	maxInstanceLogLines     = 2000
+	// instanceLogTimeout 等待 Agent 领取并回传日志的最长时间。
+	instanceLogTimeout = 20 * time.Second
+)
+
+// ErrAgentNoResponse Agent 未在超时时间内回传日志。
+var ErrAgentNoResponse = errors.New("agent did not respond in time")
+
+// InstanceLogQuery 日志查询条件，Filter 为正则表达式，只返回匹配的行。
+type InstanceLogQuery struct {
+	Lines  int    `json:"lines"`
+	Filter string `json:"filter"`
+}
+
+// LogRequest 下发给 Agent 的日志读取请求。
+type LogRequest struct {
+	ID         string `json:"id"`
+	InstanceID uint   `json:"instance_id"`
+	Lines      int    `json:"lines"`
+	Filter     string `json:"filter,omitempty"`
+}
+
+// LogResponse Agent 回传的日志内容。
+type LogResponse struct {
+	RequestID string   `json:"request_id"`
+	Lines     []string `json:"lines"`
+	Truncated bool     `json:"truncated"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// InstanceLogResult 返回给管理员的日志。
+type InstanceLogResult struct {
+	InstanceID uint     `json:"instance_id"`
+	NodeID     uint     `json:"node_id"`
+	Lines      []string `json:"lines"`
+	Truncated  bool     `json:"truncated"`
+}
+
+type pendingLogRequest struct {
+	nodeID  uint
+	request LogRequest
+	result  chan LogResponse
+}
+
+// InstanceLogService 通过 Agent 长轮询转发实例日志读取请求，请求仅保存在内存中。
+type InstanceLogService struct {
+	instanceRepo repository.InstanceRepository
+	nodeRepo     repository.NodeRepository
+	logger       *logrus.Logger
+
+	mu      sync.Mutex
+	queues  map[uint][]*pendingLogRequest
+	waiters map[uint]chan struct{}
+	pending map[string]*pendingLogRequest
+}
+
+// NewInstanceLogService 构造函数。
+func NewInstanceLogService(instanceRepo repository.InstanceRepository, nodeRepo repository.NodeRepository, logger *logrus.Logger) *InstanceLogService {
+	return &InstanceLogService{
+		instanceRepo: instanceRepo,
+		nodeRepo:     nodeRepo,
+		logger:       logger,
+		queues:       make(map[uint][]*pendingLogRequest),
+		waiters:      make(map[uint]chan struct{}),
+		pending:      make(map[string]*pendingLogRequest),
+	}
+}
+
+// Fetch 请求实例所在节点的 Agent 读取日志并等待结果。
+func (s *InstanceLogService) Fetch(ctx context.Context, instanceID uint, query InstanceLogQuery) (*InstanceLogResult, error) {
+	if query.Lines <= 0 {
+		query.Lines = defaultInstanceLogLines
+	}
+	if query.Lines > maxInstanceLogLines {
+		query.Lines = maxInstanceLogLines
+	}
+	if query.Filter != "" {
+		if _, err := regexp.Compile(query.Filter); err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	instance, err := s.instanceRepo.GetByID(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	node, err := s.nodeRepo.GetByID(instance.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node.Status != model.NodeStatusOnline {
+		return nil, fmt.Errorf("node %s is %s", node.Name, node.Status)
+	}
+
+	id, err := newLogRequestID()
+	if err != nil {
+		return nil, err
+	}
+	pending := &pendingLogRequest{
+		nodeID:  node.ID,
+		request: LogRequest{ID: id, InstanceID: instanceID, Lines: query.Lines, Filter: query.Filter},
+		result:  make(chan LogResponse, 1),
+	}
+	s.enqueue(pending)
+	defer s.forget(pending)
+
+	timer := time.NewTimer(instanceLogTimeout)
+	defer timer.Stop()
+	select {
+	case resp := <-pending.result:
+		if resp.Error != "" {
+			return nil, fmt.Errorf("agent: %s", resp.Error)
+		}
+		return &InstanceLogResult{InstanceID: instanceID, NodeID: node.ID, Lines: resp.Lines, Truncated: resp.Truncated}, nil
+	case <-timer.C:
+		s.logger.WithFields(logrus.Fields{"node_id": node.ID, "instance_id": instanceID}).Warn("instance log request timed out")
+		return nil, ErrAgentNoResponse
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Poll 返回节点待处理的日志请求，没有请求时最多等待 wait。
+func (s *InstanceLogService) Poll(ctx context.Context, nodeID uint, wait time.Duration) []LogRequest {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		requests, notify := s.take(nodeID)
+		if len(requests) > 0 {
+			return requests
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			return []LogRequest{}
+		case <-ctx.Done():
+			return []LogRequest{}
+		}
+	}
+}
+
+// Complete 将 Agent 回传的结果交给等待中的请求，请求不属于该节点或已超时时返回错误。
+func (s *InstanceLogService) Complete(nodeID uint, resp LogResponse) error {
+	s.mu.Lock()
+	pending, ok := s.pending[resp.RequestID]
+	if ok && pending.nodeID == nodeID {
+		delete(s.pending, resp.RequestID)
+	}
+	s.mu.Unlock()
+	if !ok || pending.nodeID != nodeID {
+		return fmt.Errorf("unknown log request %s", resp.RequestID)
+	}
+	pending.result <- resp
+	return nil
+}
+
+func (s *InstanceLogService) enqueue(pending *pendingLogRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[pending.request.ID] = pending
+	s.queues[pending.nodeID] = append(s.queues[pending.nodeID], pending)
+	if notify, ok := s.waiters[pending.nodeID]; ok {
+		close(notify)
+		delete(s.waiters, pending.nodeID)
+	}
+}
+
+// take 取出节点队列中的请求；队列为空时返回新请求到达时会关闭的通道。
+func (s *InstanceLogService) take(nodeID uint) ([]LogRequest, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue := s.queues[nodeID]
+	if len(queue) == 0 {
+		notify, ok := s.waiters[nodeID]
+		if !ok {
+			notify = make(chan struct{})
+			s.waiters[nodeID] = notify
+		}
+		return nil, notify
+	}
+	delete(s.queues, nodeID)
+	requests := make([]LogRequest, 0, len(queue))
+	for _, pending := range queue {
+		requests = append(requests, pending.request)
+	}
+	return requests, nil
+}
+
+// forget 清理已完成或超时的请求，包括仍未被 Agent 领取的。
+func (s *InstanceLogService) forget(pending *pendingLogRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, pending.request.ID)
+	queue := s.queues[pending.nodeID]
+	for i, queued := range queue {
+		if queued == pending {
+			s.queues[pending.nodeID] = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(s.queues[pending.nodeID]) == 0 {
+		delete(s.queues, pending.nodeID)
+	}
+}
+
+func newLogRequestID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate log request id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
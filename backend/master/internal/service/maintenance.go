@@ -0,0 +1,183 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+// MaintenanceRequest 设置维护窗口，StartAt 为空表示立即开始，EndAt 为空表示需手动结束。
+type MaintenanceRequest struct {
+	StartAt       *time.Time `json:"start_at"`
+	EndAt         *time.Time `json:"end_at"`
+	StopInstances bool       `json:"stop_instances"`
+	Message       string     `json:"message"`
+}
+
+// MaintenanceNotice 节点维护通知。
+type MaintenanceNotice struct {
+	NodeID        uint       `json:"node_id"`
+	NodeName      string     `json:"node_name"`
+	Location      string     `json:"location"`
+	CountryCode   string     `json:"country_code"`
+	StartAt       time.Time  `json:"start_at"`
+	EndAt         *time.Time `json:"end_at"`
+	Active        bool       `json:"active"`
+	StopInstances bool       `json:"stop_instances"`
+	Message       string     `json:"message"`
+}
+
+// MaintenanceService 管理节点维护窗口，维护结束后为期间被跳过的授权用户补建实例。
+type MaintenanceService struct {
+	nodeRepo    repository.NodeRepository
+	userRepo    repository.UserRepository
+	instanceSvc *InstanceService
+	logger      *logrus.Logger
+}
+
+// NewMaintenanceService 构造函数。
+func NewMaintenanceService(nodeRepo repository.NodeRepository, userRepo repository.UserRepository, instanceSvc *InstanceService, logger *logrus.Logger) *MaintenanceService {
+	return &MaintenanceService{nodeRepo: nodeRepo, userRepo: userRepo, instanceSvc: instanceSvc, logger: logger}
+}
+
+// Schedule 为节点设置维护窗口，覆盖已有窗口。
+func (s *MaintenanceService) Schedule(nodeID uint, req MaintenanceRequest) (*MaintenanceNotice, error) {
+	node, err := s.nodeRepo.GetByID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	start := now
+	if req.StartAt != nil {
+		start = *req.StartAt
+	}
+	if req.EndAt != nil && !req.EndAt.After(start) {
+		return nil, fmt.Errorf("end_at must be after start_at")
+	}
+	if req.EndAt != nil && !req.EndAt.After(now) {
+		return nil, fmt.Errorf("end_at must be in the future")
+	}
+	message := strings.TrimSpace(req.Message)
+	if err := s.nodeRepo.SetMaintenance(nodeID, &start, req.EndAt, req.StopInstances, message); err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(logrus.Fields{
+		"node_id":        nodeID,
+		"start_at":       start,
+		"end_at":         req.EndAt,
+		"stop_instances": req.StopInstances,
+	}).Info("node maintenance scheduled")
+
+	node.MaintenanceStartAt = &start
+	node.MaintenanceEndAt = req.EndAt
+	node.MaintenanceStopInstances = req.StopInstances
+	node.MaintenanceMessage = message
+	// 新窗口尚未开始时，原窗口期间跳过的实例现在即可补建
+	if !node.InMaintenance(now) {
+		s.provision(nodeID)
+	}
+	notice, _ := noticeFor(node, now)
+	return notice, nil
+}
+
+// End 立即结束或取消节点的维护窗口，并为授权用户补建实例。
+func (s *MaintenanceService) End(nodeID uint) error {
+	if _, err := s.nodeRepo.GetByID(nodeID); err != nil {
+		return err
+	}
+	if err := s.nodeRepo.SetMaintenance(nodeID, nil, nil, false, ""); err != nil {
+		return err
+	}
+	s.logger.Infof("node %d maintenance ended", nodeID)
+	s.provision(nodeID)
+	return nil
+}
+
+// List 返回所有进行中或计划中的维护窗口。
+func (s *MaintenanceService) List() ([]MaintenanceNotice, error) {
+	nodes, err := s.nodeRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	return collectNotices(nodes, time.Now()), nil
+}
+
+// UserNotices 返回用户可用节点上进行中或计划中的维护通知。
+func (s *MaintenanceService) UserNotices(userID uint) ([]MaintenanceNotice, error) {
+	nodes, err := s.userRepo.GetUserNodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectNotices(nodes, time.Now()), nil
+}
+
+// ClearExpired 清除已结束的维护窗口，并为这些节点的授权用户补建实例。
+func (s *MaintenanceService) ClearExpired() error {
+	cleared, err := s.nodeRepo.ClearExpiredMaintenance(time.Now())
+	if err != nil {
+		return err
+	}
+	if len(cleared) > 0 {
+		s.logger.Infof("cleared %d expired node maintenance windows", len(cleared))
+	}
+	for _, nodeID := range cleared {
+		s.provision(nodeID)
+	}
+	return nil
+}
+
+// provision 补建维护期间跳过的实例，并记录补建数量。
+func (s *MaintenanceService) provision(nodeID uint) {
+	if s.instanceSvc == nil {
+		return
+	}
+	if created := s.instanceSvc.ProvisionNode(nodeID); created > 0 {
+		s.logger.WithFields(logrus.Fields{"node_id": nodeID, "created": created}).Info("provisioned instances skipped during maintenance")
+	}
+}
+
+// excludeMaintenance 过滤掉处于维护窗口内的节点。
+func excludeMaintenance(nodes []model.Node, now time.Time) []model.Node {
+	result := make([]model.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if !node.InMaintenance(now) {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+func collectNotices(nodes []model.Node, now time.Time) []MaintenanceNotice {
+	notices := make([]MaintenanceNotice, 0)
+	for i := range nodes {
+		if notice, ok := noticeFor(&nodes[i], now); ok {
+			notices = append(notices, *notice)
+		}
+	}
+	return notices
+}
+
+func noticeFor(node *model.Node, now time.Time) (*MaintenanceNotice, bool) {
+	if node.MaintenanceStartAt == nil {
+		return nil, false
+	}
+	if node.MaintenanceEndAt != nil && !now.Before(*node.MaintenanceEndAt) {
+		return nil, false
+	}
+	return &MaintenanceNotice{
+		NodeID:        node.ID,
+		NodeName:      node.Name,
+		Location:      node.Location,
+		CountryCode:   node.CountryCode,
+		StartAt:       *node.MaintenanceStartAt,
+		EndAt:         node.MaintenanceEndAt,
+		Active:        node.InMaintenance(now),
+		StopInstances: node.MaintenanceStopInstances,
+		Message:       node.MaintenanceMessage,
+	}, true
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+func TestNoticeFor(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+	cases := []struct {
+		name       string
+		start, end *time.Time
+		wantNotice bool
+		wantActive bool
+	}{
+		{"no window", nil, nil, false, false},
+		{"scheduled", at(time.Hour), at(2 * time.Hour), true, false},
+		{"starts now", at(0), at(time.Hour), true, true},
+		{"active without end", at(-time.Hour), nil, true, true},
+		{"active with end", at(-time.Hour), at(time.Hour), true, true},
+		{"ends now", at(-time.Hour), at(0), false, false},
+		{"ended", at(-2 * time.Hour), at(-time.Hour), false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &model.Node{MaintenanceStartAt: tc.start, MaintenanceEndAt: tc.end}
+			notice, ok := noticeFor(node, now)
+			if ok != tc.wantNotice {
+				t.Fatalf("noticeFor ok = %v, want %v", ok, tc.wantNotice)
+			}
+			if node.InMaintenance(now) != tc.wantActive {
+				t.Fatalf("InMaintenance = %v, want %v", node.InMaintenance(now), tc.wantActive)
+			}
+			if ok && notice.Active != tc.wantActive {
+				t.Fatalf("notice active = %v, want %v", notice.Active, tc.wantActive)
+			}
+			if kept := len(excludeMaintenance([]model.Node{*node}, now)) == 1; kept == tc.wantActive {
+				t.Fatalf("excludeMaintenance kept node = %v, want %v", kept, !tc.wantActive)
+			}
+		})
+	}
+}
+
+// maintenanceFixture 一个节点，直接分配的用户与按标签授权的用户各一个，节点处于维护中。
+func maintenanceFixture(t *testing.T) (*repository.Repositories, *MaintenanceService, *model.Node, []uint) {
+	t.Helper()
+	_, repos := newTestRepos(t)
+	logger := newTestLogger()
+	instanceSvc := NewInstanceService(repos.Instance, repos.User, repos.Node, repos.Admin, logger)
+	svc := NewMaintenanceService(repos.Node, repos.User, instanceSvc, logger)
+
+	node := newTestNode(t, repos, "node-1")
+	direct := newTestUser(t, repos, "direct")
+	tagged := newTestUser(t, repos, "tagged")
+	if _, err := svc.Schedule(node.ID, MaintenanceRequest{}); err != nil {
+		t.Fatalf("schedule maintenance: %v", err)
+	}
+
+	if err := repos.User.AssignNodes(direct.ID, []uint{node.ID}); err != nil {
+		t.Fatalf("assign node: %v", err)
+	}
+	group := &model.NodeGroup{Name: "g1", Tags: []string{"premium"}}
+	if err := repos.NodeGroup.Create(group); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	if err := repos.NodeGroup.SetNodes(group.ID, []uint{node.ID}); err != nil {
+		t.Fatalf("set group nodes: %v", err)
+	}
+	if err := repos.NodeGroup.SetEntitlement(tagged.ID, nil, []string{"premium"}); err != nil {
+		t.Fatalf("set entitlement: %v", err)
+	}
+
+	userIDs := []uint{direct.ID, tagged.ID}
+	for _, userID := range userIDs {
+		created, err := instanceSvc.ProvisionEntitledInstances(userID)
+		if err != nil || created != 0 {
+			t.Fatalf("provision during maintenance = %d, %v; want 0 skipped", created, err)
+		}
+	}
+	return repos, svc, node, userIDs
+}
+
+func TestMaintenanceEndProvisionsSkippedInstances(t *testing.T) {
+	repos, svc, node, userIDs := maintenanceFixture(t)
+	if err := svc.End(node.ID); err != nil {
+		t.Fatalf("end maintenance: %v", err)
+	}
+	for _, userID := range userIDs {
+		if got := countInstances(t, repos, userID, node.ID); got != 1 {
+			t.Fatalf("user %d instances after maintenance = %d, want 1", userID, got)
+		}
+	}
+
+	// 再次结束不会重复创建
+	if err := svc.End(node.ID); err != nil {
+		t.Fatalf("end maintenance again: %v", err)
+	}
+	if got := countInstances(t, repos, userIDs[0], node.ID); got != 1 {
+		t.Fatalf("instances after second end = %d, want 1", got)
+	}
+}
+
+func TestMaintenanceClearExpiredProvisionsSkippedInstances(t *testing.T) {
+	repos, svc, node, userIDs := maintenanceFixture(t)
+	start, end := time.Now().Add(-2*time.Hour), time.Now().Add(-time.Minute)
+	if err := repos.Node.SetMaintenance(node.ID, &start, &end, true, "expired"); err != nil {
+		t.Fatalf("set expired window: %v", err)
+	}
+	if err := svc.ClearExpired(); err != nil {
+		t.Fatalf("clear expired: %v", err)
+	}
+	cleared, err := repos.Node.GetByID(node.ID)
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if cleared.MaintenanceStartAt != nil || cleared.MaintenanceStopInstances {
+		t.Fatalf("maintenance window not cleared: %+v", cleared)
+	}
+	for _, userID := range userIDs {
+		if got := countInstances(t, repos, userID, node.ID); got != 1 {
+			t.Fatalf("user %d instances after expiry = %d, want 1", userID, got)
+		}
+	}
+}
+
+func TestMaintenanceClearExpiredKeepsActiveWindows(t *testing.T) {
+	repos, svc, node, userIDs := maintenanceFixture(t)
+	if err := svc.ClearExpired(); err != nil {
+		t.Fatalf("clear expired: %v", err)
+	}
+	current, err := repos.Node.GetByID(node.ID)
+	if err != nil {
+		t.Fatalf("get node: %v", err)
+	}
+	if !current.InMaintenance(time.Now()) {
+		t.Fatal("active maintenance window without end was cleared")
+	}
+	if got := countInstances(t, repos, userIDs[0], node.ID); got != 0 {
+		t.Fatalf("instances during maintenance = %d, want 0", got)
+	}
+}
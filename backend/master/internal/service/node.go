@@ -15,32 +15,41 @@ import (
 type NodeService struct {
 	repo         repository.NodeRepository
 	instanceRepo repository.InstanceRepository
+	statusRepo   repository.NodeStatusRepository
+	configRepo   repository.SystemConfigRepository
+	sealKey      string
 	logger       *logrus.Logger
 }
 
-// NewNodeService 构造函数。
-func NewNodeService(repo repository.NodeRepository, instanceRepo repository.InstanceRepository, logger *logrus.Logger) *NodeService {
-	return &NodeService{repo: repo, instanceRepo: instanceRepo, logger: logger}
+// NewNodeService 构造函数，sealKey 用于加密轮换中待下发的新令牌。
+func NewNodeService(repo repository.NodeRepository, instanceRepo repository.InstanceRepository, statusRepo repository.NodeStatusRepository, configRepo repository.SystemConfigRepository, sealKey string, logger *logrus.Logger) *NodeService {
+	return &NodeService{repo: repo, instanceRepo: instanceRepo, statusRepo: statusRepo, configRepo: configRepo, sealKey: sealKey, logger: logger}
 }
 
-// RegisterNode 创建新节点并返回 API Token。
-func (s *NodeService) RegisterNode(name, endpoint, location, countryCode string) (*model.Node, error) {
+// RegisterNode 创建新节点并返回明文 API Token，数据库中只保存摘要。
+func (s *NodeService) RegisterNode(name, endpoint, location, countryCode string) (*model.Node, string, error) {
+	endpoints, err := normalizeEndpoints([]NodeEndpointInput{{Address: endpoint}})
+	if err != nil {
+		return nil, "", err
+	}
 	token, err := utils.GenerateAPIToken()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	node := &model.Node{
-		Name:        name,
-		Endpoint:    endpoint,
-		Location:    location,
-		CountryCode: countryCode,
-		APIToken:    token,
-		Status:      "offline",
+		Name:           name,
+		Endpoint:       endpoints[0].Address,
+		Endpoints:      endpoints,
+		Location:       location,
+		CountryCode:    countryCode,
+		APITokenHash:   utils.HashToken(token),
+		APITokenHashed: true,
+		Status:         model.NodeStatusOffline,
 	}
 	if err := s.repo.Create(node); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return node, nil
+	return node, token, nil
 }
 
 // GetNodeList 返回所有节点。
@@ -62,8 +71,22 @@ func (s *NodeService) UpdateNode(id uint, updates map[string]interface{}) (*mode
 	if name, ok := updates["name"].(string); ok && name != "" {
 		node.Name = name
 	}
+	// endpoint 字段只适用于单地址节点，多地址节点通过 SetEndpoints 维护
+	var replaced []model.NodeEndpoint
 	if endpoint, ok := updates["endpoint"].(string); ok && endpoint != "" {
-		node.Endpoint = endpoint
+		if len(node.Endpoints) > 1 {
+			return nil, fmt.Errorf("node has multiple endpoints, update them via the endpoints API")
+		}
+		input := NodeEndpointInput{Address: endpoint}
+		if len(node.Endpoints) == 1 {
+			input.Label = node.Endpoints[0].Label
+			input.Priority = node.Endpoints[0].Priority
+		}
+		replaced, err = normalizeEndpoints([]NodeEndpointInput{input})
+		if err != nil {
+			return nil, err
+		}
+		node.Endpoint = replaced[0].Address
 	}
 	if location, ok := updates["location"].(string); ok {
 		node.Location = location
@@ -71,12 +94,23 @@ func (s *NodeService) UpdateNode(id uint, updates map[string]interface{}) (*mode
 	if code, ok := updates["country_code"].(string); ok {
 		node.CountryCode = code
 	}
+	if hide, ok := updates["hide_from_auto_groups"].(bool); ok {
+		node.HideFromAutoGroups = hide
+	}
+	previousStatus := node.Status
 	if status, ok := updates["status"].(string); ok && status != "" {
 		node.Status = status
 	}
 	if err := s.repo.Update(node); err != nil {
 		return nil, err
 	}
+	if replaced != nil {
+		if err := s.repo.ReplaceEndpoints(node.ID, replaced); err != nil {
+			return nil, err
+		}
+		node.Endpoints = replaced
+	}
+	s.recordStatusChange(node.ID, previousStatus, node.Status, model.NodeStatusCauseManual)
 	return node, nil
 }
 
@@ -92,48 +126,112 @@ func (s *NodeService) DeleteNode(id uint) error {
 	return s.repo.Delete(id)
 }
 
+// HeartbeatReport Agent 上报的心跳内容，指针字段为空表示 Agent 未采集该指标。
+type HeartbeatReport struct {
+	Status         string
+	Version        string
+	CPUUsage       *float64
+	MemoryUsage    *float64
+	DiskUsage      *float64
+	NetworkRxRate  *uint64
+	NetworkTxRate  *uint64
+	Load1          *float64
+	Load5          *float64
+	Load15         *float64
+	UptimeSeconds  uint64
+	KernelVersion  string
+	SnellVersion   string
+	PortRangeStart int
+	PortRangeEnd   int
+	InstanceCount  int
+
+	HeartbeatInterval int // 秒，0 表示 Agent 未上报
+}
+
 // UpdateHeartbeat 更新节点心跳和统计。
-func (s *NodeService) UpdateHeartbeat(apiToken string, cpu, mem float64, instanceCount int, status, version string) error {
-	node, err := s.repo.GetByToken(apiToken)
+func (s *NodeService) UpdateHeartbeat(nodeID uint, report HeartbeatReport) error {
+	node, err := s.repo.GetByID(nodeID)
 	if err != nil {
 		return err
 	}
+	status := report.Status
 	if status == "" {
-		status = "online"
+		status = model.NodeStatusOnline
 	}
-	if err := s.repo.UpdateHeartbeat(node.ID, cpu, mem, instanceCount, status); err != nil {
-		return err
+	updates := map[string]interface{}{
+		"instance_count": report.InstanceCount,
+		"status":         status,
 	}
 	record := &model.NodeHeartbeat{
-		NodeID:        node.ID,
+		NodeID:        nodeID,
 		Status:        status,
-		CPUUsage:      cpu,
-		MemoryUsage:   mem,
-		InstanceCount: instanceCount,
-		Version:       version,
+		CPUUsage:      report.CPUUsage,
+		MemoryUsage:   report.MemoryUsage,
+		DiskUsage:     report.DiskUsage,
+		InstanceCount: report.InstanceCount,
+		Version:       report.Version,
 		CreatedAt:     time.Now(),
 	}
-	return s.repo.SaveHeartbeat(record)
-}
+	if report.CPUUsage != nil {
+		updates["cpu_usage"] = *report.CPUUsage
+	}
+	if report.MemoryUsage != nil {
+		updates["memory_usage"] = *report.MemoryUsage
+	}
+	if report.DiskUsage != nil {
+		updates["disk_usage"] = *report.DiskUsage
+	}
+	if report.NetworkRxRate != nil && report.NetworkTxRate != nil {
+		updates["network_rx_rate"] = *report.NetworkRxRate
+		updates["network_tx_rate"] = *report.NetworkTxRate
+		updates["bandwidth_usage"] = float64(*report.NetworkRxRate + *report.NetworkTxRate)
+		record.NetworkRxRate = *report.NetworkRxRate
+		record.NetworkTxRate = *report.NetworkTxRate
+	}
+	if report.Load1 != nil {
+		updates["load1"] = *report.Load1
+		record.Load1 = *report.Load1
+	}
+	if report.Load5 != nil {
+		updates["load5"] = *report.Load5
+	}
+	if report.Load15 != nil {
+		updates["load15"] = *report.Load15
+	}
+	if report.UptimeSeconds > 0 {
+		updates["uptime_seconds"] = report.UptimeSeconds
+	}
+	if report.HeartbeatInterval > 0 {
+		updates["heartbeat_interval"] = report.HeartbeatInterval
+	}
+	if report.KernelVersion != "" {
+		updates["kernel_version"] = report.KernelVersion
+	}
+	if report.SnellVersion != "" {
+		updates["snell_version"] = report.SnellVersion
+	}
+	if report.Version != "" {
+		updates["agent_version"] = report.Version
+	}
+	if report.PortRangeStart > 0 && report.PortRangeEnd > report.PortRangeStart {
+		updates["port_range_start"] = report.PortRangeStart
+		updates["port_range_end"] = report.PortRangeEnd
+	}
 
-// GetNodeByToken 根据 API Token 获取节点。
-func (s *NodeService) GetNodeByToken(token string) (*model.Node, error) {
-	return s.repo.GetByToken(token)
+	if err := s.repo.UpdateHeartbeat(nodeID, updates); err != nil {
+		return err
+	}
+	s.recordStatusChange(nodeID, node.Status, status, model.NodeStatusCauseHeartbeat)
+	return s.repo.SaveHeartbeat(record)
 }
 
-// RegenerateToken 重新生成 API Token。
-func (s *NodeService) RegenerateToken(id uint) (string, error) {
-	node, err := s.repo.GetByID(id)
-	if err != nil {
-		return "", err
+// recordStatusChange 在状态发生变化时写入状态事件，失败只记录日志。
+func (s *NodeService) recordStatusChange(nodeID uint, from, to, cause string) {
+	if from == to || s.statusRepo == nil {
+		return
 	}
-	token, err := utils.GenerateAPIToken()
-	if err != nil {
-		return "", err
-	}
-	node.APIToken = token
-	if err := s.repo.Update(node); err != nil {
-		return "", err
+	event := &model.NodeStatusEvent{NodeID: nodeID, FromStatus: from, ToStatus: to, Cause: cause, CreatedAt: time.Now()}
+	if err := s.statusRepo.CreateEvent(event); err != nil && s.logger != nil {
+		s.logger.WithError(err).WithField("node_id", nodeID).Warn("record node status event failed")
 	}
-	return token, nil
 }
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+const (
+	maxNodeEndpoints   = 16
+	maxEndpointAddress = 255
+	maxEndpointLabel   = 64
+	// snell-server 监听 ::0 时同时接受 IPv4 与 IPv6 连接
+	listenDualStack = "::0"
+	listenIPv4Only  = "0.0.0.0"
+)
+
+var hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// NodeEndpointInput 管理员提交的节点地址，Type 为空时按地址格式推断。
+type NodeEndpointInput struct {
+	Type     string `json:"type"`
+	Address  string `json:"address"`
+	Label    string `json:"label"`
+	Priority int    `json:"priority"`
+}
+
+// GetEndpoints 返回节点地址列表。
+func (s *NodeService) GetEndpoints(nodeID uint) ([]model.NodeEndpoint, error) {
+	if _, err := s.repo.GetByID(nodeID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEndpoints(nodeID)
+}
+
+// SetEndpoints 校验并整体替换节点地址，优先级最高的地址同步到 Endpoint 字段。
+func (s *NodeService) SetEndpoints(nodeID uint, inputs []NodeEndpointInput) ([]model.NodeEndpoint, error) {
+	if _, err := s.repo.GetByID(nodeID); err != nil {
+		return nil, err
+	}
+	endpoints, err := normalizeEndpoints(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceEndpoints(nodeID, endpoints); err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(logrus.Fields{
+		"node_id":   nodeID,
+		"endpoints": len(endpoints),
+		"preferred": endpoints[0].Address,
+	}).Info("node endpoints updated")
+	return s.repo.ListEndpoints(nodeID)
+}
+
+// ListenAddress 返回节点实例的监听地址：配置了 IPv6 地址时双栈监听，否则只监听 IPv4。
+func (s *NodeService) ListenAddress(nodeID uint) (string, error) {
+	endpoints, err := s.repo.ListEndpoints(nodeID)
+	if err != nil {
+		return "", err
+	}
+	node := model.Node{Endpoints: endpoints}
+	if node.HasIPv6() {
+		return listenDualStack, nil
+	}
+	return listenIPv4Only, nil
+}
+
+// normalizeEndpoints 校验地址并按优先级排序，拒绝重复地址。
+func normalizeEndpoints(inputs []NodeEndpointInput) ([]model.NodeEndpoint, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("at least one endpoint is required")
+	}
+	if len(inputs) > maxNodeEndpoints {
+		return nil, fmt.Errorf("at most %d endpoints are allowed", maxNodeEndpoints)
+	}
+	seen := make(map[string]bool, len(inputs))
+	endpoints := make([]model.NodeEndpoint, 0, len(inputs))
+	for _, input := range inputs {
+		endpoint, err := normalizeEndpoint(input)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(endpoint.Address)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate endpoint %s", endpoint.Address)
+		}
+		seen[key] = true
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return endpoints[i].Priority < endpoints[j].Priority
+	})
+	return endpoints, nil
+}
+
+func normalizeEndpoint(input NodeEndpointInput) (model.NodeEndpoint, error) {
+	address := strings.TrimSpace(input.Address)
+	// 允许带方括号的 IPv6 写法
+	address = strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+	if address == "" {
+		return model.NodeEndpoint{}, fmt.Errorf("endpoint address is required")
+	}
+	if len(address) > maxEndpointAddress {
+		return model.NodeEndpoint{}, fmt.Errorf("endpoint address %s is too long", address)
+	}
+	label := strings.TrimSpace(input.Label)
+	if len(label) > maxEndpointLabel {
+		return model.NodeEndpoint{}, fmt.Errorf("endpoint label must be at most %d characters", maxEndpointLabel)
+	}
+
+	detected := model.DetectEndpointType(address)
+	endpointType := strings.ToLower(strings.TrimSpace(input.Type))
+	if endpointType == "" {
+		endpointType = detected
+	}
+	switch endpointType {
+	case model.EndpointTypeIPv4, model.EndpointTypeIPv6:
+		if detected != endpointType {
+			return model.NodeEndpoint{}, fmt.Errorf("%s is not a valid %s address", address, endpointType)
+		}
+		address = net.ParseIP(address).String()
+	case model.EndpointTypeDomain:
+		address = strings.ToLower(strings.TrimSuffix(address, "."))
+		if detected != model.EndpointTypeDomain || !hostnamePattern.MatchString(address) {
+			return model.NodeEndpoint{}, fmt.Errorf("%s is not a valid domain name", address)
+		}
+	default:
+		return model.NodeEndpoint{}, fmt.Errorf("unsupported endpoint type %s", input.Type)
+	}
+	return model.NodeEndpoint{Type: endpointType, Address: address, Label: label, Priority: input.Priority}, nil
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+// NodeGroupService 管理节点组及用户的组/标签授权。
+type NodeGroupService struct {
+	repo        repository.NodeGroupRepository
+	nodeRepo    repository.NodeRepository
+	userRepo    repository.UserRepository
+	instanceSvc *InstanceService
+	logger      *logrus.Logger
+}
+
+// NewNodeGroupService 构造函数。
+func NewNodeGroupService(repo repository.NodeGroupRepository, nodeRepo repository.NodeRepository, userRepo repository.UserRepository, instanceSvc *InstanceService, logger *logrus.Logger) *NodeGroupService {
+	return &NodeGroupService{repo: repo, nodeRepo: nodeRepo, userRepo: userRepo, instanceSvc: instanceSvc, logger: logger}
+}
+
+// CreateGroup 创建节点组，带有节点时为已授权的用户补建实例。
+func (s *NodeGroupService) CreateGroup(name, description string, tags []string, nodeIDs []uint) (*model.NodeGroup, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if err := s.checkNodes(nodeIDs); err != nil {
+		return nil, err
+	}
+	group := &model.NodeGroup{Name: name, Description: description, Tags: normalizeTags(tags)}
+	if err := s.repo.Create(group); err != nil {
+		return nil, err
+	}
+	if len(nodeIDs) > 0 {
+		if err := s.repo.SetNodes(group.ID, nodeIDs); err != nil {
+			return nil, err
+		}
+		// 按标签授权的用户已存在时，新组的节点同样需要补建实例
+		s.provisionGroup(group.ID)
+	}
+	return s.repo.GetByID(group.ID)
+}
+
+// ListGroups 返回所有节点组。
+func (s *NodeGroupService) ListGroups() ([]model.NodeGroup, error) {
+	return s.repo.List()
+}
+
+// GetGroup 返回节点组详情。
+func (s *NodeGroupService) GetGroup(id uint) (*model.NodeGroup, error) {
+	return s.repo.GetByID(id)
+}
+
+// UpdateGroup 修改名称、描述或标签；标签变化后为新授权用户补建实例，并回收失去授权用户的实例。
+func (s *NodeGroupService) UpdateGroup(id uint, updates map[string]interface{}) (*model.NodeGroup, error) {
+	group, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := updates["name"].(string); ok && strings.TrimSpace(name) != "" {
+		group.Name = strings.TrimSpace(name)
+	}
+	if desc, ok := updates["description"].(string); ok {
+		group.Description = desc
+	}
+	tagsChanged := false
+	var previousUsers []uint
+	if raw, ok := updates["tags"].([]interface{}); ok {
+		tags := make([]string, 0, len(raw))
+		for _, item := range raw {
+			if tag, ok := item.(string); ok {
+				tags = append(tags, tag)
+			}
+		}
+		group.Tags = normalizeTags(tags)
+		tagsChanged = true
+		previousUsers = s.entitledUsers(id)
+	}
+	if err := s.repo.Update(group); err != nil {
+		return nil, err
+	}
+	if tagsChanged {
+		s.provisionGroup(group.ID)
+		s.deprovision(previousUsers, group.NodeIDs)
+	}
+	return s.repo.GetByID(id)
+}
+
+// DeleteGroup 删除节点组及其授权关系，并回收不再被其他授权覆盖的实例。
+func (s *NodeGroupService) DeleteGroup(id uint) error {
+	group, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	userIDs := s.entitledUsers(id)
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+	s.deprovision(userIDs, group.NodeIDs)
+	return nil
+}
+
+// SetGroupNodes 替换节点组成员，为所有被授权用户补建实例并回收移出节点上的实例。
+func (s *NodeGroupService) SetGroupNodes(id uint, nodeIDs []uint) (*model.NodeGroup, error) {
+	group, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNodes(nodeIDs); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetNodes(id, nodeIDs); err != nil {
+		return nil, err
+	}
+	s.provisionGroup(id)
+	s.deprovision(s.entitledUsers(id), removedNodes(group.NodeIDs, nodeIDs))
+	return s.repo.GetByID(id)
+}
+
+// AddNodeToGroup 将节点加入节点组。
+func (s *NodeGroupService) AddNodeToGroup(groupID, nodeID uint) error {
+	if _, err := s.repo.GetByID(groupID); err != nil {
+		return err
+	}
+	if err := s.checkNodes([]uint{nodeID}); err != nil {
+		return err
+	}
+	if err := s.repo.AddNodes(groupID, []uint{nodeID}); err != nil {
+		return err
+	}
+	s.provisionGroup(groupID)
+	return nil
+}
+
+// RemoveNodeFromGroup 将节点移出节点组，并回收不再被其他授权覆盖的实例。
+func (s *NodeGroupService) RemoveNodeFromGroup(groupID, nodeID uint) error {
+	if _, err := s.repo.GetByID(groupID); err != nil {
+		return err
+	}
+	if err := s.repo.RemoveNode(groupID, nodeID); err != nil {
+		return err
+	}
+	s.deprovision(s.entitledUsers(groupID), []uint{nodeID})
+	return nil
+}
+
+// GetUserEntitlement 返回用户的节点、节点组与标签授权。
+func (s *NodeGroupService) GetUserEntitlement(userID uint) (*model.UserEntitlement, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetEntitlement(userID)
+}
+
+// SetUserEntitlement 替换用户的节点组与标签授权，补建实例并回收失去授权节点上的实例。
+func (s *NodeGroupService) SetUserEntitlement(userID uint, groupIDs []uint, tags []string) (*model.UserEntitlement, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	for _, id := range groupIDs {
+		if _, err := s.repo.GetByID(id); err != nil {
+			return nil, fmt.Errorf("node group %d not found", id)
+		}
+	}
+	previous, err := s.userRepo.GetUserNodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetEntitlement(userID, groupIDs, normalizeTags(tags)); err != nil {
+		return nil, err
+	}
+	if _, err := s.instanceSvc.ProvisionEntitledInstances(userID); err != nil {
+		s.logger.WithError(err).WithField("user_id", userID).Warn("provision entitled instances failed")
+	}
+	nodeIDs := make([]uint, 0, len(previous))
+	for _, node := range previous {
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+	s.deprovision([]uint{userID}, nodeIDs)
+	return s.repo.GetEntitlement(userID)
+}
+
+// provisionGroup 为节点组的所有授权用户补建实例，失败仅记录日志。
+func (s *NodeGroupService) provisionGroup(groupID uint) {
+	for _, userID := range s.entitledUsers(groupID) {
+		if _, err := s.instanceSvc.ProvisionEntitledInstances(userID); err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{"group_id": groupID, "user_id": userID}).Warn("provision entitled instances failed")
+		}
+	}
+}
+
+// deprovision 删除用户在这些节点上已失去授权的实例，失败仅记录日志。
+func (s *NodeGroupService) deprovision(userIDs, nodeIDs []uint) {
+	if len(nodeIDs) == 0 {
+		return
+	}
+	for _, userID := range userIDs {
+		if _, err := s.instanceSvc.DeprovisionRevokedInstances(userID, nodeIDs); err != nil {
+			s.logger.WithError(err).WithField("user_id", userID).Warn("deprovision revoked instances failed")
+		}
+	}
+}
+
+// entitledUsers 返回通过节点组或其标签获得授权的用户，失败仅记录日志。
+func (s *NodeGroupService) entitledUsers(groupID uint) []uint {
+	userIDs, err := s.repo.GetEntitledUserIDs(groupID)
+	if err != nil {
+		s.logger.WithError(err).WithField("group_id", groupID).Warn("list entitled users failed")
+		return nil
+	}
+	return userIDs
+}
+
+// removedNodes 返回 before 中不在 after 里的节点。
+func removedNodes(before, after []uint) []uint {
+	kept := make(map[uint]struct{}, len(after))
+	for _, id := range after {
+		kept[id] = struct{}{}
+	}
+	var removed []uint
+	for _, id := range before {
+		if _, ok := kept[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	return removed
+}
+
+func (s *NodeGroupService) checkNodes(nodeIDs []uint) error {
+	for _, id := range nodeIDs {
+		if _, err := s.nodeRepo.GetByID(id); err != nil {
+			return fmt.Errorf("node %d not found", id)
+		}
+	}
+	return nil
+}
+
+// normalizeTags 去除空白与重复标签。
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+func newTestNodeGroupService(repos *repository.Repositories) *NodeGroupService {
+	logger := newTestLogger()
+	instanceSvc := NewInstanceService(repos.Instance, repos.User, repos.Node, repos.Admin, logger)
+	return NewNodeGroupService(repos.NodeGroup, repos.Node, repos.User, instanceSvc, logger)
+}
+
+// userNodeIDs 返回 GetUserNodes 的节点 ID。
+func userNodeIDs(t *testing.T, repos *repository.Repositories, userID uint) []uint {
+	t.Helper()
+	nodes, err := repos.User.GetUserNodes(userID)
+	if err != nil {
+		t.Fatalf("GetUserNodes: %v", err)
+	}
+	ids := make([]uint, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+func TestGetUserNodesUnion(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := newTestNodeGroupService(repos)
+	direct := newTestNode(t, repos, "direct")
+	grouped := newTestNode(t, repos, "grouped")
+	tagged := newTestNode(t, repos, "tagged")
+	shared := newTestNode(t, repos, "shared")
+	newTestNode(t, repos, "unrelated")
+	user := newTestUser(t, repos, "alice")
+	other := newTestUser(t, repos, "bob")
+
+	if err := repos.User.AssignNodes(user.ID, []uint{direct.ID, shared.ID}); err != nil {
+		t.Fatalf("AssignNodes: %v", err)
+	}
+	byGroup, err := svc.CreateGroup("by-group", "", nil, []uint{grouped.ID, shared.ID})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if _, err := svc.CreateGroup("by-tag", "", []string{"asia"}, []uint{tagged.ID, shared.ID}); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if _, err := svc.SetUserEntitlement(user.ID, []uint{byGroup.ID}, []string{"asia"}); err != nil {
+		t.Fatalf("SetUserEntitlement: %v", err)
+	}
+
+	// 重叠的节点只出现一次，未授权的节点不出现
+	want := []uint{direct.ID, grouped.ID, tagged.ID, shared.ID}
+	if got := userNodeIDs(t, repos, user.ID); !slices.Equal(got, want) {
+		t.Fatalf("GetUserNodes = %v, want %v", got, want)
+	}
+	if got := userNodeIDs(t, repos, other.ID); len(got) != 0 {
+		t.Fatalf("GetUserNodes for unentitled user = %v, want none", got)
+	}
+}
+
+func TestNodeGroupProvisioning(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := newTestNodeGroupService(repos)
+	a := newTestNode(t, repos, "a")
+	b := newTestNode(t, repos, "b")
+	c := newTestNode(t, repos, "c")
+	byGroup := newTestUser(t, repos, "by-group")
+	byTag := newTestUser(t, repos, "by-tag")
+	alsoDirect := newTestUser(t, repos, "also-direct")
+
+	group, err := svc.CreateGroup("asia", "", []string{"asia"}, []uint{a.ID})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if err := repos.User.AssignNodes(alsoDirect.ID, []uint{a.ID}); err != nil {
+		t.Fatalf("AssignNodes: %v", err)
+	}
+	for _, grant := range []struct {
+		userID uint
+		groups []uint
+		tags   []string
+	}{
+		{byGroup.ID, []uint{group.ID}, nil},
+		{byTag.ID, nil, []string{"asia"}},
+		{alsoDirect.ID, []uint{group.ID}, nil},
+	} {
+		if _, err := svc.SetUserEntitlement(grant.userID, grant.groups, grant.tags); err != nil {
+			t.Fatalf("SetUserEntitlement: %v", err)
+		}
+	}
+	users := []uint{byGroup.ID, byTag.ID, alsoDirect.ID}
+
+	expect := func(step string, nodeID uint, want []int) {
+		t.Helper()
+		for i, userID := range users {
+			if got := countInstances(t, repos, userID, nodeID); got != want[i] {
+				t.Fatalf("%s: user %d has %d instances on node %d, want %d", step, userID, got, nodeID, want[i])
+			}
+		}
+	}
+	expect("entitlement", a.ID, []int{1, 1, 1})
+
+	if err := svc.AddNodeToGroup(group.ID, b.ID); err != nil {
+		t.Fatalf("AddNodeToGroup: %v", err)
+	}
+	expect("add node", b.ID, []int{1, 1, 1})
+
+	if _, err := svc.SetGroupNodes(group.ID, []uint{a.ID, c.ID}); err != nil {
+		t.Fatalf("SetGroupNodes: %v", err)
+	}
+	expect("set nodes keeps", a.ID, []int{1, 1, 1})
+	expect("set nodes removes", b.ID, []int{0, 0, 0})
+	expect("set nodes adds", c.ID, []int{1, 1, 1})
+
+	// 直接分配的节点移出节点组后仍保留实例
+	if err := svc.RemoveNodeFromGroup(group.ID, a.ID); err != nil {
+		t.Fatalf("RemoveNodeFromGroup: %v", err)
+	}
+	expect("remove node", a.ID, []int{0, 0, 1})
+
+	if _, err := svc.UpdateGroup(group.ID, map[string]interface{}{"tags": []interface{}{"europe"}}); err != nil {
+		t.Fatalf("UpdateGroup: %v", err)
+	}
+	expect("retag", c.ID, []int{1, 0, 1})
+
+	if err := svc.DeleteGroup(group.ID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	expect("delete group", c.ID, []int{0, 0, 0})
+	expect("delete group keeps direct", a.ID, []int{0, 0, 1})
+}
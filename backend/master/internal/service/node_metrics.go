@@ -0,0 +1,384 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+const (
+	// defaultHeartbeatInterval Agent 默认心跳间隔，节点未上报且未下发设置时用于计算在线率。
+	defaultHeartbeatInterval = 30 * time.Second
+
+	defaultRawRetention    = 48 * time.Hour
+	defaultRollupRetention = 90 * 24 * time.Hour
+	maxMetricsRange        = 366 * 24 * time.Hour
+	maxMetricsPoints       = 2000
+	compactBatchSize       = 5000
+)
+
+// 支持的聚合粒度。
+var metricBuckets = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// NodeMetricPoint 单个时间桶内的节点指标。
+type NodeMetricPoint struct {
+	Time             time.Time `json:"time"`
+	Samples          int       `json:"samples"`
+	CPUAvg           float64   `json:"cpu_avg"`
+	CPUMax           float64   `json:"cpu_max"`
+	MemoryAvg        float64   `json:"memory_avg"`
+	MemoryMax        float64   `json:"memory_max"`
+	DiskAvg          float64   `json:"disk_avg"`
+	DiskMax          float64   `json:"disk_max"`
+	InstanceCountAvg float64   `json:"instance_count_avg"`
+	InstanceCountMax int       `json:"instance_count_max"`
+	OnlineRatio      float64   `json:"online_ratio"`
+}
+
+// NodeMetrics 节点指标时序。
+type NodeMetrics struct {
+	NodeID      uint              `json:"node_id"`
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	Bucket      string            `json:"bucket"`
+	OnlineRatio float64           `json:"online_ratio"`
+	Points      []NodeMetricPoint `json:"points"`
+}
+
+// NodeMetricsService 提供节点指标查询与心跳降采样。
+type NodeMetricsService struct {
+	repo       repository.NodeMetricRepository
+	nodeRepo   repository.NodeRepository
+	configRepo repository.SystemConfigRepository
+	logger     *logrus.Logger
+}
+
+// NewNodeMetricsService 构造函数。
+func NewNodeMetricsService(repo repository.NodeMetricRepository, nodeRepo repository.NodeRepository, configRepo repository.SystemConfigRepository, logger *logrus.Logger) *NodeMetricsService {
+	return &NodeMetricsService{repo: repo, nodeRepo: nodeRepo, configRepo: configRepo, logger: logger}
+}
+
+// GetMetrics 返回节点在 [from, to) 范围内的指标，bucket 为空时按范围自动选择粒度。
+func (s *NodeMetricsService) GetMetrics(nodeID uint, from, to time.Time, bucket string) (*NodeMetrics, error) {
+	node, err := s.nodeRepo.GetByID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("invalid time range")
+	}
+	if to.Sub(from) > maxMetricsRange {
+		return nil, fmt.Errorf("time range cannot exceed %d days", int(maxMetricsRange.Hours()/24))
+	}
+	if bucket == "" {
+		bucket = autoBucket(to.Sub(from))
+	}
+	size, ok := metricBuckets[bucket]
+	if !ok {
+		return nil, fmt.Errorf("unsupported bucket %q", bucket)
+	}
+
+	start := from.Truncate(size)
+	count := int(to.Sub(start) / size)
+	if to.Sub(start)%size != 0 {
+		count++
+	}
+	if count > maxMetricsPoints {
+		return nil, fmt.Errorf("range has %d points with bucket %s, at most %d allowed", count, bucket, maxMetricsPoints)
+	}
+
+	heartbeats, err := s.repo.ListHeartbeats(nodeID, start, to)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := s.repo.ListRollups(nodeID, start.Truncate(time.Hour), to)
+	if err != nil {
+		return nil, err
+	}
+	accs := bucketMetrics(start, size, count, heartbeats, rollups)
+
+	result := &NodeMetrics{NodeID: nodeID, From: from, To: to, Bucket: bucket, Points: make([]NodeMetricPoint, 0, count)}
+	interval := s.heartbeatInterval(node)
+	expected := float64(size) / float64(interval)
+	totalSamples := 0
+	for i := range accs {
+		point := accs[i].point(start.Add(time.Duration(i) * size))
+		point.OnlineRatio = ratio(float64(point.Samples), expected)
+		totalSamples += point.Samples
+		result.Points = append(result.Points, point)
+	}
+	result.OnlineRatio = ratio(float64(totalSamples), float64(to.Sub(start))/float64(interval))
+	return result, nil
+}
+
+// heartbeatInterval 返回节点的心跳间隔：优先使用 Agent 上报值，其次为下发的生效设置，最后为默认值。
+func (s *NodeMetricsService) heartbeatInterval(node *model.Node) time.Duration {
+	if node.HeartbeatInterval > 0 {
+		return time.Duration(node.HeartbeatInterval) * time.Second
+	}
+	values, err := s.configRepo.GetByKeys([]string{agentSettingsKey})
+	if err != nil {
+		s.logger.WithError(err).Warn("load global agent settings failed")
+		return defaultHeartbeatInterval
+	}
+	// 设置损坏时忽略该层，与 AgentSettingsService.Effective 一致
+	global, _ := parseAgentSettings(values[agentSettingsKey])
+	override, _ := parseAgentSettings(node.AgentSettings)
+	if effective := global.Merge(override); effective.HeartbeatInterval != nil && *effective.HeartbeatInterval > 0 {
+		return time.Duration(*effective.HeartbeatInterval) * time.Second
+	}
+	return defaultHeartbeatInterval
+}
+
+// bucketMetrics 将原始心跳与小时数据归入从 start 开始、长度为 size 的 count 个桶。
+// 粒度小于一小时时，小时数据的样本平均分摊到其覆盖的各个桶；落在 start 之前的部分丢弃。
+func bucketMetrics(start time.Time, size time.Duration, count int, heartbeats []model.NodeHeartbeat, rollups []model.NodeMetricRollup) []metricAccumulator {
+	accs := make([]metricAccumulator, count)
+	index := func(t time.Time) int {
+		if t.Before(start) {
+			return -1
+		}
+		return int(t.Sub(start) / size)
+	}
+	for _, hb := range heartbeats {
+		idx := index(hb.CreatedAt)
+		if idx < 0 || idx >= count {
+			continue
+		}
+		accs[idx].addHeartbeat(hb)
+	}
+
+	parts := 1
+	if size < time.Hour {
+		parts = int(time.Hour / size)
+	}
+	for _, r := range rollups {
+		for i := 0; i < parts; i++ {
+			idx := index(r.BucketStart.Add(time.Duration(i) * size))
+			if idx < 0 || idx >= count {
+				continue
+			}
+			accs[idx].addRollup(r, func(n int) int { return share(n, parts, i) })
+		}
+	}
+	return accs
+}
+
+// CompactHeartbeats 将超过保留期的原始心跳降采样为小时数据，并清理过期的小时数据。
+func (s *NodeMetricsService) CompactHeartbeats() error {
+	rawRetention, rollupRetention := s.retention()
+	now := time.Now()
+	cutoff := now.Add(-rawRetention).Truncate(time.Hour)
+
+	compacted := 0
+	for {
+		records, err := s.repo.ListHeartbeatsBefore(cutoff, compactBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			break
+		}
+		rollups, ids := rollupHeartbeats(records)
+		if err := s.repo.CompactHeartbeats(rollups, ids); err != nil {
+			return fmt.Errorf("compact heartbeats: %w", err)
+		}
+		compacted += len(records)
+		if len(records) < compactBatchSize {
+			break
+		}
+	}
+
+	removed, err := s.repo.DeleteRollupsBefore(now.Add(-rollupRetention))
+	if err != nil {
+		return fmt.Errorf("delete expired rollups: %w", err)
+	}
+	if (compacted > 0 || removed > 0) && s.logger != nil {
+		s.logger.WithFields(logrus.Fields{
+			"heartbeats": compacted,
+			"rollups":    removed,
+		}).Info("heartbeat retention completed")
+	}
+	return nil
+}
+
+func (s *NodeMetricsService) retention() (time.Duration, time.Duration) {
+	rawRetention, rollupRetention := defaultRawRetention, defaultRollupRetention
+	configs, err := s.configRepo.GetByKeys([]string{"heartbeat_raw_retention_hours", "metrics_rollup_retention_days"})
+	if err != nil {
+		return rawRetention, rollupRetention
+	}
+	if hours, err := strconv.Atoi(configs["heartbeat_raw_retention_hours"]); err == nil && hours > 0 {
+		rawRetention = time.Duration(hours) * time.Hour
+	}
+	if days, err := strconv.Atoi(configs["metrics_rollup_retention_days"]); err == nil && days > 0 {
+		rollupRetention = time.Duration(days) * 24 * time.Hour
+	}
+	return rawRetention, rollupRetention
+}
+
+// rollupHeartbeats 按节点与小时聚合原始心跳。
+func rollupHeartbeats(records []model.NodeHeartbeat) ([]model.NodeMetricRollup, []uint) {
+	type key struct {
+		nodeID uint
+		hour   int64
+	}
+	accs := make(map[key]*metricAccumulator)
+	order := make([]key, 0)
+	ids := make([]uint, 0, len(records))
+	for _, hb := range records {
+		k := key{nodeID: hb.NodeID, hour: hb.CreatedAt.Truncate(time.Hour).Unix()}
+		acc, ok := accs[k]
+		if !ok {
+			acc = &metricAccumulator{}
+			accs[k] = acc
+			order = append(order, k)
+		}
+		acc.addHeartbeat(hb)
+		ids = append(ids, hb.ID)
+	}
+
+	rollups := make([]model.NodeMetricRollup, 0, len(order))
+	for _, k := range order {
+		acc := accs[k]
+		p := acc.point(time.Unix(k.hour, 0))
+		rollups = append(rollups, model.NodeMetricRollup{
+			NodeID:           k.nodeID,
+			BucketStart:      p.Time,
+			Samples:          p.Samples,
+			CPUAvg:           p.CPUAvg,
+			CPUMax:           p.CPUMax,
+			MemoryAvg:        p.MemoryAvg,
+			MemoryMax:        p.MemoryMax,
+			DiskAvg:          p.DiskAvg,
+			DiskMax:          p.DiskMax,
+			InstanceCountAvg: p.InstanceCountAvg,
+			InstanceCountMax: p.InstanceCountMax,
+			CPUSamples:       acc.cpu.samples,
+			MemorySamples:    acc.mem.samples,
+			DiskSamples:      acc.disk.samples,
+		})
+	}
+	return rollups, ids
+}
+
+// autoBucket 根据时间范围选择聚合粒度。
+func autoBucket(span time.Duration) string {
+	switch {
+	case span <= 2*time.Hour:
+		return "1m"
+	case span <= 12*time.Hour:
+		return "5m"
+	case span <= 7*24*time.Hour:
+		return "1h"
+	default:
+		return "1d"
+	}
+}
+
+func ratio(actual, expected float64) float64 {
+	if expected <= 0 {
+		return 0
+	}
+	if actual >= expected {
+		return 1
+	}
+	return actual / expected
+}
+
+// share 将 n 个样本平均分到 parts 份，返回第 i 份的样本数，余数依次分给前面的份。
+func share(n, parts, i int) int {
+	samples := n / parts
+	if i < n%parts {
+		samples++
+	}
+	return samples
+}
+
+// metricGauge 单项指标的加权平均与最大值，只统计实际上报该指标的样本。
+type metricGauge struct {
+	samples int
+	sum     float64
+	max     float64
+}
+
+func (g *metricGauge) add(samples int, avg, peak float64) {
+	if samples <= 0 {
+		return
+	}
+	g.samples += samples
+	g.sum += avg * float64(samples)
+	g.max = max(g.max, peak)
+}
+
+func (g *metricGauge) addValue(value *float64) {
+	if value != nil {
+		g.add(1, *value, *value)
+	}
+}
+
+func (g *metricGauge) avg() float64 {
+	if g.samples == 0 {
+		return 0
+	}
+	return g.sum / float64(g.samples)
+}
+
+// metricAccumulator 累加带样本权重的指标，samples 为心跳数，用于计算在线率与实例数平均值。
+type metricAccumulator struct {
+	samples     int
+	cpu         metricGauge
+	mem         metricGauge
+	disk        metricGauge
+	instanceSum float64
+	instanceMax int
+}
+
+func (a *metricAccumulator) addHeartbeat(hb model.NodeHeartbeat) {
+	a.samples++
+	a.cpu.addValue(hb.CPUUsage)
+	a.mem.addValue(hb.MemoryUsage)
+	a.disk.addValue(hb.DiskUsage)
+	a.instanceSum += float64(hb.InstanceCount)
+	a.instanceMax = max(a.instanceMax, hb.InstanceCount)
+}
+
+// addRollup 累加小时数据的一部分，portion 将小时内的样本数换算为计入本桶的样本数。
+func (a *metricAccumulator) addRollup(r model.NodeMetricRollup, portion func(int) int) {
+	samples := portion(r.Samples)
+	if samples <= 0 {
+		return
+	}
+	a.samples += samples
+	a.cpu.add(portion(r.CPUSamples), r.CPUAvg, r.CPUMax)
+	a.mem.add(portion(r.MemorySamples), r.MemoryAvg, r.MemoryMax)
+	a.disk.add(portion(r.DiskSamples), r.DiskAvg, r.DiskMax)
+	a.instanceSum += r.InstanceCountAvg * float64(samples)
+	a.instanceMax = max(a.instanceMax, r.InstanceCountMax)
+}
+
+func (a *metricAccumulator) point(t time.Time) NodeMetricPoint {
+	p := NodeMetricPoint{Time: t, Samples: a.samples}
+	if a.samples == 0 {
+		return p
+	}
+	p.CPUAvg = a.cpu.avg()
+	p.CPUMax = a.cpu.max
+	p.MemoryAvg = a.mem.avg()
+	p.MemoryMax = a.mem.max
+	p.DiskAvg = a.disk.avg()
+	p.DiskMax = a.disk.max
+	p.InstanceCountAvg = a.instanceSum / float64(a.samples)
+	p.InstanceCountMax = a.instanceMax
+	return p
+}
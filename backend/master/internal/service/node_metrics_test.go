@@ -0,0 +1,211 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+func metricValue(v float64) *float64 { return &v }
+
+func TestBucketMetricsHeartbeats(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	heartbeats := []model.NodeHeartbeat{
+		{CPUUsage: metricValue(10), CreatedAt: start},
+		{CPUUsage: metricValue(30), CreatedAt: start.Add(30 * time.Second)},
+		{CPUUsage: metricValue(50), CreatedAt: start.Add(90 * time.Second)},
+		{CPUUsage: metricValue(90), CreatedAt: start.Add(-time.Second)},
+		{CPUUsage: metricValue(90), CreatedAt: start.Add(3 * time.Minute)},
+	}
+	accs := bucketMetrics(start, time.Minute, 3, heartbeats, nil)
+	first := accs[0].point(start)
+	if first.Samples != 2 || first.CPUAvg != 20 || first.CPUMax != 30 {
+		t.Fatalf("first bucket = %+v, want 2 samples avg 20 max 30", first)
+	}
+	if accs[1].samples != 1 || accs[2].samples != 0 {
+		t.Fatalf("samples = %d, %d; want 1, 0", accs[1].samples, accs[2].samples)
+	}
+}
+
+func TestBucketMetricsSpreadsRollupsBelowOneHour(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	rollups := []model.NodeMetricRollup{
+		{BucketStart: start, Samples: 120, CPUSamples: 120, CPUAvg: 40, CPUMax: 80},
+	}
+	accs := bucketMetrics(start, 5*time.Minute, 12, nil, rollups)
+	for i, acc := range accs {
+		p := acc.point(start)
+		if p.Samples != 10 || p.CPUAvg != 40 || p.CPUMax != 80 {
+			t.Fatalf("bucket %d = %+v, want 10 samples avg 40 max 80", i, p)
+		}
+	}
+
+	// 余数样本分给前面的桶，总数不变
+	accs = bucketMetrics(start, 5*time.Minute, 12, nil, []model.NodeMetricRollup{{BucketStart: start, Samples: 13}})
+	total := 0
+	for _, acc := range accs {
+		total += acc.samples
+	}
+	if total != 13 || accs[0].samples != 2 || accs[11].samples != 1 {
+		t.Fatalf("spread samples total=%d first=%d last=%d, want 13, 2, 1", total, accs[0].samples, accs[11].samples)
+	}
+}
+
+func TestBucketMetricsDropsRollupPartsBeforeStart(t *testing.T) {
+	hour := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	start := hour.Add(30 * time.Minute)
+	rollups := []model.NodeMetricRollup{{BucketStart: hour, Samples: 120, CPUSamples: 120, CPUAvg: 40, CPUMax: 40}}
+
+	accs := bucketMetrics(start, time.Minute, 60, nil, rollups)
+	total := 0
+	for _, acc := range accs[:30] {
+		total += acc.samples
+	}
+	if total != 60 {
+		t.Fatalf("samples in covered buckets = %d, want 60", total)
+	}
+	for i, acc := range accs[30:] {
+		if acc.samples != 0 {
+			t.Fatalf("bucket %d after the rollup hour has %d samples", 30+i, acc.samples)
+		}
+	}
+
+	// 小时粒度下不在范围内的小时数据直接丢弃，不再并入第一个桶
+	accs = bucketMetrics(hour.Add(time.Hour), time.Hour, 2, nil, rollups)
+	if accs[0].samples != 0 {
+		t.Fatalf("rollup before start counted: %d samples", accs[0].samples)
+	}
+}
+
+func TestMetricsSkipUncollectedValues(t *testing.T) {
+	hour := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	// CPU 只有两次上报，内存一次，磁盘从未上报；未上报的心跳不能按 0% 计入平均值
+	heartbeats := []model.NodeHeartbeat{
+		{NodeID: 1, CPUUsage: metricValue(40), MemoryUsage: metricValue(60), CreatedAt: hour},
+		{NodeID: 1, CPUUsage: metricValue(80), CreatedAt: hour.Add(time.Minute)},
+		{NodeID: 1, InstanceCount: 2, CreatedAt: hour.Add(2 * time.Minute)},
+	}
+
+	p := bucketMetrics(hour, time.Hour, 1, heartbeats, nil)[0].point(hour)
+	if p.Samples != 3 || p.CPUAvg != 60 || p.CPUMax != 80 || p.MemoryAvg != 60 || p.DiskAvg != 0 {
+		t.Fatalf("point = %+v, want 3 samples, cpu avg 60 max 80, memory avg 60, disk 0", p)
+	}
+
+	rollups, ids := rollupHeartbeats(heartbeats)
+	if len(rollups) != 1 || len(ids) != 3 {
+		t.Fatalf("rollups = %+v, ids = %v; want one rollup of 3 heartbeats", rollups, ids)
+	}
+	r := rollups[0]
+	if r.Samples != 3 || r.CPUSamples != 2 || r.MemorySamples != 1 || r.DiskSamples != 0 || r.CPUAvg != 60 || r.MemoryAvg != 60 {
+		t.Fatalf("rollup = %+v, want samples 3/2/1/0 with cpu avg 60 and memory avg 60", r)
+	}
+
+	// 小时数据拆分到分钟桶时按各项样本数分摊，平均值不受未上报的样本影响
+	accs := bucketMetrics(hour, 30*time.Minute, 2, nil, rollups)
+	first, second := accs[0].point(hour), accs[1].point(hour.Add(30*time.Minute))
+	if first.Samples != 2 || first.CPUAvg != 60 || first.MemoryAvg != 60 {
+		t.Fatalf("first half = %+v, want 2 samples with cpu and memory avg 60", first)
+	}
+	if second.Samples != 1 || second.CPUAvg != 60 || second.MemoryAvg != 0 {
+		t.Fatalf("second half = %+v, want 1 sample with cpu avg 60 and no memory", second)
+	}
+}
+
+func TestCompactHeartbeatsMergesByMetricSamples(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := NewNodeMetricsService(repos.NodeMetric, repos.Node, repos.SystemConfig, newTestLogger())
+	node := newTestNode(t, repos, "node-1")
+	hour := time.Now().Add(-72 * time.Hour).Truncate(time.Hour)
+
+	// 同一小时分两批压缩：第一批未采集 CPU，第二批 CPU 为 50%
+	batches := [][]model.NodeHeartbeat{
+		{{NodeID: node.ID, Status: model.NodeStatusOnline, MemoryUsage: metricValue(20), CreatedAt: hour}},
+		{{NodeID: node.ID, Status: model.NodeStatusOnline, CPUUsage: metricValue(50), MemoryUsage: metricValue(40), CreatedAt: hour.Add(time.Minute)}},
+	}
+	for _, batch := range batches {
+		for i := range batch {
+			if err := repos.Node.SaveHeartbeat(&batch[i]); err != nil {
+				t.Fatalf("save heartbeat: %v", err)
+			}
+		}
+		if err := svc.CompactHeartbeats(); err != nil {
+			t.Fatalf("compact heartbeats: %v", err)
+		}
+	}
+
+	rollups, err := repos.NodeMetric.ListRollups(node.ID, hour, hour.Add(time.Hour))
+	if err != nil || len(rollups) != 1 {
+		t.Fatalf("rollups = %+v, %v; want one merged rollup", rollups, err)
+	}
+	r := rollups[0]
+	if r.Samples != 2 || r.CPUSamples != 1 || r.CPUAvg != 50 || r.MemorySamples != 2 || r.MemoryAvg != 30 {
+		t.Fatalf("merged rollup = %+v, want 2 samples, cpu 1 sample avg 50, memory 2 samples avg 30", r)
+	}
+}
+
+func TestGetMetricsRejectsTooManyPoints(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := NewNodeMetricsService(repos.NodeMetric, repos.Node, repos.SystemConfig, newTestLogger())
+	node := &model.Node{Name: "n1", Endpoint: "1.2.3.4", APITokenHash: "hash", APITokenHashed: true}
+	if err := repos.Node.Create(node); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	to := time.Now()
+	if _, err := svc.GetMetrics(node.ID, to.Add(-366*24*time.Hour), to, "1m"); err == nil {
+		t.Fatal("1m buckets over a year accepted")
+	}
+	metrics, err := svc.GetMetrics(node.ID, to.Add(-366*24*time.Hour), to, "")
+	if err != nil {
+		t.Fatalf("auto bucket over a year: %v", err)
+	}
+	if len(metrics.Points) > maxMetricsPoints {
+		t.Fatalf("auto bucket returned %d points", len(metrics.Points))
+	}
+}
+
+func TestGetMetricsOnlineRatioUsesNodeHeartbeatInterval(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := NewNodeMetricsService(repos.NodeMetric, repos.Node, repos.SystemConfig, newTestLogger())
+	if err := repos.SystemConfig.Set(agentSettingsKey, `{"heartbeat_interval":15}`); err != nil {
+		t.Fatalf("set agent settings: %v", err)
+	}
+	from := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	cases := []struct {
+		name     string
+		reported int
+		every    time.Duration
+		want     float64
+	}{
+		{"reported 60s", 60, time.Minute, 1},
+		{"reported 15s", 15, time.Minute, 0.25},
+		{"global setting when not reported", 0, 15 * time.Second, 1},
+		{"global setting with missed heartbeats", 0, 30 * time.Second, 0.5},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := newTestNode(t, repos, fmt.Sprintf("node-%d", i))
+			if tc.reported > 0 {
+				if err := repos.Node.UpdateHeartbeat(node.ID, map[string]interface{}{"heartbeat_interval": tc.reported}); err != nil {
+					t.Fatalf("store heartbeat interval: %v", err)
+				}
+			}
+			for at := from; at.Before(to); at = at.Add(tc.every) {
+				if err := repos.Node.SaveHeartbeat(&model.NodeHeartbeat{NodeID: node.ID, Status: model.NodeStatusOnline, CreatedAt: at}); err != nil {
+					t.Fatalf("save heartbeat: %v", err)
+				}
+			}
+			metrics, err := svc.GetMetrics(node.ID, from, to, "1h")
+			if err != nil {
+				t.Fatalf("GetMetrics: %v", err)
+			}
+			if metrics.OnlineRatio != tc.want || metrics.Points[0].OnlineRatio != tc.want {
+				t.Fatalf("online ratio = %v (point %v), want %v", metrics.OnlineRatio, metrics.Points[0].OnlineRatio, tc.want)
+			}
+		})
+	}
+}
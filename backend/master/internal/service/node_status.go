@@ -0,0 +1,276 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+const (
+	maxUptimeRange       = 366 * 24 * time.Hour
+	defaultStatusEvents  = 200
+	maxStatusEventsLimit = 1000
+	// publicStatusTTL 公开状态页数据的缓存时间，避免匿名请求反复计算各节点在线率
+	publicStatusTTL = 30 * time.Second
+)
+
+// uptimeWindows 状态总览中统计的时间窗口。
+var uptimeWindows = []struct {
+	name   string
+	window time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// NodeUptime 节点在时间窗口内的在线率。
+type NodeUptime struct {
+	NodeID         uint      `json:"node_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	TrackedSeconds int64     `json:"tracked_seconds"`
+	OnlineSeconds  int64     `json:"online_seconds"`
+	UptimePercent  float64   `json:"uptime_percent"`
+	Outages        int       `json:"outages"`
+}
+
+// NodeStatusSummary 状态总览中的单个节点。
+type NodeStatusSummary struct {
+	NodeID      uint               `json:"node_id"`
+	Name        string             `json:"name"`
+	Location    string             `json:"location"`
+	CountryCode string             `json:"country_code"`
+	Status      string             `json:"status"`
+	Maintenance bool               `json:"maintenance"`
+	LastSeenAt  *time.Time         `json:"last_seen_at"`
+	Uptime      map[string]float64 `json:"uptime"`
+}
+
+// PublicNodeStatus 公开状态页展示的节点信息，不包含节点 ID 和地址。
+type PublicNodeStatus struct {
+	Name        string             `json:"name"`
+	Location    string             `json:"location"`
+	CountryCode string             `json:"country_code"`
+	Status      string             `json:"status"`
+	Maintenance bool               `json:"maintenance"`
+	Uptime      map[string]float64 `json:"uptime"`
+}
+
+// NodeStatusService 记录节点状态变化并计算在线率。
+type NodeStatusService struct {
+	repo       repository.NodeStatusRepository
+	nodeRepo   repository.NodeRepository
+	configRepo repository.SystemConfigRepository
+	logger     *logrus.Logger
+
+	publicMu     sync.Mutex
+	publicStatus []PublicNodeStatus
+	publicAt     time.Time
+}
+
+// NewNodeStatusService 构造函数。
+func NewNodeStatusService(repo repository.NodeStatusRepository, nodeRepo repository.NodeRepository, configRepo repository.SystemConfigRepository, logger *logrus.Logger) *NodeStatusService {
+	return &NodeStatusService{repo: repo, nodeRepo: nodeRepo, configRepo: configRepo, logger: logger}
+}
+
+// MarkStaleOffline 将超过 timeout 未心跳的节点置为离线。
+func (s *NodeStatusService) MarkStaleOffline(timeout time.Duration) error {
+	events, err := s.repo.MarkStaleOffline(time.Now().Add(-timeout))
+	if err != nil {
+		return err
+	}
+	if s.logger != nil {
+		for _, event := range events {
+			s.logger.WithField("node_id", event.NodeID).Warn("node marked offline after heartbeat timeout")
+		}
+	}
+	return nil
+}
+
+// ListEvents 返回节点在 [from, to) 范围内的状态变化记录。
+func (s *NodeStatusService) ListEvents(nodeID uint, from, to time.Time, limit int) ([]model.NodeStatusEvent, error) {
+	if _, err := s.nodeRepo.GetByID(nodeID); err != nil {
+		return nil, err
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("invalid time range")
+	}
+	if limit <= 0 {
+		limit = defaultStatusEvents
+	}
+	if limit > maxStatusEventsLimit {
+		limit = maxStatusEventsLimit
+	}
+	return s.repo.ListEvents(nodeID, from, to, limit)
+}
+
+// GetUptime 计算节点在 [from, to) 范围内的在线率。
+func (s *NodeStatusService) GetUptime(nodeID uint, from, to time.Time) (*NodeUptime, error) {
+	node, err := s.nodeRepo.GetByID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("invalid time range")
+	}
+	if to.Sub(from) > maxUptimeRange {
+		return nil, fmt.Errorf("time range cannot exceed %d days", int(maxUptimeRange.Hours()/24))
+	}
+	return s.uptime(node, from, to)
+}
+
+// Overview 返回所有节点的当前状态和最近 24 小时、7 天、30 天在线率。
+func (s *NodeStatusService) Overview() ([]NodeStatusSummary, error) {
+	nodes, err := s.nodeRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	summaries := make([]NodeStatusSummary, 0, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		summary := NodeStatusSummary{
+			NodeID:      node.ID,
+			Name:        node.Name,
+			Location:    node.Location,
+			CountryCode: node.CountryCode,
+			Status:      node.Status,
+			Maintenance: node.InMaintenance(now),
+			LastSeenAt:  node.LastSeenAt,
+			Uptime:      make(map[string]float64, len(uptimeWindows)),
+		}
+		for _, w := range uptimeWindows {
+			uptime, err := s.uptime(node, now.Add(-w.window), now)
+			if err != nil {
+				return nil, err
+			}
+			summary.Uptime[w.name] = uptime.UptimePercent
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// StatusPageEnabled 返回是否开放公开状态页。
+func (s *NodeStatusService) StatusPageEnabled() bool {
+	configs, err := s.configRepo.GetByKeys([]string{"status_page_enabled"})
+	if err != nil {
+		return false
+	}
+	enabled, _ := strconv.ParseBool(configs["status_page_enabled"])
+	return enabled
+}
+
+// PublicStatus 返回公开状态页数据，结果缓存 publicStatusTTL，并发请求只计算一次。
+func (s *NodeStatusService) PublicStatus() ([]PublicNodeStatus, error) {
+	s.publicMu.Lock()
+	defer s.publicMu.Unlock()
+	if s.publicStatus != nil && time.Since(s.publicAt) < publicStatusTTL {
+		return s.publicStatus, nil
+	}
+	summaries, err := s.Overview()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PublicNodeStatus, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, PublicNodeStatus{
+			Name:        summary.Name,
+			Location:    summary.Location,
+			CountryCode: summary.CountryCode,
+			Status:      summary.Status,
+			Maintenance: summary.Maintenance,
+			Uptime:      summary.Uptime,
+		})
+	}
+	s.publicStatus, s.publicAt = result, time.Now()
+	return result, nil
+}
+
+func (s *NodeStatusService) uptime(node *model.Node, from, to time.Time) (*NodeUptime, error) {
+	result := &NodeUptime{NodeID: node.ID, From: from, To: to}
+	start, end := from, to
+	if node.CreatedAt.After(start) {
+		start = node.CreatedAt
+	}
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+	if !end.After(start) {
+		return result, nil
+	}
+
+	events, err := s.repo.ListEvents(node.ID, start, end, 0)
+	if err != nil {
+		return nil, err
+	}
+	initial, err := s.initialStatus(node, start, end, events)
+	if err != nil {
+		return nil, err
+	}
+
+	online, outages := computeUptime(initial, start, end, events)
+	tracked := end.Sub(start)
+	result.TrackedSeconds = int64(tracked.Seconds())
+	result.OnlineSeconds = int64(online.Seconds())
+	result.UptimePercent = math.Round(ratio(online.Seconds(), tracked.Seconds())*10000) / 100
+	result.Outages = outages
+	return result, nil
+}
+
+// initialStatus 推断节点在 start 时刻的状态：依次取之前最后一次变化的新状态、范围内或范围之后
+// 第一次变化的原状态，从未变化过时才使用当前状态。
+func (s *NodeStatusService) initialStatus(node *model.Node, start, end time.Time, events []model.NodeStatusEvent) (string, error) {
+	previous, err := s.repo.LastEventBefore(node.ID, start)
+	if err != nil {
+		return "", err
+	}
+	if previous != nil {
+		return previous.ToStatus, nil
+	}
+	if len(events) > 0 {
+		return events[0].FromStatus, nil
+	}
+	next, err := s.repo.FirstEventFrom(node.ID, end)
+	if err != nil {
+		return "", err
+	}
+	if next != nil {
+		return next.FromStatus, nil
+	}
+	return node.Status, nil
+}
+
+// computeUptime 按状态变化序列累计 [start, end) 内的在线时长和掉线次数。
+func computeUptime(initial string, start, end time.Time, events []model.NodeStatusEvent) (time.Duration, int) {
+	var online time.Duration
+	outages := 0
+	status, cursor := initial, start
+	for _, event := range events {
+		if event.CreatedAt.Before(cursor) {
+			continue
+		}
+		if !event.CreatedAt.Before(end) {
+			break
+		}
+		if status == model.NodeStatusOnline {
+			online += event.CreatedAt.Sub(cursor)
+		}
+		if status == model.NodeStatusOnline && event.ToStatus != model.NodeStatusOnline {
+			outages++
+		}
+		status, cursor = event.ToStatus, event.CreatedAt
+	}
+	if status == model.NodeStatusOnline {
+		online += end.Sub(cursor)
+	}
+	return online, outages
+}
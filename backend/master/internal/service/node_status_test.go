@@ -0,0 +1,160 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+func TestComputeUptime(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	event := func(offset time.Duration, from, to string) model.NodeStatusEvent {
+		return model.NodeStatusEvent{FromStatus: from, ToStatus: to, CreatedAt: start.Add(offset)}
+	}
+	up := func(offset time.Duration) model.NodeStatusEvent {
+		return event(offset, model.NodeStatusOffline, model.NodeStatusOnline)
+	}
+	down := func(offset time.Duration) model.NodeStatusEvent {
+		return event(offset, model.NodeStatusOnline, model.NodeStatusOffline)
+	}
+	cases := []struct {
+		name        string
+		initial     string
+		events      []model.NodeStatusEvent
+		wantOnline  time.Duration
+		wantOutages int
+	}{
+		{"online without events", model.NodeStatusOnline, nil, 10 * time.Hour, 0},
+		{"offline without events", model.NodeStatusOffline, nil, 0, 0},
+		{"comes online", model.NodeStatusOffline, []model.NodeStatusEvent{up(4 * time.Hour)}, 6 * time.Hour, 0},
+		{"goes offline", model.NodeStatusOnline, []model.NodeStatusEvent{down(4 * time.Hour)}, 4 * time.Hour, 1},
+		{"two outages", model.NodeStatusOnline, []model.NodeStatusEvent{
+			down(time.Hour), up(2 * time.Hour), down(5 * time.Hour), up(8 * time.Hour),
+		}, 6 * time.Hour, 2},
+		{"outage at window start", model.NodeStatusOnline, []model.NodeStatusEvent{down(0), up(3 * time.Hour)}, 7 * time.Hour, 1},
+		{"recovery at window start", model.NodeStatusOffline, []model.NodeStatusEvent{up(0)}, 10 * time.Hour, 0},
+		{"event at window end ignored", model.NodeStatusOnline, []model.NodeStatusEvent{down(10 * time.Hour)}, 10 * time.Hour, 0},
+		{"event before window ignored", model.NodeStatusOnline, []model.NodeStatusEvent{down(-time.Hour)}, 10 * time.Hour, 0},
+		{"repeated offline counted once", model.NodeStatusOnline, []model.NodeStatusEvent{
+			down(2 * time.Hour), event(3*time.Hour, model.NodeStatusOffline, model.NodeStatusOffline),
+		}, 2 * time.Hour, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			online, outages := computeUptime(tc.initial, start, end, tc.events)
+			if online != tc.wantOnline || outages != tc.wantOutages {
+				t.Fatalf("computeUptime = %s, %d outages; want %s, %d", online, outages, tc.wantOnline, tc.wantOutages)
+			}
+		})
+	}
+}
+
+func TestGetUptimeWindows(t *testing.T) {
+	db, repos := newTestRepos(t)
+	svc := NewNodeStatusService(repos.NodeStatus, repos.Node, repos.SystemConfig, newTestLogger())
+	now := time.Now().Truncate(time.Second)
+	at := func(hours int) time.Time { return now.Add(time.Duration(hours) * time.Hour) }
+
+	// 节点 10 小时前创建，-8h 上线，-5h 掉线，-4h 恢复
+	node := newTestNode(t, repos, "node-1")
+	if err := db.Model(node).UpdateColumns(map[string]interface{}{"created_at": at(-10), "status": model.NodeStatusOnline}).Error; err != nil {
+		t.Fatalf("update node: %v", err)
+	}
+	for _, event := range []model.NodeStatusEvent{
+		{NodeID: node.ID, FromStatus: model.NodeStatusOffline, ToStatus: model.NodeStatusOnline, CreatedAt: at(-8)},
+		{NodeID: node.ID, FromStatus: model.NodeStatusOnline, ToStatus: model.NodeStatusOffline, CreatedAt: at(-5)},
+		{NodeID: node.ID, FromStatus: model.NodeStatusOffline, ToStatus: model.NodeStatusOnline, CreatedAt: at(-4)},
+	} {
+		if err := repos.NodeStatus.CreateEvent(&event); err != nil {
+			t.Fatalf("create event: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name        string
+		from, to    time.Time
+		wantTracked int64
+		wantPercent float64
+		wantOutages int
+	}{
+		{"since creation", at(-10), now, 10 * 3600, 70, 1},
+		{"starts before creation", at(-20), at(-9), 3600, 0, 0},
+		{"state from earlier event", at(-6), at(-4), 2 * 3600, 50, 1},
+		{"outage at window start", at(-5), at(-4), 3600, 0, 1},
+		{"recovery at window end", at(-7), at(-4), 3 * 3600, 66.67, 1},
+		{"quiet window after outage", at(-3), at(-2), 3600, 100, 0},
+		{"ends in the future", at(-1), at(1), 3600, 100, 0},
+		{"before creation", at(-20), at(-11), 0, 0, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			uptime, err := svc.GetUptime(node.ID, tc.from, tc.to)
+			if err != nil {
+				t.Fatalf("GetUptime: %v", err)
+			}
+			// 截止到当前时间的窗口在计算时多出不足一秒
+			if d := uptime.TrackedSeconds - tc.wantTracked; d < 0 || d > 1 {
+				t.Fatalf("tracked = %ds, want %ds", uptime.TrackedSeconds, tc.wantTracked)
+			}
+			if uptime.UptimePercent != tc.wantPercent || uptime.Outages != tc.wantOutages {
+				t.Fatalf("uptime = %.2f%% with %d outages, want %.2f%% with %d", uptime.UptimePercent, uptime.Outages, tc.wantPercent, tc.wantOutages)
+			}
+		})
+	}
+
+	if _, err := svc.GetUptime(node.ID, now, now); err == nil {
+		t.Fatalf("GetUptime accepted an empty range")
+	}
+	if _, err := svc.GetUptime(node.ID, now.Add(-maxUptimeRange-time.Hour), now); err == nil {
+		t.Fatalf("GetUptime accepted a range longer than %s", maxUptimeRange)
+	}
+}
+
+func TestStatusTransitionsRecorded(t *testing.T) {
+	_, repos := newTestRepos(t)
+	nodeSvc := NewNodeService(repos.Node, repos.Instance, repos.NodeStatus, repos.SystemConfig, "test-seal-key", newTestLogger())
+	statusSvc := NewNodeStatusService(repos.NodeStatus, repos.Node, repos.SystemConfig, newTestLogger())
+	node := newTestNode(t, repos, "node-1")
+	from := time.Now().Add(-time.Minute)
+
+	steps := []struct {
+		name string
+		run  func() error
+		want []string // 累计的 原状态>新状态:原因
+	}{
+		{"first heartbeat", func() error { return nodeSvc.UpdateHeartbeat(node.ID, HeartbeatReport{}) }, []string{
+			"offline>online:heartbeat",
+		}},
+		{"repeated heartbeat", func() error { return nodeSvc.UpdateHeartbeat(node.ID, HeartbeatReport{}) }, []string{
+			"offline>online:heartbeat",
+		}},
+		{"fresh heartbeat not stale", func() error { return statusSvc.MarkStaleOffline(time.Minute) }, []string{
+			"offline>online:heartbeat",
+		}},
+		{"heartbeat timeout", func() error { return statusSvc.MarkStaleOffline(-time.Minute) }, []string{
+			"offline>online:heartbeat", "online>offline:heartbeat_timeout",
+		}},
+		{"already offline", func() error { return statusSvc.MarkStaleOffline(-time.Minute) }, []string{
+			"offline>online:heartbeat", "online>offline:heartbeat_timeout",
+		}},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		events, err := statusSvc.ListEvents(node.ID, from, time.Now().Add(time.Minute), 0)
+		if err != nil {
+			t.Fatalf("%s: ListEvents: %v", step.name, err)
+		}
+		got := make([]string, 0, len(events))
+		for _, event := range events {
+			got = append(got, event.FromStatus+">"+event.ToStatus+":"+event.Cause)
+		}
+		if !slices.Equal(got, step.want) {
+			t.Fatalf("%s: events = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
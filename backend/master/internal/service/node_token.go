@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/pkg/utils"
+)
+
+// defaultTokenGrace 未配置 node_token_grace_hours 时旧令牌的宽限期。
+const defaultTokenGrace = 24 * time.Hour
+
+// ErrInvalidNodeToken 节点令牌不存在或已过宽限期。
+var ErrInvalidNodeToken = errors.New("invalid api token")
+
+// TokenRotation 令牌轮换结果。
+type TokenRotation struct {
+	Token                  string     `json:"token"`
+	PreviousTokenExpiresAt *time.Time `json:"previous_token_expires_at"`
+}
+
+// Authenticate 校验节点令牌。使用宽限期内的旧令牌认证时同时返回待下发的新令牌；
+// 使用新令牌认证说明 Agent 已完成切换，旧令牌随即失效。
+func (s *NodeService) Authenticate(token string) (*model.Node, string, error) {
+	hash := utils.HashToken(token)
+	node, err := s.repo.GetByTokenHash(hash)
+	if err == nil {
+		if node.PreviousTokenHash != "" || node.PendingTokenSealed != "" {
+			s.finishRotation(node)
+		}
+		return node, "", nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", err
+	}
+
+	node, err = s.repo.GetByPreviousTokenHash(hash, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrInvalidNodeToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if node.PendingTokenSealed == "" {
+		return node, "", nil
+	}
+	next, err := utils.Open(s.sealKey, node.PendingTokenSealed)
+	if err != nil {
+		s.logger.WithError(err).WithField("node_id", node.ID).Warn("open pending node token failed")
+		return node, "", nil
+	}
+	return node, next, nil
+}
+
+// RotateToken 生成新令牌，graceHours 为空时使用 node_token_grace_hours，为 0 时旧令牌立即失效。
+// 上一次轮换尚未被 Agent 确认时，旧令牌沿用原有的宽限期。
+func (s *NodeService) RotateToken(id uint, graceHours *int) (*TokenRotation, error) {
+	node, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	grace := s.tokenGrace()
+	if graceHours != nil {
+		if *graceHours < 0 {
+			return nil, fmt.Errorf("grace_hours must not be negative")
+		}
+		grace = time.Duration(*graceHours) * time.Hour
+	}
+
+	token, err := utils.GenerateAPIToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	updates := map[string]interface{}{
+		"api_token":                 utils.HashToken(token),
+		"api_token_hashed":          true,
+		"previous_token_hash":       "",
+		"previous_token_expires_at": nil,
+		"pending_token_sealed":      "",
+		"token_rotated_at":          &now,
+	}
+	result := &TokenRotation{Token: token}
+	if grace > 0 {
+		sealed, err := utils.Seal(s.sealKey, token)
+		if err != nil {
+			return nil, err
+		}
+		previousHash, expiresAt := node.APITokenHash, now.Add(grace)
+		// 上一次轮换的新令牌尚未下发，Agent 仍在使用更早的令牌，保留它及其宽限期，只替换待下发的令牌
+		if node.PendingTokenSealed != "" && node.PreviousTokenHash != "" &&
+			node.PreviousTokenExpiresAt != nil && node.PreviousTokenExpiresAt.After(now) {
+			previousHash, expiresAt = node.PreviousTokenHash, *node.PreviousTokenExpiresAt
+		}
+		updates["previous_token_hash"] = previousHash
+		updates["previous_token_expires_at"] = &expiresAt
+		updates["pending_token_sealed"] = sealed
+		result.PreviousTokenExpiresAt = &expiresAt
+	}
+	if err := s.repo.UpdateToken(id, updates); err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(logrus.Fields{"node_id": id, "grace": grace.String()}).Info("node api token rotated")
+	return result, nil
+}
+
+// HashLegacyTokens 将旧版本以明文保存的节点令牌替换为摘要，启动时调用。
+func (s *NodeService) HashLegacyTokens() error {
+	nodes, err := s.repo.ListLegacyTokens()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if err := s.repo.UpdateToken(node.ID, map[string]interface{}{
+			"api_token":        utils.HashToken(node.APITokenHash),
+			"api_token_hashed": true,
+		}); err != nil {
+			return fmt.Errorf("hash token of node %d: %w", node.ID, err)
+		}
+	}
+	if len(nodes) > 0 {
+		s.logger.Infof("hashed plaintext api tokens of %d nodes", len(nodes))
+	}
+	return nil
+}
+
+func (s *NodeService) finishRotation(node *model.Node) {
+	err := s.repo.UpdateToken(node.ID, map[string]interface{}{
+		"previous_token_hash":       "",
+		"previous_token_expires_at": nil,
+		"pending_token_sealed":      "",
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("node_id", node.ID).Warn("clear previous node token failed")
+		return
+	}
+	node.PreviousTokenHash = ""
+	node.PreviousTokenExpiresAt = nil
+	node.PendingTokenSealed = ""
+	s.logger.WithField("node_id", node.ID).Info("node switched to rotated api token")
+}
+
+func (s *NodeService) tokenGrace() time.Duration {
+	configs, err := s.configRepo.GetByKeys([]string{"node_token_grace_hours"})
+	if err != nil {
+		return defaultTokenGrace
+	}
+	if hours, err := strconv.Atoi(configs["node_token_grace_hours"]); err == nil && hours >= 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultTokenGrace
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestNodeService(t *testing.T) *NodeService {
+	t.Helper()
+	_, repos := newTestRepos(t)
+	return NewNodeService(repos.Node, repos.Instance, repos.NodeStatus, repos.SystemConfig, "test-seal-key", newTestLogger())
+}
+
+func TestRotateTokenTwiceKeepsOriginalToken(t *testing.T) {
+	svc := newTestNodeService(t)
+	node, original, err := svc.RegisterNode("node-1", "1.2.3.4", "", "")
+	if err != nil {
+		t.Fatalf("register node: %v", err)
+	}
+
+	grace := 1
+	if _, err := svc.RotateToken(node.ID, &grace); err != nil {
+		t.Fatalf("first rotation: %v", err)
+	}
+	second, err := svc.RotateToken(node.ID, &grace)
+	if err != nil {
+		t.Fatalf("second rotation: %v", err)
+	}
+
+	// Agent 尚未取到第一次轮换的令牌，仍使用原令牌
+	got, next, err := svc.Authenticate(original)
+	if err != nil {
+		t.Fatalf("authenticate with original token: %v", err)
+	}
+	if got.ID != node.ID {
+		t.Fatalf("authenticated node = %d, want %d", got.ID, node.ID)
+	}
+	if next != second.Token {
+		t.Fatalf("pending token = %q, want the latest rotated token", next)
+	}
+
+	if _, _, err := svc.Authenticate(second.Token); err != nil {
+		t.Fatalf("authenticate with rotated token: %v", err)
+	}
+	if _, _, err := svc.Authenticate(original); !errors.Is(err, ErrInvalidNodeToken) {
+		t.Fatalf("original token after switch: err = %v, want ErrInvalidNodeToken", err)
+	}
+}
+
+func TestRotateTokenAfterSwitchUsesCurrentToken(t *testing.T) {
+	svc := newTestNodeService(t)
+	node, _, err := svc.RegisterNode("node-1", "1.2.3.4", "", "")
+	if err != nil {
+		t.Fatalf("register node: %v", err)
+	}
+	grace := 1
+	first, err := svc.RotateToken(node.ID, &grace)
+	if err != nil {
+		t.Fatalf("first rotation: %v", err)
+	}
+	if _, _, err := svc.Authenticate(first.Token); err != nil {
+		t.Fatalf("authenticate with first token: %v", err)
+	}
+	second, err := svc.RotateToken(node.ID, &grace)
+	if err != nil {
+		t.Fatalf("second rotation: %v", err)
+	}
+	_, next, err := svc.Authenticate(first.Token)
+	if err != nil {
+		t.Fatalf("authenticate with first token after second rotation: %v", err)
+	}
+	if next != second.Token {
+		t.Fatalf("pending token = %q, want the second rotated token", next)
+	}
+}
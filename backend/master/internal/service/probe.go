@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+const (
+	defaultProbeInterval  = 5 * time.Minute
+	defaultProbeTimeout   = 3 * time.Second
+	defaultProbeThreshold = 3
+	defaultProbeRetention = 7 * 24 * time.Hour
+	probeConcurrency      = 16
+	maxProbeHistoryRange  = 31 * 24 * time.Hour
+	defaultProbeHistory   = 500
+	maxProbeHistoryLimit  = 5000
+)
+
+// ProbeSettings 探测相关系统配置，Interval 为 0 表示关闭探测。
+type ProbeSettings struct {
+	Interval   time.Duration
+	Timeout    time.Duration
+	Threshold  int
+	FromAgents bool
+	Retention  time.Duration
+}
+
+// ProbeTarget 下发给 Agent 的探测目标。
+type ProbeTarget struct {
+	InstanceID uint   `json:"instance_id"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+}
+
+// ProbeTargets Agent 协助探测的任务，Enabled 为 false 时 Agent 应稍后再查询。
+type ProbeTargets struct {
+	Enabled         bool          `json:"enabled"`
+	IntervalSeconds int           `json:"interval_seconds"`
+	TimeoutMs       int64         `json:"timeout_ms"`
+	Targets         []ProbeTarget `json:"targets"`
+}
+
+// ProbeResult Agent 上报的单次探测结果，Host 为探测的目标地址。
+type ProbeResult struct {
+	InstanceID uint   `json:"instance_id"`
+	Host       string `json:"host"`
+	Reachable  bool   `json:"reachable"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error"`
+}
+
+// ProbeSourceSummary 单个探测来源对单个地址在时间范围内的统计。
+type ProbeSourceSummary struct {
+	Source           string  `json:"source"`
+	Address          string  `json:"address"`
+	Probes           int     `json:"probes"`
+	Reachable        int     `json:"reachable"`
+	ReachablePercent float64 `json:"reachable_percent"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// ProbeHistory 实例的可达性与探测记录。
+type ProbeHistory struct {
+	InstanceID         uint                  `json:"instance_id"`
+	Reachability       string                `json:"reachability"`
+	LastProbeAt        *time.Time            `json:"last_probe_at"`
+	LastProbeLatencyMs int64                 `json:"last_probe_latency_ms"`
+	From               time.Time             `json:"from"`
+	To                 time.Time             `json:"to"`
+	Sources            []ProbeSourceSummary  `json:"sources"`
+	Probes             []model.InstanceProbe `json:"probes"`
+}
+
+// ProbeService 从 Master（以及可选的其他节点 Agent）探测实例端口的 TCP 可达性。
+// 节点的每个地址都会被探测并单独记录；任一地址可达即视为实例可达，
+// 因此 Master 缺少 IPv6 等单地址故障只体现在探测记录中，不会将实例标记为不可达。
+type ProbeService struct {
+	repo         repository.InstanceProbeRepository
+	instanceRepo repository.InstanceRepository
+	configRepo   repository.SystemConfigRepository
+	logger       *logrus.Logger
+
+	mu      sync.Mutex
+	lastRun time.Time
+}
+
+// NewProbeService 构造函数。
+func NewProbeService(repo repository.InstanceProbeRepository, instanceRepo repository.InstanceRepository, configRepo repository.SystemConfigRepository, logger *logrus.Logger) *ProbeService {
+	return &ProbeService{repo: repo, instanceRepo: instanceRepo, configRepo: configRepo, logger: logger}
+}
+
+// RunDue 距上次探测已超过 probe_interval_seconds 时探测所有实例，由调度器周期调用。
+func (s *ProbeService) RunDue() error {
+	settings := s.settings()
+	if settings.Interval <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	now := time.Now()
+	if !s.lastRun.IsZero() && now.Sub(s.lastRun) < settings.Interval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastRun = now
+	s.mu.Unlock()
+	return s.probeAll(settings)
+}
+
+// probeAll 并发探测所有应当可达的实例的每个节点地址，记录结果并更新可达状态。
+func (s *ProbeService) probeAll(settings ProbeSettings) error {
+	instances, err := s.probeableInstances(0)
+	if err != nil {
+		return err
+	}
+	probes := make([][]model.InstanceProbe, len(instances))
+	sem := make(chan struct{}, probeConcurrency)
+	var wg sync.WaitGroup
+	for i := range instances {
+		inst := &instances[i]
+		endpoints := inst.Node.SortedEndpoints()
+		probes[i] = make([]model.InstanceProbe, len(endpoints))
+		for j, endpoint := range endpoints {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i, j int, address string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				probes[i][j] = probeTCP(inst.ID, address, inst.Port, settings.Timeout)
+			}(i, j, endpoint.Address)
+		}
+	}
+	wg.Wait()
+
+	all := make([]model.InstanceProbe, 0, len(instances))
+	for i := range probes {
+		all = append(all, probes[i]...)
+	}
+	if err := s.repo.CreateProbes(all); err != nil {
+		return fmt.Errorf("save probes: %w", err)
+	}
+	for i := range instances {
+		probe := mergeEndpointProbes(probes[i])
+		s.applyProbe(&instances[i], &probe, settings.Threshold)
+	}
+	return nil
+}
+
+// mergeEndpointProbes 合并同一实例各地址的探测结果：按地址优先级取第一个可达的结果，
+// 全部失败时汇总各地址的错误。
+func mergeEndpointProbes(probes []model.InstanceProbe) model.InstanceProbe {
+	var merged model.InstanceProbe
+	errs := make([]string, 0, len(probes))
+	for _, probe := range probes {
+		if probe.Reachable {
+			return probe
+		}
+		merged = probe
+		errs = append(errs, probe.Address+": "+probe.Error)
+	}
+	merged.Error = truncateProbeError(strings.Join(errs, "; "))
+	return merged
+}
+
+// applyProbe 根据探测结果更新实例可达状态，连续失败达到阈值才标记为不可达。
+func (s *ProbeService) applyProbe(inst *model.SnellInstance, probe *model.InstanceProbe, threshold int) {
+	failures := 0
+	reachability := model.InstanceReachable
+	if !probe.Reachable {
+		failures = inst.ProbeFailures + 1
+		reachability = inst.Reachability
+		if failures >= threshold {
+			reachability = model.InstanceUnreachable
+		}
+	}
+	err := s.repo.UpdateReachability(inst.ID, map[string]interface{}{
+		"reachability":          reachability,
+		"probe_failures":        failures,
+		"last_probe_at":         probe.CreatedAt,
+		"last_probe_latency_ms": probe.LatencyMs,
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("instance_id", inst.ID).Error("update instance reachability failed")
+		return
+	}
+	if reachability != inst.Reachability && reachability != model.InstanceReachabilityUnknown {
+		entry := s.logger.WithFields(logrus.Fields{"instance_id": inst.ID, "node_id": inst.NodeID, "port": inst.Port})
+		if reachability == model.InstanceUnreachable {
+			entry.WithField("error", probe.Error).Warn("instance marked unreachable")
+		} else {
+			entry.Info("instance reachable")
+		}
+	}
+}
+
+// ProbeTargets 返回指定节点 Agent 需要协助探测的其他节点上的实例。
+func (s *ProbeService) ProbeTargets(nodeID uint) (*ProbeTargets, error) {
+	settings := s.settings()
+	result := &ProbeTargets{
+		Enabled:         settings.FromAgents && settings.Interval > 0,
+		IntervalSeconds: int(settings.Interval.Seconds()),
+		TimeoutMs:       settings.Timeout.Milliseconds(),
+		Targets:         []ProbeTarget{},
+	}
+	if !result.Enabled {
+		return result, nil
+	}
+	instances, err := s.probeableInstances(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range instances {
+		for _, endpoint := range inst.Node.SortedEndpoints() {
+			result.Targets = append(result.Targets, ProbeTarget{InstanceID: inst.ID, Host: endpoint.Address, Port: inst.Port})
+		}
+	}
+	return result, nil
+}
+
+// RecordAgentResults 保存 Agent 的探测结果。Agent 结果仅作为参考记录，不改变实例可达状态。
+func (s *ProbeService) RecordAgentResults(nodeID uint, results []ProbeResult) error {
+	if !s.settings().FromAgents {
+		return fmt.Errorf("agent probing is disabled")
+	}
+	instances, err := s.probeableInstances(nodeID)
+	if err != nil {
+		return err
+	}
+	// 实例 -> 允许的地址，旧版 Agent 不上报地址时记为空
+	allowed := make(map[uint]map[string]struct{}, len(instances))
+	for _, inst := range instances {
+		addresses := map[string]struct{}{"": {}}
+		for _, endpoint := range inst.Node.SortedEndpoints() {
+			addresses[endpoint.Address] = struct{}{}
+		}
+		allowed[inst.ID] = addresses
+	}
+	now := time.Now()
+	source := model.ProbeSourceNode(nodeID)
+	probes := make([]model.InstanceProbe, 0, len(results))
+	for _, result := range results {
+		if _, ok := allowed[result.InstanceID][result.Host]; !ok {
+			continue
+		}
+		probes = append(probes, model.InstanceProbe{
+			InstanceID: result.InstanceID,
+			Source:     source,
+			Address:    result.Host,
+			Reachable:  result.Reachable,
+			LatencyMs:  result.LatencyMs,
+			Error:      truncateProbeError(result.Error),
+			CreatedAt:  now,
+		})
+	}
+	return s.repo.CreateProbes(probes)
+}
+
+// History 返回实例在 [from, to) 范围内的探测记录与按来源汇总的统计。
+func (s *ProbeService) History(instanceID uint, from, to time.Time, limit int) (*ProbeHistory, error) {
+	inst, err := s.instanceRepo.GetByID(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("invalid time range")
+	}
+	if to.Sub(from) > maxProbeHistoryRange {
+		return nil, fmt.Errorf("time range cannot exceed %d days", int(maxProbeHistoryRange.Hours()/24))
+	}
+	if limit <= 0 {
+		limit = defaultProbeHistory
+	}
+	if limit > maxProbeHistoryLimit {
+		limit = maxProbeHistoryLimit
+	}
+	probes, err := s.repo.ListProbes(instanceID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &ProbeHistory{
+		InstanceID:         inst.ID,
+		Reachability:       inst.Reachability,
+		LastProbeAt:        inst.LastProbeAt,
+		LastProbeLatencyMs: inst.LastProbeLatencyMs,
+		From:               from,
+		To:                 to,
+		Sources:            summarizeProbes(probes),
+		Probes:             probes,
+	}, nil
+}
+
+// Cleanup 删除超过 probe_retention_days 的探测记录。
+func (s *ProbeService) Cleanup() error {
+	deleted, err := s.repo.DeleteBefore(time.Now().Add(-s.settings().Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.Infof("deleted %d expired instance probes", deleted)
+	}
+	return nil
+}
+
+// probeableInstances 返回在线且不在维护中的节点上未停止的实例，excludeNodeID 非 0 时排除该节点。
+func (s *ProbeService) probeableInstances(excludeNodeID uint) ([]model.SnellInstance, error) {
+	instances, err := s.instanceRepo.List(repository.InstanceFilter{})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := make([]model.SnellInstance, 0, len(instances))
+	for _, inst := range instances {
+		switch {
+		case inst.NodeID == excludeNodeID,
+			inst.Status == "stopped",
+			inst.Node.Status != model.NodeStatusOnline,
+			len(inst.Node.SortedEndpoints()) == 0,
+			inst.Node.InMaintenance(now):
+			continue
+		}
+		result = append(result, inst)
+	}
+	return result, nil
+}
+
+func (s *ProbeService) settings() ProbeSettings {
+	settings := ProbeSettings{
+		Interval:  defaultProbeInterval,
+		Timeout:   defaultProbeTimeout,
+		Threshold: defaultProbeThreshold,
+		Retention: defaultProbeRetention,
+	}
+	configs, err := s.configRepo.GetByKeys([]string{
+		"probe_interval_seconds", "probe_timeout_ms", "probe_failure_threshold", "probe_from_agents", "probe_retention_days",
+	})
+	if err != nil {
+		return settings
+	}
+	if seconds, err := strconv.Atoi(configs["probe_interval_seconds"]); err == nil && seconds >= 0 {
+		settings.Interval = time.Duration(seconds) * time.Second
+	}
+	if ms, err := strconv.Atoi(configs["probe_timeout_ms"]); err == nil && ms > 0 {
+		settings.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	if threshold, err := strconv.Atoi(configs["probe_failure_threshold"]); err == nil && threshold > 0 {
+		settings.Threshold = threshold
+	}
+	settings.FromAgents, _ = strconv.ParseBool(configs["probe_from_agents"])
+	if days, err := strconv.Atoi(configs["probe_retention_days"]); err == nil && days > 0 {
+		settings.Retention = time.Duration(days) * 24 * time.Hour
+	}
+	return settings
+}
+
+func probeTCP(instanceID uint, host string, port int, timeout time.Duration) model.InstanceProbe {
+	probe := model.InstanceProbe{InstanceID: instanceID, Source: model.ProbeSourceMaster, Address: host}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	started := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	probe.CreatedAt = time.Now()
+	if err != nil {
+		probe.Error = truncateProbeError(err.Error())
+		return probe
+	}
+	conn.Close()
+	probe.Reachable = true
+	probe.LatencyMs = time.Since(started).Milliseconds()
+	return probe
+}
+
+// summarizeProbes 按探测来源与地址分组统计，保持首次出现的顺序。
+func summarizeProbes(probes []model.InstanceProbe) []ProbeSourceSummary {
+	type key struct{ source, address string }
+	index := make(map[key]int)
+	summaries := make([]ProbeSourceSummary, 0)
+	latency := make([]int64, 0)
+	for _, probe := range probes {
+		k := key{probe.Source, probe.Address}
+		i, ok := index[k]
+		if !ok {
+			i = len(summaries)
+			index[k] = i
+			summaries = append(summaries, ProbeSourceSummary{Source: probe.Source, Address: probe.Address})
+			latency = append(latency, 0)
+		}
+		summaries[i].Probes++
+		if probe.Reachable {
+			summaries[i].Reachable++
+			latency[i] += probe.LatencyMs
+		}
+	}
+	for i := range summaries {
+		summary := &summaries[i]
+		summary.ReachablePercent = math.Round(ratio(float64(summary.Reachable), float64(summary.Probes))*10000) / 100
+		if summary.Reachable > 0 {
+			summary.AvgLatencyMs = math.Round(float64(latency[i])/float64(summary.Reachable)*100) / 100
+		}
+	}
+	return summaries
+}
+
+// truncateProbeError 限制错误信息长度，Agent 上报的内容不可信。
+func truncateProbeError(message string) string {
+	const maxLen = 255
+	if len(message) <= maxLen {
+		return message
+	}
+	return message[:maxLen]
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+// probeFixture 一个在线节点及其上的一个实例。
+func probeFixture(t *testing.T, port int, addresses ...string) (*repository.Repositories, *ProbeService, *model.SnellInstance) {
+	t.Helper()
+	db, repos := newTestRepos(t)
+	svc := NewProbeService(repos.Probe, repos.Instance, repos.SystemConfig, newTestLogger())
+	node := newTestNode(t, repos, "node-1")
+	if err := db.Model(node).Update("status", model.NodeStatusOnline).Error; err != nil {
+		t.Fatalf("mark node online: %v", err)
+	}
+	endpoints := make([]model.NodeEndpoint, 0, len(addresses))
+	for i, address := range addresses {
+		endpoints = append(endpoints, model.NodeEndpoint{Type: model.DetectEndpointType(address), Address: address, Priority: i})
+	}
+	if err := repos.Node.ReplaceEndpoints(node.ID, endpoints); err != nil {
+		t.Fatalf("ReplaceEndpoints: %v", err)
+	}
+	user := newTestUser(t, repos, "alice")
+	inst := &model.SnellInstance{UserID: user.ID, NodeID: node.ID, Port: port, PSK: "psk", Status: "running"}
+	if err := repos.Instance.Create(inst); err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	return repos, svc, inst
+}
+
+func TestApplyProbeThreshold(t *testing.T) {
+	cases := []struct {
+		name         string
+		threshold    int
+		results      []bool
+		wantState    string
+		wantFailures int
+	}{
+		{"first success", 3, []bool{true}, model.InstanceReachable, 0},
+		{"first failure stays unknown", 3, []bool{false}, model.InstanceReachabilityUnknown, 1},
+		{"below threshold stays reachable", 3, []bool{true, false, false}, model.InstanceReachable, 2},
+		{"reaches threshold", 3, []bool{true, false, false, false}, model.InstanceUnreachable, 3},
+		{"keeps counting past threshold", 3, []bool{false, false, false, false}, model.InstanceUnreachable, 4},
+		{"success resets failures", 3, []bool{false, false, true, false, false}, model.InstanceReachable, 2},
+		{"recovers after unreachable", 2, []bool{false, false, true}, model.InstanceReachable, 0},
+		{"threshold of one", 1, []bool{true, false}, model.InstanceUnreachable, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repos, svc, inst := probeFixture(t, 10000, "1.2.3.4")
+			for i, reachable := range tc.results {
+				current, err := repos.Instance.GetByID(inst.ID)
+				if err != nil {
+					t.Fatalf("GetByID: %v", err)
+				}
+				probe := &model.InstanceProbe{InstanceID: inst.ID, Reachable: reachable, LatencyMs: int64(i), CreatedAt: time.Now()}
+				svc.applyProbe(current, probe, tc.threshold)
+			}
+			got, err := repos.Instance.GetByID(inst.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if got.Reachability != tc.wantState || got.ProbeFailures != tc.wantFailures {
+				t.Fatalf("reachability = %s with %d failures, want %s with %d", got.Reachability, got.ProbeFailures, tc.wantState, tc.wantFailures)
+			}
+			if got.LastProbeAt == nil || got.LastProbeLatencyMs != int64(len(tc.results)-1) {
+				t.Fatalf("last probe = %v / %d ms, want the latest probe recorded", got.LastProbeAt, got.LastProbeLatencyMs)
+			}
+		})
+	}
+}
+
+func TestSummarizeProbes(t *testing.T) {
+	probe := func(source, address string, reachable bool, latency int64) model.InstanceProbe {
+		return model.InstanceProbe{Source: source, Address: address, Reachable: reachable, LatencyMs: latency}
+	}
+	got := summarizeProbes([]model.InstanceProbe{
+		probe("master", "1.2.3.4", true, 10),
+		probe("node:2", "1.2.3.4", false, 0),
+		probe("master", "1.2.3.4", true, 25),
+		probe("master", "2001:db8::1", false, 0),
+		probe("master", "1.2.3.4", false, 0),
+		probe("node:2", "1.2.3.4", true, 7),
+		probe("node:2", "1.2.3.4", false, 0),
+	})
+	want := []ProbeSourceSummary{
+		{Source: "master", Address: "1.2.3.4", Probes: 3, Reachable: 2, ReachablePercent: 66.67, AvgLatencyMs: 17.5},
+		{Source: "node:2", Address: "1.2.3.4", Probes: 3, Reachable: 1, ReachablePercent: 33.33, AvgLatencyMs: 7},
+		{Source: "master", Address: "2001:db8::1", Probes: 1, Reachable: 0, ReachablePercent: 0, AvgLatencyMs: 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("summarizeProbes = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("summary %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got := summarizeProbes(nil); got == nil || len(got) != 0 {
+		t.Fatalf("summarizeProbes(nil) = %#v, want empty slice", got)
+	}
+}
+
+func TestMergeEndpointProbes(t *testing.T) {
+	v4 := model.InstanceProbe{Address: "1.2.3.4", Error: "timeout"}
+	v6 := model.InstanceProbe{Address: "2001:db8::1", Reachable: true, LatencyMs: 12}
+	if got := mergeEndpointProbes([]model.InstanceProbe{v4, v6}); !got.Reachable || got.Address != v6.Address {
+		t.Fatalf("merge = %+v, want the reachable address", got)
+	}
+	v6 = model.InstanceProbe{Address: "2001:db8::1", Error: "refused"}
+	got := mergeEndpointProbes([]model.InstanceProbe{v4, v6})
+	if got.Reachable || got.Error != "1.2.3.4: timeout; 2001:db8::1: refused" {
+		t.Fatalf("merge = %+v, want both errors", got)
+	}
+}
+
+func TestProbeAllEndpoints(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// 127.0.0.2 上没有监听，只有优先级较低的地址可达
+	repos, svc, inst := probeFixture(t, port, "127.0.0.2", "127.0.0.1")
+	settings := ProbeSettings{Timeout: time.Second, Threshold: 1}
+	if err := svc.probeAll(settings); err != nil {
+		t.Fatalf("probeAll: %v", err)
+	}
+	probes, err := repos.Probe.ListProbes(inst.ID, time.Now().Add(-time.Minute), time.Now().Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("ListProbes: %v", err)
+	}
+	byAddress := make(map[string]bool)
+	for _, probe := range probes {
+		byAddress[probe.Address] = probe.Reachable
+	}
+	if len(probes) != 2 || byAddress["127.0.0.2"] || !byAddress["127.0.0.1"] {
+		t.Fatalf("probes = %+v, want one failed and one reachable address", probes)
+	}
+	got, err := repos.Instance.GetByID(inst.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Reachability != model.InstanceReachable {
+		t.Fatalf("reachability = %s, want reachable while any address answers", got.Reachability)
+	}
+
+	ln.Close()
+	if err := svc.probeAll(settings); err != nil {
+		t.Fatalf("probeAll: %v", err)
+	}
+	got, err = repos.Instance.GetByID(inst.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Reachability != model.InstanceUnreachable {
+		t.Fatalf("reachability = %s, want unreachable once every address fails", got.Reachability)
+	}
+}
+
+func TestRecordAgentResultsChecksAddress(t *testing.T) {
+	repos, svc, inst := probeFixture(t, 10000, "1.2.3.4", "2001:db8::1")
+	if err := repos.SystemConfig.Set("probe_from_agents", "true"); err != nil {
+		t.Fatalf("enable agent probing: %v", err)
+	}
+	targets, err := svc.ProbeTargets(inst.NodeID + 1)
+	if err != nil {
+		t.Fatalf("ProbeTargets: %v", err)
+	}
+	hosts := make([]string, 0, len(targets.Targets))
+	for _, target := range targets.Targets {
+		hosts = append(hosts, target.Host)
+	}
+	if strings.Join(hosts, ",") != "1.2.3.4,2001:db8::1" {
+		t.Fatalf("targets = %v, want every node address", hosts)
+	}
+
+	err = svc.RecordAgentResults(inst.NodeID+1, []ProbeResult{
+		{InstanceID: inst.ID, Host: "1.2.3.4", Reachable: true},
+		{InstanceID: inst.ID, Host: "2001:db8::1"},
+		{InstanceID: inst.ID, Host: "9.9.9.9", Reachable: true},
+		{InstanceID: inst.ID},
+	})
+	if err != nil {
+		t.Fatalf("RecordAgentResults: %v", err)
+	}
+	probes, err := repos.Probe.ListProbes(inst.ID, time.Now().Add(-time.Minute), time.Now().Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("ListProbes: %v", err)
+	}
+	for _, probe := range probes {
+		if probe.Address == "9.9.9.9" {
+			t.Fatalf("recorded a probe for an address the node does not have")
+		}
+	}
+	if len(probes) != 3 {
+		t.Fatalf("recorded %d probes, want 3", len(probes))
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+	templatetool "github.com/iwoov/snell-master/backend/master/internal/template"
+)
+
+const (
+	defaultRuleSetPolicy   = "Proxy"
+	defaultRuleSetInterval = 86400
+	maxRuleSetPolicy       = 64
+)
+
+// 规则集名称会出现在 Clash 规则集的本地路径中，只允许字母、数字、下划线与连字符。
+var ruleSetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// RuleSetInput 创建规则集的参数，Policy 为空时为 Proxy，Interval 为 0 时为一天。
+type RuleSetInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Source      string `json:"source"`
+	Behavior    string `json:"behavior"`
+	Content     string `json:"content"`
+	URL         string `json:"url"`
+	Policy      string `json:"policy"`
+	Interval    int    `json:"interval"`
+}
+
+// RuleSetService 管理可被模板引用的规则集。
+type RuleSetService struct {
+	repo         repository.RuleSetRepository
+	templateRepo repository.TemplateRepository
+	logger       *logrus.Logger
+}
+
+// NewRuleSetService 构造函数。
+func NewRuleSetService(repo repository.RuleSetRepository, templateRepo repository.TemplateRepository, logger *logrus.Logger) *RuleSetService {
+	return &RuleSetService{repo: repo, templateRepo: templateRepo, logger: logger}
+}
+
+// CreateRuleSet 创建规则集。
+func (s *RuleSetService) CreateRuleSet(input RuleSetInput) (*model.RuleSet, error) {
+	set := &model.RuleSet{
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+		Source:      input.Source,
+		Behavior:    input.Behavior,
+		Content:     input.Content,
+		URL:         strings.TrimSpace(input.URL),
+		Policy:      input.Policy,
+		Interval:    input.Interval,
+	}
+	if err := normalizeRuleSet(set); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// UpdateRuleSet 编辑规则集，被模板引用时不能改名。
+func (s *RuleSetService) UpdateRuleSet(id uint, updates map[string]interface{}) (*model.RuleSet, error) {
+	set, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := updates["name"].(string); ok && strings.TrimSpace(name) != set.Name {
+		if err := s.ensureUnreferenced(set.Name); err != nil {
+			return nil, err
+		}
+		set.Name = strings.TrimSpace(name)
+	}
+	if desc, ok := updates["description"].(string); ok {
+		set.Description = desc
+	}
+	if source, ok := updates["source"].(string); ok {
+		set.Source = source
+	}
+	if behavior, ok := updates["behavior"].(string); ok {
+		set.Behavior = behavior
+	}
+	if content, ok := updates["content"].(string); ok {
+		set.Content = content
+	}
+	if rawURL, ok := updates["url"].(string); ok {
+		set.URL = strings.TrimSpace(rawURL)
+	}
+	if policy, ok := updates["policy"].(string); ok {
+		set.Policy = policy
+	}
+	if interval, ok := getInt(updates["interval"]); ok {
+		set.Interval = interval
+	}
+	if err := normalizeRuleSet(set); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// DeleteRuleSet 删除规则集，被模板引用时拒绝删除。
+func (s *RuleSetService) DeleteRuleSet(id uint) error {
+	set, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureUnreferenced(set.Name); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// GetRuleSet 返回规则集。
+func (s *RuleSetService) GetRuleSet(id uint) (*model.RuleSet, error) {
+	return s.repo.GetByID(id)
+}
+
+// ListRuleSets 返回全部规则集。
+func (s *RuleSetService) ListRuleSets() ([]model.RuleSet, error) {
+	return s.repo.List()
+}
+
+// ensureUnreferenced 改名或删除前确认没有模板引用该规则集，否则这些模板会渲染失败。
+func (s *RuleSetService) ensureUnreferenced(name string) error {
+	templates, err := s.templateRepo.List()
+	if err != nil {
+		return err
+	}
+	var users []string
+	for _, tpl := range templates {
+		names, err := templatetool.RuleSetNames(tpl.Content)
+		if err != nil {
+			s.logger.WithError(err).WithField("template_id", tpl.ID).Warn("parse template for rule set references failed")
+			continue
+		}
+		for _, referenced := range names {
+			if referenced == name {
+				users = append(users, tpl.Name)
+				break
+			}
+		}
+	}
+	if len(users) > 0 {
+		return fmt.Errorf("rule set %s is used by templates: %s", name, strings.Join(users, ", "))
+	}
+	return nil
+}
+
+// normalizeRuleSet 补全默认值并校验字段；inline 规则集只保存规则行，remote 规则集只保存地址。
+func normalizeRuleSet(set *model.RuleSet) error {
+	if !ruleSetNamePattern.MatchString(set.Name) {
+		return fmt.Errorf("name must be 1-64 letters, digits, '_' or '-'")
+	}
+	set.Policy = strings.TrimSpace(set.Policy)
+	if set.Policy == "" {
+		set.Policy = defaultRuleSetPolicy
+	}
+	if len(set.Policy) > maxRuleSetPolicy || strings.ContainsAny(set.Policy, ",\r\n") {
+		return fmt.Errorf("policy must be at most %d characters without commas", maxRuleSetPolicy)
+	}
+	if set.Source == "" {
+		set.Source = model.RuleSetSourceInline
+	}
+	switch set.Source {
+	case model.RuleSetSourceInline:
+		if err := templatetool.ValidateRules(set.Content); err != nil {
+			return err
+		}
+		set.Behavior = model.RuleSetBehaviorClassical
+		set.URL = ""
+	case model.RuleSetSourceRemote:
+		u, err := url.Parse(set.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.ContainsAny(set.URL, ", \r\n") {
+			return fmt.Errorf("url must be an http(s) address without commas or spaces")
+		}
+		if set.Behavior == "" {
+			set.Behavior = model.RuleSetBehaviorClassical
+		}
+		switch set.Behavior {
+		case model.RuleSetBehaviorClassical, model.RuleSetBehaviorDomain, model.RuleSetBehaviorIPCIDR:
+		default:
+			return fmt.Errorf("unsupported behavior %s", set.Behavior)
+		}
+		set.Content = ""
+	default:
+		return fmt.Errorf("unsupported source %s", set.Source)
+	}
+	if set.Interval <= 0 {
+		set.Interval = defaultRuleSetInterval
+	}
+	return nil
+}
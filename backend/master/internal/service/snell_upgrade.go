@@ -0,0 +1,220 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+// SnellPinRequest 固定 Snell 版本：All 为真时修改全局 snell_version，否则只固定 NodeIDs 的版本。
+type SnellPinRequest struct {
+	Version string `json:"version"`
+	NodeIDs []uint `json:"node_ids"`
+	All     bool   `json:"all"`
+}
+
+// SnellUpgradeReport Agent 上报的 Snell 升级进度。
+type SnellUpgradeReport struct {
+	Version string
+	Status  string
+	Done    int
+	Total   int
+	Message string
+}
+
+// SnellNodeProgress 单个节点的 Snell 版本与升级进度。
+type SnellNodeProgress struct {
+	NodeID         uint       `json:"node_id"`
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	SnellVersion   string     `json:"snell_version"`
+	TargetVersion  string     `json:"target_version"`
+	Pinned         bool       `json:"pinned"`
+	UpgradeStatus  string     `json:"upgrade_status"`
+	UpgradeMessage string     `json:"upgrade_message"`
+	UpgradeDone    int        `json:"upgrade_done"`
+	UpgradeTotal   int        `json:"upgrade_total"`
+	UpgradeAt      *time.Time `json:"upgrade_at"`
+}
+
+// SnellUpgradeService 管理 Snell Server 版本固定、校验和与升级进度。
+type SnellUpgradeService struct {
+	repo      repository.SnellReleaseRepository
+	nodeRepo  repository.NodeRepository
+	configSvc *SystemConfigService
+	logger    *logrus.Logger
+}
+
+// NewSnellUpgradeService 构造函数。
+func NewSnellUpgradeService(repo repository.SnellReleaseRepository, nodeRepo repository.NodeRepository, configSvc *SystemConfigService, logger *logrus.Logger) *SnellUpgradeService {
+	return &SnellUpgradeService{repo: repo, nodeRepo: nodeRepo, configSvc: configSvc, logger: logger}
+}
+
+// PublishChecksums 登记某个 Snell 版本各架构压缩包的 SHA-256。
+func (s *SnellUpgradeService) PublishChecksums(version string, checksums map[string]string) (*ReleaseSummary, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return nil, fmt.Errorf("version is required")
+	}
+	normalized, err := normalizeChecksums(checksums)
+	if err != nil {
+		return nil, err
+	}
+	releases := make([]model.SnellRelease, 0, len(normalized))
+	for arch, sum := range normalized {
+		releases = append(releases, model.SnellRelease{Version: version, Arch: arch, SHA256: sum})
+	}
+	if err := s.repo.Save(releases); err != nil {
+		return nil, err
+	}
+	saved, err := s.repo.ListByVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	summaries := summarizeSnellReleases(saved)
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("release not saved")
+	}
+	return &summaries[0], nil
+}
+
+// ListReleases 返回已登记校验和的版本。
+func (s *SnellUpgradeService) ListReleases() ([]ReleaseSummary, error) {
+	releases, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	return summarizeSnellReleases(releases), nil
+}
+
+// DeleteRelease 删除版本校验和，仍被节点固定时拒绝删除。
+func (s *SnellUpgradeService) DeleteRelease(version string) error {
+	nodes, err := s.nodeRepo.List()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if node.SnellTargetVersion == version {
+			return fmt.Errorf("version %s is pinned on node %s", version, node.Name)
+		}
+	}
+	return s.repo.DeleteVersion(version)
+}
+
+// Pin 固定节点或全局的 Snell 版本，version 为空且 All 为假时取消节点的固定版本。
+func (s *SnellUpgradeService) Pin(req SnellPinRequest) error {
+	version := strings.TrimSpace(req.Version)
+	if version != "" {
+		releases, err := s.repo.ListByVersion(version)
+		if err != nil {
+			return err
+		}
+		if len(releases) == 0 {
+			return fmt.Errorf("no checksums published for snell %s", version)
+		}
+	}
+
+	if req.All {
+		if version == "" {
+			return fmt.Errorf("version is required when all is true")
+		}
+		if err := s.configSvc.UpdateSnellVersion(version); err != nil {
+			return err
+		}
+		return s.repo.ClearNodeTargets()
+	}
+
+	if len(req.NodeIDs) == 0 {
+		return fmt.Errorf("node_ids is required when all is false")
+	}
+	for _, id := range req.NodeIDs {
+		if _, err := s.nodeRepo.GetByID(id); err != nil {
+			return fmt.Errorf("node %d not found", id)
+		}
+	}
+	if err := s.repo.SetNodeTargets(req.NodeIDs, version); err != nil {
+		return err
+	}
+	s.logger.Infof("snell version pinned on %d nodes: version=%q", len(req.NodeIDs), version)
+	return nil
+}
+
+// ConfigForNode 返回节点目标版本的下载地址与校验和。
+func (s *SnellUpgradeService) ConfigForNode(node *model.Node) (*SnellConfig, error) {
+	cfg, err := s.configSvc.GetSnellConfigForVersion(node.SnellTargetVersion)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := s.repo.ListByVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Checksums = make(map[string]string, len(releases))
+	for _, release := range releases {
+		cfg.Checksums[release.Arch] = release.SHA256
+	}
+	return cfg, nil
+}
+
+// ReportProgress 记录 Agent 上报的升级进度。
+func (s *SnellUpgradeService) ReportProgress(nodeID uint, report SnellUpgradeReport) error {
+	switch report.Status {
+	case model.SnellUpgradeUpgrading, model.SnellUpgradeSucceeded, model.SnellUpgradeFailed, model.SnellUpgradeRolledBack:
+	default:
+		return fmt.Errorf("unsupported upgrade status %q", report.Status)
+	}
+	message := report.Message
+	if report.Version != "" {
+		message = strings.TrimSpace(fmt.Sprintf("%s %s", report.Version, message))
+	}
+	if report.Status == model.SnellUpgradeFailed || report.Status == model.SnellUpgradeRolledBack {
+		s.logger.Warnf("snell upgrade on node %d %s: %s", nodeID, report.Status, message)
+	}
+	return s.repo.UpdateNodeProgress(nodeID, report.Status, message, report.Done, report.Total)
+}
+
+// Progress 返回所有节点的 Snell 版本与升级进度。
+func (s *SnellUpgradeService) Progress() ([]SnellNodeProgress, error) {
+	nodes, err := s.nodeRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	global, err := s.configSvc.GetSnellConfigForVersion("")
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SnellNodeProgress, 0, len(nodes))
+	for _, node := range nodes {
+		target := node.SnellTargetVersion
+		if target == "" {
+			target = global.Version
+		}
+		result = append(result, SnellNodeProgress{
+			NodeID:         node.ID,
+			Name:           node.Name,
+			Status:         node.Status,
+			SnellVersion:   node.SnellVersion,
+			TargetVersion:  target,
+			Pinned:         node.SnellTargetVersion != "",
+			UpgradeStatus:  node.SnellUpgradeStatus,
+			UpgradeMessage: node.SnellUpgradeMessage,
+			UpgradeDone:    node.SnellUpgradeDone,
+			UpgradeTotal:   node.SnellUpgradeTotal,
+			UpgradeAt:      node.SnellUpgradeAt,
+		})
+	}
+	return result, nil
+}
+
+func summarizeSnellReleases(releases []model.SnellRelease) []ReleaseSummary {
+	var builder releaseSummaryBuilder
+	for _, release := range releases {
+		builder.add(release.Version, release.Arch, release.SHA256, release.CreatedAt)
+	}
+	return builder.result()
+}
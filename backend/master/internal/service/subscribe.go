@@ -3,6 +3,8 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -11,145 +13,282 @@ import (
 	"github.com/iwoov/snell-master/backend/master/internal/model"
 	"github.com/iwoov/snell-master/backend/master/internal/repository"
 	templatetool "github.com/iwoov/snell-master/backend/master/internal/template"
-	"github.com/iwoov/snell-master/pkg/utils"
 )
 
+// defaultSubscribeUpdateInterval 未配置时客户端自动更新订阅的间隔（秒）。
+const defaultSubscribeUpdateInterval = 86400
+
 // SubscribeService 处理订阅令牌。
 type SubscribeService struct {
 	repo         repository.SubscribeRepository
+	accessRepo   repository.SubscribeAccessRepository
 	templateRepo repository.TemplateRepository
 	userRepo     repository.UserRepository
 	nodeRepo     repository.NodeRepository
 	instanceRepo repository.InstanceRepository
+	groupRepo    repository.NodeGroupRepository
+	ruleSetRepo  repository.RuleSetRepository
+	configRepo   repository.SystemConfigRepository
+	cache        *subscriptionCache
 	logger       *logrus.Logger
 }
 
 // NewSubscribeService 构造函数。
-func NewSubscribeService(repo repository.SubscribeRepository, templateRepo repository.TemplateRepository, userRepo repository.UserRepository, nodeRepo repository.NodeRepository, instanceRepo repository.InstanceRepository, logger *logrus.Logger) *SubscribeService {
+func NewSubscribeService(repo repository.SubscribeRepository, accessRepo repository.SubscribeAccessRepository, templateRepo repository.TemplateRepository, userRepo repository.UserRepository, nodeRepo repository.NodeRepository, instanceRepo repository.InstanceRepository, groupRepo repository.NodeGroupRepository, ruleSetRepo repository.RuleSetRepository, configRepo repository.SystemConfigRepository, logger *logrus.Logger) *SubscribeService {
 	return &SubscribeService{
 		repo:         repo,
+		accessRepo:   accessRepo,
 		templateRepo: templateRepo,
 		userRepo:     userRepo,
 		nodeRepo:     nodeRepo,
 		instanceRepo: instanceRepo,
+		groupRepo:    groupRepo,
+		ruleSetRepo:  ruleSetRepo,
+		configRepo:   configRepo,
+		cache:        newSubscriptionCache(),
 		logger:       logger,
 	}
 }
 
-// CreateToken 创建订阅令牌，如果已存在则返回旧令牌。
-func (s *SubscribeService) CreateToken(userID uint, templateID *uint, expiresAt *time.Time) (*model.SubscribeToken, error) {
-	if existing, err := s.repo.GetByUser(userID); err == nil {
-		return existing, nil
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, err
-	}
-	token, err := utils.GenerateSubscribeToken()
+// ListTokens 返回全部令牌。
+func (s *SubscribeService) ListTokens() ([]model.SubscribeToken, error) {
+	return s.repo.List()
+}
+
+// GetByToken 根据令牌查询。
+func (s *SubscribeService) GetByToken(token string) (*model.SubscribeToken, error) {
+	return s.repo.GetByToken(token)
+}
+
+// ValidateToken 校验令牌有效性。
+func (s *SubscribeService) ValidateToken(token string) (*model.SubscribeToken, error) {
+	sub, err := s.repo.GetByToken(token)
 	if err != nil {
 		return nil, err
 	}
-	sub := &model.SubscribeToken{UserID: userID, Token: token, TemplateID: templateID, ExpiresAt: expiresAt}
-	if err := s.repo.Create(sub); err != nil {
-		return nil, err
+	if !sub.Enabled {
+		return nil, fmt.Errorf("token disabled")
+	}
+	if sub.ExpiresAt != nil && time.Now().After(*sub.ExpiresAt) {
+		return nil, fmt.Errorf("token expired")
 	}
 	return sub, nil
 }
 
-// RegenerateToken 重新生成订阅令牌。
-func (s *SubscribeService) RegenerateToken(userID uint) (string, error) {
-	sub, err := s.repo.GetByUser(userID)
+// SubscriptionRequest 一次订阅请求，Target 为请求显式指定的格式。
+// ClientIP 用于泄露检测，只能来自连接地址或受信任代理转发的地址，不能直接取请求头。
+type SubscriptionRequest struct {
+	Target    string
+	UserAgent string
+	ClientIP  string
+	Options   templatetool.Options
+}
+
+// SubscriptionContent 生成的订阅内容及客户端刷新所需的信息。
+type SubscriptionContent struct {
+	Body        string
+	ContentType string
+	Target      string
+	Filename    string
+	// Userinfo Subscription-Userinfo 头的值
+	Userinfo string
+	// UpdateInterval 客户端自动更新间隔（秒）
+	UpdateInterval int
+	ManagedStrict  bool
+	// MasterURL 对外访问地址，为空时由调用方按请求推断
+	MasterURL string
+	// ETag 内容的哈希，用于条件请求
+	ETag string
+}
+
+// GenerateConfig 根据订阅令牌生成配置。格式依次取请求参数、令牌设置与 User-Agent 识别结果；
+// 令牌绑定的模板格式与之不一致时使用该格式的默认模板。结果在内存中缓存，相关数据变更时失效。
+func (s *SubscribeService) GenerateConfig(token string, req SubscriptionRequest) (*SubscriptionContent, error) {
+	key := subscriptionCacheKey(token, req)
+	if content, ok := s.cachedConfig(key, req); ok {
+		return content, nil
+	}
+	generation := s.cache.generation.Load()
+	sub, err := s.ValidateToken(token)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	target := req.Target
+	if target == "" {
+		target = sub.Target
+	}
+	if target == "" {
+		target = templatetool.DetectTarget(req.UserAgent)
 	}
-	token, err := utils.GenerateSubscribeToken()
+	options := req.Options
+	input, err := s.loadInput(sub.UserID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	sub.Token = token
-	if err := s.repo.Update(sub); err != nil {
-		return "", err
+	if input.User.Status == 0 {
+		return nil, fmt.Errorf("user disabled")
 	}
-	return token, nil
-}
 
-// GetTokenByUser 返回用户订阅令牌。
-func (s *SubscribeService) GetTokenByUser(userID uint) (*model.SubscribeToken, error) {
-	return s.repo.GetByUser(userID)
+	tpl, err := s.resolveTemplate(sub, target)
+	if err != nil {
+		return nil, err
+	}
+	options.Groups = s.groupSettings()
+	generator, err := templatetool.NewGenerator(tpl.Target, tpl.Content, options)
+	if err != nil {
+		return nil, err
+	}
+	body, err := generator.Generate(*input)
+	if err != nil {
+		return nil, err
+	}
+	s.recordAccess(sub, req, tpl.Target)
+	content := &SubscriptionContent{
+		Body:        body,
+		ContentType: generator.ContentType(),
+		Target:      tpl.Target,
+		Filename:    input.User.Username + subscriptionFileExt(tpl.Target),
+		Userinfo:    subscriptionUserinfo(input.User),
+	}
+	s.applyRefreshSettings(content)
+	content.ETag = subscriptionETag(content)
+	s.storeConfig(key, sub, content, generation)
+	return content, nil
 }
 
-// DeleteToken 删除记录。
-func (s *SubscribeService) DeleteToken(id uint) error {
-	return s.repo.Delete(id)
+// applyRefreshSettings 读取订阅更新设置，读取失败时使用默认值。
+func (s *SubscribeService) applyRefreshSettings(content *SubscriptionContent) {
+	content.UpdateInterval = defaultSubscribeUpdateInterval
+	configs, err := s.configRepo.GetByKeys([]string{"subscribe_update_interval", "subscribe_managed_strict", "master_url"})
+	if err != nil {
+		s.logger.WithError(err).Warn("load subscribe settings failed")
+		return
+	}
+	if interval, err := strconv.Atoi(configs["subscribe_update_interval"]); err == nil && interval > 0 {
+		content.UpdateInterval = interval
+	}
+	content.ManagedStrict = configs["subscribe_managed_strict"] == "true"
+	content.MasterURL = strings.TrimRight(configs["master_url"], "/")
 }
 
-// IncrementAccess 记录访问。
-func (s *SubscribeService) IncrementAccess(id uint) error {
-	return s.repo.IncrementAccess(id)
+// groupSettings 读取自动代理组的测速参数，未配置或读取失败的项使用默认值。
+func (s *SubscribeService) groupSettings() templatetool.GroupSettings {
+	var settings templatetool.GroupSettings
+	configs, err := s.configRepo.GetByKeys([]string{"subscribe_group_test_url", "subscribe_group_interval", "subscribe_group_tolerance"})
+	if err != nil {
+		s.logger.WithError(err).Warn("load subscribe group settings failed")
+		return settings
+	}
+	settings.TestURL = strings.TrimSpace(configs["subscribe_group_test_url"])
+	settings.Interval, _ = strconv.Atoi(configs["subscribe_group_interval"])
+	settings.Tolerance, _ = strconv.Atoi(configs["subscribe_group_tolerance"])
+	return settings
 }
 
-// ListTokens 返回全部令牌。
-func (s *SubscribeService) ListTokens() ([]model.SubscribeToken, error) {
-	return s.repo.List()
+// subscriptionUserinfo 按当月用量与流量上限生成 Subscription-Userinfo。
+// 用户计数不区分方向，已用流量全部计入 download。
+func subscriptionUserinfo(user *model.User) string {
+	info := fmt.Sprintf("upload=0; download=%d; total=%d", user.TrafficUsedMonth, user.TrafficLimit)
+	if user.ExpireAt != nil {
+		info += fmt.Sprintf("; expire=%d", user.ExpireAt.Unix())
+	}
+	return info
 }
 
-// GetByToken 根据令牌查询。
-func (s *SubscribeService) GetByToken(token string) (*model.SubscribeToken, error) {
-	return s.repo.GetByToken(token)
+func subscriptionFileExt(target string) string {
+	switch target {
+	case model.TemplateTargetClash, model.TemplateTargetStash:
+		return ".yaml"
+	default:
+		return ".conf"
+	}
 }
 
-// ValidateToken 校验令牌有效性。
-func (s *SubscribeService) ValidateToken(token string) (*model.SubscribeToken, error) {
-	sub, err := s.repo.GetByToken(token)
+// PreviewTemplate 以指定用户的数据渲染未保存的模板内容，不计入订阅访问。
+func (s *SubscribeService) PreviewTemplate(userID uint, target, content string, options templatetool.Options) (*SubscriptionContent, error) {
+	if err := templatetool.Validate(content); err != nil {
+		return nil, err
+	}
+	input, err := s.loadInput(userID)
 	if err != nil {
 		return nil, err
 	}
-	if sub.ExpiresAt != nil && time.Now().After(*sub.ExpiresAt) {
-		return nil, fmt.Errorf("token expired")
+	options.Groups = s.groupSettings()
+	generator, err := templatetool.NewGenerator(target, content, options)
+	if err != nil {
+		return nil, err
 	}
-	return sub, nil
+	body, err := generator.Generate(*input)
+	if err != nil {
+		return nil, err
+	}
+	return &SubscriptionContent{Body: body, ContentType: generator.ContentType(), Target: target}, nil
 }
 
-// GenerateSurgeConfig 根据订阅令牌生成 Surge 配置。
-func (s *SubscribeService) GenerateSurgeConfig(token string) (string, error) {
-	sub, err := s.ValidateToken(token)
+// loadInput 读取渲染订阅所需的用户、可用节点、实例、节点组与规则集。
+func (s *SubscribeService) loadInput(userID uint) (*templatetool.Input, error) {
+	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	user, err := s.userRepo.GetByID(sub.UserID)
+	nodes, err := s.userRepo.GetUserNodes(userID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if user.Status == 0 {
-		return "", fmt.Errorf("user disabled")
+	instances, err := s.instanceRepo.GetByUser(userID)
+	if err != nil {
+		return nil, err
 	}
-	nodes, err := s.userRepo.GetUserNodes(sub.UserID)
+	groups, err := s.groupRepo.List()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	instances, err := s.instanceRepo.GetByUser(sub.UserID)
+	ruleSets, err := s.ruleSetRepo.List()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &templatetool.Input{
+		User:      user,
+		Nodes:     excludeMaintenance(nodes, time.Now()),
+		Instances: instances,
+		Groups:    groups,
+		RuleSets:  ruleSets,
+	}, nil
+}
 
-	var tpl *model.Template
+// resolveTemplate 返回令牌绑定的模板，固定了修订时使用该修订的内容；格式不一致时使用该格式的默认模板。
+func (s *SubscribeService) resolveTemplate(sub *model.SubscribeToken, target string) (*model.Template, error) {
 	if sub.TemplateID != nil {
-		tpl, err = s.templateRepo.GetByID(*sub.TemplateID)
-		if err != nil {
-			return "", err
+		tpl, err := s.templateRepo.GetByID(*sub.TemplateID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if tpl != nil && sub.RevisionID != nil {
+			rev, err := s.templateRepo.GetRevisionByID(*sub.RevisionID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if rev != nil && rev.TemplateID == tpl.ID && rev.Target == target {
+				pinned := *tpl
+				pinned.Name, pinned.Target, pinned.Content = rev.Name, rev.Target, rev.Content
+				return &pinned, nil
+			}
 		}
-	} else {
-		tpl, err = s.templateRepo.GetDefault()
-		if err != nil {
-			return "", err
+		if tpl != nil && tpl.Target == target {
+			return tpl, nil
 		}
 	}
-
-	generator := templatetool.NewSurgeGenerator(tpl.Content)
-	content, err := generator.Generate(user, nodes, instances)
-	if err != nil {
-		return "", err
+	tpl, err := s.templateRepo.GetDefault(target)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no default %s template", target)
 	}
-	if err := s.repo.IncrementAccess(sub.ID); err != nil {
-		s.logger.WithError(err).Warn("increment subscribe access failed")
+	return tpl, err
+}
+
+// truncate 按字节截断字符串，去掉被截断的半个字符。
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
 	}
-	return content, nil
+	return strings.ToValidUTF8(value[:limit], "")
 }
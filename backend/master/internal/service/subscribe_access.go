@@ -0,0 +1,228 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+const (
+	defaultLeakWindow         = 24 * time.Hour
+	defaultLeakMaxIPs         = 30
+	defaultLeakMaxNetworks    = 15
+	defaultAccessLogRetention = 30 * 24 * time.Hour
+	defaultAccessLogLimit     = 200
+	maxAccessLogLimit         = 2000
+)
+
+// LeakSettings 订阅泄露检测配置，MaxIPs 或 MaxNetworks 为 0 表示不按该项检测。
+type LeakSettings struct {
+	Window      time.Duration
+	MaxIPs      int
+	MaxNetworks int
+	AutoRotate  bool
+	Retention   time.Duration
+}
+
+// FlaggedToken 被标记的令牌及其当前窗口内的访问来源统计。
+type FlaggedToken struct {
+	model.SubscribeToken
+	Requests int64 `json:"requests"`
+	IPs      int64 `json:"ips"`
+	Networks int64 `json:"networks"`
+}
+
+// recordAccess 记录一次成功的订阅拉取并检查令牌是否疑似泄露，失败只记日志不影响本次响应。
+// 同一令牌与 IP 的频繁拉取按 accessRecordInterval 合并，避免每次请求都写数据库。
+func (s *SubscribeService) recordAccess(sub *model.SubscribeToken, req SubscriptionRequest, target string) {
+	if !s.cache.shouldRecord(strconv.FormatUint(uint64(sub.ID), 10)+"\x00"+req.ClientIP, time.Now()) {
+		return
+	}
+	userAgent := truncate(req.UserAgent, 255)
+	if err := s.repo.IncrementAccess(sub.ID, req.ClientIP, userAgent); err != nil {
+		s.logger.WithError(err).Warn("increment subscribe access failed")
+	}
+	entry := &model.SubscribeAccessLog{
+		TokenID:   sub.ID,
+		UserID:    sub.UserID,
+		IP:        req.ClientIP,
+		Network:   ipNetwork(req.ClientIP),
+		UserAgent: userAgent,
+		Target:    target,
+	}
+	if err := s.accessRepo.Create(entry); err != nil {
+		s.logger.WithError(err).Warn("record subscribe access failed")
+		return
+	}
+	if err := s.detectLeak(sub.ID); err != nil {
+		s.logger.WithError(err).WithField("token_id", sub.ID).Warn("subscribe leak detection failed")
+	}
+}
+
+// detectLeak 统计窗口内（重新生成令牌之后）的访问来源，超过阈值时标记令牌并按配置自动重新生成。
+// 已标记且此后未重新生成的令牌不再重复检测。令牌按 ID 重新读取，缓存命中时持有的渲染快照
+// 可能早于最近一次标记或重新生成，据此判断会重复标记或再次重新生成。
+func (s *SubscribeService) detectLeak(tokenID uint) error {
+	sub, err := s.repo.GetByID(tokenID)
+	if err != nil {
+		return err
+	}
+	if sub.FlaggedAt != nil && (sub.RotatedAt == nil || !sub.RotatedAt.After(*sub.FlaggedAt)) {
+		return nil
+	}
+	settings := s.leakSettings()
+	if settings.MaxIPs == 0 && settings.MaxNetworks == 0 {
+		return nil
+	}
+	now := time.Now()
+	stats, err := s.accessRepo.Stats(sub.ID, leakWindowStart(sub, settings, now))
+	if err != nil {
+		return err
+	}
+	reason := leakReason(stats, settings)
+	if reason == "" {
+		return nil
+	}
+	if err := s.repo.SetFlag(sub.ID, &now, reason); err != nil {
+		return err
+	}
+	s.logger.WithFields(logrus.Fields{"token_id": sub.ID, "user_id": sub.UserID, "reason": reason}).Warn("subscribe token flagged as leaked")
+	if !settings.AutoRotate {
+		return nil
+	}
+	if _, err := s.RegenerateToken(sub.ID); err != nil {
+		return fmt.Errorf("rotate flagged token: %w", err)
+	}
+	s.logger.WithFields(logrus.Fields{"token_id": sub.ID, "user_id": sub.UserID}).Info("flagged subscribe token rotated")
+	return nil
+}
+
+// leakReason 返回超过的阈值说明，未超过时为空。
+func leakReason(stats *repository.SubscribeAccessStats, settings LeakSettings) string {
+	window := fmt.Sprintf("%gh", settings.Window.Hours())
+	switch {
+	case settings.MaxIPs > 0 && stats.IPs > int64(settings.MaxIPs):
+		return fmt.Sprintf("fetched from %d distinct IPs within %s (limit %d)", stats.IPs, window, settings.MaxIPs)
+	case settings.MaxNetworks > 0 && stats.Networks > int64(settings.MaxNetworks):
+		return fmt.Sprintf("fetched from %d distinct networks within %s (limit %d)", stats.Networks, window, settings.MaxNetworks)
+	}
+	return ""
+}
+
+func leakWindowStart(sub *model.SubscribeToken, settings LeakSettings, now time.Time) time.Time {
+	since := now.Add(-settings.Window)
+	if sub.RotatedAt != nil && sub.RotatedAt.After(since) {
+		since = *sub.RotatedAt
+	}
+	return since
+}
+
+// ipNetwork 返回 IP 所在网段（IPv4 /24、IPv6 /48），用于近似区分来源网络；
+// 未内置 ASN 数据库，同一运营商的不同网段会被视为不同来源，移动用户切换基站时尤为常见，阈值需留有余量。
+func ipNetwork(value string) string {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return value
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// ListFlaggedTokens 返回被标记的令牌及当前窗口内的访问来源统计。
+func (s *SubscribeService) ListFlaggedTokens() ([]FlaggedToken, error) {
+	tokens, err := s.repo.ListFlagged()
+	if err != nil {
+		return nil, err
+	}
+	settings := s.leakSettings()
+	now := time.Now()
+	result := make([]FlaggedToken, 0, len(tokens))
+	for i := range tokens {
+		stats, err := s.accessRepo.Stats(tokens[i].ID, leakWindowStart(&tokens[i], settings, now))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, FlaggedToken{
+			SubscribeToken: tokens[i],
+			Requests:       stats.Requests,
+			IPs:            stats.IPs,
+			Networks:       stats.Networks,
+		})
+	}
+	return result, nil
+}
+
+// ClearFlag 清除令牌的泄露标记；未重新生成时若访问来源仍超过阈值，下次拉取会再次标记。
+func (s *SubscribeService) ClearFlag(id uint) (*model.SubscribeToken, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetFlag(id, nil, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+// ListAccessLogs 返回令牌最近的访问记录，limit 超出范围时使用默认值或上限。
+func (s *SubscribeService) ListAccessLogs(tokenID uint, limit int) ([]model.SubscribeAccessLog, error) {
+	if _, err := s.repo.GetByID(tokenID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultAccessLogLimit
+	}
+	if limit > maxAccessLogLimit {
+		limit = maxAccessLogLimit
+	}
+	return s.accessRepo.ListByToken(tokenID, limit)
+}
+
+// CleanupAccessLogs 删除超过保留期的访问记录。
+func (s *SubscribeService) CleanupAccessLogs() error {
+	deleted, err := s.accessRepo.DeleteBefore(time.Now().Add(-s.leakSettings().Retention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		s.logger.Infof("deleted %d expired subscribe access logs", deleted)
+	}
+	return nil
+}
+
+func (s *SubscribeService) leakSettings() LeakSettings {
+	settings := LeakSettings{
+		Window:      defaultLeakWindow,
+		MaxIPs:      defaultLeakMaxIPs,
+		MaxNetworks: defaultLeakMaxNetworks,
+		Retention:   defaultAccessLogRetention,
+	}
+	configs, err := s.configRepo.GetByKeys([]string{
+		"subscribe_leak_window_hours", "subscribe_leak_max_ips", "subscribe_leak_max_networks",
+		"subscribe_leak_auto_rotate", "subscribe_access_log_retention_days",
+	})
+	if err != nil {
+		return settings
+	}
+	if hours, err := strconv.Atoi(configs["subscribe_leak_window_hours"]); err == nil && hours > 0 {
+		settings.Window = time.Duration(hours) * time.Hour
+	}
+	if maxIPs, err := strconv.Atoi(configs["subscribe_leak_max_ips"]); err == nil && maxIPs >= 0 {
+		settings.MaxIPs = maxIPs
+	}
+	if maxNetworks, err := strconv.Atoi(configs["subscribe_leak_max_networks"]); err == nil && maxNetworks >= 0 {
+		settings.MaxNetworks = maxNetworks
+	}
+	settings.AutoRotate, _ = strconv.ParseBool(configs["subscribe_leak_auto_rotate"])
+	if days, err := strconv.Atoi(configs["subscribe_access_log_retention_days"]); err == nil && days > 0 {
+		settings.Retention = time.Duration(days) * 24 * time.Hour
+	}
+	return settings
+}
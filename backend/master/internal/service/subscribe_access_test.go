@@ -0,0 +1,172 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+func TestIPNetwork(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want string
+	}{
+		{"1.2.3.4", "1.2.3.0/24"},
+		{"1.2.3.255", "1.2.3.0/24"},
+		{"::ffff:1.2.3.4", "1.2.3.0/24"},
+		{"2001:db8:1:2::1", "2001:db8:1::/48"},
+		{"2001:db8:1:ffff::1", "2001:db8:1::/48"},
+		{"not-an-ip", "not-an-ip"},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := ipNetwork(tc.ip); got != tc.want {
+			t.Errorf("ipNetwork(%q) = %q, want %q", tc.ip, got, tc.want)
+		}
+	}
+}
+
+func TestLeakWindowStart(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	settings := LeakSettings{Window: 24 * time.Hour}
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+	cases := []struct {
+		name    string
+		rotated *time.Time
+		want    time.Time
+	}{
+		{"never rotated", nil, now.Add(-24 * time.Hour)},
+		{"rotated before window", at(-48 * time.Hour), now.Add(-24 * time.Hour)},
+		{"rotated at window start", at(-24 * time.Hour), now.Add(-24 * time.Hour)},
+		{"rotated inside window", at(-time.Hour), now.Add(-time.Hour)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sub := &model.SubscribeToken{RotatedAt: tc.rotated}
+			if got := leakWindowStart(sub, settings, now); !got.Equal(tc.want) {
+				t.Fatalf("leakWindowStart = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+// leakFixture 创建一个令牌并设置泄露检测阈值：最多 2 个 IP，不按网段检测。
+func leakFixture(t *testing.T, autoRotate bool) (*repository.Repositories, *SubscribeService, *model.SubscribeToken) {
+	t.Helper()
+	_, repos := newTestRepos(t)
+	svc := newTestSubscribeService(repos)
+	for key, value := range map[string]string{
+		"subscribe_leak_max_ips":      "2",
+		"subscribe_leak_max_networks": "0",
+		"subscribe_leak_auto_rotate":  strconv.FormatBool(autoRotate),
+	} {
+		if err := repos.SystemConfig.Set(key, value); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+	user := newTestUser(t, repos, "leak")
+	sub, err := svc.CreateToken(user.ID, SubscribeTokenInput{})
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	return repos, svc, sub
+}
+
+// logAccesses 为令牌写入来自 n 个不同 IP（不同网段）的访问记录。
+func logAccesses(t *testing.T, repos *repository.Repositories, sub *model.SubscribeToken, n int, at time.Time) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		ip := fmt.Sprintf("10.0.%d.1", i)
+		entry := &model.SubscribeAccessLog{TokenID: sub.ID, UserID: sub.UserID, IP: ip, Network: ipNetwork(ip), CreatedAt: at}
+		if err := repos.SubscribeLog.Create(entry); err != nil {
+			t.Fatalf("create access log: %v", err)
+		}
+	}
+}
+
+func TestDetectLeak(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+	cases := []struct {
+		name        string
+		autoRotate  bool
+		flagged     *time.Time
+		rotated     *time.Time
+		ips         int
+		accessAt    time.Time
+		wantFlagged bool
+		wantRotated bool
+	}{
+		{"within limit", false, nil, nil, 2, now, false, false},
+		{"over ip limit", false, nil, nil, 3, now, true, false},
+		{"over ip limit with auto rotate", true, nil, nil, 3, now, true, true},
+		{"already flagged", true, at(-time.Minute), nil, 3, now, false, false},
+		{"flagged before last rotation", false, at(-2 * time.Hour), at(-time.Hour), 3, now, true, false},
+		{"accesses before rotation ignored", false, nil, at(-time.Minute), 3, now.Add(-10 * time.Minute), false, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repos, svc, sub := leakFixture(t, tc.autoRotate)
+			if tc.rotated != nil {
+				sub.RotatedAt = tc.rotated
+				if err := repos.Subscribe.Update(sub); err != nil {
+					t.Fatalf("set rotated_at: %v", err)
+				}
+			}
+			if tc.flagged != nil {
+				if err := repos.Subscribe.SetFlag(sub.ID, tc.flagged, "earlier"); err != nil {
+					t.Fatalf("set flag: %v", err)
+				}
+			}
+			logAccesses(t, repos, sub, tc.ips, tc.accessAt)
+
+			if err := svc.detectLeak(sub.ID); err != nil {
+				t.Fatalf("detectLeak: %v", err)
+			}
+			got, err := repos.Subscribe.GetByID(sub.ID)
+			if err != nil {
+				t.Fatalf("get token: %v", err)
+			}
+			flagged := got.FlaggedAt != nil && (tc.flagged == nil || got.FlaggedAt.After(*tc.flagged))
+			if flagged != tc.wantFlagged {
+				t.Fatalf("newly flagged = %v (flagged_at %v, reason %q), want %v", flagged, got.FlaggedAt, got.FlagReason, tc.wantFlagged)
+			}
+			if rotated := got.Token != sub.Token; rotated != tc.wantRotated {
+				t.Fatalf("rotated = %v, want %v", rotated, tc.wantRotated)
+			}
+		})
+	}
+}
+
+func TestRecordAccessReloadsStaleSnapshot(t *testing.T) {
+	repos, svc, sub := leakFixture(t, false)
+	snapshot := *sub // 渲染时缓存的令牌，此后未失效
+	logAccesses(t, repos, sub, 3, time.Now())
+	if err := svc.detectLeak(sub.ID); err != nil {
+		t.Fatalf("detectLeak: %v", err)
+	}
+	flagged, err := repos.Subscribe.GetByID(sub.ID)
+	if err != nil || flagged.FlaggedAt == nil {
+		t.Fatalf("token not flagged: %+v, %v", flagged, err)
+	}
+
+	// 缓存命中时以旧快照记录访问，不应按未标记的快照再次标记
+	svc.recordAccess(&snapshot, SubscriptionRequest{ClientIP: "10.9.0.1"}, model.TemplateTargetSurge)
+	got, err := repos.Subscribe.GetByID(sub.ID)
+	if err != nil {
+		t.Fatalf("get token: %v", err)
+	}
+	if got.FlaggedAt == nil || !got.FlaggedAt.Equal(*flagged.FlaggedAt) {
+		t.Fatalf("flagged_at = %v, want unchanged %v", got.FlaggedAt, flagged.FlaggedAt)
+	}
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	templatetool "github.com/iwoov/snell-master/backend/master/internal/template"
+)
+
+const (
+	defaultSubscribeCacheTTL  = time.Minute
+	defaultSubscribeTokenRate = 30
+	defaultSubscribeIPRate    = 60
+	subscribeRateWindow       = time.Minute
+	// accessRecordInterval 同一令牌与 IP 的访问在该间隔内只记录一次，泄露检测只关心不同来源
+	accessRecordInterval = time.Minute
+	// subscribeSettingsTTL 未注册变更监听时缓存设置的最长时间
+	subscribeSettingsTTL = 30 * time.Second
+	subscribeCacheSweep  = time.Minute
+)
+
+// ErrRateLimited 订阅请求超过频率限制。
+var ErrRateLimited = errors.New("too many requests")
+
+// RateLimitError 携带客户端应等待的时间。
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error()
+}
+
+// Unwrap 使 errors.Is(err, ErrRateLimited) 成立。
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// subscribeCacheSettings 订阅缓存与限流配置，Rate 为每分钟请求数，0 表示不限制。
+type subscribeCacheSettings struct {
+	TTL       time.Duration
+	TokenRate int
+	IPRate    int
+}
+
+type renderCacheEntry struct {
+	sub        *model.SubscribeToken
+	content    *SubscriptionContent
+	generation uint64
+	expiresAt  time.Time
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// subscriptionCache 订阅渲染结果、限流计数与访问记录节流的内存状态。
+// generation 在渲染相关的数据写入后递增，旧代的缓存条目与设置随之失效。
+type subscriptionCache struct {
+	generation atomic.Uint64
+
+	mu          sync.Mutex
+	entries     map[string]renderCacheEntry
+	tokenHits   map[string]*rateWindow
+	ipHits      map[string]*rateWindow
+	recorded    map[string]time.Time
+	lastSweep   time.Time
+	settings    subscribeCacheSettings
+	settingsGen uint64
+	settingsAt  time.Time
+}
+
+func newSubscriptionCache() *subscriptionCache {
+	return &subscriptionCache{
+		entries:   make(map[string]renderCacheEntry),
+		tokenHits: make(map[string]*rateWindow),
+		ipHits:    make(map[string]*rateWindow),
+		recorded:  make(map[string]time.Time),
+	}
+}
+
+func (c *subscriptionCache) invalidate() {
+	c.generation.Add(1)
+}
+
+func (c *subscriptionCache) get(key string, now time.Time) (renderCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.generation != c.generation.Load() || !now.Before(entry.expiresAt) {
+		return renderCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *subscriptionCache) put(key string, entry renderCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+}
+
+// allowWindow 按固定窗口计数，超过 limit 时返回需要等待的时间。
+func allowWindow(windows map[string]*rateWindow, key string, limit int, now time.Time) (time.Duration, bool) {
+	if limit <= 0 {
+		return 0, true
+	}
+	w, ok := windows[key]
+	if !ok || now.Sub(w.start) >= subscribeRateWindow {
+		w = &rateWindow{start: now}
+		windows[key] = w
+	}
+	w.count++
+	if w.count > limit {
+		return w.start.Add(subscribeRateWindow).Sub(now), false
+	}
+	return 0, true
+}
+
+// shouldRecord 判断本次访问是否需要写入访问记录。
+func (c *subscriptionCache) shouldRecord(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last, ok := c.recorded[key]; ok && now.Sub(last) < accessRecordInterval {
+		return false
+	}
+	c.recorded[key] = now
+	return true
+}
+
+// sweep 定期清理过期的缓存条目、限流窗口与访问记录时间，调用方持有锁。
+func (c *subscriptionCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < subscribeCacheSweep {
+		return
+	}
+	c.lastSweep = now
+	generation := c.generation.Load()
+	for key, entry := range c.entries {
+		if entry.generation != generation || !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+	for _, windows := range []map[string]*rateWindow{c.tokenHits, c.ipHits} {
+		for key, w := range windows {
+			if now.Sub(w.start) >= subscribeRateWindow {
+				delete(windows, key)
+			}
+		}
+	}
+	for key, at := range c.recorded {
+		if now.Sub(at) >= accessRecordInterval {
+			delete(c.recorded, key)
+		}
+	}
+}
+
+// AllowRequest 检查令牌与来源 IP 的请求频率，只使用内存状态，可在校验令牌之前调用。
+func (s *SubscribeService) AllowRequest(token, ip string) error {
+	settings := s.cacheSettings()
+	now := time.Now()
+	c := s.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	if wait, ok := allowWindow(c.ipHits, ip, settings.IPRate, now); !ok {
+		return &RateLimitError{RetryAfter: wait}
+	}
+	if wait, ok := allowWindow(c.tokenHits, token, settings.TokenRate, now); !ok {
+		return &RateLimitError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// cachedConfig 返回缓存的订阅内容，命中时同样记录访问。
+func (s *SubscribeService) cachedConfig(key string, req SubscriptionRequest) (*SubscriptionContent, bool) {
+	entry, ok := s.cache.get(key, time.Now())
+	if !ok {
+		return nil, false
+	}
+	s.recordAccess(entry.sub, req, entry.content.Target)
+	return entry.content, true
+}
+
+// storeConfig 缓存渲染结果，generation 为开始读取数据前的代数，令牌过期时间早于缓存时间时以前者为准。
+func (s *SubscribeService) storeConfig(key string, sub *model.SubscribeToken, content *SubscriptionContent, generation uint64) {
+	ttl := s.cacheSettings().TTL
+	if ttl <= 0 {
+		return
+	}
+	expiresAt := time.Now().Add(ttl)
+	if sub.ExpiresAt != nil && sub.ExpiresAt.Before(expiresAt) {
+		expiresAt = *sub.ExpiresAt
+	}
+	s.cache.put(key, renderCacheEntry{sub: sub, content: content, generation: generation, expiresAt: expiresAt})
+}
+
+// subscriptionCacheKey 缓存键包含影响格式选择与输出的请求参数。
+func subscriptionCacheKey(token string, req SubscriptionRequest) string {
+	target := req.Target
+	if target == "" {
+		target = "ua:" + templatetool.DetectTarget(req.UserAgent)
+	}
+	return token + "\x00" + target + "\x00" + req.Options.EndpointMode
+}
+
+// subscriptionETag 由渲染结果及随响应下发的刷新信息计算，内容不变时保持不变。
+func subscriptionETag(content *SubscriptionContent) string {
+	h := sha256.New()
+	for _, part := range []string{
+		content.Target, content.ContentType, content.Filename, content.Userinfo,
+		strconv.Itoa(content.UpdateInterval), strconv.FormatBool(content.ManagedStrict), content.MasterURL, content.Body,
+	} {
+		fmt.Fprintf(h, "%d:%s\n", len(part), part)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// cacheSettings 读取缓存与限流配置，配置变更或超过 subscribeSettingsTTL 后重新读取。
+func (s *SubscribeService) cacheSettings() subscribeCacheSettings {
+	c := s.cache
+	generation := c.generation.Load()
+	c.mu.Lock()
+	if !c.settingsAt.IsZero() && c.settingsGen == generation && time.Since(c.settingsAt) < subscribeSettingsTTL {
+		settings := c.settings
+		c.mu.Unlock()
+		return settings
+	}
+	c.mu.Unlock()
+
+	settings := subscribeCacheSettings{
+		TTL:       defaultSubscribeCacheTTL,
+		TokenRate: defaultSubscribeTokenRate,
+		IPRate:    defaultSubscribeIPRate,
+	}
+	configs, err := s.configRepo.GetByKeys([]string{"subscribe_cache_ttl_seconds", "subscribe_rate_limit_token", "subscribe_rate_limit_ip"})
+	if err != nil {
+		s.logger.WithError(err).Warn("load subscribe cache settings failed")
+		return settings
+	}
+	if seconds, err := strconv.Atoi(configs["subscribe_cache_ttl_seconds"]); err == nil && seconds >= 0 {
+		settings.TTL = time.Duration(seconds) * time.Second
+	}
+	if rate, err := strconv.Atoi(configs["subscribe_rate_limit_token"]); err == nil && rate >= 0 {
+		settings.TokenRate = rate
+	}
+	if rate, err := strconv.Atoi(configs["subscribe_rate_limit_ip"]); err == nil && rate >= 0 {
+		settings.IPRate = rate
+	}
+	c.mu.Lock()
+	c.settings, c.settingsGen, c.settingsAt = settings, generation, time.Now()
+	c.mu.Unlock()
+	return settings
+}
+
+// subscriptionTables 影响订阅输出的表。
+var subscriptionTables = map[string]bool{
+	"users":              true,
+	"user_nodes":         true,
+	"user_node_groups":   true,
+	"user_node_tags":     true,
+	"nodes":              true,
+	"node_endpoints":     true,
+	"node_groups":        true,
+	"node_group_members": true,
+	"node_group_tags":    true,
+	"snell_instances":    true,
+	"templates":          true,
+	"rule_sets":          true,
+	"system_configs":     true,
+	"subscribe_tokens":   true,
+}
+
+// volatileColumns 频繁写入但不影响订阅输出的列，只更新这些列时不使缓存失效。
+// 用户流量只体现在 Subscription-Userinfo 中，由缓存时间限制其延迟。
+var volatileColumns = map[string]map[string]bool{
+	"nodes": {
+		"cpu_usage": true, "memory_usage": true, "disk_usage": true, "instance_count": true, "status": true,
+		"network_rx_rate": true, "network_tx_rate": true, "bandwidth_usage": true,
+		"load1": true, "load5": true, "load15": true, "uptime_seconds": true, "heartbeat_interval": true,
+		"kernel_version": true, "snell_version": true, "agent_version": true,
+		"port_range_start": true, "port_range_end": true, "last_seen_at": true, "updated_at": true,
+	},
+	"users": {
+		"traffic_used_today": true, "traffic_used_month": true, "traffic_used_total": true, "updated_at": true,
+	},
+	"snell_instances": {
+		"status": true, "reachability": true, "probe_failures": true, "last_probe_at": true,
+		"last_probe_latency_ms": true, "updated_at": true,
+	},
+	"subscribe_tokens": {
+		"access_count": true, "last_access_at": true, "last_access_ip": true, "last_user_agent": true, "updated_at": true,
+	},
+}
+
+// WatchChanges 注册 gorm 回调，在影响订阅输出的数据写入后使渲染缓存失效。
+func (s *SubscribeService) WatchChanges(db *gorm.DB) error {
+	invalidate := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement == nil || !subscriptionTables[tx.Statement.Table] {
+			return
+		}
+		if onlyVolatile(tx.Statement.Table, tx.Statement.Dest) {
+			return
+		}
+		s.cache.invalidate()
+	}
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("subscribe_cache:create", invalidate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("subscribe_cache:update", invalidate); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("subscribe_cache:delete", invalidate)
+}
+
+// onlyVolatile 判断按列更新时是否只写入了易变列，整行保存一律视为相关变更。
+func onlyVolatile(table string, dest interface{}) bool {
+	columns, ok := volatileColumns[table]
+	if !ok {
+		return false
+	}
+	updates, ok := dest.(map[string]interface{})
+	if !ok || len(updates) == 0 {
+		return false
+	}
+	for key := range updates {
+		if !columns[key] {
+			return false
+		}
+	}
+	return true
+}
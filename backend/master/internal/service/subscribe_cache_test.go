@@ -0,0 +1,107 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+func TestAllowWindow(t *testing.T) {
+	windows := make(map[string]*rateWindow)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if _, ok := allowWindow(windows, "a", 3, start.Add(time.Duration(i)*time.Second)); !ok {
+			t.Fatalf("request %d rejected within limit", i+1)
+		}
+	}
+	wait, ok := allowWindow(windows, "a", 3, start.Add(10*time.Second))
+	if ok {
+		t.Fatal("request over limit allowed")
+	}
+	if wait != 50*time.Second {
+		t.Fatalf("retry after = %s, want 50s", wait)
+	}
+	if _, ok := allowWindow(windows, "b", 3, start.Add(10*time.Second)); !ok {
+		t.Fatal("other key rejected")
+	}
+	if _, ok := allowWindow(windows, "a", 3, start.Add(subscribeRateWindow)); !ok {
+		t.Fatal("request in the next window rejected")
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := allowWindow(windows, "c", 0, start); !ok {
+			t.Fatal("zero limit should not restrict requests")
+		}
+	}
+}
+
+func TestOnlyVolatile(t *testing.T) {
+	cases := []struct {
+		name  string
+		table string
+		dest  interface{}
+		want  bool
+	}{
+		{"heartbeat columns", "nodes", map[string]interface{}{"cpu_usage": 1.5, "last_seen_at": time.Now(), "updated_at": time.Now()}, true},
+		{"node rename", "nodes", map[string]interface{}{"name": "n2", "updated_at": time.Now()}, false},
+		{"user traffic", "users", map[string]interface{}{"traffic_used_month": int64(1)}, true},
+		{"user status", "users", map[string]interface{}{"status": 0}, false},
+		{"token access", "subscribe_tokens", map[string]interface{}{"access_count": 1, "last_access_ip": "1.2.3.4"}, true},
+		{"whole row save", "nodes", &model.Node{}, false},
+		{"empty map", "nodes", map[string]interface{}{}, false},
+		{"table without volatile columns", "templates", map[string]interface{}{"updated_at": time.Now()}, false},
+	}
+	for _, tc := range cases {
+		if got := onlyVolatile(tc.table, tc.dest); got != tc.want {
+			t.Errorf("%s: onlyVolatile = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSubscriptionCacheInvalidation(t *testing.T) {
+	db, repos := newTestRepos(t)
+	svc := NewSubscribeService(repos.Subscribe, repos.SubscribeLog, repos.Template, repos.User, repos.Node, repos.Instance,
+		repos.NodeGroup, repos.RuleSet, repos.SystemConfig, newTestLogger())
+	if err := svc.WatchChanges(db); err != nil {
+		t.Fatalf("watch changes: %v", err)
+	}
+
+	node := &model.Node{Name: "n1", Endpoint: "1.2.3.4", APITokenHash: "hash", APITokenHashed: true}
+	if err := repos.Node.Create(node); err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+
+	now := time.Now()
+	generation := svc.cache.generation.Load()
+	svc.cache.put("key", renderCacheEntry{
+		sub:        &model.SubscribeToken{},
+		content:    &SubscriptionContent{},
+		generation: generation,
+		expiresAt:  now.Add(time.Minute),
+	})
+	if _, ok := svc.cache.get("key", now); !ok {
+		t.Fatal("fresh entry missing")
+	}
+	if _, ok := svc.cache.get("key", now.Add(time.Minute)); ok {
+		t.Fatal("expired entry returned")
+	}
+
+	if err := repos.Node.UpdateHeartbeat(node.ID, map[string]interface{}{"cpu_usage": 12.5, "status": model.NodeStatusOnline}); err != nil {
+		t.Fatalf("update heartbeat: %v", err)
+	}
+	if _, ok := svc.cache.get("key", now); !ok {
+		t.Fatal("heartbeat update invalidated the cache")
+	}
+
+	node.Name = "n1-renamed"
+	if err := repos.Node.Update(node); err != nil {
+		t.Fatalf("update node: %v", err)
+	}
+	if _, ok := svc.cache.get("key", now); ok {
+		t.Fatal("node rename did not invalidate the cache")
+	}
+	if svc.cache.generation.Load() == generation {
+		t.Fatal("generation not advanced")
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	templatetool "github.com/iwoov/snell-master/backend/master/internal/template"
+	"github.com/iwoov/snell-master/pkg/utils"
+)
+
+const (
+	maxTokensPerUser = 20
+	maxTokenLabel    = 64
+)
+
+// SubscribeTokenInput 创建订阅令牌的参数，Target 为空时由请求决定格式，Enabled 为空表示启用；
+// TemplateRevisionID 固定使用模板的某个修订，必须属于 TemplateID 指定的模板。
+type SubscribeTokenInput struct {
+	Label              string     `json:"label"`
+	TemplateID         *uint      `json:"template_id"`
+	TemplateRevisionID *uint      `json:"template_revision_id"`
+	Target             string     `json:"target"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+	Enabled            *bool      `json:"enabled"`
+}
+
+// CreateToken 为用户新增一个订阅令牌。
+func (s *SubscribeService) CreateToken(userID uint, input SubscribeTokenInput) (*model.SubscribeToken, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, err
+	}
+	count, err := s.repo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxTokensPerUser {
+		return nil, fmt.Errorf("at most %d subscription tokens are allowed per user", maxTokensPerUser)
+	}
+	token, err := utils.GenerateSubscribeToken()
+	if err != nil {
+		return nil, err
+	}
+	sub := &model.SubscribeToken{
+		UserID:     userID,
+		Token:      token,
+		Label:      input.Label,
+		TemplateID: input.TemplateID,
+		RevisionID: input.TemplateRevisionID,
+		Target:     input.Target,
+		Enabled:    input.Enabled == nil || *input.Enabled,
+		ExpiresAt:  input.ExpiresAt,
+	}
+	if err := s.normalizeToken(sub); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(sub); err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(logrus.Fields{"user_id": userID, "token_id": sub.ID, "label": sub.Label}).Info("subscribe token created")
+	return s.repo.GetByID(sub.ID)
+}
+
+// UpdateToken 修改令牌的标签、模板、固定的修订、格式、过期时间与启用状态，模板、修订与过期时间传 null 表示清除。
+func (s *SubscribeService) UpdateToken(id uint, updates map[string]interface{}) (*model.SubscribeToken, error) {
+	sub, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if label, ok := updates["label"].(string); ok {
+		sub.Label = label
+	}
+	if value, ok := updates["template_id"]; ok {
+		if value == nil {
+			sub.TemplateID = nil
+		} else if templateID, ok := getInt64(value); ok && templateID > 0 {
+			tid := uint(templateID)
+			sub.TemplateID = &tid
+		} else {
+			return nil, fmt.Errorf("invalid template_id")
+		}
+	}
+	if value, ok := updates["template_revision_id"]; ok {
+		if value == nil {
+			sub.RevisionID = nil
+		} else if revisionID, ok := getInt64(value); ok && revisionID > 0 {
+			rid := uint(revisionID)
+			sub.RevisionID = &rid
+		} else {
+			return nil, fmt.Errorf("invalid template_revision_id")
+		}
+	}
+	if target, ok := updates["target"].(string); ok {
+		sub.Target = target
+	}
+	if value, ok := updates["expires_at"]; ok {
+		switch v := value.(type) {
+		case nil:
+			sub.ExpiresAt = nil
+		case string:
+			if v == "" {
+				sub.ExpiresAt = nil
+				break
+			}
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expires_at: %w", err)
+			}
+			sub.ExpiresAt = &ts
+		default:
+			return nil, fmt.Errorf("invalid expires_at")
+		}
+	}
+	if enabled, ok := updates["enabled"].(bool); ok {
+		sub.Enabled = enabled
+	}
+	if err := s.normalizeToken(sub); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(sub); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(sub.ID)
+}
+
+// RegenerateToken 重新生成指定令牌的值，旧链接立即失效，其余设置保持不变。
+func (s *SubscribeService) RegenerateToken(id uint) (*model.SubscribeToken, error) {
+	sub, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	token, err := utils.GenerateSubscribeToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sub.Token = token
+	sub.RotatedAt = &now
+	if err := s.repo.Update(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetToken 返回令牌。
+func (s *SubscribeService) GetToken(id uint) (*model.SubscribeToken, error) {
+	return s.repo.GetByID(id)
+}
+
+// GetUserToken 返回属于该用户的令牌，不属于时按不存在处理。
+func (s *SubscribeService) GetUserToken(userID, id uint) (*model.SubscribeToken, error) {
+	sub, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.UserID != userID {
+		return nil, fmt.Errorf("subscription not found")
+	}
+	return sub, nil
+}
+
+// ListUserTokens 返回用户的全部令牌及访问统计。
+func (s *SubscribeService) ListUserTokens(userID uint) ([]model.SubscribeToken, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// DeleteToken 删除记录。
+func (s *SubscribeService) DeleteToken(id uint) error {
+	return s.repo.Delete(id)
+}
+
+// normalizeToken 校验标签与格式；绑定模板时格式必须与模板一致，未指定格式则沿用模板的格式；
+// 固定的修订必须属于所绑定的模板，不再绑定模板时一并清除。
+func (s *SubscribeService) normalizeToken(sub *model.SubscribeToken) error {
+	sub.Label = strings.TrimSpace(sub.Label)
+	if len(sub.Label) > maxTokenLabel {
+		return fmt.Errorf("label must be at most %d characters", maxTokenLabel)
+	}
+	target, err := templatetool.ParseTarget(sub.Target)
+	if err != nil {
+		return err
+	}
+	sub.Target = target
+	if sub.TemplateID == nil {
+		sub.RevisionID = nil
+		return nil
+	}
+	tpl, err := s.templateRepo.GetByID(*sub.TemplateID)
+	if err != nil {
+		return fmt.Errorf("template %d not found", *sub.TemplateID)
+	}
+	if sub.RevisionID != nil {
+		rev, err := s.templateRepo.GetRevisionByID(*sub.RevisionID)
+		if err != nil || rev.TemplateID != tpl.ID {
+			return fmt.Errorf("revision %d does not belong to template %s", *sub.RevisionID, tpl.Name)
+		}
+	}
+	if sub.Target == "" {
+		sub.Target = tpl.Target
+	} else if sub.Target != tpl.Target {
+		return fmt.Errorf("template %s renders %s, not %s", tpl.Name, tpl.Target, sub.Target)
+	}
+	return nil
+}
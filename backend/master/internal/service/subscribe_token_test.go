@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/backend/master/internal/repository"
+)
+
+func newTestSubscribeService(repos *repository.Repositories) *SubscribeService {
+	return NewSubscribeService(repos.Subscribe, repos.SubscribeLog, repos.Template, repos.User, repos.Node, repos.Instance,
+		repos.NodeGroup, repos.RuleSet, repos.SystemConfig, newTestLogger())
+}
+
+// createTokens 为用户创建带标签的令牌。
+func createTokens(t *testing.T, svc *SubscribeService, userID uint, labels ...string) []*model.SubscribeToken {
+	t.Helper()
+	tokens := make([]*model.SubscribeToken, 0, len(labels))
+	for _, label := range labels {
+		sub, err := svc.CreateToken(userID, SubscribeTokenInput{Label: label})
+		if err != nil {
+			t.Fatalf("create token %s: %v", label, err)
+		}
+		tokens = append(tokens, sub)
+	}
+	return tokens
+}
+
+func TestCreateMultipleTokens(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := newTestSubscribeService(repos)
+	user := newTestUser(t, repos, "alice")
+	other := newTestUser(t, repos, "bob")
+
+	tokens := createTokens(t, svc, user.ID, " phone ", "laptop", "router")
+	createTokens(t, svc, other.ID, "bob")
+	seen := make(map[string]struct{})
+	for _, sub := range tokens {
+		if !sub.Enabled || sub.Token == "" {
+			t.Fatalf("token %+v should be enabled with a value", sub)
+		}
+		if _, ok := seen[sub.Token]; ok {
+			t.Fatalf("duplicate token value %s", sub.Token)
+		}
+		seen[sub.Token] = struct{}{}
+	}
+
+	list, err := svc.ListUserTokens(user.ID)
+	if err != nil {
+		t.Fatalf("ListUserTokens: %v", err)
+	}
+	labels := make([]string, 0, len(list))
+	for _, sub := range list {
+		labels = append(labels, sub.Label)
+	}
+	if got := strings.Join(labels, ","); got != "phone,laptop,router" {
+		t.Fatalf("labels = %s, want phone,laptop,router", got)
+	}
+	if _, err := svc.GetUserToken(other.ID, tokens[0].ID); err == nil {
+		t.Fatalf("GetUserToken returned another user's token")
+	}
+
+	cases := []struct {
+		name  string
+		input SubscribeTokenInput
+	}{
+		{"label too long", SubscribeTokenInput{Label: strings.Repeat("x", maxTokenLabel+1)}},
+		{"unknown target", SubscribeTokenInput{Target: "unknown"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := svc.CreateToken(user.ID, tc.input); err == nil {
+				t.Fatalf("CreateToken succeeded, want error")
+			}
+		})
+	}
+
+	for i := len(list); i < maxTokensPerUser; i++ {
+		createTokens(t, svc, user.ID, fmt.Sprintf("token-%d", i))
+	}
+	if _, err := svc.CreateToken(user.ID, SubscribeTokenInput{}); err == nil {
+		t.Fatalf("CreateToken beyond %d tokens succeeded", maxTokensPerUser)
+	}
+}
+
+func TestRevokeTokenKeepsOthers(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := newTestSubscribeService(repos)
+	user := newTestUser(t, repos, "alice")
+	tokens := createTokens(t, svc, user.ID, "phone", "laptop", "router")
+	phone, laptop, router := tokens[0], tokens[1], tokens[2]
+
+	valid := func(step string, want map[string]bool) {
+		t.Helper()
+		for value, ok := range want {
+			if _, err := svc.ValidateToken(value); (err == nil) != ok {
+				t.Fatalf("%s: ValidateToken(%s) error = %v, want valid %v", step, value, err, ok)
+			}
+		}
+	}
+
+	if _, err := svc.UpdateToken(phone.ID, map[string]interface{}{"enabled": false}); err != nil {
+		t.Fatalf("disable token: %v", err)
+	}
+	valid("disable", map[string]bool{phone.Token: false, laptop.Token: true, router.Token: true})
+
+	rotated, err := svc.RegenerateToken(laptop.ID)
+	if err != nil {
+		t.Fatalf("RegenerateToken: %v", err)
+	}
+	valid("regenerate", map[string]bool{laptop.Token: false, rotated.Token: true, router.Token: true})
+
+	if err := svc.DeleteToken(rotated.ID); err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+	valid("delete", map[string]bool{rotated.Token: false, router.Token: true})
+
+	list, err := svc.ListUserTokens(user.ID)
+	if err != nil {
+		t.Fatalf("ListUserTokens: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != phone.ID || list[1].ID != router.ID {
+		t.Fatalf("remaining tokens = %+v, want phone and router", list)
+	}
+	if list[0].Enabled || !list[1].Enabled || list[1].Label != "router" {
+		t.Fatalf("remaining tokens changed: %+v", list)
+	}
+}
+
+func TestTokenAccessStats(t *testing.T) {
+	_, repos := newTestRepos(t)
+	svc := newTestSubscribeService(repos)
+	user := newTestUser(t, repos, "alice")
+	tokens := createTokens(t, svc, user.ID, "phone", "laptop", "unused")
+
+	accesses := []struct {
+		token *model.SubscribeToken
+		ip    string
+	}{
+		{tokens[0], "10.0.0.1"},
+		{tokens[0], "10.0.1.1"},
+		{tokens[1], "10.0.2.1"},
+		{tokens[0], "10.0.3.1"},
+	}
+	for _, access := range accesses {
+		svc.recordAccess(access.token, SubscriptionRequest{ClientIP: access.ip, UserAgent: "Surge"}, model.TemplateTargetSurge)
+	}
+
+	cases := []struct {
+		token     *model.SubscribeToken
+		wantCount int64
+		wantIP    string
+	}{
+		{tokens[0], 3, "10.0.3.1"},
+		{tokens[1], 1, "10.0.2.1"},
+		{tokens[2], 0, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.token.Label, func(t *testing.T) {
+			sub, err := svc.GetToken(tc.token.ID)
+			if err != nil {
+				t.Fatalf("GetToken: %v", err)
+			}
+			if sub.AccessCount != tc.wantCount || sub.LastAccessIP != tc.wantIP {
+				t.Fatalf("stats = %d accesses from %q, want %d from %q", sub.AccessCount, sub.LastAccessIP, tc.wantCount, tc.wantIP)
+			}
+			if (sub.LastAccessAt != nil) != (tc.wantCount > 0) {
+				t.Fatalf("last_access_at = %v with %d accesses", sub.LastAccessAt, tc.wantCount)
+			}
+			logs, err := svc.ListAccessLogs(tc.token.ID, 0)
+			if err != nil {
+				t.Fatalf("ListAccessLogs: %v", err)
+			}
+			if int64(len(logs)) != tc.wantCount {
+				t.Fatalf("access logs = %d, want %d", len(logs), tc.wantCount)
+			}
+			for _, entry := range logs {
+				if entry.TokenID != tc.token.ID {
+					t.Fatalf("access log %+v belongs to another token", entry)
+				}
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ type SnellConfig struct {
 	Version      string            `json:"version"`
 	BaseURL      string            `json:"base_url"`
 	DownloadURLs map[string]string `json:"download_urls"`
+	Checksums    map[string]string `json:"checksums,omitempty"`
 }
 
 // SystemInfo 系统信息
@@ -37,6 +38,11 @@ func NewSystemConfigService(repo repository.SystemConfigRepository, logger *logr
 
 // GetSnellConfig 获取 Snell Server 配置
 func (s *SystemConfigService) GetSnellConfig() (*SnellConfig, error) {
+	return s.GetSnellConfigForVersion("")
+}
+
+// GetSnellConfigForVersion 获取指定版本的 Snell Server 下载配置，version 为空时使用全局 snell_version
+func (s *SystemConfigService) GetSnellConfigForVersion(version string) (*SnellConfig, error) {
 	configs, err := s.repo.GetByKeys([]string{
 		"snell_version",
 		"snell_base_url",
@@ -47,7 +53,9 @@ func (s *SystemConfigService) GetSnellConfig() (*SnellConfig, error) {
 		return nil, err
 	}
 
-	version := configs["snell_version"]
+	if version == "" {
+		version = configs["snell_version"]
+	}
 	baseURL := configs["snell_base_url"]
 	mirrorURL := configs["snell_mirror_url"]
 
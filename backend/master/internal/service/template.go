@@ -7,28 +7,38 @@ import (
 
 	"github.com/iwoov/snell-master/backend/master/internal/model"
 	"github.com/iwoov/snell-master/backend/master/internal/repository"
+	templatetool "github.com/iwoov/snell-master/backend/master/internal/template"
 )
 
-// TemplateService 管理 Surge 模板。
+// TemplateService 管理订阅模板。
 type TemplateService struct {
-	repo   repository.TemplateRepository
-	logger *logrus.Logger
+	repo        repository.TemplateRepository
+	ruleSetRepo repository.RuleSetRepository
+	logger      *logrus.Logger
 }
 
 // NewTemplateService 构造函数。
-func NewTemplateService(repo repository.TemplateRepository, logger *logrus.Logger) *TemplateService {
-	return &TemplateService{repo: repo, logger: logger}
+func NewTemplateService(repo repository.TemplateRepository, ruleSetRepo repository.RuleSetRepository, logger *logrus.Logger) *TemplateService {
+	return &TemplateService{repo: repo, ruleSetRepo: ruleSetRepo, logger: logger}
 }
 
-// CreateTemplate 创建模板。
-func (s *TemplateService) CreateTemplate(name, content, description string, isDefault bool) (*model.Template, error) {
+// CreateTemplate 创建模板并记录第一个修订，target 为空时为 Surge 模板。
+func (s *TemplateService) CreateTemplate(name, content, description, target string, isDefault bool, adminID uint) (*model.Template, error) {
+	target, err := normalizeTemplateTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateContent(content); err != nil {
+		return nil, err
+	}
 	tpl := &model.Template{
 		Name:        name,
 		Content:     content,
 		Description: description,
+		Target:      target,
 		IsDefault:   isDefault,
 	}
-	if err := s.repo.Create(tpl); err != nil {
+	if err := s.repo.SaveWithRevision(tpl, newTemplateRevision(tpl, nil, "created", adminID)); err != nil {
 		return nil, err
 	}
 	if isDefault {
@@ -39,8 +49,8 @@ func (s *TemplateService) CreateTemplate(name, content, description string, isDe
 	return tpl, nil
 }
 
-// UpdateTemplate 编辑模板。
-func (s *TemplateService) UpdateTemplate(id uint, updates map[string]interface{}) (*model.Template, error) {
+// UpdateTemplate 编辑模板，名称、描述、格式或内容有变化时记录新修订，note 为可选的修订说明。
+func (s *TemplateService) UpdateTemplate(id uint, updates map[string]interface{}, adminID uint) (*model.Template, error) {
 	tpl, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, err
@@ -49,12 +59,27 @@ func (s *TemplateService) UpdateTemplate(id uint, updates map[string]interface{}
 		tpl.Name = name
 	}
 	if content, ok := updates["content"].(string); ok && content != "" {
+		if err := s.validateContent(content); err != nil {
+			return nil, err
+		}
 		tpl.Content = content
 	}
 	if desc, ok := updates["description"].(string); ok {
 		tpl.Description = desc
 	}
-	if err := s.repo.Update(tpl); err != nil {
+	if raw, ok := updates["target"].(string); ok {
+		target, err := normalizeTemplateTarget(raw)
+		if err != nil {
+			return nil, err
+		}
+		// 默认模板改变格式会让原格式失去默认模板
+		if target != tpl.Target && tpl.IsDefault {
+			return nil, fmt.Errorf("cannot change target of default template")
+		}
+		tpl.Target = target
+	}
+	note, _ := updates["note"].(string)
+	if err := s.saveRevision(tpl, note, adminID); err != nil {
 		return nil, err
 	}
 	if isDefault, ok := updates["is_default"].(bool); ok && isDefault {
@@ -77,17 +102,59 @@ func (s *TemplateService) DeleteTemplate(id uint) error {
 	return s.repo.Delete(id)
 }
 
+// GetTemplate 返回模板。
+func (s *TemplateService) GetTemplate(id uint) (*model.Template, error) {
+	return s.repo.GetByID(id)
+}
+
 // ListTemplates 返回全部模板。
 func (s *TemplateService) ListTemplates() ([]model.Template, error) {
 	return s.repo.List()
 }
 
-// GetDefaultTemplate 返回默认模板。
-func (s *TemplateService) GetDefaultTemplate() (*model.Template, error) {
-	return s.repo.GetDefault()
+// GetDefaultTemplate 返回指定格式的默认模板。
+func (s *TemplateService) GetDefaultTemplate(target string) (*model.Template, error) {
+	return s.repo.GetDefault(target)
 }
 
 // SetDefaultTemplate 设置默认模板。
 func (s *TemplateService) SetDefaultTemplate(id uint) error {
 	return s.repo.SetDefault(id)
 }
+
+// normalizeTemplateTarget 校验模板格式，空值表示 Surge。
+func normalizeTemplateTarget(target string) (string, error) {
+	target, err := templatetool.ParseTarget(target)
+	if err != nil {
+		return "", err
+	}
+	if target == "" {
+		return model.TemplateTargetSurge, nil
+	}
+	return target, nil
+}
+
+// validateContent 检查模板语法，并确认模板按名称引用的规则集都存在。
+func (s *TemplateService) validateContent(content string) error {
+	if err := templatetool.Validate(content); err != nil {
+		return err
+	}
+	names, err := templatetool.RuleSetNames(content)
+	if err != nil || len(names) == 0 {
+		return err
+	}
+	sets, err := s.ruleSetRepo.List()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(sets))
+	for _, set := range sets {
+		existing[set.Name] = true
+	}
+	for _, name := range names {
+		if !existing[name] {
+			return fmt.Errorf("rule set %q not found", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+	"github.com/iwoov/snell-master/pkg/utils"
+)
+
+// TemplateRevisionDiff 两个修订之间的内容差异。
+type TemplateRevisionDiff struct {
+	TemplateID uint   `json:"template_id"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Diff       string `json:"diff"`
+}
+
+// newTemplateRevision 以模板当前内容生成快照，prev 为上一修订，为空时差异相对空内容计算。
+func newTemplateRevision(tpl *model.Template, prev *model.TemplateRevision, note string, adminID uint) *model.TemplateRevision {
+	rev := &model.TemplateRevision{
+		Name:        tpl.Name,
+		Description: tpl.Description,
+		Target:      tpl.Target,
+		Content:     tpl.Content,
+		Note:        truncate(note, 255),
+	}
+	if adminID != 0 {
+		rev.CreatedBy = &adminID
+	}
+	prevContent, prevRevision := "", 0
+	if prev != nil {
+		prevContent, prevRevision = prev.Content, prev.Revision
+	}
+	rev.Diff = utils.UnifiedDiff(revisionLabel(prevRevision), revisionLabel(prevRevision+1), prevContent, tpl.Content)
+	return rev
+}
+
+func revisionLabel(revision int) string {
+	return fmt.Sprintf("revision %d", revision)
+}
+
+// saveRevision 保存模板；与最新修订相比名称、描述、格式或内容有变化时同时追加修订。
+func (s *TemplateService) saveRevision(tpl *model.Template, note string, adminID uint) error {
+	prev, err := s.repo.LatestRevision(tpl.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if prev != nil && prev.Name == tpl.Name && prev.Description == tpl.Description &&
+		prev.Target == tpl.Target && prev.Content == tpl.Content {
+		return s.repo.Update(tpl)
+	}
+	return s.repo.SaveWithRevision(tpl, newTemplateRevision(tpl, prev, note, adminID))
+}
+
+// ListRevisions 返回模板的修订历史，最新的在前。
+func (s *TemplateService) ListRevisions(id uint) ([]model.TemplateRevision, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListRevisions(id)
+}
+
+// GetRevision 返回模板的指定修订。
+func (s *TemplateService) GetRevision(id uint, revision int) (*model.TemplateRevision, error) {
+	return s.repo.GetRevision(id, revision)
+}
+
+// CompareRevisions 返回从 from 到 to 两个修订之间的内容差异。
+func (s *TemplateService) CompareRevisions(id uint, from, to int) (*TemplateRevisionDiff, error) {
+	fromRev, err := s.repo.GetRevision(id, from)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d not found", from)
+	}
+	toRev, err := s.repo.GetRevision(id, to)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d not found", to)
+	}
+	return &TemplateRevisionDiff{
+		TemplateID: id,
+		From:       from,
+		To:         to,
+		Diff:       utils.UnifiedDiff(revisionLabel(from), revisionLabel(to), fromRev.Content, toRev.Content),
+	}, nil
+}
+
+// RollbackTemplate 将模板的内容、描述与格式恢复为指定修订，并记录为新的修订；名称与默认状态保持不变。
+func (s *TemplateService) RollbackTemplate(id uint, revision int, adminID uint) (*model.Template, error) {
+	tpl, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := s.repo.GetRevision(id, revision)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d not found", revision)
+	}
+	// 修订引用的规则集可能已被删除
+	if err := s.validateContent(rev.Content); err != nil {
+		return nil, fmt.Errorf("revision %d can no longer be used: %w", revision, err)
+	}
+	if rev.Target != tpl.Target && tpl.IsDefault {
+		return nil, fmt.Errorf("cannot change target of default template")
+	}
+	tpl.Content = rev.Content
+	tpl.Description = rev.Description
+	tpl.Target = rev.Target
+	if err := s.saveRevision(tpl, fmt.Sprintf("rollback to revision %d", revision), adminID); err != nil {
+		return nil, err
+	}
+	s.logger.WithField("template_id", id).Infof("template rolled back to revision %d", revision)
+	return tpl, nil
+}
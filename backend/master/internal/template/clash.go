@@ -0,0 +1,141 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// 各客户端支持的最高 Snell 版本，更高版本的实例不会输出：Mihomo 只实现了 v1-v3，Stash 支持到 v4。
+const (
+	clashMaxSnellVersion = 3
+	stashMaxSnellVersion = 4
+)
+
+// clashProxy Clash/Mihomo 的 snell 代理定义，字段顺序即输出顺序。
+type clashProxy struct {
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Server   string         `json:"server"`
+	Port     int            `json:"port"`
+	PSK      string         `json:"psk"`
+	Version  int            `json:"version"`
+	ObfsOpts *clashObfsOpts `json:"obfs-opts,omitempty"`
+	UDP      bool           `json:"udp,omitempty"`
+}
+
+type clashObfsOpts struct {
+	Mode string `json:"mode"`
+}
+
+// clashGroup Clash 语法的代理组定义。
+type clashGroup struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Proxies   []string `json:"proxies"`
+	URL       string   `json:"url,omitempty"`
+	Interval  int      `json:"interval,omitempty"`
+	Tolerance int      `json:"tolerance,omitempty"`
+}
+
+// yamlFormat Clash 语法的代理以 JSON 流式映射输出，JSON 是 YAML 的子集，
+// 可避免名称中的特殊字符破坏缩进结构。模板中单独使用 Line 时写作 "  - {{.Line}}"。
+func yamlFormat(maxVersion int) format {
+	return format{
+		maxVersion: maxVersion,
+		proxyLine: func(p proxy) (string, error) {
+			entry := clashProxy{
+				Name:    p.Name,
+				Type:    "snell",
+				Server:  p.Server,
+				Port:    p.Port,
+				PSK:     p.PSK,
+				Version: p.Version,
+				// Snell v3 起支持 UDP 转发
+				UDP: p.Version >= 3,
+			}
+			if p.Obfs != "" && p.Obfs != "off" {
+				entry.ObfsOpts = &clashObfsOpts{Mode: p.Obfs}
+			}
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return "", fmt.Errorf("encode proxy %s: %w", p.Name, err)
+			}
+			return string(data), nil
+		},
+		// 首行的缩进由模板提供
+		joinLines: func(lines []string) string {
+			if len(lines) == 0 {
+				// 空列表时 proxies 段保持合法
+				return "[]"
+			}
+			return "- " + strings.Join(lines, "\n  - ")
+		},
+		joinNames: func(names []string) (string, error) {
+			data, err := json.Marshal(names)
+			if err != nil {
+				return "", fmt.Errorf("encode proxy names: %w", err)
+			}
+			return string(data), nil
+		},
+		groupLine: func(group proxyGroup) (string, error) {
+			data, err := json.Marshal(clashGroup{
+				Name:      group.Name,
+				Type:      group.Type,
+				Proxies:   group.Members,
+				URL:       group.URL,
+				Interval:  group.Interval,
+				Tolerance: group.Tolerance,
+			})
+			if err != nil {
+				return "", fmt.Errorf("encode proxy group %s: %w", group.Name, err)
+			}
+			return string(data), nil
+		},
+		rules: yamlRuleSyntax,
+	}
+}
+
+// ClashGenerator 将模板渲染为 Clash/Mihomo YAML 配置。
+type ClashGenerator struct {
+	template string
+	options  Options
+}
+
+// NewClashGenerator 创建生成器。
+func NewClashGenerator(tpl string, options Options) *ClashGenerator {
+	return &ClashGenerator{template: tpl, options: options}
+}
+
+// ContentType Clash 配置为 YAML。
+func (g *ClashGenerator) ContentType() string {
+	return "text/yaml; charset=utf-8"
+}
+
+// Generate 根据用户、节点和实例数据生成 YAML。
+func (g *ClashGenerator) Generate(input Input) (string, error) {
+	return render(g.template, model.TemplateTargetClash, input, g.options, yamlFormat(clashMaxSnellVersion))
+}
+
+// StashGenerator 将模板渲染为 Stash 配置，代理语法与 Clash 相同。
+type StashGenerator struct {
+	template string
+	options  Options
+}
+
+// NewStashGenerator 创建生成器。
+func NewStashGenerator(tpl string, options Options) *StashGenerator {
+	return &StashGenerator{template: tpl, options: options}
+}
+
+// ContentType Stash 配置为 YAML。
+func (g *StashGenerator) ContentType() string {
+	return "text/yaml; charset=utf-8"
+}
+
+// Generate 根据用户、节点和实例数据生成 YAML。
+func (g *StashGenerator) Generate(input Input) (string, error) {
+	return render(g.template, model.TemplateTargetStash, input, g.options, yamlFormat(stashMaxSnellVersion))
+}
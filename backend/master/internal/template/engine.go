@@ -0,0 +1,446 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// 模板由管理员编写，但渲染发生在公开的订阅接口上，需限制体积、耗时与循环嵌套。
+const (
+	maxTemplateSize = 64 << 10
+	maxOutputSize   = 4 << 20
+	renderTimeout   = 2 * time.Second
+	maxRangeDepth   = 3
+)
+
+var (
+	errOutputTooLarge = errors.New("rendered output exceeds size limit")
+	errRenderTimeout  = errors.New("rendering timed out")
+
+	// ErrUnsupportedSnellVersion 用户的全部实例都高于目标客户端支持的 Snell 版本，订阅中不会有任何代理。
+	ErrUnsupportedSnellVersion = errors.New("no instance uses a snell version supported by the client")
+)
+
+// Input 渲染订阅所需的原始数据，Groups 用于按节点组与标签筛选，RuleSets 供模板按名称引用。
+type Input struct {
+	User      *model.User
+	Nodes     []model.Node
+	Instances []model.SnellInstance
+	Groups    []model.NodeGroup
+	RuleSets  []model.RuleSet
+}
+
+// Data 模板中 "." 的内容，只包含值字段。
+type Data struct {
+	Target  string
+	User    UserData
+	Nodes   []NodeData
+	Proxies []ProxyData
+	Regions []RegionData
+}
+
+// UserData 模板可见的用户信息。
+type UserData struct {
+	Username     string
+	Email        string
+	TrafficLimit int64
+	TrafficUsed  int64
+	ExpireAt     *time.Time
+}
+
+// NodeData 模板可见的节点信息，Proxies 为该节点上输出的代理。
+type NodeData struct {
+	ID       uint
+	Name     string
+	Location string
+	Country  string
+	Emoji    string
+	Address  string
+	Groups   []string
+	Tags     []string
+	Proxies  []ProxyData
+}
+
+// ProxyData 模板可见的代理，Line 为目标格式的一行代理定义。
+type ProxyData struct {
+	Name     string
+	Server   string
+	Port     int
+	PSK      string
+	Version  int
+	Obfs     string
+	NodeName string
+	Country  string
+	Emoji    string
+	Groups   []string
+	Tags     []string
+	Line     string
+
+	hidden bool
+}
+
+// format 一种客户端格式的代理语法。
+type format struct {
+	// maxVersion 支持的最高 Snell 版本，0 表示不限
+	maxVersion int
+	proxyLine  func(p proxy) (string, error)
+	joinLines  func(lines []string) string
+	joinNames  func(names []string) (string, error)
+	groupLine  func(group proxyGroup) (string, error)
+	rules      ruleSyntax
+}
+
+// Validate 检查模板语法，保存模板前调用。
+func Validate(tpl string) error {
+	_, err := parseTemplate(tpl, newFuncMap(&Data{}, format{}, GroupSettings{}, nil))
+	return err
+}
+
+// render 用 text/template 渲染模板，旧的 {{node_list}} 等占位符以同名函数实现。
+func render(tpl, target string, input Input, options Options, f format) (string, error) {
+	if input.User == nil {
+		return "", fmt.Errorf("user is required")
+	}
+	if tpl == "" {
+		return "", fmt.Errorf("template content is empty")
+	}
+
+	var accept func(*model.SnellInstance) bool
+	if f.maxVersion > 0 {
+		accept = func(inst *model.SnellInstance) bool { return inst.Version <= f.maxVersion }
+	}
+	proxies := buildProxies(input.Nodes, input.Instances, options, accept)
+	if len(proxies) == 0 && accept != nil {
+		if skipped := len(buildProxies(input.Nodes, input.Instances, options, nil)); skipped > 0 {
+			return "", fmt.Errorf("%w: %s supports snell up to v%d, all %d proxies use newer versions",
+				ErrUnsupportedSnellVersion, target, f.maxVersion, skipped)
+		}
+	}
+	data, err := buildData(target, input, proxies, f)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := parseTemplate(tpl, newFuncMap(data, f, options.Groups.withDefaults(), input.RuleSets))
+	if err != nil {
+		return "", err
+	}
+	if steps := estimateSteps(t, newRenderSizes(data, input.RuleSets)); steps > maxRenderSteps {
+		return "", fmt.Errorf("template is too expensive to render for %d proxies", len(data.Proxies))
+	}
+	out := &limitedWriter{limit: maxOutputSize, deadline: time.Now().Add(renderTimeout)}
+	if err := t.Execute(out, data); err != nil {
+		return "", fmt.Errorf("render template: %w", err)
+	}
+	return out.buf.String(), nil
+}
+
+func parseTemplate(tpl string, funcs texttemplate.FuncMap) (*texttemplate.Template, error) {
+	if len(tpl) > maxTemplateSize {
+		return nil, fmt.Errorf("template exceeds %d bytes", maxTemplateSize)
+	}
+	t, err := texttemplate.New("subscription").Funcs(funcs).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	if err := checkTemplates(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func buildData(target string, input Input, proxies []proxy, f format) (*Data, error) {
+	groups := make(map[uint][]string)
+	tags := make(map[uint][]string)
+	for _, group := range input.Groups {
+		for _, nodeID := range group.NodeIDs {
+			groups[nodeID] = append(groups[nodeID], group.Name)
+			tags[nodeID] = appendUnique(tags[nodeID], group.Tags...)
+		}
+	}
+
+	user := input.User
+	data := &Data{
+		Target: target,
+		User: UserData{
+			Username:     user.Username,
+			Email:        user.Email,
+			TrafficLimit: user.TrafficLimit,
+			TrafficUsed:  user.TrafficUsedMonth,
+			ExpireAt:     user.ExpireAt,
+		},
+	}
+
+	byNode := make(map[uint][]ProxyData)
+	for _, p := range proxies {
+		item := ProxyData{
+			Name:     p.Name,
+			Server:   p.Server,
+			Port:     p.Port,
+			PSK:      p.PSK,
+			Version:  p.Version,
+			Obfs:     p.Obfs,
+			NodeName: p.Node.Name,
+			Country:  p.Node.CountryCode,
+			Emoji:    countryEmoji(p.Node.CountryCode),
+			Groups:   groups[p.Node.ID],
+			Tags:     tags[p.Node.ID],
+			hidden:   p.Node.HideFromAutoGroups,
+		}
+		if f.proxyLine != nil {
+			line, err := f.proxyLine(p)
+			if err != nil {
+				return nil, err
+			}
+			item.Line = line
+		}
+		data.Proxies = append(data.Proxies, item)
+		byNode[p.Node.ID] = append(byNode[p.Node.ID], item)
+	}
+
+	for i := range input.Nodes {
+		node := &input.Nodes[i]
+		data.Nodes = append(data.Nodes, NodeData{
+			ID:       node.ID,
+			Name:     node.Name,
+			Location: node.Location,
+			Country:  node.CountryCode,
+			Emoji:    countryEmoji(node.CountryCode),
+			Address:  node.PreferredAddress(),
+			Groups:   groups[node.ID],
+			Tags:     tags[node.ID],
+			Proxies:  byNode[node.ID],
+		})
+	}
+	data.Regions = buildRegions(data.Proxies)
+	return data, nil
+}
+
+// newFuncMap 返回模板函数：旧占位符、自动代理组、规则集、筛选函数与辅助函数。
+// 旧占位符中的单节点字段取第一个代理所在节点。
+func newFuncMap(data *Data, f format, settings GroupSettings, ruleSets []model.RuleSet) texttemplate.FuncMap {
+	var primary ProxyData
+	if len(data.Proxies) > 0 {
+		primary = data.Proxies[0]
+	}
+	var primaryAddress string
+	for _, node := range data.Nodes {
+		if node.Name == primary.NodeName {
+			primaryAddress = node.Address
+			break
+		}
+	}
+	pick := func(lists [][]ProxyData) []ProxyData {
+		if len(lists) > 0 {
+			return lists[0]
+		}
+		return data.Proxies
+	}
+	port := ""
+	if primary.Port > 0 {
+		port = strconv.Itoa(primary.Port)
+	}
+
+	funcs := texttemplate.FuncMap{
+		"username":  func() string { return data.User.Username },
+		"email":     func() string { return data.User.Email },
+		"country":   func() string { return primary.Country },
+		"node_name": func() string { return primary.NodeName },
+		"server":    func() string { return primaryAddress },
+		"port":      func() string { return port },
+		"psk":       func() string { return primary.PSK },
+		// emoji 无参数时为主节点国旗，否则为给定国家代码的国旗
+		"emoji": func(codes ...string) string {
+			if len(codes) == 0 {
+				return primary.Emoji
+			}
+			return countryEmoji(codes[0])
+		},
+		"node_list": func(lists ...[]ProxyData) string {
+			items := pick(lists)
+			lines := make([]string, 0, len(items))
+			for _, item := range items {
+				lines = append(lines, item.Line)
+			}
+			if f.joinLines == nil {
+				return strings.Join(lines, "\n")
+			}
+			return f.joinLines(lines)
+		},
+		"node_names": func(lists ...[]ProxyData) (string, error) {
+			names := proxyNames(pick(lists))
+			if f.joinNames == nil {
+				return strings.Join(names, ", "), nil
+			}
+			return f.joinNames(names)
+		},
+		// region_groups 各地区的 url-test 与 fallback 组，auto_groups 另在最前面加上顶层选择组
+		"region_groups": func() (string, error) {
+			return formatGroups(regionGroups(data.Regions, settings), f)
+		},
+		"region_names": func() (string, error) {
+			names := regionGroupNames(data.Regions)
+			if f.joinNames == nil {
+				return strings.Join(names, ", "), nil
+			}
+			return f.joinNames(names)
+		},
+		"auto_groups": func() (string, error) {
+			groups := append([]proxyGroup{selectorGroup(data.Regions, data.Proxies)}, regionGroups(data.Regions, settings)...)
+			return formatGroups(groups, f)
+		},
+		"byCountry": func(codes string, list any) (any, error) {
+			wanted := splitValues(codes, strings.ToUpper)
+			return filterList(list, func(country string, _, _ []string) bool {
+				return wanted[strings.ToUpper(country)]
+			})
+		},
+		"byGroup": func(names string, list any) (any, error) {
+			wanted := splitValues(names, nil)
+			return filterList(list, func(_ string, groups, _ []string) bool {
+				return containsAny(groups, wanted)
+			})
+		},
+		"byTag": func(values string, list any) (any, error) {
+			wanted := splitValues(values, nil)
+			return filterList(list, func(_ string, _, tags []string) bool {
+				return containsAny(tags, wanted)
+			})
+		},
+		"names": proxyNames,
+		"join": func(sep string, items []string) string {
+			return strings.Join(items, sep)
+		},
+		"bytes": formatBytes,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}
+	for name, fn := range ruleSetFuncs(ruleSets, f.rules) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+func proxyNames(items []ProxyData) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+// filterList 筛选代理或节点列表，参数放在最后以便用于管道：{{range .Proxies | byCountry "JP,HK"}}。
+func filterList(list any, match func(country string, groups, tags []string) bool) (any, error) {
+	switch items := list.(type) {
+	case []ProxyData:
+		out := make([]ProxyData, 0, len(items))
+		for _, item := range items {
+			if match(item.Country, item.Groups, item.Tags) {
+				out = append(out, item)
+			}
+		}
+		return out, nil
+	case []NodeData:
+		out := make([]NodeData, 0, len(items))
+		for _, item := range items {
+			if match(item.Country, item.Groups, item.Tags) {
+				out = append(out, item)
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cannot filter %T", list)
+	}
+}
+
+// splitValues 解析逗号分隔的筛选值。
+func splitValues(values string, normalize func(string) string) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range strings.Split(values, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if normalize != nil {
+			value = normalize(value)
+		}
+		set[value] = true
+	}
+	return set
+}
+
+func containsAny(values []string, wanted map[string]bool) bool {
+	for _, value := range values {
+		if wanted[value] {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(values []string, extra ...string) []string {
+	for _, value := range extra {
+		found := false
+		for _, existing := range values {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// formatBytes 将字节数格式化为 1.5 GB 形式，接受模板中的各种整数类型。
+func formatBytes(value any) (string, error) {
+	var n float64
+	switch v := value.(type) {
+	case int:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case uint64:
+		n = float64(v)
+	case float64:
+		n = v
+	default:
+		return "", fmt.Errorf("bytes: unsupported type %T", value)
+	}
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i]), nil
+	}
+	return fmt.Sprintf("%.2f %s", n, units[i]), nil
+}
+
+// limitedWriter 限制输出体积与渲染耗时，超出时让模板执行返回错误。
+type limitedWriter struct {
+	buf      bytes.Buffer
+	limit    int
+	deadline time.Time
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if time.Now().After(w.deadline) {
+		return 0, errRenderTimeout
+	}
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, errOutputTooLarge
+	}
+	return w.buf.Write(p)
+}
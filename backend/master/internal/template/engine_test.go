@@ -0,0 +1,138 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// testInput 生成 nodes 个节点，每个节点 perNode 个实例。
+func testInput(nodes, perNode int) Input {
+	input := Input{User: &model.User{Username: "alice", Email: "alice@example.com"}}
+	countries := []string{"JP", "HK", "US"}
+	for i := 1; i <= nodes; i++ {
+		input.Nodes = append(input.Nodes, model.Node{
+			ID:          uint(i),
+			Name:        fmt.Sprintf("node%d", i),
+			CountryCode: countries[(i-1)%len(countries)],
+			Endpoint:    fmt.Sprintf("10.0.0.%d", i),
+		})
+		for j := 0; j < perNode; j++ {
+			input.Instances = append(input.Instances, model.SnellInstance{
+				NodeID:  uint(i),
+				Port:    10000 + j,
+				PSK:     fmt.Sprintf("psk%d-%d", i, j),
+				Version: 4,
+			})
+		}
+	}
+	return input
+}
+
+func TestValidateRejectsUnboundedLoops(t *testing.T) {
+	cases := map[string]string{
+		"range over variable number":  `{{$n := 30000000}}{{range $n}}{{end}}ok`,
+		"nested range over variable":  `{{$n := 100000}}{{range $n}}{{range $n}}{{end}}{{end}}`,
+		"range over literal":          `{{range 10}}{{end}}`,
+		"range over int field":        `{{range .User.TrafficLimit}}{{end}}`,
+		"range over plain variable":   `{{$p := .Proxies}}{{range $p}}{{end}}`,
+		"range over unlisted func":    `{{range lower "abc"}}{{end}}`,
+		"filter with non-literal arg": `{{range byCountry .Target .Proxies}}{{end}}`,
+		"self recursion":              `{{define "a"}}{{template "a" .}}{{end}}{{template "a" .}}`,
+		"branching recursion": `{{define "r"}}{{if .}}{{template "r" slice . 1}}{{template "r" slice . 1}}{{end}}{{end}}` +
+			`{{template "r" .Proxies}}`,
+		"mutual recursion": `{{define "a"}}{{template "b" .}}{{end}}{{define "b"}}{{template "a" .}}{{end}}{{template "a" .}}`,
+		"nested too deep":  `{{range .Nodes}}{{range .Proxies}}{{range .Tags}}{{range .Groups}}{{end}}{{end}}{{end}}{{end}}`,
+		"nested through template call": `{{define "inner"}}{{range .Proxies}}{{range .Proxies}}{{end}}{{end}}{{end}}` +
+			`{{range .Nodes}}{{range .Proxies}}{{template "inner" $}}{{end}}{{end}}`,
+	}
+	for name, tpl := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := Validate(tpl); err == nil {
+				t.Fatalf("Validate(%q) succeeded, want error", tpl)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsTemplateFanOut(t *testing.T) {
+	// 每层调用下一层两次，没有递归但展开后的调用次数为 2^30
+	var b strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&b, `{{define "t%d"}}{{template "t%d" .}}{{template "t%d" .}}{{end}}`, i, i+1, i+1)
+	}
+	b.WriteString(`{{define "t30"}}x{{end}}{{template "t0" .}}`)
+	if err := Validate(b.String()); err == nil {
+		t.Fatal("Validate succeeded for exponential template fan-out, want error")
+	}
+}
+
+func TestValidateAcceptsListRanges(t *testing.T) {
+	cases := []string{
+		`{{range .Proxies}}{{.Line}}{{end}}`,
+		`{{range .Proxies | byCountry "JP,HK"}}{{.Name}}{{end}}`,
+		`{{range .Nodes | byGroup "g1" | byTag "t1"}}{{.Name}}{{end}}`,
+		`{{range byTag "a" .Nodes}}{{.Name}}{{end}}`,
+		`{{range $i, $p := .Proxies}}{{$i}}{{$p.Name}}{{end}}`,
+		`{{range $r := .Regions}}{{range $r.Proxies}}{{.Name}}{{end}}{{end}}`,
+		`{{range .Nodes}}{{range $.Proxies}}{{.Name}}{{end}}{{end}}`,
+		`{{range names .Proxies}}{{.}}{{end}}`,
+		`{{range byCountry "JP" (byTag "a" .Proxies)}}{{.Name}}{{end}}`,
+		`{{define "line"}}{{.Line}}{{end}}{{range .Proxies}}{{template "line" .}}{{end}}`,
+		`{{range .Nodes}}{{range .Proxies}}{{range .Tags}}{{.}}{{end}}{{end}}{{end}}`,
+	}
+	for _, tpl := range cases {
+		if err := Validate(tpl); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", tpl, err)
+		}
+	}
+}
+
+func TestRenderRejectsExpensiveTemplate(t *testing.T) {
+	tpl := `{{range .Proxies}}{{range $.Proxies}}{{range $.Proxies}}{{end}}{{end}}{{end}}ok`
+	if err := Validate(tpl); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	small, err := NewSurgeGenerator(tpl, Options{}).Generate(testInput(2, 2))
+	if err != nil || small != "ok" {
+		t.Fatalf("render with 4 proxies = %q, %v; want ok", small, err)
+	}
+	if _, err := NewSurgeGenerator(tpl, Options{}).Generate(testInput(50, 4)); err == nil {
+		t.Fatal("render with 200 proxies succeeded, want too expensive error")
+	}
+}
+
+func TestRenderLegacyPlaceholders(t *testing.T) {
+	tpl := strings.Join([]string{
+		"{{username}} {{email}}",
+		"{{node_name}} {{country}} {{emoji}} {{server}}:{{port}} {{psk}}",
+		"{{node_list}}",
+		"{{node_names}}",
+	}, "\n")
+	got, err := NewSurgeGenerator(tpl, Options{}).Generate(testInput(2, 1))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := strings.Join([]string{
+		"alice alice@example.com",
+		"node1 JP 🇯🇵 10.0.0.1:10000 psk1-0",
+		"🇯🇵 node1-10000 = snell, 10.0.0.1, 10000, psk=psk1-0, version=4",
+		"🇭🇰 node2-10000 = snell, 10.0.0.2, 10000, psk=psk2-0, version=4",
+		"🇯🇵 node1-10000, 🇭🇰 node2-10000",
+	}, "\n")
+	if got != want {
+		t.Fatalf("Generate =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderEmptyPlaceholders(t *testing.T) {
+	got, err := NewSurgeGenerator("[{{node_name}}][{{port}}][{{node_list}}][{{node_names}}]", Options{}).Generate(testInput(0, 0))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got != "[][][][]" {
+		t.Fatalf("Generate = %q, want empty placeholders", got)
+	}
+}
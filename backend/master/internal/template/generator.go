@@ -0,0 +1,174 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// 节点地址的输出方式。
+const (
+	EndpointModePreferred = "preferred"
+	EndpointModeAll       = "all"
+)
+
+// Options 生成选项。
+type Options struct {
+	// EndpointMode 为 all 时每个节点地址各生成一个代理，否则只使用优先地址
+	EndpointMode string
+	// Groups 按地区自动生成代理组时的测速参数
+	Groups GroupSettings
+}
+
+// Generator 将模板渲染为某一客户端格式的订阅内容。
+type Generator interface {
+	Generate(input Input) (string, error)
+	ContentType() string
+}
+
+// NewGenerator 按模板的目标客户端创建生成器。
+func NewGenerator(target, tpl string, options Options) (Generator, error) {
+	switch target {
+	case model.TemplateTargetSurge:
+		return NewSurgeGenerator(tpl, options), nil
+	case model.TemplateTargetClash:
+		return NewClashGenerator(tpl, options), nil
+	case model.TemplateTargetStash:
+		return NewStashGenerator(tpl, options), nil
+	case model.TemplateTargetLoon:
+		return NewLoonGenerator(tpl, options), nil
+	default:
+		return nil, fmt.Errorf("unsupported target %s", target)
+	}
+}
+
+// ParseTarget 校验目标客户端，空值返回空字符串由调用方决定默认值。
+func ParseTarget(target string) (string, error) {
+	target = strings.ToLower(strings.TrimSpace(target))
+	switch target {
+	case "":
+		return "", nil
+	case model.TemplateTargetSurge, model.TemplateTargetStash, model.TemplateTargetLoon:
+		return target, nil
+	case model.TemplateTargetClash, "mihomo", "meta":
+		return model.TemplateTargetClash, nil
+	default:
+		return "", fmt.Errorf("unsupported target %s", target)
+	}
+}
+
+// DetectTarget 根据客户端 User-Agent 推断目标格式，无法识别时返回 Surge。
+// Stash 的 User-Agent 同时带有 Clash 字样，需先于 Clash 判断。
+func DetectTarget(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "stash"):
+		return model.TemplateTargetStash
+	case strings.Contains(ua, "loon"):
+		return model.TemplateTargetLoon
+	case strings.Contains(ua, "clash"), strings.Contains(ua, "mihomo"):
+		return model.TemplateTargetClash
+	default:
+		return model.TemplateTargetSurge
+	}
+}
+
+// ParseEndpointMode 校验地址输出方式，空值表示 preferred。
+func ParseEndpointMode(mode string) (string, error) {
+	switch mode {
+	case "", EndpointModePreferred:
+		return EndpointModePreferred, nil
+	case EndpointModeAll:
+		return EndpointModeAll, nil
+	default:
+		return "", fmt.Errorf("unsupported endpoint mode %s", mode)
+	}
+}
+
+// proxy 一个实例在某个节点地址上的代理。
+type proxy struct {
+	Name     string
+	Server   string
+	Port     int
+	PSK      string
+	Version  int
+	Obfs     string
+	Node     *model.Node
+	Instance *model.SnellInstance
+}
+
+// buildProxies 展开实例与节点地址，accept 为空时接受所有实例。
+func buildProxies(nodes []model.Node, instances []model.SnellInstance, options Options, accept func(*model.SnellInstance) bool) []proxy {
+	nodeMap := make(map[uint]*model.Node)
+	for i := range nodes {
+		nodeMap[nodes[i].ID] = &nodes[i]
+	}
+
+	usedNames := make(map[string]int)
+	var proxies []proxy
+	for i := range instances {
+		inst := &instances[i]
+		node := nodeMap[inst.NodeID]
+		if node == nil || (accept != nil && !accept(inst)) {
+			continue
+		}
+		baseName := fmt.Sprintf("%s-%d", node.Name, inst.Port)
+		if emoji := countryEmoji(node.CountryCode); emoji != "" {
+			baseName = emoji + " " + baseName
+		}
+		endpoints := node.SortedEndpoints()
+		if options.EndpointMode != EndpointModeAll && len(endpoints) > 1 {
+			endpoints = endpoints[:1]
+		}
+		for _, endpoint := range endpoints {
+			name := baseName
+			if len(endpoints) > 1 {
+				name += " " + endpointLabel(endpoint)
+			}
+			proxies = append(proxies, proxy{
+				Name:     uniqueName(usedNames, name),
+				Server:   endpoint.Address,
+				Port:     inst.Port,
+				PSK:      inst.PSK,
+				Version:  inst.Version,
+				Obfs:     inst.Obfs,
+				Node:     node,
+				Instance: inst,
+			})
+		}
+	}
+	return proxies
+}
+
+// endpointLabel 多地址输出时用于区分代理名称，未设置标签时使用地址类型。
+func endpointLabel(endpoint model.NodeEndpoint) string {
+	if endpoint.Label != "" {
+		return endpoint.Label
+	}
+	return endpoint.Type
+}
+
+// uniqueName 为重名的代理追加序号，客户端要求代理名称唯一。
+func uniqueName(used map[string]int, name string) string {
+	used[name]++
+	if used[name] == 1 {
+		return name
+	}
+	return fmt.Sprintf("%s %d", name, used[name])
+}
+
+func countryEmoji(code string) string {
+	if len(code) != 2 {
+		return ""
+	}
+	code = strings.ToUpper(code)
+	runes := []rune{}
+	for _, ch := range code {
+		if ch < 'A' || ch > 'Z' {
+			return ""
+		}
+		runes = append(runes, rune(0x1F1E6+(ch-'A')))
+	}
+	return string(runes)
+}
@@ -0,0 +1,133 @@
+package template
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// proxyTestInput 一个节点上 Snell v2 到 v5 的实例各一个，v3 使用 http 混淆，v4 使用 tls 混淆。
+func proxyTestInput() Input {
+	input := testInput(1, 4)
+	for i := range input.Instances {
+		input.Instances[i].Version = i + 2
+	}
+	input.Instances[1].Obfs = "http"
+	input.Instances[2].Obfs = "tls"
+	return input
+}
+
+func TestProxyLinesByTarget(t *testing.T) {
+	cases := []struct {
+		target string
+		want   []string
+	}{
+		{
+			model.TemplateTargetSurge,
+			[]string{
+				"🇯🇵 node1-10000 = snell, 10.0.0.1, 10000, psk=psk1-0, version=2",
+				"🇯🇵 node1-10001 = snell, 10.0.0.1, 10001, psk=psk1-1, version=3, obfs=http",
+				"🇯🇵 node1-10002 = snell, 10.0.0.1, 10002, psk=psk1-2, version=4, obfs=tls",
+				"🇯🇵 node1-10003 = snell, 10.0.0.1, 10003, psk=psk1-3, version=5",
+			},
+		},
+		{
+			// Mihomo 只支持到 v3，v4 与 v5 不输出
+			model.TemplateTargetClash,
+			[]string{
+				`- {"name":"🇯🇵 node1-10000","type":"snell","server":"10.0.0.1","port":10000,"psk":"psk1-0","version":2}`,
+				`  - {"name":"🇯🇵 node1-10001","type":"snell","server":"10.0.0.1","port":10001,"psk":"psk1-1","version":3,"obfs-opts":{"mode":"http"},"udp":true}`,
+			},
+		},
+		{
+			model.TemplateTargetStash,
+			[]string{
+				`- {"name":"🇯🇵 node1-10000","type":"snell","server":"10.0.0.1","port":10000,"psk":"psk1-0","version":2}`,
+				`  - {"name":"🇯🇵 node1-10001","type":"snell","server":"10.0.0.1","port":10001,"psk":"psk1-1","version":3,"obfs-opts":{"mode":"http"},"udp":true}`,
+				`  - {"name":"🇯🇵 node1-10002","type":"snell","server":"10.0.0.1","port":10002,"psk":"psk1-2","version":4,"obfs-opts":{"mode":"tls"},"udp":true}`,
+			},
+		},
+		{
+			model.TemplateTargetLoon,
+			[]string{
+				"🇯🇵 node1-10000 = Snell,10.0.0.1,10000,psk=psk1-0,version=2",
+				"🇯🇵 node1-10001 = Snell,10.0.0.1,10001,psk=psk1-1,version=3,obfs=http,udp=true",
+				"🇯🇵 node1-10002 = Snell,10.0.0.1,10002,psk=psk1-2,version=4,obfs=tls,udp=true",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.target, func(t *testing.T) {
+			g, err := NewGenerator(tc.target, "{{node_list}}", Options{})
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+			got, err := g.Generate(proxyTestInput())
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if want := strings.Join(tc.want, "\n"); got != want {
+				t.Fatalf("Generate =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestClashObfsOffOmitted(t *testing.T) {
+	input := testInput(1, 1)
+	input.Instances[0].Version = 3
+	input.Instances[0].Obfs = "off"
+	got, err := NewClashGenerator("{{node_list}}", Options{}).Generate(input)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := `- {"name":"🇯🇵 node1-10000","type":"snell","server":"10.0.0.1","port":10000,"psk":"psk1-0","version":3,"udp":true}`
+	if got != want {
+		t.Fatalf("Generate = %s, want %s", got, want)
+	}
+}
+
+func TestGenerateRejectsUnsupportedVersions(t *testing.T) {
+	cases := []struct {
+		name    string
+		target  string
+		version int
+		wantErr bool
+	}{
+		{"clash v4", model.TemplateTargetClash, 4, true},
+		{"clash v3", model.TemplateTargetClash, 3, false},
+		{"stash v5", model.TemplateTargetStash, 5, true},
+		{"stash v4", model.TemplateTargetStash, 4, false},
+		{"loon v5", model.TemplateTargetLoon, 5, true},
+		{"surge without cap", model.TemplateTargetSurge, 5, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := testInput(2, 1)
+			for i := range input.Instances {
+				input.Instances[i].Version = tc.version
+			}
+			g, err := NewGenerator(tc.target, "{{node_list}}", Options{})
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+			_, err = g.Generate(input)
+			if got := errors.Is(err, ErrUnsupportedSnellVersion); got != tc.wantErr {
+				t.Fatalf("Generate error = %v, want unsupported version error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+		})
+	}
+
+	// 部分实例被过滤时仍正常输出其余代理
+	input := testInput(1, 2)
+	input.Instances[0].Version = 3
+	got, err := NewClashGenerator("{{node_names}}", Options{}).Generate(input)
+	if err != nil || got != `["🇯🇵 node1-10000"]` {
+		t.Fatalf("Generate = %q, %v; want only the v3 proxy", got, err)
+	}
+}
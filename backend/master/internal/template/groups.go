@@ -0,0 +1,155 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 自动代理组的类型与默认测速参数。
+const (
+	groupTypeSelect   = "select"
+	groupTypeURLTest  = "url-test"
+	groupTypeFallback = "fallback"
+
+	// selectorGroupName 顶层选择组名称，与默认模板中的规则一致
+	selectorGroupName = "Proxy"
+
+	DefaultGroupTestURL   = "http://www.gstatic.com/generate_204"
+	DefaultGroupInterval  = 300
+	DefaultGroupTolerance = 50
+)
+
+// GroupSettings 按地区自动生成的代理组的测速参数，零值字段使用默认值。
+type GroupSettings struct {
+	TestURL   string
+	Interval  int
+	Tolerance int
+}
+
+func (s GroupSettings) withDefaults() GroupSettings {
+	if s.TestURL == "" {
+		s.TestURL = DefaultGroupTestURL
+	}
+	if s.Interval <= 0 {
+		s.Interval = DefaultGroupInterval
+	}
+	if s.Tolerance <= 0 {
+		s.Tolerance = DefaultGroupTolerance
+	}
+	return s
+}
+
+// RegionData 一个国家/地区的自动代理组，Name 为 url-test 组，FallbackName 为 fallback 组。
+type RegionData struct {
+	Country      string
+	Emoji        string
+	Name         string
+	FallbackName string
+	Proxies      []ProxyData
+}
+
+// proxyGroup 一个代理组定义，URL 为空时不输出测速参数。
+type proxyGroup struct {
+	Name      string
+	Type      string
+	Members   []string
+	URL       string
+	Interval  int
+	Tolerance int
+}
+
+// buildRegions 按国家代码分组，未设置国家或被隐藏的节点不进入地区组。
+func buildRegions(proxies []ProxyData) []RegionData {
+	index := make(map[string]int)
+	var regions []RegionData
+	for _, p := range proxies {
+		if p.Country == "" || p.hidden {
+			continue
+		}
+		code := strings.ToUpper(p.Country)
+		i, ok := index[code]
+		if !ok {
+			name := code
+			if emoji := countryEmoji(code); emoji != "" {
+				name = emoji + " " + code
+			}
+			regions = append(regions, RegionData{
+				Country:      code,
+				Emoji:        countryEmoji(code),
+				Name:         name,
+				FallbackName: name + " Fallback",
+			})
+			i = len(regions) - 1
+			index[code] = i
+		}
+		regions[i].Proxies = append(regions[i].Proxies, p)
+	}
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].Country < regions[j].Country })
+	return regions
+}
+
+// regionGroups 每个地区生成一个 url-test 组与一个 fallback 组。
+func regionGroups(regions []RegionData, settings GroupSettings) []proxyGroup {
+	groups := make([]proxyGroup, 0, len(regions)*2)
+	for _, region := range regions {
+		members := proxyNames(region.Proxies)
+		groups = append(groups,
+			proxyGroup{Name: region.Name, Type: groupTypeURLTest, Members: members, URL: settings.TestURL, Interval: settings.Interval, Tolerance: settings.Tolerance},
+			proxyGroup{Name: region.FallbackName, Type: groupTypeFallback, Members: members, URL: settings.TestURL, Interval: settings.Interval},
+		)
+	}
+	return groups
+}
+
+func regionGroupNames(regions []RegionData) []string {
+	names := make([]string, 0, len(regions)*2)
+	for _, region := range regions {
+		names = append(names, region.Name, region.FallbackName)
+	}
+	return names
+}
+
+// selectorGroup 顶层选择组：先列地区组，再列全部代理；没有代理时只包含 DIRECT。
+func selectorGroup(regions []RegionData, proxies []ProxyData) proxyGroup {
+	members := append(regionGroupNames(regions), proxyNames(proxies)...)
+	if len(members) == 0 {
+		members = []string{"DIRECT"}
+	}
+	return proxyGroup{Name: selectorGroupName, Type: groupTypeSelect, Members: members}
+}
+
+// formatGroups 用目标格式输出一组代理组，没有代理组时输出为空。
+func formatGroups(groups []proxyGroup, f format) (string, error) {
+	lines := make([]string, 0, len(groups))
+	for _, group := range groups {
+		line, err := f.groupLine(group)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	if f.joinLines == nil {
+		return strings.Join(lines, "\n"), nil
+	}
+	return f.joinLines(lines), nil
+}
+
+// iniGroupLine Surge/Loon 风格的代理组定义，sep 为参数分隔符。
+func iniGroupLine(group proxyGroup, sep string) (string, error) {
+	if len(group.Members) == 0 {
+		return "", fmt.Errorf("proxy group %s has no members", group.Name)
+	}
+	parts := append([]string{group.Type}, group.Members...)
+	if group.URL != "" {
+		parts = append(parts, "url="+group.URL, "interval="+strconv.Itoa(group.Interval))
+		if group.Tolerance > 0 {
+			parts = append(parts, "tolerance="+strconv.Itoa(group.Tolerance))
+		}
+	}
+	return group.Name + " = " + strings.Join(parts, sep), nil
+}
@@ -0,0 +1,198 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+const testGroupURL = "http://www.gstatic.com/generate_204"
+
+func TestBuildRegions(t *testing.T) {
+	jp1 := ProxyData{Name: "jp1", Country: "JP"}
+	jp2 := ProxyData{Name: "jp2", Country: "jp"}
+	hk := ProxyData{Name: "hk", Country: "HK"}
+	cases := []struct {
+		name    string
+		proxies []ProxyData
+		want    []RegionData
+	}{
+		{"empty proxies", nil, nil},
+		{"no country", []ProxyData{{Name: "a"}}, nil},
+		{"only hidden", []ProxyData{{Name: "a", Country: "JP", hidden: true}}, nil},
+		{
+			"grouped and sorted by country",
+			[]ProxyData{jp1, hk, {Name: "hidden", Country: "HK", hidden: true}, jp2},
+			[]RegionData{
+				{Country: "HK", Emoji: "🇭🇰", Name: "🇭🇰 HK", FallbackName: "🇭🇰 HK Fallback", Proxies: []ProxyData{hk}},
+				{Country: "JP", Emoji: "🇯🇵", Name: "🇯🇵 JP", FallbackName: "🇯🇵 JP Fallback", Proxies: []ProxyData{jp1, jp2}},
+			},
+		},
+		{
+			"code without emoji",
+			[]ProxyData{{Name: "x", Country: "EU1"}},
+			[]RegionData{{Country: "EU1", Name: "EU1", FallbackName: "EU1 Fallback", Proxies: []ProxyData{{Name: "x", Country: "EU1"}}}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := buildRegions(tc.proxies); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("buildRegions = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegionGroups(t *testing.T) {
+	settings := GroupSettings{TestURL: "http://test", Interval: 60, Tolerance: 20}
+	if got := regionGroups(nil, settings); len(got) != 0 {
+		t.Fatalf("regionGroups(nil) = %+v, want empty", got)
+	}
+
+	regions := []RegionData{{Name: "🇯🇵 JP", FallbackName: "🇯🇵 JP Fallback", Proxies: []ProxyData{{Name: "a"}, {Name: "b"}}}}
+	want := []proxyGroup{
+		{Name: "🇯🇵 JP", Type: groupTypeURLTest, Members: []string{"a", "b"}, URL: "http://test", Interval: 60, Tolerance: 20},
+		{Name: "🇯🇵 JP Fallback", Type: groupTypeFallback, Members: []string{"a", "b"}, URL: "http://test", Interval: 60},
+	}
+	if got := regionGroups(regions, settings); !reflect.DeepEqual(got, want) {
+		t.Fatalf("regionGroups = %+v, want %+v", got, want)
+	}
+}
+
+func TestIniGroupLine(t *testing.T) {
+	urlTest := proxyGroup{Name: "JP", Type: groupTypeURLTest, Members: []string{"a", "b"}, URL: "http://test", Interval: 60, Tolerance: 20}
+	fallback := proxyGroup{Name: "JP Fallback", Type: groupTypeFallback, Members: []string{"a"}, URL: "http://test", Interval: 60}
+	selector := proxyGroup{Name: "Proxy", Type: groupTypeSelect, Members: []string{"JP", "a"}}
+	cases := []struct {
+		target string
+		sep    string
+		group  proxyGroup
+		want   string
+	}{
+		{model.TemplateTargetSurge, ", ", urlTest, "JP = url-test, a, b, url=http://test, interval=60, tolerance=20"},
+		{model.TemplateTargetSurge, ", ", fallback, "JP Fallback = fallback, a, url=http://test, interval=60"},
+		{model.TemplateTargetSurge, ", ", selector, "Proxy = select, JP, a"},
+		{model.TemplateTargetLoon, ",", urlTest, "JP = url-test,a,b,url=http://test,interval=60,tolerance=20"},
+		{model.TemplateTargetLoon, ",", fallback, "JP Fallback = fallback,a,url=http://test,interval=60"},
+		{model.TemplateTargetLoon, ",", selector, "Proxy = select,JP,a"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.target+"/"+tc.group.Name, func(t *testing.T) {
+			got, err := iniGroupLine(tc.group, tc.sep)
+			if err != nil || got != tc.want {
+				t.Fatalf("iniGroupLine = %q, %v; want %q", got, err, tc.want)
+			}
+		})
+	}
+
+	if _, err := iniGroupLine(proxyGroup{Name: "empty", Type: groupTypeSelect}, ", "); err == nil {
+		t.Fatal("iniGroupLine succeeded for a group without members, want error")
+	}
+}
+
+// groupTestInput 三个 Snell v3 节点，第三个节点不进入地区组。
+func groupTestInput() Input {
+	input := testInput(3, 1)
+	for i := range input.Instances {
+		input.Instances[i].Version = 3
+	}
+	input.Nodes[2].HideFromAutoGroups = true
+	return input
+}
+
+func TestAutoGroupsByTarget(t *testing.T) {
+	const tpl = "{{region_names}}\n{{auto_groups}}"
+	cases := []struct {
+		name   string
+		target string
+		input  Input
+		want   []string
+	}{
+		{
+			"surge", model.TemplateTargetSurge, groupTestInput(),
+			[]string{
+				"🇭🇰 HK, 🇭🇰 HK Fallback, 🇯🇵 JP, 🇯🇵 JP Fallback",
+				"Proxy = select, 🇭🇰 HK, 🇭🇰 HK Fallback, 🇯🇵 JP, 🇯🇵 JP Fallback, 🇯🇵 node1-10000, 🇭🇰 node2-10000, 🇺🇸 node3-10000",
+				"🇭🇰 HK = url-test, 🇭🇰 node2-10000, url=" + testGroupURL + ", interval=300, tolerance=50",
+				"🇭🇰 HK Fallback = fallback, 🇭🇰 node2-10000, url=" + testGroupURL + ", interval=300",
+				"🇯🇵 JP = url-test, 🇯🇵 node1-10000, url=" + testGroupURL + ", interval=300, tolerance=50",
+				"🇯🇵 JP Fallback = fallback, 🇯🇵 node1-10000, url=" + testGroupURL + ", interval=300",
+			},
+		},
+		{
+			"loon", model.TemplateTargetLoon, groupTestInput(),
+			[]string{
+				"🇭🇰 HK,🇭🇰 HK Fallback,🇯🇵 JP,🇯🇵 JP Fallback",
+				"Proxy = select,🇭🇰 HK,🇭🇰 HK Fallback,🇯🇵 JP,🇯🇵 JP Fallback,🇯🇵 node1-10000,🇭🇰 node2-10000,🇺🇸 node3-10000",
+				"🇭🇰 HK = url-test,🇭🇰 node2-10000,url=" + testGroupURL + ",interval=300,tolerance=50",
+				"🇭🇰 HK Fallback = fallback,🇭🇰 node2-10000,url=" + testGroupURL + ",interval=300",
+				"🇯🇵 JP = url-test,🇯🇵 node1-10000,url=" + testGroupURL + ",interval=300,tolerance=50",
+				"🇯🇵 JP Fallback = fallback,🇯🇵 node1-10000,url=" + testGroupURL + ",interval=300",
+			},
+		},
+		{
+			"clash", model.TemplateTargetClash, groupTestInput(),
+			[]string{
+				`["🇭🇰 HK","🇭🇰 HK Fallback","🇯🇵 JP","🇯🇵 JP Fallback"]`,
+				`- {"name":"Proxy","type":"select","proxies":["🇭🇰 HK","🇭🇰 HK Fallback","🇯🇵 JP","🇯🇵 JP Fallback","🇯🇵 node1-10000","🇭🇰 node2-10000","🇺🇸 node3-10000"]}`,
+				`  - {"name":"🇭🇰 HK","type":"url-test","proxies":["🇭🇰 node2-10000"],"url":"` + testGroupURL + `","interval":300,"tolerance":50}`,
+				`  - {"name":"🇭🇰 HK Fallback","type":"fallback","proxies":["🇭🇰 node2-10000"],"url":"` + testGroupURL + `","interval":300}`,
+				`  - {"name":"🇯🇵 JP","type":"url-test","proxies":["🇯🇵 node1-10000"],"url":"` + testGroupURL + `","interval":300,"tolerance":50}`,
+				`  - {"name":"🇯🇵 JP Fallback","type":"fallback","proxies":["🇯🇵 node1-10000"],"url":"` + testGroupURL + `","interval":300}`,
+			},
+		},
+		{
+			"stash", model.TemplateTargetStash, groupTestInput(),
+			[]string{
+				`["🇭🇰 HK","🇭🇰 HK Fallback","🇯🇵 JP","🇯🇵 JP Fallback"]`,
+				`- {"name":"Proxy","type":"select","proxies":["🇭🇰 HK","🇭🇰 HK Fallback","🇯🇵 JP","🇯🇵 JP Fallback","🇯🇵 node1-10000","🇭🇰 node2-10000","🇺🇸 node3-10000"]}`,
+				`  - {"name":"🇭🇰 HK","type":"url-test","proxies":["🇭🇰 node2-10000"],"url":"` + testGroupURL + `","interval":300,"tolerance":50}`,
+				`  - {"name":"🇭🇰 HK Fallback","type":"fallback","proxies":["🇭🇰 node2-10000"],"url":"` + testGroupURL + `","interval":300}`,
+				`  - {"name":"🇯🇵 JP","type":"url-test","proxies":["🇯🇵 node1-10000"],"url":"` + testGroupURL + `","interval":300,"tolerance":50}`,
+				`  - {"name":"🇯🇵 JP Fallback","type":"fallback","proxies":["🇯🇵 node1-10000"],"url":"` + testGroupURL + `","interval":300}`,
+			},
+		},
+		// 没有代理时顶层选择组只包含 DIRECT
+		{"surge empty", model.TemplateTargetSurge, testInput(0, 0), []string{"", "Proxy = select, DIRECT"}},
+		{"loon empty", model.TemplateTargetLoon, testInput(0, 0), []string{"", "Proxy = select,DIRECT"}},
+		{"clash empty", model.TemplateTargetClash, testInput(0, 0), []string{"[]", `- {"name":"Proxy","type":"select","proxies":["DIRECT"]}`}},
+		{"stash empty", model.TemplateTargetStash, testInput(0, 0), []string{"[]", `- {"name":"Proxy","type":"select","proxies":["DIRECT"]}`}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := NewGenerator(tc.target, tpl, Options{})
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+			got, err := g.Generate(tc.input)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if want := strings.Join(tc.want, "\n"); got != want {
+				t.Fatalf("Generate =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRegionGroupsEmptyByTarget(t *testing.T) {
+	// 所有节点都被隐藏时没有地区组，region_groups 输出为空
+	input := groupTestInput()
+	for i := range input.Nodes {
+		input.Nodes[i].HideFromAutoGroups = true
+	}
+	for _, target := range []string{model.TemplateTargetSurge, model.TemplateTargetClash, model.TemplateTargetStash, model.TemplateTargetLoon} {
+		t.Run(target, func(t *testing.T) {
+			g, err := NewGenerator(target, "[{{region_groups}}]", Options{})
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+			got, err := g.Generate(input)
+			if err != nil || got != "[]" {
+				t.Fatalf("Generate = %q, %v; want empty region groups", got, err)
+			}
+		})
+	}
+}
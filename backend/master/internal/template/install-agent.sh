@@ -8,12 +8,14 @@ set -e
 
 # 配置变量（由 Master 自动填充）
 MASTER_URL="{{.MasterURL}}"
-API_TOKEN="{{.APIToken}}"
 AGENT_VERSION="{{.AgentVersion}}"
 NODE_NAME="{{.NodeName}}"
 AGENT_DOWNLOAD_URL_TEMPLATE="{{.AgentDownloadURL}}"
 AGENT_BINARY_URL_TEMPLATE="{{.AgentBinaryURL}}"
 
+# API Token 不写入脚本，通过第一个参数或环境变量 API_TOKEN 传入
+API_TOKEN="${1:-${API_TOKEN:-}}"
+
 # 安装目录
 INSTALL_DIR="/opt/snell-master/agent"
 CONFIG_DIR="/etc/snell-master"
@@ -38,6 +40,13 @@ if [ "$EUID" -ne 0 ]; then
     exit 1
 fi
 
+# 检查 API Token
+if [ -z "$API_TOKEN" ]; then
+    echo -e "${RED}错误: 缺少 API Token，用法: bash $0 <API_TOKEN>${NC}"
+    echo "新节点的 Token 在创建时显示，已有节点可在管理后台重置 API Token 获取"
+    exit 1
+fi
+
 # 检测系统架构
 detect_arch() {
     local arch=$(uname -m)
@@ -12,7 +12,6 @@ var installScriptTemplate string
 // InstallScriptData 部署脚本数据
 type InstallScriptData struct {
 	MasterURL        string // Master 服务器地址
-	APIToken         string // 节点 API Token
 	AgentVersion     string // Agent 版本号
 	NodeName         string // 节点名称
 	AgentDownloadURL string // Agent 下载地址模板（包含 {version} 和 {arch} 占位符）
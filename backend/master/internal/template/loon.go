@@ -0,0 +1,50 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// Loon 支持的最高 Snell 版本。
+const loonMaxSnellVersion = 4
+
+var loonFormat = format{
+	maxVersion: loonMaxSnellVersion,
+	proxyLine: func(p proxy) (string, error) {
+		line := fmt.Sprintf("%s = Snell,%s,%d,psk=%s,version=%d", p.Name, p.Server, p.Port, p.PSK, p.Version)
+		if p.Obfs != "" {
+			line += ",obfs=" + p.Obfs
+		}
+		if p.Version >= 3 {
+			line += ",udp=true"
+		}
+		return line, nil
+	},
+	joinLines: func(lines []string) string { return strings.Join(lines, "\n") },
+	joinNames: func(names []string) (string, error) { return strings.Join(names, ","), nil },
+	groupLine: func(group proxyGroup) (string, error) { return iniGroupLine(group, ",") },
+	rules:     loonRuleSyntax,
+}
+
+// LoonGenerator 将模板渲染为 Loon 配置。
+type LoonGenerator struct {
+	template string
+	options  Options
+}
+
+// NewLoonGenerator 创建生成器。
+func NewLoonGenerator(tpl string, options Options) *LoonGenerator {
+	return &LoonGenerator{template: tpl, options: options}
+}
+
+// ContentType Loon 配置为纯文本。
+func (g *LoonGenerator) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// Generate 根据用户、节点和实例数据生成文本。
+func (g *LoonGenerator) Generate(input Input) (string, error) {
+	return render(g.template, model.TemplateTargetLoon, input, g.options, loonFormat)
+}
@@ -0,0 +1,364 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// 规则集相关的模板函数名，RuleSetNames 据此找出模板引用的规则集。
+const (
+	funcRules         = "rules"
+	funcRuleProviders = "rule_providers"
+)
+
+// ruleSyntax 一种客户端格式的规则与远程规则集语法，规则行以 Surge 写法保存。
+type ruleSyntax struct {
+	// remote 远程规则集在规则段中的一行，为 nil 表示该格式在单独的段中引用
+	remote func(set model.RuleSet) string
+	// provider 远程规则集在规则集段中的定义，为 nil 表示该格式不需要单独定义
+	provider func(set model.RuleSet) (string, error)
+	// rename 与 Surge 写法不同的规则类型，drop 为该格式不支持、需要跳过的规则类型
+	rename        map[string]string
+	drop          map[string]bool
+	joinRules     func(lines []string) string
+	joinProviders func(lines []string) string
+}
+
+// 不能出现在规则集中的规则类型：兜底规则由模板书写，规则集之间不互相引用。
+var reservedRuleTypes = map[string]bool{
+	"FINAL":      true,
+	"MATCH":      true,
+	"RULE-SET":   true,
+	"DOMAIN-SET": true,
+}
+
+var iniRuleSyntax = ruleSyntax{
+	joinRules:     func(lines []string) string { return strings.Join(lines, "\n") },
+	joinProviders: func(lines []string) string { return strings.Join(lines, "\n") },
+}
+
+var surgeRuleSyntax = func() ruleSyntax {
+	syntax := iniRuleSyntax
+	syntax.remote = func(set model.RuleSet) string {
+		kind := "RULE-SET"
+		if set.Behavior == model.RuleSetBehaviorDomain {
+			kind = "DOMAIN-SET"
+		}
+		return fmt.Sprintf("%s,%s,%s,update-interval=%d", kind, set.URL, set.Policy, set.Interval)
+	}
+	return syntax
+}()
+
+// loonRuleSyntax Loon 的远程规则在 [Remote Rule] 段中定义，规则段中不再引用。
+var loonRuleSyntax = func() ruleSyntax {
+	syntax := iniRuleSyntax
+	syntax.provider = func(set model.RuleSet) (string, error) {
+		return fmt.Sprintf("%s, policy=%s, tag=%s, enabled=true", set.URL, set.Policy, set.Name), nil
+	}
+	return syntax
+}()
+
+// clashRuleProvider Clash 的 rule-providers 条目，字段顺序即输出顺序。
+type clashRuleProvider struct {
+	Type     string `json:"type"`
+	Behavior string `json:"behavior"`
+	Format   string `json:"format"`
+	URL      string `json:"url"`
+	Path     string `json:"path"`
+	Interval int    `json:"interval"`
+}
+
+// yamlRuleSyntax Clash/Stash 的规则以 JSON 字符串输出，避免策略名中的特殊字符破坏 YAML。
+var yamlRuleSyntax = ruleSyntax{
+	remote: func(set model.RuleSet) string {
+		return "RULE-SET," + set.Name + "," + set.Policy
+	},
+	provider: func(set model.RuleSet) (string, error) {
+		data, err := json.Marshal(clashRuleProvider{
+			Type:     "http",
+			Behavior: set.Behavior,
+			Format:   ruleSetFileFormat(set.URL),
+			URL:      set.URL,
+			Path:     "./rule-sets/" + set.Name + ruleSetFileExt(set.URL),
+			Interval: set.Interval,
+		})
+		if err != nil {
+			return "", fmt.Errorf("encode rule set %s: %w", set.Name, err)
+		}
+		return strconv.Quote(set.Name) + ": " + string(data), nil
+	},
+	rename: map[string]string{
+		"DEST-PORT": "DST-PORT",
+		"SRC-IP":    "SRC-IP-CIDR",
+	},
+	drop: map[string]bool{
+		"USER-AGENT": true,
+		"URL-REGEX":  true,
+	},
+	// 首行的缩进由模板提供，与 node_list 一致
+	joinRules: func(lines []string) string {
+		quoted := make([]string, 0, len(lines))
+		for _, line := range lines {
+			data, _ := json.Marshal(line)
+			quoted = append(quoted, string(data))
+		}
+		if len(quoted) == 0 {
+			return ""
+		}
+		return "- " + strings.Join(quoted, "\n  - ")
+	},
+	joinProviders: func(lines []string) string {
+		if len(lines) == 0 {
+			// 空映射时 rule-providers 段保持合法
+			return "{}"
+		}
+		return strings.Join(lines, "\n  ")
+	},
+}
+
+// ruleSetFileFormat 按远程地址的扩展名判断 Clash 规则集文件格式，默认每行一条的文本。
+func ruleSetFileFormat(rawURL string) string {
+	switch ruleSetFileExt(rawURL) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".mrs":
+		return "mrs"
+	default:
+		return "text"
+	}
+}
+
+func ruleSetFileExt(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ".txt"
+	}
+	switch ext := strings.ToLower(path.Ext(u.Path)); ext {
+	case ".yaml", ".yml", ".mrs":
+		return ext
+	default:
+		return ".txt"
+	}
+}
+
+// ValidateRules 检查 inline 规则集内容，每行为不含策略的 Surge 写法规则，# 或 // 开头的行为注释。
+func ValidateRules(content string) error {
+	_, err := parseRules(content)
+	return err
+}
+
+// parseRules 将规则行拆分为顶层字段，括号内的逗号属于逻辑规则的子规则。
+func parseRules(content string) ([][]string, error) {
+	var rules [][]string
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		fields, err := splitRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if len(fields) < 2 || fields[1] == "" {
+			return nil, fmt.Errorf("line %d: expected TYPE,VALUE", i+1)
+		}
+		fields[0] = strings.ToUpper(fields[0])
+		if reservedRuleTypes[fields[0]] {
+			return nil, fmt.Errorf("line %d: %s is not allowed in a rule set", i+1, fields[0])
+		}
+		for _, c := range fields[0] {
+			if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+				return nil, fmt.Errorf("line %d: invalid rule type %q", i+1, fields[0])
+			}
+		}
+		rules = append(rules, fields)
+	}
+	return rules, nil
+}
+
+func splitRule(line string) ([]string, error) {
+	var fields []string
+	depth, start := 0, 0
+	for i, c := range line {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				fields = append(fields, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return append(fields, strings.TrimSpace(line[start:])), nil
+}
+
+// ruleLines 按目标格式输出规则集的规则，策略插在规则值之后、no-resolve 等选项之前。
+func ruleLines(set model.RuleSet, syntax ruleSyntax) ([]string, error) {
+	if set.Source == model.RuleSetSourceRemote {
+		if syntax.remote == nil {
+			return nil, nil
+		}
+		return []string{syntax.remote(set)}, nil
+	}
+	rules, err := parseRules(set.Content)
+	if err != nil {
+		return nil, fmt.Errorf("rule set %s: %w", set.Name, err)
+	}
+	lines := make([]string, 0, len(rules))
+	for _, fields := range rules {
+		kind := fields[0]
+		if syntax.drop[kind] {
+			continue
+		}
+		if renamed, ok := syntax.rename[kind]; ok {
+			kind = renamed
+		}
+		parts := append([]string{kind, fields[1], set.Policy}, fields[2:]...)
+		lines = append(lines, strings.Join(parts, ","))
+	}
+	return lines, nil
+}
+
+// ruleSetFuncs 返回 rules 与 rule_providers 模板函数。
+// rules 按给定顺序输出规则集的规则；rule_providers 输出远程规则集的定义，未给名称时输出全部远程规则集。
+func ruleSetFuncs(sets []model.RuleSet, syntax ruleSyntax) map[string]any {
+	byName := make(map[string]model.RuleSet, len(sets))
+	for _, set := range sets {
+		byName[set.Name] = set
+	}
+	lookup := func(names []string) ([]model.RuleSet, error) {
+		result := make([]model.RuleSet, 0, len(names))
+		for _, name := range names {
+			set, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("rule set %q not found", name)
+			}
+			result = append(result, set)
+		}
+		return result, nil
+	}
+	return map[string]any{
+		funcRules: func(names ...string) (string, error) {
+			if len(names) == 0 {
+				return "", fmt.Errorf("rules: at least one rule set name is required")
+			}
+			selected, err := lookup(names)
+			if err != nil {
+				return "", err
+			}
+			var lines []string
+			for _, set := range selected {
+				setLines, err := ruleLines(set, syntax)
+				if err != nil {
+					return "", err
+				}
+				lines = append(lines, setLines...)
+			}
+			return syntax.joinRules(lines), nil
+		},
+		funcRuleProviders: func(names ...string) (string, error) {
+			selected := sets
+			if len(names) > 0 {
+				var err error
+				if selected, err = lookup(names); err != nil {
+					return "", err
+				}
+			}
+			var lines []string
+			for _, set := range selected {
+				if set.Source != model.RuleSetSourceRemote || syntax.provider == nil {
+					continue
+				}
+				line, err := syntax.provider(set)
+				if err != nil {
+					return "", err
+				}
+				lines = append(lines, line)
+			}
+			return syntax.joinProviders(lines), nil
+		},
+	}
+}
+
+// RuleSetNames 返回模板中以字符串字面量传给 rules 与 rule_providers 的规则集名称。
+func RuleSetNames(tpl string) ([]string, error) {
+	t, err := parseTemplate(tpl, newFuncMap(&Data{}, format{}, GroupSettings{}, nil))
+	if err != nil {
+		return nil, err
+	}
+	// 按模板名遍历，结果顺序稳定
+	subs := t.Templates()
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Name() < subs[j].Name() })
+	var names []string
+	for _, sub := range subs {
+		if sub.Tree != nil {
+			collectRuleSetNames(sub.Tree.Root, &names)
+		}
+	}
+	return names, nil
+}
+
+func collectRuleSetNames(node parse.Node, names *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectRuleSetNames(child, names)
+		}
+	case *parse.ActionNode:
+		collectRuleSetNames(n.Pipe, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectRuleSetNames(cmd, names)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 0 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == funcRules || ident.Ident == funcRuleProviders) {
+				for _, arg := range n.Args[1:] {
+					if str, ok := arg.(*parse.StringNode); ok {
+						*names = appendUnique(*names, str.Text)
+					}
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			collectRuleSetNames(arg, names)
+		}
+	case *parse.IfNode:
+		collectRuleSetNames(n.Pipe, names)
+		collectRuleSetNames(n.List, names)
+		collectRuleSetNames(n.ElseList, names)
+	case *parse.RangeNode:
+		collectRuleSetNames(n.Pipe, names)
+		collectRuleSetNames(n.List, names)
+		collectRuleSetNames(n.ElseList, names)
+	case *parse.WithNode:
+		collectRuleSetNames(n.Pipe, names)
+		collectRuleSetNames(n.List, names)
+		collectRuleSetNames(n.ElseList, names)
+	case *parse.TemplateNode:
+		collectRuleSetNames(n.Pipe, names)
+	}
+}
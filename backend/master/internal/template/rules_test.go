@@ -0,0 +1,309 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+func TestSplitRule(t *testing.T) {
+	cases := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "DOMAIN,example.com", want: []string{"DOMAIN", "example.com"}},
+		{line: "IP-CIDR, 10.0.0.0/8 ,no-resolve", want: []string{"IP-CIDR", "10.0.0.0/8", "no-resolve"}},
+		{line: "DOMAIN,", want: []string{"DOMAIN", ""}},
+		{
+			line: "AND,((DOMAIN,example.com),(DEST-PORT,443))",
+			want: []string{"AND", "((DOMAIN,example.com),(DEST-PORT,443))"},
+		},
+		{line: "AND,((DOMAIN,example.com)", wantErr: true},
+		{line: "AND,)(DOMAIN,example.com(", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.line, func(t *testing.T) {
+			got, err := splitRule(tc.line)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("splitRule(%q) = %q, want error", tc.line, got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("splitRule(%q) = %q, %v; want %q", tc.line, got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	content := strings.Join([]string{
+		"# comment",
+		"",
+		"// comment",
+		"domain-suffix,example.com",
+		"  IP-CIDR,10.0.0.0/8,no-resolve  ",
+		"OR,((DOMAIN,a.com),(DOMAIN,b.com))",
+	}, "\n")
+	want := [][]string{
+		{"DOMAIN-SUFFIX", "example.com"},
+		{"IP-CIDR", "10.0.0.0/8", "no-resolve"},
+		{"OR", "((DOMAIN,a.com),(DOMAIN,b.com))"},
+	}
+	got, err := parseRules(content)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseRules = %q, %v; want %q", got, err, want)
+	}
+
+	if got, err := parseRules("\n# only comments\n"); err != nil || len(got) != 0 {
+		t.Fatalf("parseRules(comments) = %q, %v; want no rules", got, err)
+	}
+
+	invalid := map[string]string{
+		"missing value": "DOMAIN",
+		"empty value":   "DOMAIN,",
+		"final rule":    "FINAL,DIRECT",
+		"match rule":    "match,DIRECT",
+		"nested set":    "RULE-SET,https://example.com/list.txt",
+		"invalid type":  "DOMAIN SUFFIX,example.com",
+		"unbalanced":    "AND,((DOMAIN,a.com)",
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseRules(content); err == nil {
+				t.Fatalf("parseRules(%q) succeeded, want error", content)
+			}
+		})
+	}
+	if _, err := parseRules("DOMAIN,a.com\nDOMAIN"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("parseRules error = %v, want it to name line 2", err)
+	}
+}
+
+var testRuleSets = []model.RuleSet{
+	{
+		Name:   "inline",
+		Source: model.RuleSetSourceInline,
+		Policy: "Proxy",
+		Content: strings.Join([]string{
+			"DOMAIN-SUFFIX,example.com",
+			"IP-CIDR,10.0.0.0/8,no-resolve",
+			"DEST-PORT,443",
+			"SRC-IP,192.168.1.1",
+			"USER-AGENT,Example*",
+			"URL-REGEX,^https://example.com/ad",
+		}, "\n"),
+	},
+	{
+		Name:     "remote",
+		Source:   model.RuleSetSourceRemote,
+		Behavior: model.RuleSetBehaviorDomain,
+		URL:      "https://example.com/list.txt",
+		Policy:   "DIRECT",
+		Interval: 86400,
+	},
+	{
+		Name:     "classical",
+		Source:   model.RuleSetSourceRemote,
+		Behavior: model.RuleSetBehaviorClassical,
+		URL:      "https://example.com/rules.yaml",
+		Policy:   "REJECT",
+		Interval: 3600,
+	},
+}
+
+func TestRuleLinesByTarget(t *testing.T) {
+	iniInline := []string{
+		"DOMAIN-SUFFIX,example.com,Proxy",
+		"IP-CIDR,10.0.0.0/8,Proxy,no-resolve",
+		"DEST-PORT,443,Proxy",
+		"SRC-IP,192.168.1.1,Proxy",
+		"USER-AGENT,Example*,Proxy",
+		"URL-REGEX,^https://example.com/ad,Proxy",
+	}
+	// Clash/Stash 改用各自的规则类型名，并跳过不支持的 USER-AGENT 与 URL-REGEX
+	yamlInline := []string{
+		"DOMAIN-SUFFIX,example.com,Proxy",
+		"IP-CIDR,10.0.0.0/8,Proxy,no-resolve",
+		"DST-PORT,443,Proxy",
+		"SRC-IP-CIDR,192.168.1.1,Proxy",
+	}
+	cases := []struct {
+		target    string
+		syntax    ruleSyntax
+		inline    []string
+		remote    []string
+		classical []string
+	}{
+		{
+			model.TemplateTargetSurge, surgeFormat.rules, iniInline,
+			[]string{"DOMAIN-SET,https://example.com/list.txt,DIRECT,update-interval=86400"},
+			[]string{"RULE-SET,https://example.com/rules.yaml,REJECT,update-interval=3600"},
+		},
+		// Loon 的远程规则只在 [Remote Rule] 段中出现
+		{model.TemplateTargetLoon, loonFormat.rules, iniInline, nil, nil},
+		{
+			model.TemplateTargetClash, yamlFormat(clashMaxSnellVersion).rules, yamlInline,
+			[]string{"RULE-SET,remote,DIRECT"},
+			[]string{"RULE-SET,classical,REJECT"},
+		},
+		{
+			model.TemplateTargetStash, yamlFormat(stashMaxSnellVersion).rules, yamlInline,
+			[]string{"RULE-SET,remote,DIRECT"},
+			[]string{"RULE-SET,classical,REJECT"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.target, func(t *testing.T) {
+			for i, want := range [][]string{tc.inline, tc.remote, tc.classical} {
+				got, err := ruleLines(testRuleSets[i], tc.syntax)
+				if err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("ruleLines(%s) = %q, %v; want %q", testRuleSets[i].Name, got, err, want)
+				}
+			}
+		})
+	}
+
+	broken := model.RuleSet{Name: "broken", Source: model.RuleSetSourceInline, Content: "DOMAIN"}
+	if _, err := ruleLines(broken, surgeRuleSyntax); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("ruleLines error = %v, want it to name the rule set", err)
+	}
+}
+
+func TestRuleSetFuncsByTarget(t *testing.T) {
+	const tpl = "{{rules \"inline\" \"remote\"}}\n---\n{{rule_providers}}\n---\n{{rule_providers \"inline\"}}"
+	cases := []struct {
+		target string
+		want   []string
+	}{
+		{
+			model.TemplateTargetSurge,
+			[]string{
+				"DOMAIN-SUFFIX,example.com,Proxy",
+				"IP-CIDR,10.0.0.0/8,Proxy,no-resolve",
+				"DEST-PORT,443,Proxy",
+				"SRC-IP,192.168.1.1,Proxy",
+				"USER-AGENT,Example*,Proxy",
+				"URL-REGEX,^https://example.com/ad,Proxy",
+				"DOMAIN-SET,https://example.com/list.txt,DIRECT,update-interval=86400",
+				"---",
+				"",
+				"---",
+				"",
+			},
+		},
+		{
+			model.TemplateTargetLoon,
+			[]string{
+				"DOMAIN-SUFFIX,example.com,Proxy",
+				"IP-CIDR,10.0.0.0/8,Proxy,no-resolve",
+				"DEST-PORT,443,Proxy",
+				"SRC-IP,192.168.1.1,Proxy",
+				"USER-AGENT,Example*,Proxy",
+				"URL-REGEX,^https://example.com/ad,Proxy",
+				"---",
+				"https://example.com/list.txt, policy=DIRECT, tag=remote, enabled=true",
+				"https://example.com/rules.yaml, policy=REJECT, tag=classical, enabled=true",
+				"---",
+				"",
+			},
+		},
+		{
+			model.TemplateTargetClash,
+			[]string{
+				`- "DOMAIN-SUFFIX,example.com,Proxy"`,
+				`  - "IP-CIDR,10.0.0.0/8,Proxy,no-resolve"`,
+				`  - "DST-PORT,443,Proxy"`,
+				`  - "SRC-IP-CIDR,192.168.1.1,Proxy"`,
+				`  - "RULE-SET,remote,DIRECT"`,
+				"---",
+				`"remote": {"type":"http","behavior":"domain","format":"text","url":"https://example.com/list.txt","path":"./rule-sets/remote.txt","interval":86400}`,
+				`  "classical": {"type":"http","behavior":"classical","format":"yaml","url":"https://example.com/rules.yaml","path":"./rule-sets/classical.yaml","interval":3600}`,
+				"---",
+				"{}",
+			},
+		},
+		{
+			model.TemplateTargetStash,
+			[]string{
+				`- "DOMAIN-SUFFIX,example.com,Proxy"`,
+				`  - "IP-CIDR,10.0.0.0/8,Proxy,no-resolve"`,
+				`  - "DST-PORT,443,Proxy"`,
+				`  - "SRC-IP-CIDR,192.168.1.1,Proxy"`,
+				`  - "RULE-SET,remote,DIRECT"`,
+				"---",
+				`"remote": {"type":"http","behavior":"domain","format":"text","url":"https://example.com/list.txt","path":"./rule-sets/remote.txt","interval":86400}`,
+				`  "classical": {"type":"http","behavior":"classical","format":"yaml","url":"https://example.com/rules.yaml","path":"./rule-sets/classical.yaml","interval":3600}`,
+				"---",
+				"{}",
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.target, func(t *testing.T) {
+			g, err := NewGenerator(tc.target, tpl, Options{})
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+			input := testInput(1, 1)
+			input.Instances[0].Version = 3 // Clash 不支持 v4，全部实例被过滤时生成失败
+			input.RuleSets = testRuleSets
+			got, err := g.Generate(input)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if want := strings.Join(tc.want, "\n"); got != want {
+				t.Fatalf("Generate =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRuleSetFuncsErrors(t *testing.T) {
+	cases := map[string]string{
+		"no names":         `{{rules}}`,
+		"unknown rules":    `{{rules "missing"}}`,
+		"unknown provider": `{{rule_providers "missing"}}`,
+	}
+	for name, tpl := range cases {
+		t.Run(name, func(t *testing.T) {
+			input := testInput(1, 1)
+			input.RuleSets = testRuleSets
+			if _, err := NewSurgeGenerator(tpl, Options{}).Generate(input); err == nil {
+				t.Fatalf("Generate(%q) succeeded, want error", tpl)
+			}
+		})
+	}
+}
+
+func TestRuleSetNames(t *testing.T) {
+	cases := []struct {
+		name string
+		tpl  string
+		want []string
+	}{
+		{"none", `{{node_list}}`, nil},
+		{"rules and providers", `{{rules "a" "b"}}{{rule_providers "c"}}`, []string{"a", "b", "c"}},
+		{"duplicates", `{{rules "a"}}{{rules "a" "b"}}{{rule_providers "b"}}`, []string{"a", "b"}},
+		{"all providers", `{{rule_providers}}`, nil},
+		{"inside blocks", `{{if .Proxies}}{{rules "a"}}{{else}}{{rules "b"}}{{end}}{{range .Proxies}}{{rules "c"}}{{end}}{{with .User}}{{rules "d"}}{{end}}`, []string{"a", "b", "c", "d"}},
+		{"nested pipeline", `{{print (rules "a")}}`, []string{"a"}},
+		{"defined templates", `{{define "z"}}{{rules "z"}}{{end}}{{define "b"}}{{rules "b"}}{{end}}{{rules "main"}}{{template "z"}}{{template "b"}}`, []string{"b", "main", "z"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RuleSetNames(tc.tpl)
+			if err != nil || !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("RuleSetNames = %q, %v; want %q", got, err, tc.want)
+			}
+		})
+	}
+
+	if _, err := RuleSetNames(`{{rules "a"`); err == nil {
+		t.Fatal("RuleSetNames succeeded for an invalid template, want error")
+	}
+}
@@ -0,0 +1,335 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/iwoov/snell-master/backend/master/internal/model"
+)
+
+// text/template 无法中断不产生输出的循环，渲染前需静态限制模板：
+// range 只能遍历列表字段及其筛选结果，template 之间不能递归调用，
+// 并按数据量估算执行步数，超出 maxRenderSteps 时拒绝渲染。
+const maxRenderSteps = 5_000_000
+
+var (
+	// rangeFields 可以 range 的列表字段。
+	rangeFields = map[string]bool{"Proxies": true, "Nodes": true, "Regions": true, "Groups": true, "Tags": true}
+	// rangeFuncs 可以在 range 中使用的函数，结果不长于传入的列表。
+	rangeFuncs = map[string]bool{"byCountry": true, "byGroup": true, "byTag": true, "names": true}
+	// listFuncs 开销与代理、节点或规则数量成正比的函数。
+	listFuncs = map[string]bool{
+		"node_list": true, "node_names": true, "region_groups": true, "region_names": true, "auto_groups": true,
+		"byCountry": true, "byGroup": true, "byTag": true, "names": true, "join": true,
+		funcRules: true, funcRuleProviders: true,
+	}
+)
+
+// renderSizes 渲染数据中各列表的长度，fields 未列出的列表按 items 计算。
+type renderSizes struct {
+	fields map[string]int
+	items  int
+}
+
+// unitSizes 校验模板时没有数据，每个列表按一项计算，只衡量 template 调用的展开次数。
+var unitSizes = renderSizes{items: 1}
+
+func newRenderSizes(data *Data, ruleSets []model.RuleSet) renderSizes {
+	sizes := renderSizes{fields: map[string]int{
+		"Proxies": len(data.Proxies),
+		"Nodes":   len(data.Nodes),
+		"Regions": len(data.Regions),
+	}}
+	for _, node := range data.Nodes {
+		sizes.fields["Groups"] = max(sizes.fields["Groups"], len(node.Groups))
+		sizes.fields["Tags"] = max(sizes.fields["Tags"], len(node.Tags))
+	}
+	sizes.items = len(data.Proxies) + len(data.Nodes)
+	for _, set := range ruleSets {
+		sizes.items += strings.Count(set.Content, "\n") + 1
+	}
+	return sizes
+}
+
+func (s renderSizes) field(name string) int {
+	if n, ok := s.fields[name]; ok {
+		return n
+	}
+	return s.items
+}
+
+// checkTemplates 检查 range 的数据来源、包含 template 调用在内的 range 嵌套深度，以及调用是否成环。
+func checkTemplates(t *texttemplate.Template) error {
+	trees := make(map[string]*parse.Tree)
+	for _, sub := range t.Templates() {
+		if sub.Tree != nil {
+			trees[sub.Name()] = sub.Tree
+		}
+	}
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c := &templateChecker{trees: trees, depth: make(map[string]int), visiting: make(map[string]bool)}
+	for _, name := range names {
+		depth, err := c.templateDepth(name)
+		if err != nil {
+			return fmt.Errorf("template %s: %w", name, err)
+		}
+		if depth > maxRangeDepth {
+			return fmt.Errorf("template %s: range nested deeper than %d levels", name, maxRangeDepth)
+		}
+	}
+	if steps := estimateSteps(t, unitSizes); steps > maxRenderSteps {
+		return fmt.Errorf("template calls expand to more than %d steps", maxRenderSteps)
+	}
+	return nil
+}
+
+type templateChecker struct {
+	trees    map[string]*parse.Tree
+	depth    map[string]int
+	visiting map[string]bool
+}
+
+// templateDepth 返回模板中包含被调用模板在内的最大 range 嵌套深度。
+func (c *templateChecker) templateDepth(name string) (int, error) {
+	if depth, ok := c.depth[name]; ok {
+		return depth, nil
+	}
+	tree, ok := c.trees[name]
+	if !ok {
+		// 调用未定义的模板在执行时报错
+		return 0, nil
+	}
+	if c.visiting[name] {
+		return 0, fmt.Errorf("template %s is called recursively", name)
+	}
+	c.visiting[name] = true
+	depth, err := c.nodeDepth(tree.Root)
+	delete(c.visiting, name)
+	if err != nil {
+		return 0, err
+	}
+	c.depth[name] = depth
+	return depth, nil
+}
+
+func (c *templateChecker) nodeDepth(node parse.Node) (int, error) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return 0, nil
+		}
+		depth := 0
+		for _, child := range n.Nodes {
+			d, err := c.nodeDepth(child)
+			if err != nil {
+				return 0, err
+			}
+			depth = max(depth, d)
+		}
+		return depth, nil
+	case *parse.RangeNode:
+		if err := checkRangePipe(n.Pipe); err != nil {
+			return 0, err
+		}
+		body, err := c.nodeDepth(n.List)
+		if err != nil {
+			return 0, err
+		}
+		elseDepth, err := c.nodeDepth(n.ElseList)
+		if err != nil {
+			return 0, err
+		}
+		return max(body+1, elseDepth), nil
+	case *parse.IfNode:
+		return c.branchDepth(n.List, n.ElseList)
+	case *parse.WithNode:
+		return c.branchDepth(n.List, n.ElseList)
+	case *parse.TemplateNode:
+		return c.templateDepth(n.Name)
+	}
+	return 0, nil
+}
+
+func (c *templateChecker) branchDepth(list, elseList *parse.ListNode) (int, error) {
+	depth, err := c.nodeDepth(list)
+	if err != nil {
+		return 0, err
+	}
+	elseDepth, err := c.nodeDepth(elseList)
+	if err != nil {
+		return 0, err
+	}
+	return max(depth, elseDepth), nil
+}
+
+// checkRangePipe 只允许 range 列表字段（.Proxies、$.Nodes、$r.Proxies 等）及其经 byCountry 等函数筛选的结果，
+// 整数、变量或其他函数的结果可能产生极长且不产生输出的循环。
+func checkRangePipe(pipe *parse.PipeNode) error {
+	if rangeSource(pipe) == "" {
+		return fmt.Errorf("range over %s is not allowed, range over a list field such as .Proxies or its byCountry/byGroup/byTag result", pipe)
+	}
+	return nil
+}
+
+// rangeSource 返回 range 遍历的列表字段名，不是允许的来源时返回空字符串。
+func rangeSource(pipe *parse.PipeNode) string {
+	if pipe == nil || len(pipe.Cmds) == 0 {
+		return ""
+	}
+	source := ""
+	for i, cmd := range pipe.Cmds {
+		if len(cmd.Args) == 0 {
+			return ""
+		}
+		if i == 0 && len(cmd.Args) == 1 {
+			if field := listField(cmd.Args[0]); field != "" {
+				source = field
+				continue
+			}
+		}
+		ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+		if !ok || !rangeFuncs[ident.Ident] {
+			return ""
+		}
+		args := cmd.Args[1:]
+		// 管道中的后续函数以前一命令的结果为最后一个参数，首个命令需显式给出列表
+		if i == 0 {
+			if len(args) == 0 {
+				return ""
+			}
+			if source = listArg(args[len(args)-1]); source == "" {
+				return ""
+			}
+			args = args[:len(args)-1]
+		}
+		for _, arg := range args {
+			if _, ok := arg.(*parse.StringNode); !ok {
+				return ""
+			}
+		}
+	}
+	return source
+}
+
+func listArg(node parse.Node) string {
+	if pipe, ok := node.(*parse.PipeNode); ok {
+		if len(pipe.Decl) > 0 {
+			return ""
+		}
+		return rangeSource(pipe)
+	}
+	return listField(node)
+}
+
+// listField 返回以列表字段结尾的字段链的字段名。
+func listField(node parse.Node) string {
+	var idents []string
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		idents = n.Ident
+	case *parse.VariableNode:
+		idents = n.Ident[1:]
+	}
+	if len(idents) == 0 || !rangeFields[idents[len(idents)-1]] {
+		return ""
+	}
+	return idents[len(idents)-1]
+}
+
+// estimateSteps 按列表长度估算执行模板需要的步数，结果不超过 maxRenderSteps+1。
+// 调用方需先通过 checkTemplates 确认模板之间没有递归调用。
+func estimateSteps(t *texttemplate.Template, sizes renderSizes) int {
+	e := &stepEstimator{t: t, sizes: sizes, memo: make(map[string]int)}
+	return e.templateSteps(t.Name())
+}
+
+type stepEstimator struct {
+	t     *texttemplate.Template
+	sizes renderSizes
+	memo  map[string]int
+}
+
+func (e *stepEstimator) templateSteps(name string) int {
+	if steps, ok := e.memo[name]; ok {
+		return steps
+	}
+	sub := e.t.Lookup(name)
+	if sub == nil || sub.Tree == nil {
+		return 1
+	}
+	steps := e.nodeSteps(sub.Tree.Root)
+	e.memo[name] = steps
+	return steps
+}
+
+func (e *stepEstimator) nodeSteps(node parse.Node) int {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return 0
+		}
+		steps := 0
+		for _, child := range n.Nodes {
+			steps = addSteps(steps, e.nodeSteps(child))
+		}
+		return steps
+	case *parse.ActionNode:
+		return e.pipeSteps(n.Pipe)
+	case *parse.IfNode:
+		return addSteps(e.pipeSteps(n.Pipe), addSteps(e.nodeSteps(n.List), e.nodeSteps(n.ElseList)))
+	case *parse.WithNode:
+		return addSteps(e.pipeSteps(n.Pipe), addSteps(e.nodeSteps(n.List), e.nodeSteps(n.ElseList)))
+	case *parse.RangeNode:
+		// 循环体为空时每次迭代仍计一步
+		body := mulSteps(e.sizes.field(rangeSource(n.Pipe)), addSteps(1, e.nodeSteps(n.List)))
+		return addSteps(e.pipeSteps(n.Pipe), addSteps(body, e.nodeSteps(n.ElseList)))
+	case *parse.TemplateNode:
+		return addSteps(e.pipeSteps(n.Pipe), e.templateSteps(n.Name))
+	}
+	return 1
+}
+
+// pipeSteps 每条命令计一步，处理整个列表的函数按条目数计算。
+func (e *stepEstimator) pipeSteps(pipe *parse.PipeNode) int {
+	if pipe == nil {
+		return 1
+	}
+	steps := 1
+	for _, cmd := range pipe.Cmds {
+		cost := 1
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode:
+				if listFuncs[a.Ident] {
+					cost = max(cost, e.sizes.items)
+				}
+			case *parse.PipeNode:
+				cost = addSteps(cost, e.pipeSteps(a))
+			}
+		}
+		steps = addSteps(steps, cost)
+	}
+	return steps
+}
+
+func addSteps(a, b int) int {
+	return min(a+b, maxRenderSteps+1)
+}
+
+func mulSteps(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > (maxRenderSteps+1)/b {
+		return maxRenderSteps + 1
+	}
+	return min(a*b, maxRenderSteps+1)
+}
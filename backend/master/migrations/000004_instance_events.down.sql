@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_instance_events_node;
+DROP INDEX IF EXISTS idx_instance_events_instance;
+DROP TABLE IF EXISTS instance_events;
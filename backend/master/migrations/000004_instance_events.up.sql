@@ -0,0 +1,15 @@
+-- Instance events reported by agents (config apply failures, rollbacks)
+CREATE TABLE IF NOT EXISTS instance_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    instance_id INTEGER NOT NULL,
+    node_id INTEGER NOT NULL,
+    event_type TEXT NOT NULL,
+    message TEXT,
+    occurred_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(instance_id) REFERENCES snell_instances(id) ON DELETE CASCADE,
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_instance_events_instance ON instance_events(instance_id);
+CREATE INDEX IF NOT EXISTS idx_instance_events_node ON instance_events(node_id);
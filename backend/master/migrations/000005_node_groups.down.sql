@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_user_node_tags_tag;
+DROP INDEX IF EXISTS idx_user_node_groups_group;
+DROP INDEX IF EXISTS idx_node_group_members_node;
+DROP INDEX IF EXISTS idx_node_group_tags_tag;
+
+DROP TABLE IF EXISTS user_node_tags;
+DROP TABLE IF EXISTS user_node_groups;
+DROP TABLE IF EXISTS node_group_members;
+DROP TABLE IF EXISTS node_group_tags;
+DROP TABLE IF EXISTS node_groups;
@@ -0,0 +1,50 @@
+-- Node groups
+CREATE TABLE IF NOT EXISTS node_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tags attached to node groups
+CREATE TABLE IF NOT EXISTS node_group_tags (
+    group_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY(group_id, tag),
+    FOREIGN KEY(group_id) REFERENCES node_groups(id) ON DELETE CASCADE
+);
+
+-- Node membership of groups
+CREATE TABLE IF NOT EXISTS node_group_members (
+    group_id INTEGER NOT NULL,
+    node_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(group_id, node_id),
+    FOREIGN KEY(group_id) REFERENCES node_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+-- Users entitled to whole groups
+CREATE TABLE IF NOT EXISTS user_node_groups (
+    user_id INTEGER NOT NULL,
+    group_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(user_id, group_id),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY(group_id) REFERENCES node_groups(id) ON DELETE CASCADE
+);
+
+-- Users entitled to every group carrying a tag
+CREATE TABLE IF NOT EXISTS user_node_tags (
+    user_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY(user_id, tag),
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_group_tags_tag ON node_group_tags(tag);
+CREATE INDEX IF NOT EXISTS idx_node_group_members_node ON node_group_members(node_id);
+CREATE INDEX IF NOT EXISTS idx_user_node_groups_group ON user_node_groups(group_id);
+CREATE INDEX IF NOT EXISTS idx_user_node_tags_tag ON user_node_tags(tag);
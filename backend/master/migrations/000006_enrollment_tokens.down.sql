@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_enrollment_tokens_hash;
+ALTER TABLE nodes DROP COLUMN hostname;
+ALTER TABLE nodes DROP COLUMN enrollment_token_id;
+DROP TABLE IF EXISTS enrollment_tokens;
@@ -0,0 +1,22 @@
+-- One-time enrollment tokens for node self-registration
+CREATE TABLE IF NOT EXISTS enrollment_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token_hash TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    description TEXT,
+    max_uses INTEGER NOT NULL DEFAULT 1,
+    used_count INTEGER NOT NULL DEFAULT 0,
+    group_id INTEGER,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+    last_used_at DATETIME,
+    created_by INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(group_id) REFERENCES node_groups(id) ON DELETE SET NULL
+);
+
+-- Nodes remember which enrollment token registered them
+ALTER TABLE nodes ADD COLUMN enrollment_token_id INTEGER;
+ALTER TABLE nodes ADD COLUMN hostname TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_enrollment_tokens_hash ON enrollment_tokens(token_hash);
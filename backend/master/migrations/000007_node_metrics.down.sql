@@ -0,0 +1,5 @@
+DELETE FROM system_configs WHERE key IN ('heartbeat_raw_retention_hours', 'metrics_rollup_retention_days');
+DROP INDEX IF EXISTS idx_metric_rollups_node_bucket;
+DROP TABLE IF EXISTS node_metric_rollups;
+DROP INDEX IF EXISTS idx_heartbeats_node_created;
+ALTER TABLE node_heartbeats DROP COLUMN disk_usage;
@@ -0,0 +1,28 @@
+-- Disk usage is now reported with every heartbeat
+ALTER TABLE node_heartbeats ADD COLUMN disk_usage REAL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_heartbeats_node_created ON node_heartbeats(node_id, created_at);
+
+-- Hourly downsampled heartbeat metrics
+CREATE TABLE IF NOT EXISTS node_metric_rollups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id INTEGER NOT NULL,
+    bucket_start DATETIME NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    cpu_avg REAL DEFAULT 0,
+    cpu_max REAL DEFAULT 0,
+    memory_avg REAL DEFAULT 0,
+    memory_max REAL DEFAULT 0,
+    disk_avg REAL DEFAULT 0,
+    disk_max REAL DEFAULT 0,
+    instance_count_avg REAL DEFAULT 0,
+    instance_count_max INTEGER DEFAULT 0,
+    UNIQUE(node_id, bucket_start),
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_metric_rollups_node_bucket ON node_metric_rollups(node_id, bucket_start);
+
+INSERT INTO system_configs (key, value, description) VALUES
+('heartbeat_raw_retention_hours', '48', '原始心跳保留时长（小时），超出后降采样为小时数据'),
+('metrics_rollup_retention_days', '90', '小时级节点指标保留天数');
@@ -0,0 +1,15 @@
+ALTER TABLE node_heartbeats DROP COLUMN load1;
+ALTER TABLE node_heartbeats DROP COLUMN network_tx_rate;
+ALTER TABLE node_heartbeats DROP COLUMN network_rx_rate;
+
+ALTER TABLE nodes DROP COLUMN port_range_end;
+ALTER TABLE nodes DROP COLUMN port_range_start;
+ALTER TABLE nodes DROP COLUMN agent_version;
+ALTER TABLE nodes DROP COLUMN snell_version;
+ALTER TABLE nodes DROP COLUMN kernel_version;
+ALTER TABLE nodes DROP COLUMN uptime_seconds;
+ALTER TABLE nodes DROP COLUMN load15;
+ALTER TABLE nodes DROP COLUMN load5;
+ALTER TABLE nodes DROP COLUMN load1;
+ALTER TABLE nodes DROP COLUMN network_tx_rate;
+ALTER TABLE nodes DROP COLUMN network_rx_rate;
@@ -0,0 +1,16 @@
+-- Extended heartbeat: network throughput, load, host information
+ALTER TABLE nodes ADD COLUMN network_rx_rate INTEGER DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN network_tx_rate INTEGER DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN load1 REAL DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN load5 REAL DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN load15 REAL DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN uptime_seconds INTEGER DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN kernel_version TEXT;
+ALTER TABLE nodes ADD COLUMN snell_version TEXT;
+ALTER TABLE nodes ADD COLUMN agent_version TEXT;
+ALTER TABLE nodes ADD COLUMN port_range_start INTEGER DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN port_range_end INTEGER DEFAULT 0;
+
+ALTER TABLE node_heartbeats ADD COLUMN network_rx_rate INTEGER DEFAULT 0;
+ALTER TABLE node_heartbeats ADD COLUMN network_tx_rate INTEGER DEFAULT 0;
+ALTER TABLE node_heartbeats ADD COLUMN load1 REAL DEFAULT 0;
@@ -0,0 +1,3 @@
+DELETE FROM system_configs WHERE key = 'status_page_enabled';
+DROP INDEX IF EXISTS idx_node_status_events_node_created;
+DROP TABLE IF EXISTS node_status_events;
@@ -0,0 +1,15 @@
+-- Node online/offline transition history
+CREATE TABLE IF NOT EXISTS node_status_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id INTEGER NOT NULL,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    cause TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_status_events_node_created ON node_status_events(node_id, created_at);
+
+INSERT INTO system_configs (key, value, description) VALUES
+('status_page_enabled', 'false', '是否开放公开节点状态页');
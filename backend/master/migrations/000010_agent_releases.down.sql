@@ -0,0 +1,6 @@
+DELETE FROM system_configs WHERE key = 'agent_auto_update';
+ALTER TABLE nodes DROP COLUMN agent_update_at;
+ALTER TABLE nodes DROP COLUMN agent_update_message;
+ALTER TABLE nodes DROP COLUMN agent_update_status;
+ALTER TABLE nodes DROP COLUMN agent_target_version;
+DROP TABLE IF EXISTS agent_releases;
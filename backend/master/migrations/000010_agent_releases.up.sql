@@ -0,0 +1,18 @@
+-- Published agent binaries and their checksums, one row per architecture
+CREATE TABLE IF NOT EXISTS agent_releases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version TEXT NOT NULL,
+    arch TEXT NOT NULL,
+    sha256 TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(version, arch)
+);
+
+-- Per-node agent version pin and self-update progress
+ALTER TABLE nodes ADD COLUMN agent_target_version TEXT;
+ALTER TABLE nodes ADD COLUMN agent_update_status TEXT;
+ALTER TABLE nodes ADD COLUMN agent_update_message TEXT;
+ALTER TABLE nodes ADD COLUMN agent_update_at DATETIME;
+
+INSERT INTO system_configs (key, value, description) VALUES
+('agent_auto_update', 'false', '未单独指定目标版本的节点是否自动升级到 agent_version');
@@ -0,0 +1,7 @@
+ALTER TABLE nodes DROP COLUMN snell_upgrade_at;
+ALTER TABLE nodes DROP COLUMN snell_upgrade_total;
+ALTER TABLE nodes DROP COLUMN snell_upgrade_done;
+ALTER TABLE nodes DROP COLUMN snell_upgrade_message;
+ALTER TABLE nodes DROP COLUMN snell_upgrade_status;
+ALTER TABLE nodes DROP COLUMN snell_target_version;
+DROP TABLE IF EXISTS snell_releases;
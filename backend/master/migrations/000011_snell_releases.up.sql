@@ -0,0 +1,17 @@
+-- Checksums of Snell Server archives, one row per architecture
+CREATE TABLE IF NOT EXISTS snell_releases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version TEXT NOT NULL,
+    arch TEXT NOT NULL,
+    sha256 TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(version, arch)
+);
+
+-- Per-node Snell version pin and upgrade progress
+ALTER TABLE nodes ADD COLUMN snell_target_version TEXT;
+ALTER TABLE nodes ADD COLUMN snell_upgrade_status TEXT;
+ALTER TABLE nodes ADD COLUMN snell_upgrade_message TEXT;
+ALTER TABLE nodes ADD COLUMN snell_upgrade_done INTEGER DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN snell_upgrade_total INTEGER DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN snell_upgrade_at DATETIME;
@@ -0,0 +1,4 @@
+ALTER TABLE nodes DROP COLUMN maintenance_message;
+ALTER TABLE nodes DROP COLUMN maintenance_stop_instances;
+ALTER TABLE nodes DROP COLUMN maintenance_end_at;
+ALTER TABLE nodes DROP COLUMN maintenance_start_at;
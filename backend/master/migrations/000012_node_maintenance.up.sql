@@ -0,0 +1,5 @@
+-- Scheduled maintenance window, independent of the online/offline status
+ALTER TABLE nodes ADD COLUMN maintenance_start_at DATETIME;
+ALTER TABLE nodes ADD COLUMN maintenance_end_at DATETIME;
+ALTER TABLE nodes ADD COLUMN maintenance_stop_instances BOOLEAN DEFAULT 0;
+ALTER TABLE nodes ADD COLUMN maintenance_message TEXT;
@@ -0,0 +1,8 @@
+-- Hashed tokens cannot be restored to plaintext; affected agents must be re-provisioned
+DELETE FROM system_configs WHERE key = 'node_token_grace_hours';
+DROP INDEX IF EXISTS idx_nodes_previous_token_hash;
+ALTER TABLE nodes DROP COLUMN token_rotated_at;
+ALTER TABLE nodes DROP COLUMN pending_token_sealed;
+ALTER TABLE nodes DROP COLUMN previous_token_expires_at;
+ALTER TABLE nodes DROP COLUMN previous_token_hash;
+ALTER TABLE nodes DROP COLUMN api_token_hashed;
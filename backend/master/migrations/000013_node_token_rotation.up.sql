@@ -0,0 +1,14 @@
+-- api_token now holds the SHA-256 of the node token; rows migrated from plaintext are hashed on startup
+ALTER TABLE nodes ADD COLUMN api_token_hashed BOOLEAN NOT NULL DEFAULT 0;
+
+-- Token rotation: the previous token stays valid until previous_token_expires_at, and the new token
+-- is kept encrypted until the agent picks it up
+ALTER TABLE nodes ADD COLUMN previous_token_hash TEXT;
+ALTER TABLE nodes ADD COLUMN previous_token_expires_at DATETIME;
+ALTER TABLE nodes ADD COLUMN pending_token_sealed TEXT;
+ALTER TABLE nodes ADD COLUMN token_rotated_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_nodes_previous_token_hash ON nodes(previous_token_hash);
+
+INSERT INTO system_configs (key, value, description) VALUES
+('node_token_grace_hours', '24', '轮换节点 API Token 后旧令牌继续有效的时长（小时）');
@@ -0,0 +1,6 @@
+DELETE FROM system_configs WHERE key IN ('probe_interval_seconds', 'probe_timeout_ms', 'probe_failure_threshold', 'probe_from_agents', 'probe_retention_days');
+ALTER TABLE snell_instances DROP COLUMN last_probe_latency_ms;
+ALTER TABLE snell_instances DROP COLUMN last_probe_at;
+ALTER TABLE snell_instances DROP COLUMN probe_failures;
+ALTER TABLE snell_instances DROP COLUMN reachability;
+DROP TABLE IF EXISTS instance_probes;
@@ -0,0 +1,27 @@
+-- TCP reachability probes of instance ports, taken by the master or by agents on other nodes
+CREATE TABLE IF NOT EXISTS instance_probes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    instance_id INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    reachable BOOLEAN NOT NULL,
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(instance_id) REFERENCES snell_instances(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_instance_probes_instance_created ON instance_probes(instance_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_instance_probes_created ON instance_probes(created_at);
+
+-- Reachability as seen from the master
+ALTER TABLE snell_instances ADD COLUMN reachability TEXT DEFAULT 'unknown';
+ALTER TABLE snell_instances ADD COLUMN probe_failures INTEGER DEFAULT 0;
+ALTER TABLE snell_instances ADD COLUMN last_probe_at DATETIME;
+ALTER TABLE snell_instances ADD COLUMN last_probe_latency_ms INTEGER DEFAULT 0;
+
+INSERT INTO system_configs (key, value, description) VALUES
+('probe_interval_seconds', '300', '实例端口可达性探测间隔（秒），0 表示关闭'),
+('probe_timeout_ms', '3000', '单次 TCP 探测超时（毫秒）'),
+('probe_failure_threshold', '3', '连续探测失败多少次后将实例标记为不可达'),
+('probe_from_agents', 'false', '是否由其他在线节点的 Agent 协助探测'),
+('probe_retention_days', '7', '探测记录保留天数');
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_node_endpoints_node;
+DROP TABLE IF EXISTS node_endpoints;
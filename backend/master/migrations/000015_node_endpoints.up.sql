@@ -0,0 +1,26 @@
+-- Typed addresses a node can be reached at; nodes.endpoint mirrors the preferred one
+CREATE TABLE IF NOT EXISTS node_endpoints (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    address TEXT NOT NULL,
+    label TEXT,
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(node_id) REFERENCES nodes(id) ON DELETE CASCADE,
+    UNIQUE(node_id, address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_endpoints_node ON node_endpoints(node_id, priority);
+
+INSERT INTO node_endpoints (node_id, type, address, label, priority)
+SELECT id,
+       CASE
+           WHEN endpoint LIKE '%:%' THEN 'ipv6'
+           WHEN endpoint GLOB '[0-9]*.[0-9]*.[0-9]*.[0-9]*' AND endpoint NOT GLOB '*[^0-9.]*' THEN 'ipv4'
+           ELSE 'domain'
+       END,
+       endpoint, '', 0
+FROM nodes
+WHERE endpoint IS NOT NULL AND endpoint <> '';
@@ -0,0 +1,2 @@
+DELETE FROM system_configs WHERE key = 'agent_settings';
+ALTER TABLE nodes DROP COLUMN agent_settings;
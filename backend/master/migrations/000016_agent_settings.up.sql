@@ -0,0 +1,5 @@
+-- Agent runtime settings profile delivered with config sync; per-node JSON overrides the global one
+ALTER TABLE nodes ADD COLUMN agent_settings TEXT DEFAULT '';
+
+INSERT INTO system_configs (key, value, description) VALUES
+('agent_settings', '{}', 'Agent 运行时设置（JSON），随配置同步下发，节点设置优先，Agent 本地锁定的项不受影响');
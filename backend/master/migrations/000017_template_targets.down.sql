@@ -0,0 +1,2 @@
+DELETE FROM templates WHERE name = 'default_clash';
+ALTER TABLE templates DROP COLUMN target;
@@ -0,0 +1,27 @@
+-- Templates are typed by the client format they render; each target has its own default
+ALTER TABLE templates ADD COLUMN target TEXT NOT NULL DEFAULT 'surge';
+
+INSERT INTO templates (name, description, content, is_default, target)
+VALUES (
+    'default_clash',
+    '默认 Clash/Mihomo 订阅模板',
+    'mixed-port: 7890
+allow-lan: false
+mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: {{node_names}}
+
+rules:
+  - MATCH,Proxy
+',
+    1,
+    'clash'
+)
+ON CONFLICT(name) DO NOTHING;
@@ -0,0 +1 @@
+DELETE FROM templates WHERE name IN ('default_stash', 'default_loon');
@@ -0,0 +1,40 @@
+-- Default templates for Stash and Loon subscriptions
+INSERT INTO templates (name, description, content, is_default, target)
+VALUES (
+    'default_stash',
+    '默认 Stash 订阅模板',
+    'mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: {{node_names}}
+
+rules:
+  - MATCH,Proxy
+',
+    1,
+    'stash'
+), (
+    'default_loon',
+    '默认 Loon 订阅模板',
+    '[General]
+skip-proxy = 192.168.0.0/16,10.0.0.0/8,172.16.0.0/12,localhost,*.local
+
+[Proxy]
+{{node_list}}
+
+[Proxy Group]
+Proxy = select,{{node_names}}
+
+[Rule]
+FINAL,Proxy
+',
+    1,
+    'loon'
+)
+ON CONFLICT(name) DO NOTHING;
@@ -0,0 +1 @@
+DELETE FROM system_configs WHERE key IN ('subscribe_update_interval', 'subscribe_managed_strict');
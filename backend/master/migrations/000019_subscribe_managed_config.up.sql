@@ -0,0 +1,4 @@
+-- Subscription refresh settings used for the managed-config line and profile headers
+INSERT INTO system_configs (key, value, description) VALUES
+('subscribe_update_interval', '86400', '订阅自动更新间隔（秒），写入 MANAGED-CONFIG 与 Profile-Update-Interval'),
+('subscribe_managed_strict', 'false', '订阅更新失败时是否强制客户端停止使用旧配置（MANAGED-CONFIG strict）');
@@ -0,0 +1,96 @@
+DELETE FROM system_configs WHERE key IN ('subscribe_group_test_url', 'subscribe_group_interval', 'subscribe_group_tolerance');
+ALTER TABLE nodes DROP COLUMN hide_from_auto_groups;
+
+UPDATE templates SET content = '[Proxy]\n{{node_list}}\n\n[Proxy Group]\nProxy = select, {{node_names}}\n\n[Rule]\nFINAL, Proxy'
+WHERE name = 'default_surge' AND content = '[Proxy]
+{{node_list}}
+
+[Proxy Group]
+{{auto_groups}}
+
+[Rule]
+FINAL, Proxy
+';
+
+UPDATE templates SET content = 'mixed-port: 7890
+allow-lan: false
+mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: {{node_names}}
+
+rules:
+  - MATCH,Proxy
+'
+WHERE name = 'default_clash' AND content = 'mixed-port: 7890
+allow-lan: false
+mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  {{auto_groups}}
+
+rules:
+  - MATCH,Proxy
+';
+
+UPDATE templates SET content = 'mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: {{node_names}}
+
+rules:
+  - MATCH,Proxy
+'
+WHERE name = 'default_stash' AND content = 'mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  {{auto_groups}}
+
+rules:
+  - MATCH,Proxy
+';
+
+UPDATE templates SET content = '[General]
+skip-proxy = 192.168.0.0/16,10.0.0.0/8,172.16.0.0/12,localhost,*.local
+
+[Proxy]
+{{node_list}}
+
+[Proxy Group]
+Proxy = select,{{node_names}}
+
+[Rule]
+FINAL,Proxy
+'
+WHERE name = 'default_loon' AND content = '[General]
+skip-proxy = 192.168.0.0/16,10.0.0.0/8,172.16.0.0/12,localhost,*.local
+
+[Proxy]
+{{node_list}}
+
+[Proxy Group]
+{{auto_groups}}
+
+[Rule]
+FINAL,Proxy
+';
@@ -0,0 +1,102 @@
+-- Per-region url-test/fallback proxy groups in subscriptions
+ALTER TABLE nodes ADD COLUMN hide_from_auto_groups INTEGER NOT NULL DEFAULT 0;
+
+INSERT INTO system_configs (key, value, description) VALUES
+('subscribe_group_test_url', 'http://www.gstatic.com/generate_204', '订阅自动代理组的测速地址'),
+('subscribe_group_interval', '300', '订阅自动代理组的测速间隔（秒）'),
+('subscribe_group_tolerance', '50', '订阅 url-test 代理组的切换容差（毫秒）');
+
+-- Switch the shipped default templates to auto groups unless an admin has edited them
+UPDATE templates SET content = '[Proxy]
+{{node_list}}
+
+[Proxy Group]
+{{auto_groups}}
+
+[Rule]
+FINAL, Proxy
+'
+WHERE name = 'default_surge' AND content = '[Proxy]\n{{node_list}}\n\n[Proxy Group]\nProxy = select, {{node_names}}\n\n[Rule]\nFINAL, Proxy';
+
+UPDATE templates SET content = 'mixed-port: 7890
+allow-lan: false
+mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  {{auto_groups}}
+
+rules:
+  - MATCH,Proxy
+'
+WHERE name = 'default_clash' AND content = 'mixed-port: 7890
+allow-lan: false
+mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: {{node_names}}
+
+rules:
+  - MATCH,Proxy
+';
+
+UPDATE templates SET content = 'mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  {{auto_groups}}
+
+rules:
+  - MATCH,Proxy
+'
+WHERE name = 'default_stash' AND content = 'mode: rule
+log-level: info
+
+proxies:
+  {{node_list}}
+
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: {{node_names}}
+
+rules:
+  - MATCH,Proxy
+';
+
+UPDATE templates SET content = '[General]
+skip-proxy = 192.168.0.0/16,10.0.0.0/8,172.16.0.0/12,localhost,*.local
+
+[Proxy]
+{{node_list}}
+
+[Proxy Group]
+{{auto_groups}}
+
+[Rule]
+FINAL,Proxy
+'
+WHERE name = 'default_loon' AND content = '[General]
+skip-proxy = 192.168.0.0/16,10.0.0.0/8,172.16.0.0/12,localhost,*.local
+
+[Proxy]
+{{node_list}}
+
+[Proxy Group]
+Proxy = select,{{node_names}}
+
+[Rule]
+FINAL,Proxy
+';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_subscribe_tokens_user;
+ALTER TABLE subscribe_tokens DROP COLUMN last_user_agent;
+ALTER TABLE subscribe_tokens DROP COLUMN last_access_ip;
+ALTER TABLE subscribe_tokens DROP COLUMN enabled;
+ALTER TABLE subscribe_tokens DROP COLUMN target;
+ALTER TABLE subscribe_tokens DROP COLUMN label;
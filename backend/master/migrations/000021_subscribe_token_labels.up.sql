@@ -0,0 +1,8 @@
+-- Multiple labeled subscription tokens per user, each with its own format and enabled flag
+ALTER TABLE subscribe_tokens ADD COLUMN label TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscribe_tokens ADD COLUMN target TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscribe_tokens ADD COLUMN enabled INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE subscribe_tokens ADD COLUMN last_access_ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscribe_tokens ADD COLUMN last_user_agent TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_subscribe_tokens_user ON subscribe_tokens(user_id);
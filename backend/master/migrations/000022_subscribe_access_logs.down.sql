@@ -0,0 +1,7 @@
+DELETE FROM system_configs WHERE key IN ('subscribe_leak_window_hours', 'subscribe_leak_max_ips', 'subscribe_leak_max_networks', 'subscribe_leak_auto_rotate', 'subscribe_access_log_retention_days');
+ALTER TABLE subscribe_tokens DROP COLUMN rotated_at;
+ALTER TABLE subscribe_tokens DROP COLUMN flag_reason;
+ALTER TABLE subscribe_tokens DROP COLUMN flagged_at;
+DROP INDEX IF EXISTS idx_subscribe_access_logs_created;
+DROP INDEX IF EXISTS idx_subscribe_access_logs_token_time;
+DROP TABLE IF EXISTS subscribe_access_logs;
@@ -0,0 +1,26 @@
+-- Per-fetch subscription access log used to detect tokens shared beyond their owner
+CREATE TABLE IF NOT EXISTS subscribe_access_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    network TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(token_id) REFERENCES subscribe_tokens(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscribe_access_logs_token_time ON subscribe_access_logs(token_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_subscribe_access_logs_created ON subscribe_access_logs(created_at);
+
+ALTER TABLE subscribe_tokens ADD COLUMN flagged_at DATETIME;
+ALTER TABLE subscribe_tokens ADD COLUMN flag_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscribe_tokens ADD COLUMN rotated_at DATETIME;
+
+INSERT INTO system_configs (key, value, description) VALUES
+('subscribe_leak_window_hours', '24', '订阅泄露检测的统计窗口（小时）'),
+('subscribe_leak_max_ips', '30', '窗口内单个订阅令牌允许的不同 IP 数，超过则标记，0 表示不按 IP 检测；移动网络的出口 IP 经常变化，不宜设置过低'),
+('subscribe_leak_max_networks', '15', '窗口内单个订阅令牌允许的不同网段数（IPv4 /24、IPv6 /48，近似 ASN），超过则标记，0 表示不按网段检测；移动用户会在同一运营商的多个网段间切换，不宜设置过低'),
+('subscribe_leak_auto_rotate', 'false', '令牌被标记时是否自动重新生成；来源 IP 取自连接地址，位于反向代理之后时需配置 server.trusted_proxies，否则来源可被伪造'),
+('subscribe_access_log_retention_days', '30', '订阅访问日志保留天数');
@@ -0,0 +1,14 @@
+-- Named rule lists shared by subscription templates
+CREATE TABLE IF NOT EXISTS rule_sets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT 'inline',
+    behavior TEXT NOT NULL DEFAULT 'classical',
+    content TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    policy TEXT NOT NULL DEFAULT 'Proxy',
+    interval INTEGER NOT NULL DEFAULT 86400,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
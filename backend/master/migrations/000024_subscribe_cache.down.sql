@@ -0,0 +1 @@
+DELETE FROM system_configs WHERE key IN ('subscribe_cache_ttl_seconds', 'subscribe_rate_limit_token', 'subscribe_rate_limit_ip');
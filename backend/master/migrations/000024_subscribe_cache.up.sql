@@ -0,0 +1,4 @@
+INSERT INTO system_configs (key, value, description) VALUES
+('subscribe_cache_ttl_seconds', '60', '订阅渲染结果的内存缓存时间（秒），相关数据变更时立即失效，0 表示不缓存'),
+('subscribe_rate_limit_token', '30', '单个订阅令牌每分钟允许的请求数，0 表示不限制'),
+('subscribe_rate_limit_ip', '60', '单个 IP 每分钟允许的订阅请求数，0 表示不限制');
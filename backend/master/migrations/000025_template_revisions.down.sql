@@ -0,0 +1,2 @@
+ALTER TABLE subscribe_tokens DROP COLUMN template_revision_id;
+DROP TABLE IF EXISTS template_revisions;
@@ -0,0 +1,22 @@
+-- Immutable history of template saves; tokens may pin one revision of their template
+CREATE TABLE IF NOT EXISTS template_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT 'surge',
+    content TEXT NOT NULL,
+    diff TEXT NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    created_by INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(template_id) REFERENCES templates(id) ON DELETE CASCADE,
+    UNIQUE(template_id, revision)
+);
+
+-- Existing templates start from revision 1
+INSERT INTO template_revisions (template_id, revision, name, description, target, content, note, created_at)
+SELECT id, 1, name, COALESCE(description, ''), target, content, 'initial', created_at FROM templates;
+
+ALTER TABLE subscribe_tokens ADD COLUMN template_revision_id INTEGER;
@@ -0,0 +1,2 @@
+-- Agent 上报的心跳间隔（秒），用于计算在线率
+ALTER TABLE nodes ADD COLUMN heartbeat_interval INTEGER DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE node_metric_rollups DROP COLUMN disk_samples;
+ALTER TABLE node_metric_rollups DROP COLUMN memory_samples;
+ALTER TABLE node_metric_rollups DROP COLUMN cpu_samples;
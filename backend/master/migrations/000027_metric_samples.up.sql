@@ -0,0 +1,6 @@
+-- 未采集的 CPU、内存、磁盘指标以 NULL 记录，小时数据按各项实际样本数加权
+ALTER TABLE node_metric_rollups ADD COLUMN cpu_samples INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE node_metric_rollups ADD COLUMN memory_samples INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE node_metric_rollups ADD COLUMN disk_samples INTEGER NOT NULL DEFAULT 0;
+
+UPDATE node_metric_rollups SET cpu_samples = samples, memory_samples = samples, disk_samples = samples;
@@ -0,0 +1,2 @@
+DELETE FROM system_configs WHERE key = 'agent_allow_downgrade';
+ALTER TABLE nodes DROP COLUMN agent_allow_downgrade;
@@ -0,0 +1,5 @@
+-- Rollouts must explicitly allow installing a version older than the running agent
+ALTER TABLE nodes ADD COLUMN agent_allow_downgrade BOOLEAN NOT NULL DEFAULT 0;
+
+INSERT INTO system_configs (key, value, description) VALUES
+('agent_allow_downgrade', 'false', '全局升级目标低于节点当前版本时是否允许降级');
@@ -0,0 +1 @@
+ALTER TABLE instance_probes DROP COLUMN address;
@@ -0,0 +1,2 @@
+-- Instances are probed on every node address; record which one each probe used
+ALTER TABLE instance_probes ADD COLUMN address TEXT DEFAULT '';
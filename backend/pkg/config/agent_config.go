@@ -24,6 +24,7 @@ type AgentSettings struct {
 	CountryCode           string `mapstructure:"country_code"`
 	MasterURL             string `mapstructure:"master_url"`
 	APIToken              string `mapstructure:"api_token"`
+	EnrollmentToken       string `mapstructure:"enrollment_token"`
 	InstanceDir           string `mapstructure:"instance_dir"`
 	PortRangeStart        int    `mapstructure:"port_range_start"`
 	PortRangeEnd          int    `mapstructure:"port_range_end"`
@@ -34,12 +35,39 @@ type AgentSettings struct {
 	LogLevel              string `mapstructure:"log_level"`
 	LogFormat             string `mapstructure:"log_format"`
 	LogFile               string `mapstructure:"log_file"`
+	AutoUpdate            bool   `mapstructure:"auto_update"`
+	UpdateCheckInterval   int    `mapstructure:"update_check_interval"`
+	ServiceName           string `mapstructure:"service_name"`
+	ControlSocket         string `mapstructure:"control_socket"`
+	InstanceLogMaxSizeMB  int    `mapstructure:"instance_log_max_size_mb"`
+	InstanceLogMaxAgeDays int    `mapstructure:"instance_log_max_age_days"`
+	InstanceLogMaxBackups int    `mapstructure:"instance_log_max_backups"`
+	// LocalOverrides 列出的设置以本地配置为准，忽略 Master 下发的值
+	LocalOverrides []string `mapstructure:"local_overrides"`
+}
+
+// RemoteSettingKeys Master 可远程调整的设置项。
+var RemoteSettingKeys = []string{
+	"heartbeat_interval",
+	"config_sync_interval",
+	"traffic_report_interval",
+	"update_check_interval",
+	"log_level",
+	"monitor.enable_cpu",
+	"monitor.enable_memory",
+	"monitor.enable_disk",
+	"monitor.enable_network",
+	"monitor.enable_load",
+	"monitor.enable_traffic",
 }
 
 // MonitorSettings 控制监控模块的开关。
 type MonitorSettings struct {
 	EnableCPU     bool `mapstructure:"enable_cpu"`
 	EnableMemory  bool `mapstructure:"enable_memory"`
+	EnableDisk    bool `mapstructure:"enable_disk"`
+	EnableNetwork bool `mapstructure:"enable_network"`
+	EnableLoad    bool `mapstructure:"enable_load"`
 	EnableTraffic bool `mapstructure:"enable_traffic"`
 }
 
@@ -61,6 +89,7 @@ func LoadAgentConfig(path string) (*AgentConfig, error) {
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 	bindAgentEnv(v)
+	setAgentDefaults(v)
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("read agent config: %w", err)
@@ -96,8 +125,8 @@ func (c *AgentConfig) Validate() error {
 		return fmt.Errorf("agent.country_code is required")
 	case trim(agent.MasterURL) == "":
 		return fmt.Errorf("agent.master_url is required")
-	case trim(agent.APIToken) == "":
-		return fmt.Errorf("agent.api_token is required")
+	case trim(agent.APIToken) == "" && trim(agent.EnrollmentToken) == "":
+		return fmt.Errorf("agent.api_token or agent.enrollment_token is required")
 	case trim(agent.InstanceDir) == "":
 		return fmt.Errorf("agent.instance_dir is required")
 	case trim(agent.SnellBinary) == "":
@@ -125,6 +154,16 @@ func (c *AgentConfig) Validate() error {
 	if agent.TrafficReportInterval <= 0 {
 		return fmt.Errorf("agent.traffic_report_interval must be greater than zero")
 	}
+	if agent.AutoUpdate && agent.UpdateCheckInterval <= 0 {
+		return fmt.Errorf("agent.update_check_interval must be greater than zero")
+	}
+	if agent.InstanceLogMaxSizeMB < 0 || agent.InstanceLogMaxAgeDays < 0 || agent.InstanceLogMaxBackups < 0 {
+		return fmt.Errorf("agent.instance_log_* settings must not be negative")
+	}
+
+	if err := validateLocalOverrides(agent.LocalOverrides); err != nil {
+		return err
+	}
 
 	if err := validateLogFormat(agent.LogFormat); err != nil {
 		return err
@@ -149,6 +188,22 @@ func validatePortRange(start, end int) error {
 	return nil
 }
 
+func validateLocalOverrides(keys []string) error {
+	for _, key := range keys {
+		known := false
+		for _, remote := range RemoteSettingKeys {
+			if key == remote {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unsupported agent.local_overrides entry %q", key)
+		}
+	}
+	return nil
+}
+
 func validateLogFormat(format string) error {
 	switch strings.ToLower(format) {
 	case "json", "text":
@@ -171,6 +226,28 @@ func validateLogLevel(level string) error {
 	return nil
 }
 
+// setAgentDefaults 为未在配置文件中出现的可选项设置默认值，监控开关默认全部启用。
+func setAgentDefaults(v *viper.Viper) {
+	v.SetDefault("agent.auto_update", true)
+	v.SetDefault("agent.update_check_interval", 600)
+	v.SetDefault("agent.service_name", "snell-agent")
+	v.SetDefault("agent.control_socket", "/run/snell-agent.sock")
+	v.SetDefault("agent.instance_log_max_size_mb", 10)
+	v.SetDefault("agent.instance_log_max_age_days", 7)
+	v.SetDefault("agent.instance_log_max_backups", 5)
+
+	for _, key := range []string{
+		"monitor.enable_cpu",
+		"monitor.enable_memory",
+		"monitor.enable_disk",
+		"monitor.enable_network",
+		"monitor.enable_load",
+		"monitor.enable_traffic",
+	} {
+		v.SetDefault(key, true)
+	}
+}
+
 func bindAgentEnv(v *viper.Viper) {
 	mappings := map[string]string{
 		"agent.node_name":               "AGENT_NODE_NAME",
@@ -178,6 +255,7 @@ func bindAgentEnv(v *viper.Viper) {
 		"agent.country_code":            "AGENT_COUNTRY_CODE",
 		"agent.master_url":              "AGENT_MASTER_URL",
 		"agent.api_token":               "AGENT_API_TOKEN",
+		"agent.enrollment_token":        "AGENT_ENROLLMENT_TOKEN",
 		"agent.instance_dir":            "AGENT_INSTANCE_DIR",
 		"agent.port_range_start":        "AGENT_PORT_RANGE_START",
 		"agent.port_range_end":          "AGENT_PORT_RANGE_END",
@@ -188,8 +266,14 @@ func bindAgentEnv(v *viper.Viper) {
 		"agent.log_level":               "AGENT_LOG_LEVEL",
 		"agent.log_format":              "AGENT_LOG_FORMAT",
 		"agent.log_file":                "AGENT_LOG_FILE",
+		"agent.auto_update":             "AGENT_AUTO_UPDATE",
+		"agent.update_check_interval":   "AGENT_UPDATE_CHECK_INTERVAL",
+		"agent.service_name":            "AGENT_SERVICE_NAME",
 		"monitor.enable_cpu":            "AGENT_MONITOR_ENABLE_CPU",
 		"monitor.enable_memory":         "AGENT_MONITOR_ENABLE_MEMORY",
+		"monitor.enable_disk":           "AGENT_MONITOR_ENABLE_DISK",
+		"monitor.enable_network":        "AGENT_MONITOR_ENABLE_NETWORK",
+		"monitor.enable_load":           "AGENT_MONITOR_ENABLE_LOAD",
 		"monitor.enable_traffic":        "AGENT_MONITOR_ENABLE_TRAFFIC",
 	}
 
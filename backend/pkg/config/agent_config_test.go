@@ -26,6 +26,7 @@ func TestLoadAgentConfig(t *testing.T) {
   log_level: info
   log_format: json
   log_file: /var/log/snell-agent.log
+  local_overrides: ["log_level", "monitor.enable_traffic"]
 monitor:
   enable_cpu: true
   enable_memory: true
@@ -43,6 +44,22 @@ monitor:
 	if !cfg.Monitor.EnableCPU || !cfg.Monitor.EnableMemory || cfg.Monitor.EnableTraffic {
 		t.Fatalf("monitor flags not parsed correctly: %+v", cfg.Monitor)
 	}
+	if !cfg.Monitor.EnableDisk || !cfg.Monitor.EnableNetwork || !cfg.Monitor.EnableLoad {
+		t.Fatalf("unset monitor flags should default to true: %+v", cfg.Monitor)
+	}
+	if !cfg.Agent.AutoUpdate || cfg.Agent.UpdateCheckInterval != 600 || cfg.Agent.ServiceName != "snell-agent" || cfg.Agent.ControlSocket != "/run/snell-agent.sock" {
+		t.Fatalf("update defaults not applied: %+v", cfg.Agent)
+	}
+	if cfg.Agent.InstanceLogMaxSizeMB != 10 || cfg.Agent.InstanceLogMaxAgeDays != 7 || cfg.Agent.InstanceLogMaxBackups != 5 {
+		t.Fatalf("instance log defaults not applied: %+v", cfg.Agent)
+	}
+	if len(cfg.Agent.LocalOverrides) != 2 || cfg.Agent.LocalOverrides[1] != "monitor.enable_traffic" {
+		t.Fatalf("local overrides not parsed: %v", cfg.Agent.LocalOverrides)
+	}
+	cfg.Agent.LocalOverrides = []string{"log_format"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "local_overrides") {
+		t.Fatalf("expected local_overrides validation error, got %v", err)
+	}
 }
 
 func TestLoadAgentConfigEnvOverride(t *testing.T) {
@@ -121,3 +138,74 @@ func writeTempAgentConfig(t *testing.T, content string) string {
 	}
 	return path
 }
+
+func TestLoadAgentConfigEnrollmentToken(t *testing.T) {
+	t.Parallel()
+
+	cfgPath := writeTempAgentConfig(t, `agent:
+  node_name: test-node
+  location: Hong Kong
+  country_code: HK
+  master_url: https://master.example.com
+  enrollment_token: join-token
+  instance_dir: /var/lib/snell
+  port_range_start: 10000
+  port_range_end: 20000
+  snell_binary: /usr/local/bin/snell-server
+  heartbeat_interval: 30
+  config_sync_interval: 60
+  traffic_report_interval: 300
+  log_level: info
+  log_format: json
+`)
+
+	cfg, err := LoadAgentConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadAgentConfig() error = %v", err)
+	}
+	if cfg.Agent.APIToken != "" || cfg.Agent.EnrollmentToken != "join-token" {
+		t.Fatalf("unexpected tokens: %+v", cfg.Agent)
+	}
+}
+
+func TestUpdateAgentConfigValues(t *testing.T) {
+	t.Parallel()
+
+	cfgPath := writeTempAgentConfig(t, `# Agent 配置
+agent:
+  node_name: test-node
+  # 注册令牌
+  enrollment_token: "join-token"
+  log_level: info
+
+monitor:
+  enable_cpu: true
+`)
+
+	err := UpdateAgentConfigValues(cfgPath, "agent", map[string]string{
+		"api_token":        "permanent",
+		"enrollment_token": "",
+	})
+	if err != nil {
+		t.Fatalf("UpdateAgentConfigValues() error = %v", err)
+	}
+
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	want := `# Agent 配置
+agent:
+  api_token: "permanent"
+  node_name: test-node
+  # 注册令牌
+  enrollment_token: ""
+  log_level: info
+
+monitor:
+  enable_cpu: true
+`
+	if string(data) != want {
+		t.Fatalf("unexpected config:\n%s", data)
+	}
+}
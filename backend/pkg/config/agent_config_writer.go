@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UpdateAgentConfigValues 在保留注释与其余内容的前提下，修改配置文件中某一顶层段落下的字符串键值。
+// 不存在的键会追加到段落开头，不存在的段落会追加到文件末尾。文件通过临时文件原子替换。
+func UpdateAgentConfigValues(path, section string, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat agent config: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read agent config: %w", err)
+	}
+
+	updated := setSectionValues(string(data), section, values)
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, []byte(updated), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write agent config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("replace agent config: %w", err)
+	}
+	return nil
+}
+
+func setSectionValues(content, section string, values map[string]string) string {
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	start, end := findSection(lines, section)
+	if start < 0 {
+		lines = append(lines, section+":")
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("  %s: %s", key, strconv.Quote(values[key])))
+		}
+		return strings.Join(lines, "\n") + "\n"
+	}
+
+	indent := "  "
+	remaining := make(map[string]struct{}, len(values))
+	for _, key := range keys {
+		remaining[key] = struct{}{}
+	}
+	for i := start + 1; i < end; i++ {
+		trimmed := strings.TrimLeft(lines[i], " \t")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		lineIndent := lines[i][:len(lines[i])-len(trimmed)]
+		if indent == "  " && lineIndent != "" {
+			indent = lineIndent
+		}
+		key, _, ok := strings.Cut(trimmed, ":")
+		if !ok || lineIndent != indent {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if value, found := values[key]; found {
+			lines[i] = fmt.Sprintf("%s%s: %s", lineIndent, key, strconv.Quote(value))
+			delete(remaining, key)
+		}
+	}
+
+	if len(remaining) > 0 {
+		inserted := make([]string, 0, len(remaining))
+		for _, key := range keys {
+			if _, ok := remaining[key]; ok {
+				inserted = append(inserted, fmt.Sprintf("%s%s: %s", indent, key, strconv.Quote(values[key])))
+			}
+		}
+		lines = append(lines[:start+1], append(inserted, lines[start+1:]...)...)
+	}
+
+	result := strings.Join(lines, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result
+}
+
+// findSection 返回顶层段落标题所在行及其结束行（不含）。
+func findSection(lines []string, section string) (int, int) {
+	start := -1
+	for i, line := range lines {
+		if start < 0 {
+			if strings.TrimRight(line, " \t") == section+":" {
+				start = i
+			}
+			continue
+		}
+		if line != "" && line[0] != ' ' && line[0] != '\t' && line[0] != '#' {
+			return start, i
+		}
+	}
+	if start < 0 {
+		return -1, -1
+	}
+	return start, len(lines)
+}
@@ -62,3 +62,15 @@ func WithModule(module string) *logrus.Entry {
 func AgentLogger() *logrus.Logger {
 	return agentLogger
 }
+
+// SetAgentLevel 在运行中调整日志级别。
+func SetAgentLevel(level string) error {
+	lvl, err := logrus.ParseLevel(strings.ToLower(strings.TrimSpace(level)))
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	agentLoggerMu.Lock()
+	defer agentLoggerMu.Unlock()
+	agentLogger.SetLevel(lvl)
+	return nil
+}
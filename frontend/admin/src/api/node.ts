@@ -4,7 +4,6 @@ import request from '@/utils/request'
 export interface Node {
     id: number
     name: string
-    api_token: string
     endpoint: string
     location?: string
     country_code?: string
@@ -42,9 +41,14 @@ export function getNodeList() {
     })
 }
 
+// Create Node Response, api_token is only returned once
+export interface CreateNodeResponse extends Node {
+    api_token: string
+}
+
 // Create Node
 export function createNode(data: CreateNodeRequest) {
-    return request<Node>({
+    return request<CreateNodeResponse>({
         url: '/admin/nodes',
         method: 'post',
         data
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -21,6 +22,8 @@ type ServerConfig struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
 	Mode string `mapstructure:"mode"`
+	// TrustedProxies 允许设置 X-Forwarded-For 的反向代理地址或网段，为空时只使用连接的对端地址
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // DatabaseConfig 定义 SQLite 相关配置。
@@ -82,6 +85,13 @@ func (c *Config) Validate() error {
 	if c.Server.Port <= 0 {
 		return fmt.Errorf("server.port must be greater than zero")
 	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("server.trusted_proxies: invalid address %q", proxy)
+			}
+		}
+	}
 	if c.Database.Path == "" {
 		return fmt.Errorf("database.path is required")
 	}
@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	to := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	want := "--- r1\n+++ r2\n" +
+		"@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n" +
+		"@@ -10,3 +10,4 @@\n j\n k\n l\n+m\n"
+	if got := UnifiedDiff("r1", "r2", from, to); got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+	// 间隔不超过两倍上下文的改动合并为一段
+	merged := "--- r1\n+++ r2\n@@ -1,6 +1,6 @@\n a\n-b\n+B\n c\n d\n-e\n+E\n f\n"
+	if got := UnifiedDiff("r1", "r2", "a\nb\nc\nd\ne\nf\n", "a\nB\nc\nd\nE\nf\n"); got != merged {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, merged)
+	}
+	if got := UnifiedDiff("r1", "r2", from, from); got != "" {
+		t.Fatalf("identical text should produce no diff, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffEmptySide(t *testing.T) {
+	want := "--- r1\n+++ r2\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := UnifiedDiff("r1", "r2", "", "x\ny\n"); got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+	want = "--- r1\n+++ r2\n@@ -1 +0,0 @@\n-x\n"
+	if got := UnifiedDiff("r1", "r2", "x\n", ""); got != want {
+		t.Fatalf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 )
@@ -16,6 +19,60 @@ func GenerateSubscribeToken() (string, error) {
 	return randomHex(32)
 }
 
+// GenerateEnrollmentToken 生成节点自助注册使用的一次性令牌。
+func GenerateEnrollmentToken() (string, error) {
+	return randomHex(24)
+}
+
+// HashToken 返回令牌的 SHA-256 十六进制摘要，用于存储与比对。
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Seal 使用由 key 派生的 AES-GCM 密钥加密 plaintext，返回十六进制密文。
+func Seal(key, plaintext string) (string, error) {
+	aead, err := sealCipher(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return hex.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 生成的密文，key 不匹配时返回错误。
+func Open(key, sealed string) (string, error) {
+	aead, err := sealCipher(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("decode sealed value: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("sealed value too short")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("open sealed value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func sealCipher(key string) (cipher.AEAD, error) {
+	derived := sha256.Sum256([]byte("snell-master/seal:" + key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
 func randomHex(n int) (string, error) {
 	buf := make([]byte, n)
 	if _, err := rand.Read(buf); err != nil {
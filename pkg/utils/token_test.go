@@ -0,0 +1,46 @@
+package utils
+
+import "testing"
+
+func TestHashToken(t *testing.T) {
+	token, err := GenerateEnrollmentToken()
+	if err != nil {
+		t.Fatalf("generate enrollment token failed: %v", err)
+	}
+	if len(token) != 48 {
+		t.Fatalf("unexpected token length: %d", len(token))
+	}
+
+	hash := HashToken(token)
+	if hash == token || len(hash) != 64 {
+		t.Fatalf("unexpected hash: %s", hash)
+	}
+	if HashToken(token) != hash {
+		t.Fatalf("hash should be deterministic")
+	}
+	if HashToken(token+"x") == hash {
+		t.Fatalf("different tokens should not share a hash")
+	}
+}
+
+func TestSealAndOpen(t *testing.T) {
+	token, _ := GenerateAPIToken()
+
+	sealed, err := Seal("secret", token)
+	if err != nil {
+		t.Fatalf("seal failed: %v", err)
+	}
+	if sealed == token {
+		t.Fatalf("sealed value should differ from plaintext")
+	}
+	opened, err := Open("secret", sealed)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if opened != token {
+		t.Fatalf("unexpected plaintext: %s", opened)
+	}
+	if _, err := Open("other", sealed); err == nil {
+		t.Fatalf("wrong key should not open the sealed value")
+	}
+}